@@ -42,6 +42,65 @@ type Config struct {
 	BedrockMaxTokens   int
 	BedrockTemperature float64
 
+	// OpenAI-compatible providers (Azure OpenAI, vLLM, LiteLLM, Groq, Together, ...)
+	OpenAICompatibleProviders []OpenAICompatibleProvider
+
+	// Local LLM (Ollama / llama.cpp server)
+	LocalLLMBaseURL     string // e.g. http://localhost:11434 (empty = disabled)
+	LocalLLMAPI         string // "ollama" (native /api/chat) or "openai" (OpenAI-compatible /v1, e.g. llama.cpp server)
+	LocalLLMModel       string
+	LocalLLMMaxTokens   int
+	LocalLLMTemperature float64
+
+	// Provider Failover
+	FailoverProviders   string // Comma-separated fallback providers, tried in order ("" = no failover, see below)
+	FailoverMaxRetries  int    // Retries per provider for retryable errors
+	FailoverBaseDelayMs int    // First backoff delay in milliseconds
+	FailoverMaxDelayMs  int    // Backoff cap in milliseconds
+
+	// Tool calling
+	ToolMaxRounds int // Max model → tool → model rounds per answer
+
+	// Structured output
+	StructuredOutputMaxRetries int // Repair attempts when a reply does not match its JSON schema
+
+	// Conversation titles
+	TitleProvider string // Provider that writes conversation titles ("" = the provider that answered)
+	TitleModel    string // Model for titles ("" = the provider's default model)
+
+	// Conversation summaries
+	SummaryProvider     string // Provider that summarizes long sessions ("" = the provider that answered)
+	SummaryModel        string // Model for summaries ("" = the provider's default model)
+	SummaryTokenBudget  int    // Unsummarized history tokens that trigger a summary
+	SummaryKeepMessages int    // Recent messages left out of the summary
+
+	// Search
+	EmbeddingModel      string // OpenAI embedding model (vectors are 1536-dimensional)
+	SearchEmbedMessages bool   // Embed chat messages for semantic search (needs pgvector)
+
+	// Knowledge bases (retrieval-augmented generation)
+	KnowledgeChunkSize    int     // Characters per chunk of a knowledge base document
+	KnowledgeChunkOverlap int     // Characters shared by consecutive chunks
+	KnowledgeTopK         int     // Chunks retrieved for each question
+	KnowledgeMinScore     float64 // Lowest cosine similarity of a retrieved chunk (0-1)
+
+	// OCR (Tesseract) for scanned PDFs and images
+	OCREnabled      bool
+	OCRBinaryPath   string // tesseract binary (a name is looked up in PATH)
+	OCRRendererPath string // pdftoppm binary that renders PDF pages to images for OCR
+	OCRLanguages    string // Tesseract languages, e.g. "tha+eng"
+	OCRDataDir      string // Directory of the .traineddata files ("" = Tesseract's default)
+	OCRTempDir      string
+	OCRDPI          int // Resolution PDF pages are rendered at
+	OCRMinPageChars int // PDF pages with less extracted text than this are read with OCR
+	OCRMaxPages     int // Most pages of one PDF read with OCR
+
+	// Pricing (cost tracking)
+	Pricing PricingTable
+
+	// Model catalog (validation, limits, context windows)
+	ModelCatalog []CatalogModel
+
 	// ElevenLabs
 	ElevenLabsAPIKey string
 
@@ -62,6 +121,19 @@ type Config struct {
 	WhisperSupportedModels  string // Comma-separated list of supported models
 }
 
+// OpenAICompatibleProvider is a named provider that speaks the OpenAI chat API
+type OpenAICompatibleProvider struct {
+	Name          string   // Provider name used by requests and personas (e.g. "groq")
+	BaseURL       string   // API base URL (e.g. https://api.groq.com/openai/v1)
+	APIKey        string
+	DefaultModel  string
+	AllowedModels []string // Empty = any model
+	APIType       string   // "openai" (default) or "azure"
+	APIVersion    string   // Azure only
+	Vision        bool     // Models accept image input
+	Local         bool     // Runs on-premises: requests never fail over to another provider
+}
+
 var AppConfig *Config
 
 // LoadConfig loads configuration from .env.development file
@@ -96,6 +168,12 @@ func LoadConfig() *Config {
 		bedrockTemperature = 0.7
 	}
 
+	// Parse local LLM temperature (default: 0.7)
+	localLLMTemperature, err := strconv.ParseFloat(getEnv("LOCAL_LLM_TEMPERATURE", "0.7"), 64)
+	if err != nil {
+		localLLMTemperature = 0.7
+	}
+
 	config := &Config{
 		// Server
 		Port:    getEnv("PORT", "3000"),
@@ -125,6 +203,67 @@ func LoadConfig() *Config {
 		BedrockMaxTokens:   bedrockMaxTokens,
 		BedrockTemperature: bedrockTemperature,
 
+		// OpenAI-compatible providers
+		OpenAICompatibleProviders: loadOpenAICompatibleProviders(),
+
+		// Local LLM (Ollama / llama.cpp server)
+		LocalLLMBaseURL:     strings.TrimSuffix(getEnv("LOCAL_LLM_BASE_URL", ""), "/"),
+		LocalLLMAPI:         strings.ToLower(getEnv("LOCAL_LLM_API", "ollama")),
+		LocalLLMModel:       getEnv("LOCAL_LLM_MODEL", "llama3.1"),
+		LocalLLMMaxTokens:   getEnvAsInt("LOCAL_LLM_MAX_TOKENS", 2000),
+		LocalLLMTemperature: localLLMTemperature,
+
+		// Provider Failover
+		// Off unless configured: a fallback provider receives the whole conversation,
+		// so sending it to another vendor is the operator's choice
+		FailoverProviders:   getEnv("FAILOVER_PROVIDERS", ""),
+		FailoverMaxRetries:  getEnvAsInt("FAILOVER_MAX_RETRIES", 2),
+		FailoverBaseDelayMs: getEnvAsInt("FAILOVER_BASE_DELAY_MS", 500),
+		FailoverMaxDelayMs:  getEnvAsInt("FAILOVER_MAX_DELAY_MS", 4000),
+
+		// Tool calling
+		ToolMaxRounds: getEnvAsInt("TOOL_MAX_ROUNDS", 5),
+
+		// Structured output
+		StructuredOutputMaxRetries: getEnvAsInt("STRUCTURED_OUTPUT_MAX_RETRIES", 2),
+
+		// Conversation titles - generated from the first exchange
+		TitleProvider: getEnv("CONVERSATION_TITLE_PROVIDER", ""),
+		TitleModel:    getEnv("CONVERSATION_TITLE_MODEL", ""),
+
+		// Conversation summaries - older turns of long sessions are folded into a rolling summary
+		SummaryProvider:     getEnv("SUMMARY_PROVIDER", ""),
+		SummaryModel:        getEnv("SUMMARY_MODEL", ""),
+		SummaryTokenBudget:  getEnvAsInt("SUMMARY_TOKEN_BUDGET", 3000),
+		SummaryKeepMessages: getEnvAsInt("SUMMARY_KEEP_MESSAGES", 4),
+
+		// Search - full-text always, semantic when messages are embedded
+		EmbeddingModel:      getEnv("EMBEDDING_MODEL", "text-embedding-3-small"),
+		SearchEmbedMessages: getEnvAsBool("SEARCH_EMBED_MESSAGES", false),
+
+		// Knowledge bases - documents are chunked and embedded once, chunks are retrieved per question
+		KnowledgeChunkSize:    getEnvAsInt("KNOWLEDGE_CHUNK_SIZE", 1200),
+		KnowledgeChunkOverlap: getEnvAsInt("KNOWLEDGE_CHUNK_OVERLAP", 200),
+		KnowledgeTopK:         getEnvAsInt("KNOWLEDGE_TOP_K", 5),
+		KnowledgeMinScore:     getEnvAsFloat("KNOWLEDGE_MIN_SCORE", 0.3),
+
+		// OCR - scanned PDF pages and images are read by a local Tesseract
+		OCREnabled:      getEnvAsBool("OCR_ENABLED", false),
+		OCRBinaryPath:   getEnv("TESSERACT_PATH", "tesseract"),
+		OCRRendererPath: getEnv("PDFTOPPM_PATH", "pdftoppm"),
+		OCRLanguages:    getEnv("OCR_LANGUAGES", "tha+eng"),
+		OCRDataDir:      ocrDataDir(),
+		OCRTempDir:      getAbsolutePath(getEnv("OCR_TEMP_DIR", "./ocr/temp")),
+		OCRDPI:          getEnvAsInt("OCR_DPI", 300),
+		OCRMinPageChars: getEnvAsInt("OCR_MIN_PAGE_CHARS", 20),
+		OCRMaxPages:     getEnvAsInt("OCR_MAX_PAGES", 20),
+
+		// Pricing - defaults overridden by PRICING_FILE (JSON)
+		Pricing: loadPricingTable(pricingFile()),
+
+		// Model catalog - built-in models merged with MODEL_CATALOG_FILE (JSON)
+		ModelCatalog: loadModelCatalog(modelCatalogFile()),
+
 		// ElevenLabs
 		ElevenLabsAPIKey: getEnv("ELEVENLABS_API_KEY", ""),
 
@@ -183,6 +322,20 @@ func getEnvAsInt(key string, defaultValue int) int {
 	return value
 }
 
+// getEnvAsFloat retrieves environment variable as float or returns default value
+func getEnvAsFloat(key string, defaultValue float64) float64 {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return defaultValue
+	}
+	value, err := strconv.ParseFloat(valueStr, 64)
+	if err != nil {
+		log.Printf("Warning: Invalid number value for %s, using default: %v", key, defaultValue)
+		return defaultValue
+	}
+	return value
+}
+
 // getEnvAsBool retrieves environment variable as boolean or returns default value
 func getEnvAsBool(key string, defaultValue bool) bool {
 	valueStr := os.Getenv(key)
@@ -202,6 +355,80 @@ func getEnvAsBool(key string, defaultValue bool) bool {
 	}
 }
 
+// loadOpenAICompatibleProviders reads named providers listed in OPENAI_COMPATIBLE_PROVIDERS
+// Each provider is configured with OPENAI_COMPAT_<NAME>_* variables, e.g. for "groq":
+// OPENAI_COMPAT_GROQ_BASE_URL, _API_KEY, _MODEL, _MODELS (comma-separated), _API_TYPE, _API_VERSION, _VISION
+func loadOpenAICompatibleProviders() []OpenAICompatibleProvider {
+	providers := make([]OpenAICompatibleProvider, 0)
+
+	for _, name := range splitList(getEnv("OPENAI_COMPATIBLE_PROVIDERS", "")) {
+		name = strings.ToLower(name)
+		prefix := "OPENAI_COMPAT_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+
+		provider := OpenAICompatibleProvider{
+			Name:          name,
+			BaseURL:       strings.TrimSuffix(getEnv(prefix+"BASE_URL", ""), "/"),
+			APIKey:        getEnv(prefix+"API_KEY", ""),
+			DefaultModel:  getEnv(prefix+"MODEL", ""),
+			AllowedModels: splitList(getEnv(prefix+"MODELS", "")),
+			APIType:       strings.ToLower(getEnv(prefix+"API_TYPE", "openai")),
+			APIVersion:    getEnv(prefix+"API_VERSION", "2024-06-01"),
+			Vision:        getEnvAsBool(prefix+"VISION", false),
+			Local:         getEnvAsBool(prefix+"LOCAL", false),
+		}
+
+		if provider.BaseURL == "" {
+			log.Printf("Warning: %sBASE_URL is not set, skipping provider %s", prefix, name)
+			continue
+		}
+		if provider.DefaultModel == "" && len(provider.AllowedModels) > 0 {
+			provider.DefaultModel = provider.AllowedModels[0]
+		}
+
+		providers = append(providers, provider)
+	}
+
+	return providers
+}
+
+// splitList splits a comma-separated value and drops empty items
+func splitList(value string) []string {
+	items := make([]string, 0)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// pricingFile returns the absolute path of PRICING_FILE (empty if not set)
+func pricingFile() string {
+	path := getEnv("PRICING_FILE", "")
+	if path == "" {
+		return ""
+	}
+	return getAbsolutePath(path)
+}
+
+// modelCatalogFile returns the absolute path of MODEL_CATALOG_FILE (empty if not set)
+func modelCatalogFile() string {
+	path := getEnv("MODEL_CATALOG_FILE", "")
+	if path == "" {
+		return ""
+	}
+	return getAbsolutePath(path)
+}
+
+// ocrDataDir returns the absolute path of TESSDATA_PREFIX (empty if not set)
+func ocrDataDir() string {
+	path := getEnv("TESSDATA_PREFIX", "")
+	if path == "" {
+		return ""
+	}
+	return getAbsolutePath(path)
+}
+
 // getWhisperBinaryPath returns the correct Whisper binary path based on the OS
 func getWhisperBinaryPath() string {
 	var envKey string
@@ -0,0 +1,98 @@
+package config
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"strings"
+)
+
+// CatalogModel describes a chat model that personas and requests may use
+type CatalogModel struct {
+	Provider        string   `json:"provider"`             // Provider name from the registry (e.g. "openai", "bedrock")
+	ID              string   `json:"id"`                   // Model ID sent to the provider
+	Name            string   `json:"name,omitempty"`       // Display name
+	Aliases         []string `json:"aliases,omitempty"`    // Shorthand names resolved to ID (e.g. "claude-sonnet-4")
+	ContextWindow   int      `json:"context_window"`       // Max prompt + completion tokens
+	MaxOutputTokens int      `json:"max_output_tokens"`    // Max completion tokens
+	Vision          bool     `json:"vision"`               // Accepts images
+	Reasoning       bool     `json:"reasoning"`            // Supports a thinking budget (extended thinking / reasoning effort)
+	Deprecated      string   `json:"deprecated,omitempty"` // Date the model stops being accepted (YYYY-MM-DD)
+}
+
+// ModelCatalogFile is the JSON format of MODEL_CATALOG_FILE
+// Entries replace the built-in model with the same provider and ID, or are added
+type ModelCatalogFile struct {
+	Models []CatalogModel `json:"models"`
+}
+
+// DefaultModelCatalog returns the built-in chat models
+func DefaultModelCatalog() []CatalogModel {
+	return []CatalogModel{
+		// OpenAI
+		{Provider: "openai", ID: "gpt-4o-mini", Name: "GPT-4o mini", ContextWindow: 128000, MaxOutputTokens: 16384, Vision: true},
+		{Provider: "openai", ID: "gpt-4o", Name: "GPT-4o", ContextWindow: 128000, MaxOutputTokens: 16384, Vision: true},
+		{Provider: "openai", ID: "gpt-4.1-nano", Name: "GPT-4.1 nano", ContextWindow: 1047576, MaxOutputTokens: 32768, Vision: true},
+		{Provider: "openai", ID: "gpt-4.1-mini", Name: "GPT-4.1 mini", ContextWindow: 1047576, MaxOutputTokens: 32768, Vision: true},
+		{Provider: "openai", ID: "gpt-4.1", Name: "GPT-4.1", ContextWindow: 1047576, MaxOutputTokens: 32768, Vision: true},
+		{Provider: "openai", ID: "gpt-4-turbo", Name: "GPT-4 Turbo", ContextWindow: 128000, MaxOutputTokens: 4096, Vision: true},
+		{Provider: "openai", ID: "gpt-4", Name: "GPT-4", ContextWindow: 8192, MaxOutputTokens: 8192},
+		{Provider: "openai", ID: "gpt-3.5-turbo", Name: "GPT-3.5 Turbo", ContextWindow: 16385, MaxOutputTokens: 4096},
+		{Provider: "openai", ID: "o3-mini", Name: "o3-mini", ContextWindow: 200000, MaxOutputTokens: 100000, Reasoning: true},
+		{Provider: "openai", ID: "o4-mini", Name: "o4-mini", ContextWindow: 200000, MaxOutputTokens: 100000, Vision: true, Reasoning: true},
+
+		// AWS Bedrock - Cross-Region Inference (CRI) profiles
+		{Provider: "bedrock", ID: "apac.anthropic.claude-sonnet-4-20250514-v1:0", Name: "Claude Sonnet 4", Aliases: []string{"claude-sonnet-4"}, ContextWindow: 200000, MaxOutputTokens: 64000, Vision: true, Reasoning: true},
+		{Provider: "bedrock", ID: "apac.anthropic.claude-3-7-sonnet-20250219-v1:0", Name: "Claude 3.7 Sonnet", Aliases: []string{"claude-3-7-sonnet"}, ContextWindow: 200000, MaxOutputTokens: 64000, Vision: true, Reasoning: true},
+		{Provider: "bedrock", ID: "apac.anthropic.claude-3-5-sonnet-20241022-v2:0", Name: "Claude 3.5 Sonnet v2", ContextWindow: 200000, MaxOutputTokens: 8192, Vision: true},
+
+		// AWS Bedrock - Single-region models
+		{Provider: "bedrock", ID: "anthropic.claude-3-5-sonnet-20241022-v2:0", Name: "Claude 3.5 Sonnet v2", ContextWindow: 200000, MaxOutputTokens: 8192, Vision: true},
+		{Provider: "bedrock", ID: "anthropic.claude-3-5-sonnet-20240620-v1:0", Name: "Claude 3.5 Sonnet", Aliases: []string{"claude-3-5-sonnet"}, ContextWindow: 200000, MaxOutputTokens: 8192, Vision: true},
+		{Provider: "bedrock", ID: "anthropic.claude-3-opus-20240229-v1:0", Name: "Claude 3 Opus", Aliases: []string{"claude-3-opus"}, ContextWindow: 200000, MaxOutputTokens: 4096, Vision: true},
+		{Provider: "bedrock", ID: "anthropic.claude-3-sonnet-20240229-v1:0", Name: "Claude 3 Sonnet", Aliases: []string{"claude-3-sonnet"}, ContextWindow: 200000, MaxOutputTokens: 4096, Vision: true, Deprecated: "2025-07-21"},
+	}
+}
+
+// loadModelCatalog returns the built-in models merged with the JSON file at path (if any)
+func loadModelCatalog(path string) []CatalogModel {
+	catalog := DefaultModelCatalog()
+	if path == "" {
+		return catalog
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Printf("Warning: Cannot read model catalog %s, using built-in models: %v", path, err)
+		return catalog
+	}
+
+	var file ModelCatalogFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		log.Printf("Warning: Invalid model catalog %s, using built-in models: %v", path, err)
+		return catalog
+	}
+
+	for _, model := range file.Models {
+		if model.Provider == "" || model.ID == "" {
+			log.Printf("Warning: Model catalog entry without provider or id skipped: %+v", model)
+			continue
+		}
+		model.Provider = strings.ToLower(model.Provider)
+
+		replaced := false
+		for i, existing := range catalog {
+			if existing.Provider == model.Provider && existing.ID == model.ID {
+				catalog[i] = model
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			catalog = append(catalog, model)
+		}
+	}
+
+	log.Printf("✓ Model catalog loaded from %s (%d models)", path, len(catalog))
+	return catalog
+}
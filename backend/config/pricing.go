@@ -0,0 +1,93 @@
+package config
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+)
+
+// ModelPrice is the chat price of a model in USD per million tokens
+type ModelPrice struct {
+	Input      float64 `json:"input"`       // Uncached prompt tokens
+	Output     float64 `json:"output"`      // Completion tokens
+	Cached     float64 `json:"cached"`      // Prompt tokens served from the provider cache (0 = same as input)
+	CacheWrite float64 `json:"cache_write"` // Prompt tokens written to the provider cache (0 = same as input)
+}
+
+// PricingTable holds chat, TTS and STT prices keyed by model name
+// Model keys match exactly or by prefix (e.g. "gpt-4o" also prices "gpt-4o-2024-08-06");
+// Bedrock IDs also match without their region/vendor prefix ("apac.anthropic.claude-sonnet-4-..." → "claude-sonnet-4").
+// A provider name as key (e.g. "local", "whispercpp") prices every model of that provider without its own entry.
+type PricingTable struct {
+	Models map[string]ModelPrice `json:"models"` // USD per 1M tokens
+	TTS    map[string]float64    `json:"tts"`    // USD per character
+	STT    map[string]float64    `json:"stt"`    // USD per minute of audio
+}
+
+// DefaultPricingTable returns the built-in prices (USD, list prices)
+func DefaultPricingTable() PricingTable {
+	return PricingTable{
+		Models: map[string]ModelPrice{
+			"gpt-4o-mini":       {Input: 0.15, Output: 0.60, Cached: 0.075},
+			"gpt-4o":            {Input: 2.50, Output: 10.00, Cached: 1.25},
+			"gpt-4.1-nano":      {Input: 0.10, Output: 0.40, Cached: 0.025},
+			"gpt-4.1-mini":      {Input: 0.40, Output: 1.60, Cached: 0.10},
+			"gpt-4.1":           {Input: 2.00, Output: 8.00, Cached: 0.50},
+			"gpt-4-turbo":       {Input: 10.00, Output: 30.00},
+			"gpt-3.5-turbo":     {Input: 0.50, Output: 1.50},
+			"o3-mini":           {Input: 1.10, Output: 4.40, Cached: 0.55},
+			"o4-mini":           {Input: 1.10, Output: 4.40, Cached: 0.275},
+			"claude-sonnet-4":   {Input: 3.00, Output: 15.00, Cached: 0.30, CacheWrite: 3.75},
+			"claude-3-7-sonnet": {Input: 3.00, Output: 15.00, Cached: 0.30, CacheWrite: 3.75},
+			"claude-3-5-sonnet": {Input: 3.00, Output: 15.00, Cached: 0.30, CacheWrite: 3.75},
+			"claude-3-5-haiku":  {Input: 0.80, Output: 4.00, Cached: 0.08, CacheWrite: 1.00},
+			"claude-3-haiku":    {Input: 0.25, Output: 1.25, Cached: 0.03, CacheWrite: 0.30},
+			"local":             {}, // Provider fallback: Ollama / llama.cpp run locally
+		},
+		TTS: map[string]float64{
+			"tts-1":                  15.00 / 1_000_000,
+			"tts-1-hd":               30.00 / 1_000_000,
+			"gpt-4o-mini-tts":        12.00 / 1_000_000,
+			"eleven_multilingual_v2": 0.30 / 1_000,
+			"eleven_turbo_v2_5":      0.15 / 1_000,
+			"eleven_flash_v2_5":      0.15 / 1_000,
+		},
+		STT: map[string]float64{
+			"whisper-1":  0.006,
+			"whispercpp": 0, // Provider fallback: runs locally
+		},
+	}
+}
+
+// loadPricingTable returns the default prices overridden by the JSON file at path (if any)
+func loadPricingTable(path string) PricingTable {
+	table := DefaultPricingTable()
+	if path == "" {
+		return table
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Printf("Warning: Cannot read pricing file %s, using default prices: %v", path, err)
+		return table
+	}
+
+	var overrides PricingTable
+	if err := json.Unmarshal(data, &overrides); err != nil {
+		log.Printf("Warning: Invalid pricing file %s, using default prices: %v", path, err)
+		return table
+	}
+
+	for model, price := range overrides.Models {
+		table.Models[model] = price
+	}
+	for model, price := range overrides.TTS {
+		table.TTS[model] = price
+	}
+	for model, price := range overrides.STT {
+		table.STT[model] = price
+	}
+
+	log.Printf("✓ Pricing loaded from %s", path)
+	return table
+}
@@ -14,13 +14,15 @@ import (
 type AudioController struct {
 	openaiService *services.OpenAIService
 	ttsService    *services.TTSService
+	usageService  *services.UsageService
 }
 
 // NewAudioController creates a new audio controller
-func NewAudioController(openaiService *services.OpenAIService, ttsService *services.TTSService) *AudioController {
+func NewAudioController(openaiService *services.OpenAIService, ttsService *services.TTSService, usageService *services.UsageService) *AudioController {
 	return &AudioController{
 		openaiService: openaiService,
 		ttsService:    ttsService,
+		usageService:  usageService,
 	}
 }
 
@@ -30,6 +32,7 @@ type TranscribeResponse struct {
 	Language   string    `json:"language"`
 	Duration   float64   `json:"duration"`
 	Confidence float64   `json:"confidence"`
+	Cost       *float64  `json:"cost,omitempty"` // USD cost from the pricing table
 	Timestamp  time.Time `json:"timestamp"`
 }
 
@@ -83,12 +86,16 @@ func (ctrl *AudioController) TranscribeAudio(c *fiber.Ctx) error {
 
 	println("✅ Transcription successful:", transcription.Text)
 
+	// Record STT usage (session_id is an optional form field)
+	cost := ctrl.usageService.RecordSTT(c.FormValue("session_id"), nil, "openai", transcription.Model, transcription.Duration)
+
 	// Create response
 	response := TranscribeResponse{
 		Text:       transcription.Text,
 		Language:   transcription.Language,
 		Duration:   transcription.Duration,
 		Confidence: 0.95, // Mock confidence for now
+		Cost:       cost,
 		Timestamp:  time.Now(),
 	}
 
@@ -102,6 +109,8 @@ type TTSRequest struct {
 	Model          string   `json:"model"`          // tts-1, tts-1-hd, gpt-4o-mini-tts (default: gpt-4o-mini-tts)
 	ResponseFormat string   `json:"response_format"` // mp3, opus, aac, flac, wav, pcm (default: mp3)
 	Speed          *float64 `json:"speed"`          // 0.25 - 4.0 (default: 1.0)
+	SessionID      string   `json:"session_id"`     // Optional, for usage reporting
+	PersonaID      *int     `json:"persona_id"`     // Optional, for usage reporting
 }
 
 // TTSResponse represents the TTS response with audio data
@@ -112,6 +121,7 @@ type TTSResponse struct {
 	Duration       float64   `json:"duration"`
 	CharactersUsed int       `json:"characters_used"`
 	Voice          string    `json:"voice"`
+	Cost           *float64  `json:"cost,omitempty"` // USD cost from the pricing table
 	Timestamp      time.Time `json:"timestamp"`
 }
 
@@ -160,6 +170,13 @@ func (ctrl *AudioController) TextToSpeech(c *fiber.Ctx) error {
 		})
 	}
 
+	// Record TTS usage
+	model := req.Model
+	if model == "" {
+		model = services.DefaultTTSModel
+	}
+	cost := ctrl.usageService.RecordTTS(req.SessionID, req.PersonaID, "openai", model, ttsRes.CharactersUsed)
+
 	// Check if client wants binary audio or JSON response
 	accept := c.Get("Accept")
 
@@ -196,6 +213,7 @@ func (ctrl *AudioController) TextToSpeech(c *fiber.Ctx) error {
 		Duration:       ttsRes.Duration,
 		CharactersUsed: ttsRes.CharactersUsed,
 		Voice:          ttsRes.Voice,
+		Cost:           cost,
 		Timestamp:      time.Now(),
 	}
 
@@ -1,11 +1,10 @@
 package controllers
 
 import (
-	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
-	"os"
 	"time"
 
 	"chatbot/models"
@@ -17,27 +16,53 @@ import (
 
 // BedrockController handles Bedrock-specific chat requests
 type BedrockController struct {
-	bedrockService   *services.BedrockService
+	providers        *services.ProviderRegistry
 	personaRepo      *repositories.PersonaRepository
 	messageRepo      *repositories.MessageRepository
 	contextService   *services.ContextService
 	fileAnalysisRepo *repositories.FileAnalysisRepository
+	usageService     *services.UsageService
+	tools            *services.ToolOrchestrator
+	structured       *services.StructuredOutputService
+	catalog          *services.ModelCatalog
+	knowledge        *services.KnowledgeService
+	streamer         *chatStreamer
 }
 
 // NewBedrockController creates a new Bedrock controller
 func NewBedrockController(
-	bedrockService *services.BedrockService,
+	providers *services.ProviderRegistry,
 	personaRepo *repositories.PersonaRepository,
 	messageRepo *repositories.MessageRepository,
 	contextService *services.ContextService,
 	fileAnalysisRepo *repositories.FileAnalysisRepository,
+	usageService *services.UsageService,
+	tools *services.ToolOrchestrator,
+	structured *services.StructuredOutputService,
+	failover *services.FailoverStreamer,
+	catalog *services.ModelCatalog,
+	conversations *services.ConversationService,
+	knowledge *services.KnowledgeService,
 ) *BedrockController {
 	return &BedrockController{
-		bedrockService:   bedrockService,
+		providers:        providers,
 		personaRepo:      personaRepo,
 		messageRepo:      messageRepo,
 		contextService:   contextService,
 		fileAnalysisRepo: fileAnalysisRepo,
+		usageService:     usageService,
+		tools:            tools,
+		structured:       structured,
+		catalog:          catalog,
+		knowledge:        knowledge,
+		streamer: &chatStreamer{
+			messageRepo:    messageRepo,
+			contextService: contextService,
+			failover:       failover,
+			usageService:   usageService,
+			tools:          tools,
+			conversations:  conversations,
+		},
 	}
 }
 
@@ -47,26 +72,35 @@ type BedrockMessageRequest struct {
 	PersonaID    uint     `json:"persona_id" validate:"required"`
 	Temperature  float64  `json:"temperature,omitempty"`
 	MaxTokens    int      `json:"max_tokens,omitempty"`
+	Model        string   `json:"model,omitempty"` // Bedrock model ID or alias (persona's model or Bedrock default if empty)
 	SystemPrompt string   `json:"system_prompt,omitempty"`
 	SessionID    string   `json:"session_id,omitempty"`
 	UseHistory   bool     `json:"use_history,omitempty"`
-	FileIDs      []string `json:"file_ids,omitempty"` // File IDs for current message only
+	FileIDs      []string `json:"file_ids,omitempty"`  // File IDs for current message only
+	Stream       bool     `json:"stream,omitempty"`    // Reply with Server-Sent Events (same as Accept: text/event-stream)
+	ParentID     string   `json:"parent_id,omitempty"` // Message to reply after (latest message of the session if empty)
+
+	ResponseSchema *services.ResponseSchema `json:"response_schema,omitempty"` // Reply with JSON matching this schema
 }
 
 type BedrockMessageResponse struct {
 	MessageID string `json:"message_id"`
 	SessionID string `json:"session_id"`
 	Reply     string `json:"reply"`
+	Reasoning string `json:"reasoning,omitempty"` // Reasoning before the reply (persona thinking_budget)
 	Persona   struct {
 		ID        uint   `json:"id"`
 		Name      string `json:"name"`
 		Expertise string `json:"expertise"`
 		Icon      string `json:"icon"`
 	} `json:"persona"`
-	TokensUsed int    `json:"tokens_used"`
-	Model      string `json:"model"`
-	Provider   string `json:"provider"`
-	Timestamp  string `json:"timestamp"`
+	TokensUsed int                 `json:"tokens_used"`
+	Model      string              `json:"model"`
+	Provider   string              `json:"provider"`
+	Cost       *float64            `json:"cost,omitempty"`       // USD cost from the pricing table
+	ToolCalls  []services.ToolCall `json:"tool_calls,omitempty"` // Tools executed while answering
+	Structured json.RawMessage     `json:"structured,omitempty"` // Reply parsed as JSON (response_schema)
+	Timestamp  string              `json:"timestamp"`
 }
 
 func (bc *BedrockController) SendBedrockMessage(c *fiber.Ctx) error {
@@ -92,6 +126,14 @@ func (bc *BedrockController) SendBedrockMessage(c *fiber.Ctx) error {
 		})
 	}
 
+	if req.ResponseSchema != nil {
+		if err := req.ResponseSchema.Check(); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid response_schema: " + err.Error(),
+			})
+		}
+	}
+
 	// Generate session ID if not provided
 	sessionID := req.SessionID
 	if sessionID == "" {
@@ -110,6 +152,16 @@ func (bc *BedrockController) SendBedrockMessage(c *fiber.Ctx) error {
 		})
 	}
 
+	// Resolve Bedrock through the provider registry
+	bedrockService, err := bc.providers.Get("bedrock")
+	if err != nil {
+		log.Printf("❌ Bedrock provider unavailable: %v", err)
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"error":   "Bedrock provider not available",
+			"details": err.Error(),
+		})
+	}
+
 	// Build system prompt (combine persona system_prompt + persona description)
 	systemPrompt := fmt.Sprintf("%s\n\nYou are %s, a %s.",
 		persona.SystemPrompt, persona.Name, persona.Description)
@@ -118,119 +170,84 @@ func (bc *BedrockController) SendBedrockMessage(c *fiber.Ctx) error {
 		systemPrompt += "\n\n" + req.SystemPrompt
 	}
 
-	// Build messages with history and file context
-	var messages []services.ClaudeMessage
-	var fileContext string
-	var hasImages bool
-
-	// Get file context if files provided
-	if len(req.FileIDs) > 0 {
-		// Separate image files from text files
-		imageFileIDs := make([]string, 0)
-		textFileIDs := make([]string, 0)
-
-		for _, fileID := range req.FileIDs {
-			file, err := bc.fileAnalysisRepo.FindByID(fileID)
-			if err != nil {
-				log.Printf("⚠️ File not found: %s", fileID)
-				continue
-			}
-
-			if bc.isImageFile(file.MimeType) {
-				imageFileIDs = append(imageFileIDs, fileID)
-				hasImages = true
-			} else {
-				textFileIDs = append(textFileIDs, fileID)
-			}
-		}
+	// Build provider-neutral conversation with the branch history and file context
+	parentID, err := bc.contextService.ActiveParent(sessionID, req.ParentID)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	historyLimit := 0
+	if req.UseHistory && sessionID != "" {
+		historyLimit = services.DefaultHistoryLimit
+	}
+	conversation, err := bc.contextService.BuildConversation(sessionID, parentID, systemPrompt, req.Message, historyLimit, req.FileIDs)
+	if err != nil {
+		log.Printf("⚠️ Failed to build conversation: %v", err)
+		conversation = services.NewSimpleConversation(systemPrompt, req.Message)
+	}
+	bc.knowledge.Augment(c.UserContext(), conversation, persona)
+
+	// Resolve generation settings: request → persona → Bedrock defaults
+	settings, err := bc.catalog.ResolveGeneration(services.GenerationOverrides{
+		Provider:    "bedrock",
+		Model:       req.Model,
+		Temperature: req.Temperature,
+		MaxTokens:   req.MaxTokens,
+	}, persona, "bedrock")
+	if err != nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"error":   "Bedrock provider not available",
+			"details": err.Error(),
+		})
+	}
 
-		// Build text file context (for non-image files)
-		if len(textFileIDs) > 0 {
-			var err error
-			fileContext, err = bc.contextService.BuildFileContext(textFileIDs)
-			if err != nil {
-				log.Printf("⚠️ Failed to build file context: %v", err)
-			}
-		}
+	personaIDInt := int(req.PersonaID)
+	chatReq := settings.Apply(services.StreamingChatRequest{
+		Messages:     conversation.Messages,
+		SystemPrompt: conversation.SystemPrompt,
+	})
 
-		// Store image file IDs for later multimodal processing
-		if hasImages {
-			log.Printf("📸 Found %d image files", len(imageFileIDs))
-		}
+	if req.ResponseSchema != nil {
+		chatReq.ResponseSchema = req.ResponseSchema
+	} else {
+		chatReq.Tools = bc.tools.Registry().Definitions(persona.GetTools())
 	}
 
-	// Build messages based on history and file support
-	if req.UseHistory && sessionID != "" {
-		// Get conversation history
-		history, err := bc.messageRepo.GetRecentBySession(sessionID, 10)
-		if err == nil && len(history) > 0 {
-			// Convert history to Claude messages
-			claudeHistory := make([]services.ClaudeMessage, 0, len(history))
-			for _, msg := range history {
-				role := "user"
-				if msg.Role == models.RoleAssistant {
-					role = "assistant"
-				}
-				claudeHistory = append(claudeHistory, services.ClaudeMessage{
-					Role:    role,
-					Content: msg.Content,
-				})
-			}
-
-			// Build with history
-			if fileContext != "" {
-				// Add file context to user message
-				fullMessage := fileContext + "\n\n" + req.Message
-				messages = bc.bedrockService.BuildMessagesWithHistory(fullMessage, claudeHistory)
-			} else {
-				messages = bc.bedrockService.BuildMessagesWithHistory(req.Message, claudeHistory)
-			}
-		} else {
-			// No history found, build simple message
-			if fileContext != "" {
-				messages = bc.bedrockService.BuildMessagesWithContext(req.Message, fileContext)
-			} else {
-				messages = bc.bedrockService.BuildMessages(req.Message, systemPrompt)
-			}
-		}
-	} else {
-		// No history requested
-		if fileContext != "" {
-			messages = bc.bedrockService.BuildMessagesWithContext(req.Message, fileContext)
-		} else {
-			messages = bc.bedrockService.BuildMessages(req.Message, systemPrompt)
-		}
+	// Fit the request to the model's limits (context window, max output, vision)
+	chatReq, err = bc.catalog.Prepare("bedrock", chatReq)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
 	}
 
-	// Convert to multimodal format if there are images
-	if hasImages && len(req.FileIDs) > 0 {
-		messages = bc.convertToMultimodalMessages(messages, req.FileIDs)
+	// Stream the answer as Server-Sent Events when the client asked for it
+	if wantsEventStream(c, req.Stream) {
+		c.Set("X-Session-ID", sessionID)
+		return bc.streamer.streamSSE(c, chatTurn{
+			SessionID: sessionID,
+			PersonaID: &personaIDInt,
+			Content:   req.Message,
+			FileIDs:   req.FileIDs,
+			Provider:  "bedrock",
+			Request:   chatReq,
+			ParentID:  parentID,
+		})
 	}
 
 	// Save user message to database
-	personaIDInt := int(req.PersonaID)
 	userMsg := &models.Message{
 		SessionID: sessionID,
 		Role:      "user",
 		Content:   req.Message,
 		PersonaID: &personaIDInt,
+		ParentID:  parentID,
 	}
 
 	// Save file attachments if provided
 	if len(req.FileIDs) > 0 {
-		fileAttachments := make([]models.FileAttachment, 0, len(req.FileIDs))
-		for _, fileID := range req.FileIDs {
-			fileRecord, err := bc.fileAnalysisRepo.FindByID(fileID)
-			if err == nil {
-				fileAttachments = append(fileAttachments, models.FileAttachment{
-					FileID:   fileID,
-					Filename: fileRecord.FileName,
-					FileType: fileRecord.MimeType,
-					FileSize: fileRecord.FileSize,
-				})
-			}
-		}
-		if err := userMsg.SetFileAttachments(fileAttachments); err != nil {
+		if err := userMsg.SetFileAttachments(bc.contextService.FileAttachments(req.FileIDs)); err != nil {
 			log.Printf("⚠️ Failed to set file attachments: %v", err)
 		}
 	}
@@ -239,15 +256,28 @@ func (bc *BedrockController) SendBedrockMessage(c *fiber.Ctx) error {
 		log.Printf("⚠️ Failed to save user message: %v", err)
 	}
 
-	// Send request to Bedrock
-	bedrockReq := services.BedrockChatRequest{
-		Messages:     messages,
-		SystemPrompt: systemPrompt,
-		Temperature:  req.Temperature,
-		MaxTokens:    req.MaxTokens,
+	// Send request to Bedrock (runs the tool loop when the persona has tools,
+	// or asks for validated JSON when a response_schema is given)
+	var bedrockResp *services.ChatCompletion
+	toolRun := &services.ToolRunResult{}
+	if req.ResponseSchema != nil {
+		var result *services.StructuredResult
+		result, err = bc.structured.Complete(c.UserContext(), bedrockService, chatReq)
+		if result != nil {
+			bedrockResp = result.Completion
+		}
+	} else {
+		bedrockResp, toolRun, err = bc.tools.Complete(c.UserContext(), bedrockService, chatReq)
+	}
+	var structuredErr *services.StructuredOutputError
+	if errors.As(err, &structuredErr) {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error":    "AI response did not match response_schema",
+			"problems": structuredErr.Problems,
+			"attempts": structuredErr.Attempts,
+			"raw":      structuredErr.Content,
+		})
 	}
-
-	bedrockResp, err := bc.bedrockService.SendChatRequest(bedrockReq)
 	if err != nil {
 		log.Printf("❌ Bedrock API error: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -256,14 +286,28 @@ func (bc *BedrockController) SendBedrockMessage(c *fiber.Ctx) error {
 		})
 	}
 
-	// Save assistant message to database
-	metadataJSON, _ := json.Marshal(map[string]interface{}{
+	// Tool calls and results go between the question and the answer
+	branch := services.ToolStepRecords(toolRun.Steps, sessionID, &personaIDInt)
+
+	// Save assistant message to database (reasoning goes to metadata, not the answer)
+	metadata := map[string]interface{}{
 		"model":       bedrockResp.Model,
 		"provider":    "bedrock",
-		"stop_reason": bedrockResp.StopReason,
+		"stop_reason": bedrockResp.FinishReason,
 		"use_history": req.UseHistory,
 		"file_count":  len(req.FileIDs),
-	})
+	}
+	reasoning := services.ReasoningText(bedrockResp.Reasoning)
+	if reasoning != "" {
+		metadata["reasoning"] = reasoning
+	}
+	if chatReq.PromptCaching {
+		metadata["prompt_caching"] = true
+	}
+	for key, value := range bedrockResp.Usage.CacheMetadata() {
+		metadata[key] = value
+	}
+	metadataJSON, _ := json.Marshal(metadata)
 
 	assistantMsg := &models.Message{
 		SessionID:  sessionID,
@@ -273,9 +317,23 @@ func (bc *BedrockController) SendBedrockMessage(c *fiber.Ctx) error {
 		TokensUsed: &bedrockResp.TokensUsed,
 		Metadata:   metadataJSON,
 	}
-	if err := bc.messageRepo.Create(assistantMsg); err != nil {
+	if usage := bedrockResp.Usage; usage != nil {
+		assistantMsg.SetTokenUsage(usage.PromptTokens, usage.CompletionTokens, usage.CachedTokens)
+	}
+	bc.usageService.PriceMessage(assistantMsg, bedrockResp.Provider, bedrockResp.Model, bedrockResp.Usage)
+	branch = append(branch, assistantMsg)
+	if err := bc.messageRepo.CreateBranch(&userMsg.ID, branch...); err != nil {
 		log.Printf("⚠️ Failed to save assistant message: %v", err)
 	}
+	bc.streamer.conversations.RecordTurn(services.ConversationTurn{
+		SessionID: sessionID,
+		LeafID:    assistantMsg.ID,
+		PersonaID: &personaIDInt,
+		Provider:  bedrockResp.Provider,
+		Model:     bedrockResp.Model,
+		Question:  req.Message,
+		Answer:    bedrockResp.Content,
+	})
 
 	log.Printf("✅ Bedrock response sent: message_id=%s, tokens=%d",
 		assistantMsg.ID.String(), bedrockResp.TokensUsed)
@@ -285,11 +343,17 @@ func (bc *BedrockController) SendBedrockMessage(c *fiber.Ctx) error {
 		MessageID:  assistantMsg.ID.String(),
 		SessionID:  sessionID,
 		Reply:      bedrockResp.Content,
+		Reasoning:  reasoning,
 		TokensUsed: bedrockResp.TokensUsed,
 		Model:      bedrockResp.Model,
 		Provider:   "bedrock",
+		Cost:       assistantMsg.Cost,
+		ToolCalls:  services.ExecutedToolCalls(toolRun.Steps),
 		Timestamp:  assistantMsg.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
 	}
+	if req.ResponseSchema != nil {
+		response.Structured = json.RawMessage(bedrockResp.Content)
+	}
 	response.Persona.ID = uint(persona.ID)
 	response.Persona.Name = persona.Name
 	response.Persona.Expertise = persona.Expertise
@@ -297,101 +361,3 @@ func (bc *BedrockController) SendBedrockMessage(c *fiber.Ctx) error {
 
 	return c.JSON(response)
 }
-
-// isImageFile checks if a MIME type is an image
-func (bc *BedrockController) isImageFile(mimeType string) bool {
-	switch mimeType {
-	case "image/jpeg", "image/jpg", "image/png", "image/gif", "image/webp":
-		return true
-	default:
-		return false
-	}
-}
-
-// convertToMultimodalMessages converts Claude messages to support images
-// For the last user message, if there are image files, convert to multimodal content blocks
-func (bc *BedrockController) convertToMultimodalMessages(messages []services.ClaudeMessage, fileIDs []string) []services.ClaudeMessage {
-	if len(fileIDs) == 0 || len(messages) == 0 {
-		return messages
-	}
-
-	// Find the last user message
-	lastUserIdx := -1
-	for i := len(messages) - 1; i >= 0; i-- {
-		if messages[i].Role == "user" {
-			lastUserIdx = i
-			break
-		}
-	}
-
-	if lastUserIdx == -1 {
-		return messages
-	}
-
-	// Get image files from fileIDs
-	contentBlocks := make([]services.ClaudeContentBlock, 0)
-
-	// Add text content first
-	textContent, ok := messages[lastUserIdx].Content.(string)
-	if ok && textContent != "" {
-		contentBlocks = append(contentBlocks, services.ClaudeContentBlock{
-			Type: "text",
-			Text: textContent,
-		})
-	}
-
-	// Add image blocks
-	for _, fileID := range fileIDs {
-		file, err := bc.fileAnalysisRepo.FindByID(fileID)
-		if err != nil {
-			log.Printf("⚠️  File not found: %s", fileID)
-			continue
-		}
-
-		// Check if it's an image
-		if !bc.isImageFile(file.MimeType) {
-			continue
-		}
-
-		// Read image file and encode to base64
-		imageData, mediaType, err := bc.readImageFile(file.StoragePath, file.MimeType)
-		if err != nil {
-			log.Printf("⚠️  Failed to read image: %v", err)
-			continue
-		}
-
-		// Add image content block
-		contentBlocks = append(contentBlocks, services.ClaudeContentBlock{
-			Type: "image",
-			Source: &services.ClaudeImageSource{
-				Type:      "base64",
-				MediaType: mediaType,
-				Data:      imageData,
-			},
-		})
-
-		log.Printf("✓ Added image to content: %s (%s)", file.FileName, mediaType)
-	}
-
-	// If we have multiple content blocks (text + images), use multimodal format
-	if len(contentBlocks) > 1 {
-		messages[lastUserIdx].Content = contentBlocks
-		log.Printf("✓ Converted to multimodal message: %d blocks (%d images)", len(contentBlocks), len(contentBlocks)-1)
-	}
-
-	return messages
-}
-
-// readImageFile reads an image file and returns base64 encoded data
-func (bc *BedrockController) readImageFile(filePath string, mimeType string) (string, string, error) {
-	// Read file
-	data, err := os.ReadFile(filePath)
-	if err != nil {
-		return "", "", fmt.Errorf("failed to read file: %w", err)
-	}
-
-	// Encode to base64
-	encoded := base64.StdEncoding.EncodeToString(data)
-
-	return encoded, mimeType, nil
-}
@@ -0,0 +1,417 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"chatbot/models"
+	"chatbot/repositories"
+	"chatbot/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// ========================================
+// Conversation Branching (edit and regenerate)
+// ========================================
+// Editing a user message saves the new text as a sibling of the original and
+// answers it; regenerating a reply answers the same user message again. Both
+// leave the old branch in place, so the client can switch between alternatives.
+
+// errMessageNotFound is returned for an unknown message ID
+var errMessageNotFound = errors.New("message not found")
+
+// BranchRequest is the body of the edit and regenerate requests
+type BranchRequest struct {
+	Content      string   `json:"content,omitempty"`       // New text of the user message (edit only)
+	FileIDs      []string `json:"file_ids,omitempty"`      // Files of the edited message (edit only, the original files if omitted)
+	PersonaID    *int     `json:"persona_id,omitempty"`    // Persona (the message's persona if omitted)
+	SystemPrompt string   `json:"system_prompt,omitempty"` // Optional custom system prompt
+	Provider     string   `json:"provider,omitempty"`      // Provider name (optional, see ResolveGeneration)
+	Model        string   `json:"model,omitempty"`         // Model ID (optional, persona's model or provider default if empty)
+	Temperature  float64  `json:"temperature,omitempty"`   // Optional, persona's temperature if 0
+	MaxTokens    int      `json:"max_tokens,omitempty"`    // Optional, persona's max tokens if 0
+	Stream       bool     `json:"stream,omitempty"`        // Reply with Server-Sent Events (same as Accept: text/event-stream)
+}
+
+// branchTurns builds the turns that edit a user message or regenerate a reply
+// It is shared by the REST endpoints and the WebSocket "edit" and "regenerate" messages
+type branchTurns struct {
+	messageRepo    *repositories.MessageRepository
+	personaRepo    *repositories.PersonaRepository
+	contextService *services.ContextService
+	catalog        *services.ModelCatalog
+	tools          *services.ToolOrchestrator
+	knowledge      *services.KnowledgeService
+}
+
+// edit builds the turn answering a new version of the user message messageID
+func (b *branchTurns) edit(messageID string, req BranchRequest) (chatTurn, error) {
+	if strings.TrimSpace(req.Content) == "" {
+		return chatTurn{}, fmt.Errorf("content is required")
+	}
+	original, _, err := b.load(messageID)
+	if err != nil {
+		return chatTurn{}, err
+	}
+	if original.Role != models.RoleUser {
+		return chatTurn{}, fmt.Errorf("only user messages can be edited")
+	}
+
+	// The edited message keeps the original's files unless the request replaces them
+	fileIDs := req.FileIDs
+	if fileIDs == nil {
+		attachments, _ := original.GetFileAttachments()
+		for _, attachment := range attachments {
+			fileIDs = append(fileIDs, attachment.FileID)
+		}
+	}
+
+	return b.build(original, original.ParentID, req.Content, fileIDs, req)
+}
+
+// regenerate builds the turn answering the user message behind messageID again
+// messageID is an assistant reply or the user message itself
+func (b *branchTurns) regenerate(messageID string, req BranchRequest) (chatTurn, error) {
+	message, tree, err := b.load(messageID)
+	if err != nil {
+		return chatTurn{}, err
+	}
+	prompt, ok := tree.PromptOf(message.ID)
+	if !ok {
+		return chatTurn{}, fmt.Errorf("message %s does not answer a user message", messageID)
+	}
+
+	var fileIDs []string
+	attachments, _ := prompt.GetFileAttachments()
+	for _, attachment := range attachments {
+		fileIDs = append(fileIDs, attachment.FileID)
+	}
+
+	turn, err := b.build(prompt, prompt.ParentID, prompt.Content, fileIDs, req)
+	if err != nil {
+		return chatTurn{}, err
+	}
+	turn.ParentID = &prompt.ID
+	turn.Regenerate = true
+	return turn, nil
+}
+
+// load finds a message and the tree of its session
+func (b *branchTurns) load(messageID string) (*models.Message, *services.MessageTree, error) {
+	if _, err := uuid.Parse(messageID); err != nil {
+		return nil, nil, errMessageNotFound
+	}
+	message, err := b.messageRepo.FindByID(messageID)
+	if err != nil {
+		return nil, nil, errMessageNotFound
+	}
+	tree, err := b.contextService.SessionTree(message.SessionID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return message, tree, nil
+}
+
+// build resolves the persona and generation settings and the history up to parentID
+// (the same steps as a new WebSocket message) for a user message with content
+func (b *branchTurns) build(original *models.Message, parentID *uuid.UUID, content string, fileIDs []string, req BranchRequest) (chatTurn, error) {
+	// 1. Persona: request → the message's persona → default persona
+	personaID := 1
+	if req.PersonaID != nil {
+		personaID = *req.PersonaID
+	} else if original.PersonaID != nil {
+		personaID = *original.PersonaID
+	}
+	persona, err := b.personaRepo.FindByID(personaID)
+	if err != nil {
+		return chatTurn{}, fmt.Errorf("persona with ID %d not found", personaID)
+	}
+
+	systemPrompt := persona.SystemPrompt
+	if req.SystemPrompt != "" {
+		systemPrompt = systemPrompt + "\n\n--- Additional Instructions ---\n" + req.SystemPrompt
+	}
+
+	// 2. Resolve provider, model and generation settings: request → persona → provider default
+	settings, err := b.catalog.ResolveGeneration(services.GenerationOverrides{
+		Provider:    req.Provider,
+		Model:       req.Model,
+		Temperature: req.Temperature,
+		MaxTokens:   req.MaxTokens,
+	}, persona, "")
+	if err != nil {
+		return chatTurn{}, err
+	}
+
+	// 3. History of the branch the message continues
+	conversation, err := b.contextService.BuildConversation(original.SessionID, parentID, systemPrompt, content, services.DefaultHistoryLimit, fileIDs)
+	if err != nil {
+		log.Printf("⚠️  Failed to build context: %v", err)
+		conversation = services.NewSimpleConversation(systemPrompt, content)
+	}
+	b.knowledge.Augment(context.Background(), conversation, persona)
+
+	// 4. Fit the request to the model's limits (context window, max output, vision)
+	chatReq, err := b.catalog.Prepare(settings.Provider, settings.Apply(services.StreamingChatRequest{
+		Messages:     conversation.Messages,
+		SystemPrompt: conversation.SystemPrompt,
+		Tools:        b.tools.Registry().Definitions(persona.GetTools()),
+	}))
+	if err != nil {
+		return chatTurn{}, err
+	}
+
+	return chatTurn{
+		SessionID: original.SessionID,
+		PersonaID: &personaID,
+		Content:   content,
+		FileIDs:   fileIDs,
+		Provider:  settings.Provider,
+		Request:   chatReq,
+		ParentID:  parentID,
+	}, nil
+}
+
+// BranchController handles editing, regenerating and browsing conversation branches
+type BranchController struct {
+	contextService *services.ContextService
+	turns          *branchTurns
+	streamer       *chatStreamer
+}
+
+// NewBranchController creates a new branch controller
+func NewBranchController(
+	messageRepo *repositories.MessageRepository,
+	personaRepo *repositories.PersonaRepository,
+	contextService *services.ContextService,
+	failover *services.FailoverStreamer,
+	usageService *services.UsageService,
+	tools *services.ToolOrchestrator,
+	catalog *services.ModelCatalog,
+	conversations *services.ConversationService,
+	knowledge *services.KnowledgeService,
+) *BranchController {
+	return &BranchController{
+		contextService: contextService,
+		turns: &branchTurns{
+			messageRepo:    messageRepo,
+			personaRepo:    personaRepo,
+			contextService: contextService,
+			catalog:        catalog,
+			tools:          tools,
+			knowledge:      knowledge,
+		},
+		streamer: &chatStreamer{
+			messageRepo:    messageRepo,
+			contextService: contextService,
+			failover:       failover,
+			usageService:   usageService,
+			tools:          tools,
+			conversations:  conversations,
+		},
+	}
+}
+
+// BranchTurnResponse is the JSON reply of the edit and regenerate endpoints
+type BranchTurnResponse struct {
+	MessageID     string                   `json:"message_id"`                // New assistant message
+	UserMessageID string                   `json:"user_message_id,omitempty"` // New user message (edit only)
+	ParentID      string                   `json:"parent_id,omitempty"`       // Message the reply follows
+	SessionID     string                   `json:"session_id"`
+	Reply         string                   `json:"reply"`
+	Reasoning     string                   `json:"reasoning,omitempty"`
+	TokensUsed    int                      `json:"tokens_used"`
+	Model         string                   `json:"model"`
+	Provider      string                   `json:"provider"`
+	Cost          *float64                 `json:"cost,omitempty"`
+	ToolCalls     []services.ToolCall      `json:"tool_calls,omitempty"`
+	Citations     []models.MessageCitation `json:"citations,omitempty"` // Passages of the attached files the reply cites
+	Timestamp     time.Time                `json:"timestamp"`
+}
+
+// BranchInfo places a message among its alternatives
+type BranchInfo struct {
+	Index      int      `json:"index"`       // Position among the alternatives (0 = oldest)
+	Count      int      `json:"count"`       // Number of alternatives, the message included
+	SiblingIDs []string `json:"sibling_ids"` // Alternatives, oldest first
+}
+
+// BranchMessageItem is a message of the active branch
+type BranchMessageItem struct {
+	MessageHistoryItem
+	Branch BranchInfo `json:"branch"`
+}
+
+// BranchResponse is the active branch of a session
+type BranchResponse struct {
+	SessionID string              `json:"session_id"`
+	LeafID    string              `json:"leaf_id,omitempty"` // Last message of the branch (parent_id of the next message)
+	Messages  []BranchMessageItem `json:"messages"`
+}
+
+// EditMessage handles POST /api/chats/messages/:id/edit
+func (ctrl *BranchController) EditMessage(c *fiber.Ctx) error {
+	var req BranchRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	turn, err := ctrl.turns.edit(c.Params("id"), req)
+	if err != nil {
+		return branchError(c, err)
+	}
+	return ctrl.answer(c, turn, req.Stream)
+}
+
+// RegenerateMessage handles POST /api/chats/messages/:id/regenerate
+func (ctrl *BranchController) RegenerateMessage(c *fiber.Ctx) error {
+	var req BranchRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+	}
+
+	turn, err := ctrl.turns.regenerate(c.Params("id"), req)
+	if err != nil {
+		return branchError(c, err)
+	}
+	return ctrl.answer(c, turn, req.Stream)
+}
+
+// answer streams the turn as Server-Sent Events or collects it into a JSON reply
+func (ctrl *BranchController) answer(c *fiber.Ctx, turn chatTurn, stream bool) error {
+	c.Set("X-Session-ID", turn.SessionID)
+	if wantsEventStream(c, stream) {
+		return ctrl.streamer.streamSSE(c, turn)
+	}
+
+	var reply, reasoning strings.Builder
+	var toolCalls []services.ToolCall
+	var done WSResponse
+	err := ctrl.streamer.stream(c.UserContext(), turn, func(frame WSResponse) error {
+		switch {
+		case frame.Done:
+			done = frame
+		case frame.Type == "chunk":
+			reply.WriteString(frame.Content)
+		case frame.Type == "reasoning":
+			reasoning.WriteString(frame.Content)
+		case frame.Type == "tool_call" && frame.ToolCall != nil:
+			toolCalls = append(toolCalls, *frame.ToolCall)
+		}
+		return nil
+	})
+	if err != nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"error": fmt.Sprintf("Failed to get AI response: %v", err),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(BranchTurnResponse{
+		MessageID:     done.MessageID,
+		UserMessageID: done.UserMessageID,
+		ParentID:      done.ParentID,
+		SessionID:     turn.SessionID,
+		Reply:         reply.String(),
+		Reasoning:     reasoning.String(),
+		TokensUsed:    done.TokensUsed,
+		Model:         done.Model,
+		Provider:      done.Provider,
+		Cost:          done.Cost,
+		ToolCalls:     toolCalls,
+		Citations:     done.Citations,
+		Timestamp:     time.Now(),
+	})
+}
+
+// GetBranch handles GET /api/chats/session/:sessionId/branch
+// Returns the branch through message_id (down to its most recent reply), or the most recent branch
+func (ctrl *BranchController) GetBranch(c *fiber.Ctx) error {
+	sessionID := c.Params("sessionId")
+	if sessionID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Session ID is required",
+		})
+	}
+
+	tree, err := ctrl.contextService.SessionTree(sessionID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve chat history for session",
+		})
+	}
+
+	response := BranchResponse{SessionID: sessionID, Messages: []BranchMessageItem{}}
+
+	leaf := tree.Latest()
+	if messageID := c.Query("message_id"); messageID != "" {
+		id, err := uuid.Parse(messageID)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid message_id",
+			})
+		}
+		if leaf = tree.LatestLeaf(id); leaf == nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Message not found in session",
+			})
+		}
+	}
+	if leaf == nil {
+		return c.Status(fiber.StatusOK).JSON(response)
+	}
+
+	response.LeafID = leaf.ID.String()
+	for _, msg := range tree.Path(leaf.ID) {
+		item := BranchMessageItem{
+			MessageHistoryItem: MessageHistoryItem{
+				ID:         msg.ID.String(),
+				Role:       msg.Role,
+				Content:    msg.Content,
+				PersonaID:  msg.PersonaID,
+				CreatedAt:  msg.CreatedAt,
+				ToolCallID: msg.ToolCallID,
+				ToolName:   msg.ToolName,
+			},
+		}
+		if msg.ParentID != nil {
+			item.ParentID = msg.ParentID.String()
+		}
+		item.ToolCalls, _ = msg.GetToolCalls()
+		item.Citations, _ = msg.GetCitations()
+
+		siblings := tree.Siblings(msg.ID)
+		item.Branch.Count = len(siblings)
+		for i, sibling := range siblings {
+			if sibling.ID == msg.ID {
+				item.Branch.Index = i
+			}
+			item.Branch.SiblingIDs = append(item.Branch.SiblingIDs, sibling.ID.String())
+		}
+		response.Messages = append(response.Messages, item)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+// branchError maps an edit or regenerate error to a response
+func branchError(c *fiber.Ctx, err error) error {
+	status := fiber.StatusBadRequest
+	if errors.Is(err, errMessageNotFound) {
+		status = fiber.StatusNotFound
+	}
+	return c.Status(status).JSON(fiber.Map{
+		"error": err.Error(),
+	})
+}
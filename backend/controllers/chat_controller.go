@@ -1,6 +1,9 @@
 package controllers
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
@@ -9,7 +12,7 @@ import (
 	"chatbot/services"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/sashabaranov/go-openai"
+	"github.com/google/uuid"
 )
 
 // ChatController handles chat-related HTTP requests
@@ -17,8 +20,15 @@ type ChatController struct {
 	messageRepo      *repositories.MessageRepository
 	personaRepo      *repositories.PersonaRepository
 	fileAnalysisRepo *repositories.FileAnalysisRepository
-	openaiService    *services.OpenAIService
+	providers        *services.ProviderRegistry
 	contextService   *services.ContextService
+	usageService     *services.UsageService
+	tools            *services.ToolOrchestrator
+	structured       *services.StructuredOutputService
+	catalog          *services.ModelCatalog
+	conversations    *services.ConversationService
+	knowledge        *services.KnowledgeService
+	streamer         *chatStreamer
 }
 
 // NewChatController creates a new chat controller
@@ -26,14 +36,36 @@ func NewChatController(
 	messageRepo *repositories.MessageRepository,
 	personaRepo *repositories.PersonaRepository,
 	fileAnalysisRepo *repositories.FileAnalysisRepository,
-	openaiService *services.OpenAIService,
+	contextService *services.ContextService,
+	providers *services.ProviderRegistry,
+	usageService *services.UsageService,
+	tools *services.ToolOrchestrator,
+	structured *services.StructuredOutputService,
+	failover *services.FailoverStreamer,
+	catalog *services.ModelCatalog,
+	conversations *services.ConversationService,
+	knowledge *services.KnowledgeService,
 ) *ChatController {
 	return &ChatController{
 		messageRepo:      messageRepo,
 		personaRepo:      personaRepo,
 		fileAnalysisRepo: fileAnalysisRepo,
-		openaiService:    openaiService,
-		contextService:   services.NewContextService(messageRepo, fileAnalysisRepo),
+		providers:        providers,
+		contextService:   contextService,
+		usageService:     usageService,
+		tools:            tools,
+		structured:       structured,
+		catalog:          catalog,
+		conversations:    conversations,
+		knowledge:        knowledge,
+		streamer: &chatStreamer{
+			messageRepo:    messageRepo,
+			contextService: contextService,
+			failover:       failover,
+			usageService:   usageService,
+			tools:          tools,
+			conversations:  conversations,
+		},
 	}
 }
 
@@ -45,31 +77,45 @@ type ChatRequest struct {
 	SystemPrompt string   `json:"system_prompt,omitempty"`
 	Temperature  float32  `json:"temperature,omitempty"`
 	MaxTokens    int      `json:"max_tokens,omitempty"`
-	Model        string   `json:"model,omitempty"`
+	Model        string   `json:"model,omitempty"`    // Persona's model or provider default if empty
+	Provider     string   `json:"provider,omitempty"` // Provider name (persona's provider, the model's provider or OpenAI if empty)
 	UseHistory   bool     `json:"use_history,omitempty"`
-	FileIDs      []string `json:"file_ids,omitempty"` // File IDs for current message only
+	FileIDs      []string `json:"file_ids,omitempty"`  // File IDs for current message only
+	Stream       bool     `json:"stream,omitempty"`    // Reply with Server-Sent Events (same as Accept: text/event-stream)
+	ParentID     string   `json:"parent_id,omitempty"` // Message to reply after (latest message of the session if empty)
+
+	ResponseSchema *services.ResponseSchema `json:"response_schema,omitempty"` // Reply with JSON matching this schema
 }
 
 // PersonaInfo contains persona information in response
 type PersonaInfo struct {
-	ID          int    `json:"id"`
-	Name        string `json:"name"`
-	Expertise   string `json:"expertise"`
-	Icon        string `json:"icon"`
-	Description string `json:"description"`
+	ID          int      `json:"id"`
+	Name        string   `json:"name"`
+	Expertise   string   `json:"expertise"`
+	Icon        string   `json:"icon"`
+	Description string   `json:"description"`
+	Provider    string   `json:"provider,omitempty"` // Provider the persona is bound to
+	Model       string   `json:"model,omitempty"`
+	Tools       []string `json:"tools,omitempty"` // Tools the persona may call
 }
 
 // ChatResponse represents the API response
 type ChatResponse struct {
-	MessageID    string       `json:"message_id"`
-	SessionID    string       `json:"session_id"`
-	Reply        string       `json:"reply"`
-	PersonaUsed  *PersonaInfo `json:"persona,omitempty"`
-	TokensUsed   int          `json:"tokens_used"`
-	Model        string       `json:"model"`
-	Timestamp    time.Time    `json:"timestamp"`
-	HistoryUsed  bool         `json:"history_used"`
-	HistoryCount int          `json:"history_count"`
+	MessageID    string                   `json:"message_id"`
+	SessionID    string                   `json:"session_id"`
+	Reply        string                   `json:"reply"`
+	Reasoning    string                   `json:"reasoning,omitempty"` // Reasoning before the reply (persona thinking_budget)
+	PersonaUsed  *PersonaInfo             `json:"persona,omitempty"`
+	TokensUsed   int                      `json:"tokens_used"`
+	Model        string                   `json:"model"`
+	Provider     string                   `json:"provider,omitempty"`
+	Cost         *float64                 `json:"cost,omitempty"`       // USD cost from the pricing table
+	ToolCalls    []services.ToolCall      `json:"tool_calls,omitempty"` // Tools executed while answering
+	Structured   json.RawMessage          `json:"structured,omitempty"` // Reply parsed as JSON (response_schema)
+	Citations    []models.MessageCitation `json:"citations,omitempty"`  // Passages of the attached files the reply cites
+	Timestamp    time.Time                `json:"timestamp"`
+	HistoryUsed  bool                     `json:"history_used"`
+	HistoryCount int                      `json:"history_count"`
 }
 
 // MessageHistoryItem represents a message in history
@@ -79,7 +125,14 @@ type MessageHistoryItem struct {
 	Content   string    `json:"content"`
 	PersonaID *int      `json:"persona_id,omitempty"`
 	SessionID string    `json:"session_id,omitempty"`
+	ParentID  string    `json:"parent_id,omitempty"` // Previous message in the branch
 	CreatedAt time.Time `json:"created_at"`
+
+	ToolCalls  []models.MessageToolCall `json:"tool_calls,omitempty"`   // Tools requested by an assistant message
+	ToolCallID string                   `json:"tool_call_id,omitempty"` // Call answered by a tool message
+	ToolName   string                   `json:"tool_name,omitempty"`
+
+	Citations []models.MessageCitation `json:"citations,omitempty"` // Passages of the attached files an assistant message cites
 }
 
 // ChatHistoryResponse represents the chat history API response
@@ -99,7 +152,7 @@ func (ctrl *ChatController) HandleChat(c *fiber.Ctx) error {
 	}
 
 	// 2. Get persona and system prompt
-	systemPrompt, personaInfo, err := ctrl.getPersonaInfo(req)
+	systemPrompt, persona, personaInfo, err := ctrl.getPersonaInfo(req)
 	if err != nil {
 		return err
 	}
@@ -107,26 +160,82 @@ func (ctrl *ChatController) HandleChat(c *fiber.Ctx) error {
 	// 3. Generate or use session ID
 	sessionID := ctrl.getOrGenerateSessionID(req)
 
-	// 4. Build context with conversation history
-	messages, historyCount := ctrl.buildMessages(req, sessionID, systemPrompt)
+	// 4. Build context with the history of the branch the message continues
+	parentID, err := ctrl.contextService.ActiveParent(sessionID, req.ParentID)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	conversation, historyCount := ctrl.buildConversation(req, sessionID, parentID, systemPrompt)
+	ctrl.knowledge.Augment(c.UserContext(), conversation, persona)
+
+	// 5. Resolve the provider and generation settings, then fit the request to the model's limits
+	providerName, chatReq, err := ctrl.providerRequest(req, persona, conversation)
+	if err != nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"error": fmt.Sprintf("Failed to get AI response: %v", err),
+		})
+	}
+	chatReq, err = ctrl.catalog.Prepare(providerName, chatReq)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	// 6. Stream the answer as Server-Sent Events when the client asked for it
+	if wantsEventStream(c, req.Stream) {
+		c.Set("X-Session-ID", sessionID)
+		return ctrl.streamer.streamSSE(c, chatTurn{
+			SessionID: sessionID,
+			PersonaID: req.PersonaID,
+			Content:   req.Message,
+			FileIDs:   req.FileIDs,
+			Provider:  providerName,
+			Request:   chatReq,
+			ParentID:  parentID,
+		})
+	}
 
-	// 5. Call OpenAI service
-	openaiResp, err := ctrl.callOpenAI(req, messages, systemPrompt)
+	// 7. Call the persona's provider (OpenAI by default)
+	openaiResp, toolSteps, err := ctrl.callProvider(c.UserContext(), providerName, chatReq)
+	var structuredErr *services.StructuredOutputError
+	if errors.As(err, &structuredErr) {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error":    "AI response did not match response_schema",
+			"problems": structuredErr.Problems,
+			"attempts": structuredErr.Attempts,
+			"raw":      structuredErr.Content,
+		})
+	}
 	if err != nil {
 		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
 			"error": fmt.Sprintf("Failed to get AI response: %v", err),
 		})
 	}
 
-	// 6. Save messages to database
-	if err := ctrl.saveMessages(req, sessionID, openaiResp); err != nil {
+	// 8. Resolve the citations of the attached files and save messages to database
+	var citations []models.MessageCitation
+	if req.ResponseSchema == nil {
+		citations = ctrl.contextService.FileCitations(req.FileIDs, openaiResp.Content)
+	}
+	assistantMessage, err := ctrl.saveMessages(req, sessionID, parentID, openaiResp, toolSteps, citations, chatReq.PromptCaching)
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to save messages",
 		})
 	}
 
-	// 7. Build and return response
+	// 9. Build and return response
 	response := ctrl.buildResponse(sessionID, openaiResp, personaInfo, req.UseHistory, historyCount)
+	response.MessageID = assistantMessage.ID.String()
+	response.Cost = assistantMessage.Cost
+	response.ToolCalls = services.ExecutedToolCalls(toolSteps)
+	response.Citations = citations
+	if req.ResponseSchema != nil {
+		response.Structured = json.RawMessage(openaiResp.Content)
+	}
 	return c.Status(fiber.StatusOK).JSON(response)
 }
 
@@ -145,18 +254,26 @@ func (ctrl *ChatController) parseRequest(c *fiber.Ctx) (*ChatRequest, error) {
 		})
 	}
 
+	if req.ResponseSchema != nil {
+		if err := req.ResponseSchema.Check(); err != nil {
+			return nil, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid response_schema: " + err.Error(),
+			})
+		}
+	}
+
 	return &req, nil
 }
 
 // getPersonaInfo retrieves persona information and determines system prompt
-func (ctrl *ChatController) getPersonaInfo(req *ChatRequest) (string, *PersonaInfo, error) {
+func (ctrl *ChatController) getPersonaInfo(req *ChatRequest) (string, *models.Persona, *PersonaInfo, error) {
 	if req.PersonaID == nil {
-		return req.SystemPrompt, nil, nil
+		return req.SystemPrompt, nil, nil, nil
 	}
 
 	persona, err := ctrl.personaRepo.FindByID(*req.PersonaID)
 	if err != nil {
-		return "", nil, fmt.Errorf("persona with ID %d not found", *req.PersonaID)
+		return "", nil, nil, fmt.Errorf("persona with ID %d not found", *req.PersonaID)
 	}
 
 	// Start with persona's system prompt
@@ -173,9 +290,12 @@ func (ctrl *ChatController) getPersonaInfo(req *ChatRequest) (string, *PersonaIn
 		Expertise:   persona.Expertise,
 		Icon:        persona.Icon,
 		Description: persona.Description,
+		Provider:    persona.Provider,
+		Model:       persona.Model,
+		Tools:       persona.GetTools(),
 	}
 
-	return systemPrompt, personaInfo, nil
+	return systemPrompt, persona, personaInfo, nil
 }
 
 // getOrGenerateSessionID returns existing session ID or generates a new one
@@ -186,100 +306,75 @@ func (ctrl *ChatController) getOrGenerateSessionID(req *ChatRequest) string {
 	return fmt.Sprintf("session_%d", time.Now().UnixNano())
 }
 
-// buildMessages builds OpenAI messages array with optional history and current files
-func (ctrl *ChatController) buildMessages(req *ChatRequest, sessionID, systemPrompt string) ([]openai.ChatCompletionMessage, int) {
-	historyCount := 0
-
-	// Build context with history if enabled
+// buildConversation builds the provider-neutral conversation with optional history and current files
+func (ctrl *ChatController) buildConversation(req *ChatRequest, sessionID string, parentID *uuid.UUID, systemPrompt string) (*services.Conversation, int) {
+	historyLimit := 0
 	if req.UseHistory && sessionID != "" {
-		messages, err := ctrl.contextService.BuildContextWithHistory(
-			sessionID, systemPrompt, req.Message, 10,
-		)
-		if err != nil {
-			fmt.Printf("⚠️  Failed to build context with history: %v\n", err)
-		} else {
-			historyCount = len(messages) - 1 // Subtract current message
-			if systemPrompt != "" {
-				historyCount--
-			}
-
-			// Add file context for current message if files provided
-			if len(req.FileIDs) > 0 {
-				fileContext, err := ctrl.contextService.BuildFileContext(req.FileIDs)
-				if err == nil && fileContext != "" {
-					// Insert file context before the last user message
-					messages = append(
-						messages[:len(messages)-1],
-						openai.ChatCompletionMessage{
-							Role:    openai.ChatMessageRoleSystem,
-							Content: fileContext,
-						},
-						messages[len(messages)-1],
-					)
-				}
-			}
-
-			return messages, historyCount
-		}
+		historyLimit = services.DefaultHistoryLimit
 	}
 
-	// Fallback: simple message with optional file context
-	messages := []openai.ChatCompletionMessage{}
-
-	if systemPrompt != "" {
-		messages = append(messages, openai.ChatCompletionMessage{
-			Role:    openai.ChatMessageRoleSystem,
-			Content: systemPrompt,
-		})
+	conversation, err := ctrl.contextService.BuildConversation(
+		sessionID, parentID, systemPrompt, req.Message, historyLimit, req.FileIDs,
+	)
+	if err != nil {
+		fmt.Printf("⚠️  Failed to build context with history: %v\n", err)
+		return services.NewSimpleConversation(systemPrompt, req.Message), 0
 	}
 
-	// Add file context if files provided
-	if len(req.FileIDs) > 0 {
-		fileContext, err := ctrl.contextService.BuildFileContext(req.FileIDs)
-		if err == nil && fileContext != "" {
-			messages = append(messages, openai.ChatCompletionMessage{
-				Role:    openai.ChatMessageRoleSystem,
-				Content: fileContext,
-			})
-		}
+	return conversation, conversation.HistoryCount
+}
+
+// providerRequest resolves the provider and generation settings (request → persona → OpenAI defaults)
+// and builds the request to send. Requests with a response_schema ask for JSON instead of offering the persona's tools
+func (ctrl *ChatController) providerRequest(req *ChatRequest, persona *models.Persona, conversation *services.Conversation) (string, services.StreamingChatRequest, error) {
+	settings, err := ctrl.catalog.ResolveGeneration(services.GenerationOverrides{
+		Provider:    req.Provider,
+		Model:       req.Model,
+		Temperature: float64(req.Temperature),
+		MaxTokens:   req.MaxTokens,
+	}, persona, "openai")
+	if err != nil {
+		return "", services.StreamingChatRequest{}, err
 	}
 
-	messages = append(messages, openai.ChatCompletionMessage{
-		Role:    openai.ChatMessageRoleUser,
-		Content: req.Message,
+	chatReq := settings.Apply(services.StreamingChatRequest{
+		Messages:     conversation.Messages,
+		SystemPrompt: conversation.SystemPrompt,
 	})
-
-	return messages, 0
+	if req.ResponseSchema != nil {
+		chatReq.ResponseSchema = req.ResponseSchema
+	} else if persona != nil {
+		chatReq.Tools = ctrl.tools.Registry().Definitions(persona.GetTools())
+	}
+	return settings.Provider, chatReq, nil
 }
 
-// callOpenAI sends request to OpenAI API
-func (ctrl *ChatController) callOpenAI(req *ChatRequest, messages []openai.ChatCompletionMessage, systemPrompt string) (*services.ChatResponse, error) {
-	// Only include system prompt if not using history (to avoid duplication)
-	systemPromptForAPI := ""
-	if !req.UseHistory || len(messages) == 1 {
-		systemPromptForAPI = systemPrompt
+// callProvider sends the request to the provider resolved from the registry, running the
+// tool loop when the persona has tools; it also returns the tool steps to persist
+// Requests with a response_schema get validated JSON instead (tools are not used)
+func (ctrl *ChatController) callProvider(ctx context.Context, providerName string, chatReq services.StreamingChatRequest) (*services.ChatCompletion, []services.ConversationMessage, error) {
+	provider, err := ctrl.providers.Get(providerName)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	// Debug log
-	fmt.Printf("🔍 Debug callOpenAI:\n")
-	fmt.Printf("  - UseHistory: %v\n", req.UseHistory)
-	fmt.Printf("  - Messages count: %d\n", len(messages))
-	fmt.Printf("  - SystemPrompt from param: %q\n", systemPrompt)
-	fmt.Printf("  - SystemPrompt for API: %q\n", systemPromptForAPI)
-
-	openaiReq := services.ChatRequest{
-		Messages:     messages,
-		Model:        req.Model,
-		Temperature:  req.Temperature,
-		MaxTokens:    req.MaxTokens,
-		SystemPrompt: systemPromptForAPI,
+	if chatReq.ResponseSchema != nil {
+		result, err := ctrl.structured.Complete(ctx, provider, chatReq)
+		if err != nil {
+			return nil, nil, err
+		}
+		return result.Completion, nil, nil
 	}
 
-	return ctrl.openaiService.SendChatRequest(openaiReq)
+	completion, run, err := ctrl.tools.Complete(ctx, provider, chatReq)
+	if err != nil {
+		return nil, nil, err
+	}
+	return completion, run.Steps, nil
 }
 
-// saveMessages saves user message and AI response to database
-func (ctrl *ChatController) saveMessages(req *ChatRequest, sessionID string, openaiResp *services.ChatResponse) error {
+// saveMessages saves user message and AI response as a branch after parentID and returns the saved AI response
+func (ctrl *ChatController) saveMessages(req *ChatRequest, sessionID string, parentID *uuid.UUID, openaiResp *services.ChatCompletion, toolSteps []services.ConversationMessage, citations []models.MessageCitation, promptCaching bool) (*models.Message, error) {
 	// Save user message
 	userMessage := &models.Message{
 		SessionID: sessionID,
@@ -287,12 +382,17 @@ func (ctrl *ChatController) saveMessages(req *ChatRequest, sessionID string, ope
 		Content:   req.Message,
 		PersonaID: req.PersonaID,
 	}
-
-	if err := ctrl.messageRepo.Create(userMessage); err != nil {
-		return err
+	if len(req.FileIDs) > 0 {
+		if err := userMessage.SetFileAttachments(ctrl.contextService.FileAttachments(req.FileIDs)); err != nil {
+			fmt.Printf("⚠️  Failed to set file attachments: %v\n", err)
+		}
 	}
 
-	// Save AI response
+	// Tool calls and results go between the question and the answer
+	branch := []*models.Message{userMessage}
+	branch = append(branch, services.ToolStepRecords(toolSteps, sessionID, req.PersonaID)...)
+
+	// AI response
 	tokensUsed := openaiResp.TokensUsed
 	assistantMessage := &models.Message{
 		SessionID:  sessionID,
@@ -301,18 +401,55 @@ func (ctrl *ChatController) saveMessages(req *ChatRequest, sessionID string, ope
 		PersonaID:  req.PersonaID,
 		TokensUsed: &tokensUsed,
 	}
-	return ctrl.messageRepo.Create(assistantMessage)
+	metadata := map[string]interface{}{}
+	if reasoning := services.ReasoningText(openaiResp.Reasoning); reasoning != "" {
+		// Reasoning is kept in metadata for debugging, never in the saved answer
+		metadata["reasoning"] = reasoning
+	}
+	if promptCaching {
+		metadata["prompt_caching"] = true
+	}
+	if len(citations) > 0 {
+		metadata["citations"] = citations
+	}
+	for key, value := range openaiResp.Usage.CacheMetadata() {
+		metadata[key] = value
+	}
+	if len(metadata) > 0 {
+		assistantMessage.Metadata, _ = json.Marshal(metadata)
+	}
+	if usage := openaiResp.Usage; usage != nil {
+		assistantMessage.SetTokenUsage(usage.PromptTokens, usage.CompletionTokens, usage.CachedTokens)
+	}
+	ctrl.usageService.PriceMessage(assistantMessage, openaiResp.Provider, openaiResp.Model, openaiResp.Usage)
+
+	branch = append(branch, assistantMessage)
+	if err := ctrl.messageRepo.CreateBranch(parentID, branch...); err != nil {
+		return nil, err
+	}
+	ctrl.conversations.RecordTurn(services.ConversationTurn{
+		SessionID: sessionID,
+		LeafID:    assistantMessage.ID,
+		PersonaID: req.PersonaID,
+		Provider:  openaiResp.Provider,
+		Model:     openaiResp.Model,
+		Question:  req.Message,
+		Answer:    openaiResp.Content,
+	})
+	return assistantMessage, nil
 }
 
 // buildResponse builds the final chat response
-func (ctrl *ChatController) buildResponse(sessionID string, openaiResp *services.ChatResponse, personaInfo *PersonaInfo, useHistory bool, historyCount int) ChatResponse {
+func (ctrl *ChatController) buildResponse(sessionID string, openaiResp *services.ChatCompletion, personaInfo *PersonaInfo, useHistory bool, historyCount int) ChatResponse {
 	return ChatResponse{
 		MessageID:    "", // Will be set after save
 		SessionID:    sessionID,
 		Reply:        openaiResp.Content,
+		Reasoning:    services.ReasoningText(openaiResp.Reasoning),
 		PersonaUsed:  personaInfo,
 		TokensUsed:   openaiResp.TokensUsed,
 		Model:        openaiResp.Model,
+		Provider:     openaiResp.Provider,
 		Timestamp:    time.Now(),
 		HistoryUsed:  useHistory && historyCount > 0,
 		HistoryCount: historyCount,
@@ -355,6 +492,13 @@ func (ctrl *ChatController) GetChatHistory(c *fiber.Ctx) error {
 			SessionID: msg.SessionID,
 			CreatedAt: msg.CreatedAt,
 		}
+		if msg.ParentID != nil {
+			items[i].ParentID = msg.ParentID.String()
+		}
+		items[i].ToolCalls, _ = msg.GetToolCalls()
+		items[i].ToolCallID = msg.ToolCallID
+		items[i].ToolName = msg.ToolName
+		items[i].Citations, _ = msg.GetCitations()
 	}
 
 	// Build response
@@ -370,8 +514,8 @@ func (ctrl *ChatController) GetChatHistory(c *fiber.Ctx) error {
 
 // DeleteAllMessages handles DELETE /api/chats endpoint
 func (ctrl *ChatController) DeleteAllMessages(c *fiber.Ctx) error {
-	// Delete all messages from database
-	if err := ctrl.messageRepo.DeleteAll(); err != nil {
+	// Delete all messages and their conversations from database
+	if err := ctrl.conversations.DeleteAll(); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to delete messages",
 		})
@@ -392,8 +536,8 @@ func (ctrl *ChatController) DeleteMessagesBySession(c *fiber.Ctx) error {
 		})
 	}
 
-	// Delete all messages in this session
-	if err := ctrl.messageRepo.DeleteSession(sessionID); err != nil {
+	// Delete all messages in this session along with its conversation
+	if err := ctrl.conversations.Delete(sessionID); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to delete messages for session",
 		})
@@ -462,6 +606,13 @@ func (ctrl *ChatController) GetChatHistoryBySession(c *fiber.Ctx) error {
 			PersonaID: msg.PersonaID,
 			CreatedAt: msg.CreatedAt,
 		}
+		if msg.ParentID != nil {
+			items[i].ParentID = msg.ParentID.String()
+		}
+		items[i].ToolCalls, _ = msg.GetToolCalls()
+		items[i].ToolCallID = msg.ToolCallID
+		items[i].ToolName = msg.ToolName
+		items[i].Citations, _ = msg.GetCitations()
 	}
 
 	// Build response
@@ -0,0 +1,324 @@
+package controllers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"chatbot/models"
+	"chatbot/repositories"
+	"chatbot/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/valyala/fasthttp"
+)
+
+// ========================================
+// Streaming Chat Turn (WebSocket and SSE)
+// ========================================
+// chatStreamer runs one streaming turn: provider failover, the tool loop,
+// saving the messages and the final done frame. The WebSocket controller
+// writes its frames as JSON messages, the REST endpoints as Server-Sent Events.
+
+// chatTurn is one user message to answer with streaming
+type chatTurn struct {
+	SessionID string
+	PersonaID *int
+	Content   string   // User message as saved to history
+	FileIDs   []string // Files attached to the user message
+	Provider  string   // Requested provider ("" = auto-detect)
+	Request   services.StreamingChatRequest
+
+	ParentID   *uuid.UUID // Message the turn follows (nil starts the session)
+	Regenerate bool       // Answer ParentID (an existing user message) again without saving a new user message
+}
+
+// chatStreamer streams a chat turn and saves it to the session history
+type chatStreamer struct {
+	messageRepo    *repositories.MessageRepository
+	contextService *services.ContextService
+	failover       *services.FailoverStreamer
+	usageService   *services.UsageService
+	tools          *services.ToolOrchestrator
+	conversations  *services.ConversationService
+}
+
+// stream answers turn, sending every frame to emit; a cancelled ctx ends it quietly
+func (s *chatStreamer) stream(ctx context.Context, turn chatTurn, emit func(WSResponse) error) error {
+	req := turn.Request
+	if req.ResponseSchema != nil {
+		// Structured replies stream as plain JSON text and are checked at the end
+		req.Tools = nil
+	}
+
+	// Open each round's stream with retry and provider failover (before its first chunk is sent)
+	// Rounds after the first are pinned to the provider and model that answered it
+	var result *services.FailoverResult
+	attempts := 0
+	openStream := func(ctx context.Context, req services.StreamingChatRequest) (services.StreamReader, error) {
+		provider := turn.Provider
+		if result != nil {
+			provider = result.Provider
+			req.Model = result.Model
+			req.PinProvider = true
+		}
+		stream, res, err := s.failover.CreateStreamingChat(ctx, provider, req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create streaming request: %w", err)
+		}
+		attempts += res.Attempts
+		result = res
+		log.Printf("🟢 Using %s for streaming (requested: %q, attempts: %d)", res.Provider, turn.Provider, res.Attempts)
+		return stream, nil
+	}
+
+	// Stream the response, running any tools the model calls
+	run, err := s.tools.Stream(ctx, req, openStream, services.ToolEvents{
+		OnChunk: func(chunk string) error {
+			return emit(WSResponse{Type: "chunk", Content: chunk})
+		},
+		OnReasoning: func(text string) error {
+			return emit(WSResponse{Type: "reasoning", Content: text})
+		},
+		OnToolCall: func(call services.ToolCall) error {
+			return emit(WSResponse{Type: "tool_call", ToolCall: &call})
+		},
+		OnToolResult: func(call services.ToolCall, output string, failed bool) error {
+			return emit(WSResponse{Type: "tool_result", Content: output, ToolCall: &call, ToolError: failed})
+		},
+	})
+	if ctx.Err() != nil {
+		// Request was cancelled
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	result.Attempts = attempts
+	fullContent := run.Content
+
+	// Streamed replies cannot be repaired, so a mismatch is reported instead of saved
+	var structured json.RawMessage
+	if req.ResponseSchema != nil {
+		data := services.ExtractJSON(fullContent)
+		if err := services.ValidateJSONSchema(req.ResponseSchema.Schema, []byte(data)); err != nil {
+			return err
+		}
+		fullContent = data
+		structured = json.RawMessage(data)
+	}
+
+	// Citations of the attached files; structured replies are JSON and cite nothing
+	var citations []models.MessageCitation
+	if req.ResponseSchema == nil {
+		citations = s.contextService.FileCitations(turn.FileIDs, fullContent)
+	}
+
+	// The turn is saved as one branch: user message (unless regenerating), tool steps, answer
+	var branch []*models.Message
+	var userMessage *models.Message
+	if !turn.Regenerate {
+		userMessage = &models.Message{
+			SessionID: turn.SessionID,
+			Role:      models.RoleUser,
+			Content:   turn.Content,
+			PersonaID: turn.PersonaID,
+		}
+		if len(turn.FileIDs) > 0 {
+			if err := userMessage.SetFileAttachments(s.contextService.FileAttachments(turn.FileIDs)); err != nil {
+				log.Printf("⚠️  Failed to set file attachments: %v", err)
+			}
+		}
+		branch = append(branch, userMessage)
+	}
+
+	// Tool calls and results go between the question and the answer so history replays them
+	branch = append(branch, services.ToolStepRecords(run.Steps, turn.SessionID, turn.PersonaID)...)
+
+	// Assistant response
+	// Use the usage reported by the provider, fall back to a rough estimate if it sent none
+	usage := run.Usage
+	tokensEstimated := usage == nil
+	if tokensEstimated {
+		usage = &services.TokenUsage{CompletionTokens: len(fullContent) / 4}
+	}
+
+	metadata := map[string]interface{}{
+		"provider":         result.Provider,
+		"attempts":         result.Attempts,
+		"tokens_estimated": tokensEstimated,
+		"tool_rounds":      run.Rounds - 1,
+	}
+	if req.ResponseSchema != nil {
+		metadata["response_schema"] = req.ResponseSchema.Name
+	}
+	if reasoning := services.ReasoningText(run.Reasoning); reasoning != "" {
+		// Kept for debugging only; the saved answer and the replayed history stay free of reasoning
+		metadata["reasoning"] = reasoning
+	}
+	if req.PromptCaching {
+		metadata["prompt_caching"] = true
+	}
+	if len(citations) > 0 {
+		metadata["citations"] = citations
+	}
+	for key, value := range usage.CacheMetadata() {
+		metadata[key] = value
+	}
+	metadataJSON, _ := json.Marshal(metadata)
+
+	assistantMessage := &models.Message{
+		SessionID: turn.SessionID,
+		Role:      models.RoleAssistant,
+		Content:   fullContent,
+		PersonaID: turn.PersonaID,
+		Metadata:  metadataJSON,
+	}
+	assistantMessage.SetTokenUsage(usage.PromptTokens, usage.CompletionTokens, usage.CachedTokens)
+
+	// Estimated usage is not priced
+	pricedUsage := usage
+	if tokensEstimated {
+		pricedUsage = nil
+	}
+	s.usageService.PriceMessage(assistantMessage, result.Provider, result.Model, pricedUsage)
+
+	branch = append(branch, assistantMessage)
+	if err := s.messageRepo.CreateBranch(turn.ParentID, branch...); err != nil {
+		log.Printf("Failed to save messages: %v", err)
+	}
+	s.conversations.RecordTurn(services.ConversationTurn{
+		SessionID: turn.SessionID,
+		LeafID:    assistantMessage.ID,
+		PersonaID: turn.PersonaID,
+		Provider:  result.Provider,
+		Model:     result.Model,
+		Question:  turn.Content,
+		Answer:    fullContent,
+	})
+
+	// Send completion message
+	done := WSResponse{
+		Type:             "chunk",
+		Content:          "",
+		Done:             true,
+		MessageID:        assistantMessage.ID.String(),
+		TokensUsed:       usage.Total(),
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		CachedTokens:     usage.CachedTokens,
+		Provider:         result.Provider,
+		Model:            result.Model,
+		Attempts:         result.Attempts,
+		Cost:             assistantMessage.Cost,
+		Structured:       structured,
+		Citations:        citations,
+	}
+	if assistantMessage.ParentID != nil {
+		done.ParentID = assistantMessage.ParentID.String()
+	}
+	if userMessage != nil {
+		done.UserMessageID = userMessage.ID.String()
+	}
+	return emit(done)
+}
+
+// wantsEventStream reports whether a REST chat request asked for Server-Sent Events
+func wantsEventStream(c *fiber.Ctx, stream bool) bool {
+	return stream || strings.Contains(c.Get(fiber.HeaderAccept), "text/event-stream")
+}
+
+// streamSSE answers turn as Server-Sent Events: chunk, reasoning, tool_call, tool_result, done and error
+// Each event's data is the same JSON frame the WebSocket endpoint sends
+func (s *chatStreamer) streamSSE(c *fiber.Ctx, turn chatTurn) error {
+	return serveSSE(c, func(ctx context.Context, out *sseWriter) {
+		err := s.stream(ctx, turn, func(frame WSResponse) error {
+			event := frame.Type
+			if frame.Done {
+				event = "done"
+			}
+			return out.event(event, frame)
+		})
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Error streaming chat: %v", err)
+			out.event("error", sseError(err))
+		}
+	})
+}
+
+// sseWriter writes events to a streaming response, cancelling its context once the client is gone
+type sseWriter struct {
+	w      *bufio.Writer
+	cancel context.CancelFunc
+}
+
+// event writes one event; an empty name sends a data-only event
+func (s *sseWriter) event(name string, data interface{}) error {
+	if err := WriteSSEEvent(s.w, name, data); err != nil {
+		s.cancel()
+		return err
+	}
+	return nil
+}
+
+// data writes a data-only event with a literal payload (such as [DONE])
+func (s *sseWriter) data(payload string) error {
+	if _, err := fmt.Fprintf(s.w, "data: %s\n\n", payload); err != nil {
+		s.cancel()
+		return err
+	}
+	if err := s.w.Flush(); err != nil {
+		s.cancel()
+		return err
+	}
+	return nil
+}
+
+// serveSSE switches the response to an event stream written by write
+// write runs after the handler has returned, so it must not use c
+func serveSSE(c *fiber.Ctx, write func(ctx context.Context, out *sseWriter)) error {
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	c.Set("X-Accel-Buffering", "no") // Stop nginx from buffering the stream
+
+	c.Context().SetBodyStreamWriter(fasthttp.StreamWriter(func(w *bufio.Writer) {
+		// Stop generating once the client is gone
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		write(ctx, &sseWriter{w: w, cancel: cancel})
+	}))
+	return nil
+}
+
+// sseError is the error frame, matching the WebSocket one
+func sseError(err error) fiber.Map {
+	frame := fiber.Map{"type": "error", "error": err.Error()}
+	if violation, ok := err.(*services.SchemaViolationError); ok {
+		frame["problems"] = violation.Problems
+	}
+	return frame
+}
+
+// WriteSSEEvent writes one Server-Sent Event with a JSON data line and flushes it
+// An empty event name omits the event line (clients then see a "message" event)
+func WriteSSEEvent(w *bufio.Writer, event string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if event != "" {
+		if _, err := fmt.Fprintf(w, "event: %s\n", event); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintf(w, "data: %s\n\n", payload); err != nil {
+		return err
+	}
+	return w.Flush()
+}
@@ -0,0 +1,385 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"chatbot/models"
+	"chatbot/repositories"
+	"chatbot/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ConversationController handles conversation (chat session) CRUD and listing
+type ConversationController struct {
+	conversationRepo *repositories.ConversationRepository
+	messageRepo      *repositories.MessageRepository
+	personaRepo      *repositories.PersonaRepository
+	providers        *services.ProviderRegistry
+	conversations    *services.ConversationService
+}
+
+// NewConversationController creates a new conversation controller
+func NewConversationController(
+	conversationRepo *repositories.ConversationRepository,
+	messageRepo *repositories.MessageRepository,
+	personaRepo *repositories.PersonaRepository,
+	providers *services.ProviderRegistry,
+	conversations *services.ConversationService,
+) *ConversationController {
+	return &ConversationController{
+		conversationRepo: conversationRepo,
+		messageRepo:      messageRepo,
+		personaRepo:      personaRepo,
+		providers:        providers,
+		conversations:    conversations,
+	}
+}
+
+// ConversationResponse represents a conversation in API responses
+type ConversationResponse struct {
+	ID            string     `json:"id"` // Use as session_id of chat requests
+	Title         string     `json:"title"`
+	PersonaID     *int       `json:"persona_id,omitempty"`
+	Provider      string     `json:"provider,omitempty"`
+	Model         string     `json:"model,omitempty"`
+	Archived      bool       `json:"archived"`
+	Pinned        bool       `json:"pinned"`
+	Tags          []string   `json:"tags"`
+	MessageCount  int64      `json:"message_count"`
+	LastMessageAt *time.Time `json:"last_message_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// ConversationListResponse represents the conversation listing
+type ConversationListResponse struct {
+	Conversations []ConversationResponse `json:"conversations"`
+	Total         int64                  `json:"total"`
+	Limit         int                    `json:"limit"`
+	Offset        int                    `json:"offset"`
+}
+
+// ConversationRequest is the body of create and update requests (update only changes the fields sent)
+type ConversationRequest struct {
+	Title     *string   `json:"title"`
+	PersonaID *int      `json:"persona_id"`
+	Provider  *string   `json:"provider"`
+	Model     *string   `json:"model"`
+	Archived  *bool     `json:"archived"`
+	Pinned    *bool     `json:"pinned"`
+	Tags      *[]string `json:"tags"`
+}
+
+// ListConversations handles GET /api/conversations
+// Query: limit, offset, search (title or tag), archived (true|false|all, default false), pinned, persona_id, tag
+func (ctrl *ConversationController) ListConversations(c *fiber.Ctx) error {
+	limit, offset := pagination(c)
+	filter := repositories.ConversationFilter{
+		Search: strings.TrimSpace(c.Query("search")),
+		Tag:    c.Query("tag"),
+		Limit:  limit,
+		Offset: offset,
+	}
+
+	switch archived := c.Query("archived", "false"); archived {
+	case "all":
+	case "true", "false":
+		value := archived == "true"
+		filter.Archived = &value
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "archived must be true, false or all",
+		})
+	}
+	if pinned := c.Query("pinned"); pinned != "" {
+		value, err := strconv.ParseBool(pinned)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "pinned must be true or false",
+			})
+		}
+		filter.Pinned = &value
+	}
+	if personaID := c.Query("persona_id"); personaID != "" {
+		id, err := strconv.Atoi(personaID)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid persona_id",
+			})
+		}
+		filter.PersonaID = &id
+	}
+
+	summaries, total, err := ctrl.conversationRepo.List(filter)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve conversations",
+		})
+	}
+
+	items := make([]ConversationResponse, len(summaries))
+	for i := range summaries {
+		items[i] = conversationResponse(&summaries[i])
+	}
+	return c.Status(fiber.StatusOK).JSON(ConversationListResponse{
+		Conversations: items,
+		Total:         total,
+		Limit:         limit,
+		Offset:        offset,
+	})
+}
+
+// CreateConversation handles POST /api/conversations
+// The conversation gets a new ID; without a title one is generated after the first exchange
+func (ctrl *ConversationController) CreateConversation(c *fiber.Ctx) error {
+	var req ConversationRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+	}
+
+	conversation := &models.Conversation{ID: "conv_" + uuid.NewString()}
+	if err := ctrl.apply(conversation, req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err := ctrl.conversationRepo.Create(conversation); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create conversation",
+		})
+	}
+
+	return ctrl.respond(c, fiber.StatusCreated, conversation.ID)
+}
+
+// GetConversation handles GET /api/conversations/:id
+func (ctrl *ConversationController) GetConversation(c *fiber.Ctx) error {
+	return ctrl.respond(c, fiber.StatusOK, c.Params("id"))
+}
+
+// UpdateConversation handles PATCH /api/conversations/:id (rename, archive, pin, tag, rebind persona)
+func (ctrl *ConversationController) UpdateConversation(c *fiber.Ctx) error {
+	conversation, err := ctrl.conversationRepo.FindByID(c.Params("id"))
+	if err != nil {
+		return conversationNotFound(c, err)
+	}
+
+	var req ConversationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if err := ctrl.apply(conversation, req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err := ctrl.conversationRepo.Update(conversation); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update conversation",
+		})
+	}
+
+	return ctrl.respond(c, fiber.StatusOK, conversation.ID)
+}
+
+// DeleteConversation handles DELETE /api/conversations/:id (the conversation and its messages)
+func (ctrl *ConversationController) DeleteConversation(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if _, err := ctrl.conversationRepo.FindByID(id); err != nil {
+		return conversationNotFound(c, err)
+	}
+
+	if err := ctrl.conversations.Delete(id); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete conversation",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Conversation deleted successfully",
+		"id":      id,
+	})
+}
+
+// GenerateTitle handles POST /api/conversations/:id/title (replaces the title with a generated one)
+func (ctrl *ConversationController) GenerateTitle(c *fiber.Ctx) error {
+	conversation, err := ctrl.conversationRepo.FindByID(c.Params("id"))
+	if err != nil {
+		return conversationNotFound(c, err)
+	}
+
+	if _, err := ctrl.conversations.Retitle(c.UserContext(), conversation); err != nil {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return ctrl.respond(c, fiber.StatusOK, conversation.ID)
+}
+
+// GetConversationMessages handles GET /api/conversations/:id/messages (all branches, oldest first)
+// Query: limit (default 50, max 100), offset
+func (ctrl *ConversationController) GetConversationMessages(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if _, err := ctrl.conversationRepo.FindByID(id); err != nil {
+		return conversationNotFound(c, err)
+	}
+
+	limit, offset := pagination(c)
+	messages, total, err := ctrl.messageRepo.FindByConversation(id, limit, offset)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve conversation messages",
+		})
+	}
+
+	items := make([]MessageHistoryItem, len(messages))
+	for i, msg := range messages {
+		items[i] = MessageHistoryItem{
+			ID:         msg.ID.String(),
+			Role:       msg.Role,
+			Content:    msg.Content,
+			PersonaID:  msg.PersonaID,
+			SessionID:  msg.SessionID,
+			CreatedAt:  msg.CreatedAt,
+			ToolCallID: msg.ToolCallID,
+			ToolName:   msg.ToolName,
+		}
+		if msg.ParentID != nil {
+			items[i].ParentID = msg.ParentID.String()
+		}
+		items[i].ToolCalls, _ = msg.GetToolCalls()
+	}
+
+	return c.Status(fiber.StatusOK).JSON(ChatHistoryResponse{
+		Messages: items,
+		Total:    total,
+		Limit:    limit,
+		Offset:   offset,
+	})
+}
+
+// apply validates the fields of req and sets them on conversation
+func (ctrl *ConversationController) apply(conversation *models.Conversation, req ConversationRequest) error {
+	if req.Title != nil {
+		title := strings.TrimSpace(*req.Title)
+		if len([]rune(title)) > 200 {
+			return fmt.Errorf("title must be at most 200 characters")
+		}
+		conversation.Title = title
+	}
+	if req.PersonaID != nil {
+		if _, err := ctrl.personaRepo.FindByID(*req.PersonaID); err != nil {
+			return fmt.Errorf("persona with ID %d not found", *req.PersonaID)
+		}
+		conversation.PersonaID = req.PersonaID
+	}
+	if req.Provider != nil {
+		if *req.Provider != "" {
+			if _, ok := ctrl.providers.Capabilities(*req.Provider); !ok {
+				return fmt.Errorf("unknown provider %q", *req.Provider)
+			}
+		}
+		conversation.Provider = *req.Provider
+	}
+	if req.Model != nil {
+		conversation.Model = *req.Model
+	}
+	if req.Archived != nil {
+		conversation.Archived = *req.Archived
+	}
+	if req.Pinned != nil {
+		conversation.Pinned = *req.Pinned
+	}
+	if req.Tags != nil {
+		if err := conversation.SetTags(normalizeTags(*req.Tags)); err != nil {
+			return err
+		}
+	} else if len(conversation.Tags) == 0 {
+		conversation.SetTags(nil)
+	}
+	return nil
+}
+
+// respond sends the conversation with its message stats
+func (ctrl *ConversationController) respond(c *fiber.Ctx, status int, id string) error {
+	summary, err := ctrl.conversationRepo.Summary(id)
+	if err != nil {
+		return conversationNotFound(c, err)
+	}
+	return c.Status(status).JSON(conversationResponse(summary))
+}
+
+// conversationResponse converts a conversation summary to its API response
+func conversationResponse(summary *repositories.ConversationSummary) ConversationResponse {
+	return ConversationResponse{
+		ID:            summary.ID,
+		Title:         summary.Title,
+		PersonaID:     summary.PersonaID,
+		Provider:      summary.Provider,
+		Model:         summary.Model,
+		Archived:      summary.Archived,
+		Pinned:        summary.Pinned,
+		Tags:          summary.GetTags(),
+		MessageCount:  summary.MessageCount,
+		LastMessageAt: summary.LastMessageAt,
+		CreatedAt:     summary.CreatedAt,
+		UpdatedAt:     summary.UpdatedAt,
+	}
+}
+
+// conversationNotFound answers 404 for a missing conversation, 500 for other errors
+func conversationNotFound(c *fiber.Ctx, err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Conversation not found",
+		})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": "Failed to retrieve conversation",
+	})
+}
+
+// normalizeTags trims tags and drops empty and duplicate ones
+func normalizeTags(tags []string) []string {
+	seen := make(map[string]bool, len(tags))
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
+
+// pagination reads limit (default 50, max 100) and offset from the query
+func pagination(c *fiber.Ctx) (limit, offset int) {
+	limit = c.QueryInt("limit", 50)
+	offset = c.QueryInt("offset", 0)
+	if limit <= 0 {
+		limit = 50
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return limit, offset
+}
@@ -12,12 +12,14 @@ import (
 // ElevenLabsController handles ElevenLabs TTS HTTP requests
 type ElevenLabsController struct {
 	elevenLabsService *services.ElevenLabsService
+	usageService      *services.UsageService
 }
 
 // NewElevenLabsController creates a new ElevenLabs controller
-func NewElevenLabsController(elevenLabsService *services.ElevenLabsService) *ElevenLabsController {
+func NewElevenLabsController(elevenLabsService *services.ElevenLabsService, usageService *services.UsageService) *ElevenLabsController {
 	return &ElevenLabsController{
 		elevenLabsService: elevenLabsService,
+		usageService:      usageService,
 	}
 }
 
@@ -32,6 +34,8 @@ type ElevenLabsTTSRequest struct {
 	Speed           *float64                `json:"speed,omitempty"`
 	UseSpeakerBoost *bool                   `json:"use_speaker_boost,omitempty"`
 	VoiceSettings   *services.VoiceSettings `json:"voice_settings,omitempty"`
+	SessionID       string                  `json:"session_id,omitempty"` // Optional, for usage reporting
+	PersonaID       *int                    `json:"persona_id,omitempty"` // Optional, for usage reporting
 }
 
 // ElevenLabsTTSResponse represents the response from ElevenLabs TTS API
@@ -42,6 +46,7 @@ type ElevenLabsTTSResponse struct {
 	ModelID         string    `json:"model_id"`
 	VoiceID         string    `json:"voice_id"`
 	DurationSeconds float64   `json:"duration_seconds"`
+	Cost            *float64  `json:"cost,omitempty"` // USD cost from the pricing table
 	Timestamp       time.Time `json:"timestamp"`
 }
 
@@ -92,6 +97,9 @@ func (ctrl *ElevenLabsController) TextToSpeech(c *fiber.Ctx) error {
 		})
 	}
 
+	// Record TTS usage
+	cost := ctrl.usageService.RecordTTS(req.SessionID, req.PersonaID, "elevenlabs", ttsRes.ModelID, ttsRes.CharactersUsed)
+
 	// Check if client wants binary audio or JSON response
 	accept := c.Get("Accept")
 
@@ -116,6 +124,7 @@ func (ctrl *ElevenLabsController) TextToSpeech(c *fiber.Ctx) error {
 		ModelID:         ttsRes.ModelID,
 		VoiceID:         ttsRes.VoiceID,
 		DurationSeconds: ttsRes.DurationSeconds,
+		Cost:            cost,
 		Timestamp:       time.Now(),
 	}
 
@@ -15,12 +15,14 @@ import (
 // ElevenLabsWSController - ควบคุมการทำงานของ WebSocket สำหรับ ElevenLabs TTS
 type ElevenLabsWSController struct {
 	elevenLabsService *services.ElevenLabsService
+	usageService      *services.UsageService
 }
 
 // NewElevenLabsWSController - สร้าง controller instance ใหม่สำหรับจัดการ ElevenLabs WebSocket
-func NewElevenLabsWSController(elevenLabsService *services.ElevenLabsService) *ElevenLabsWSController {
+func NewElevenLabsWSController(elevenLabsService *services.ElevenLabsService, usageService *services.UsageService) *ElevenLabsWSController {
 	return &ElevenLabsWSController{
 		elevenLabsService: elevenLabsService,
+		usageService:      usageService,
 	}
 }
 
@@ -127,6 +129,9 @@ func (ctrl *ElevenLabsWSController) handleTTSRequest(c *websocket.Conn, msg WebS
 			return
 		}
 
+		// บันทึก usage ของ chunk นี้ (คิดราคาตามจำนวนตัวอักษร)
+		ctrl.usageService.RecordTTS(msg.SessionID, nil, "elevenlabs", ttsRes.ModelID, ttsRes.CharactersUsed)
+
 		// log ขนาดของ audio data ที่ได้รับ
 		log.Printf("✅ Chunk %d/%d processed successfully (%d bytes)",
 			i+1, totalChunks, len(ttsRes.AudioData))
@@ -17,6 +17,7 @@ type FileController struct {
 	fileService *services.FileService
 	repository  *repositories.FileAnalysisRepository
 	messageRepo *repositories.MessageRepository
+	extraction  *services.FileExtractionService
 }
 
 // NewFileController creates a new file controller
@@ -24,11 +25,13 @@ func NewFileController(
 	fileService *services.FileService,
 	repository *repositories.FileAnalysisRepository,
 	messageRepo *repositories.MessageRepository,
+	extraction *services.FileExtractionService,
 ) *FileController {
 	return &FileController{
 		fileService: fileService,
 		repository:  repository,
 		messageRepo: messageRepo,
+		extraction:  extraction,
 	}
 }
 
@@ -116,14 +119,18 @@ func (ctrl *FileController) UploadFiles(c *fiber.Ctx) error {
 			continue
 		}
 
+		// Extract its text in the background (read by chat turns and knowledge bases)
+		ctrl.extraction.Extract(*fileAnalysis)
+
 		// Add to successful uploads
 		uploadedFiles = append(uploadedFiles, fiber.Map{
-			"file_id":      fileAnalysis.ID.String(),
-			"file_name":    fileAnalysis.FileName,
-			"storage_path": fileAnalysis.StoragePath,
-			"mime_type":    fileAnalysis.MimeType,
-			"file_size":    fileAnalysis.FileSize,
-			"uploaded_at":  fileAnalysis.UploadedAt,
+			"file_id":           fileAnalysis.ID.String(),
+			"file_name":         fileAnalysis.FileName,
+			"storage_path":      fileAnalysis.StoragePath,
+			"mime_type":         fileAnalysis.MimeType,
+			"file_size":         fileAnalysis.FileSize,
+			"uploaded_at":       fileAnalysis.UploadedAt,
+			"extraction_status": models.ExtractionStatusPending,
 		})
 
 		log.Printf("✅ File uploaded successfully: %s (ID: %s)", file.Filename, fileAnalysis.ID.String())
@@ -203,12 +210,21 @@ func (ctrl *FileController) buildFileHistoryResponse(files []models.FileAnalysis
 	response := make([]fiber.Map, len(files))
 	for i, file := range files {
 		response[i] = fiber.Map{
-			"file_id":      file.ID.String(),
-			"file_name":    file.FileName,
-			"storage_path": file.StoragePath,
-			"mime_type":    file.MimeType,
-			"file_size":    file.FileSize,
-			"uploaded_at":  file.UploadedAt,
+			"file_id":           file.ID.String(),
+			"file_name":         file.FileName,
+			"storage_path":      file.StoragePath,
+			"mime_type":         file.MimeType,
+			"file_size":         file.FileSize,
+			"uploaded_at":       file.UploadedAt,
+			"extraction_status": file.ExtractionStatus,
+			"page_count":        file.PageCount,
+			"language":          file.Language,
+		}
+		if file.ExtractionError != "" {
+			response[i]["extraction_error"] = file.ExtractionError
+		}
+		if pages, _ := file.GetOCRPages(); len(pages) > 0 {
+			response[i]["ocr_pages"] = pages
 		}
 	}
 
@@ -0,0 +1,350 @@
+package controllers
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"chatbot/models"
+	"chatbot/repositories"
+	"chatbot/services"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// KnowledgeController handles knowledge bases, their documents and retrieval
+type KnowledgeController struct {
+	knowledgeRepo *repositories.KnowledgeRepository
+	knowledge     *services.KnowledgeService
+}
+
+// NewKnowledgeController creates a new knowledge controller
+func NewKnowledgeController(knowledgeRepo *repositories.KnowledgeRepository, knowledge *services.KnowledgeService) *KnowledgeController {
+	return &KnowledgeController{
+		knowledgeRepo: knowledgeRepo,
+		knowledge:     knowledge,
+	}
+}
+
+// KnowledgeBaseResponse represents a knowledge base in API responses
+type KnowledgeBaseResponse struct {
+	ID            string                     `json:"id"` // Use in a persona's knowledge_bases
+	Name          string                     `json:"name"`
+	Description   string                     `json:"description"`
+	DocumentCount int64                      `json:"document_count"`
+	ChunkCount    int64                      `json:"chunk_count"`
+	Documents     []models.KnowledgeDocument `json:"documents,omitempty"` // Single knowledge base only
+	CreatedAt     time.Time                  `json:"created_at"`
+	UpdatedAt     time.Time                  `json:"updated_at"`
+}
+
+// KnowledgeBaseRequest is the body of create and update requests (update only changes the fields sent)
+type KnowledgeBaseRequest struct {
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+}
+
+// KnowledgeDocumentsRequest adds uploaded files (see POST /api/file/uploads) to a knowledge base
+type KnowledgeDocumentsRequest struct {
+	FileIDs []string `json:"file_ids"`
+}
+
+// KnowledgeQueryRequest retrieves the chunks most relevant to a query
+type KnowledgeQueryRequest struct {
+	Query string `json:"query"`
+	TopK  int    `json:"top_k"` // Optional (default KNOWLEDGE_TOP_K, max 20)
+}
+
+// ListKnowledgeBases handles GET /api/knowledge-bases
+func (ctrl *KnowledgeController) ListKnowledgeBases(c *fiber.Ctx) error {
+	summaries, err := ctrl.knowledgeRepo.List()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve knowledge bases",
+		})
+	}
+
+	items := make([]KnowledgeBaseResponse, len(summaries))
+	for i := range summaries {
+		items[i] = knowledgeBaseResponse(&summaries[i])
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"knowledge_bases": items,
+		"available":       ctrl.knowledge.Available(),
+	})
+}
+
+// CreateKnowledgeBase handles POST /api/knowledge-bases
+func (ctrl *KnowledgeController) CreateKnowledgeBase(c *fiber.Ctx) error {
+	var req KnowledgeBaseRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if req.Name == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "name is required",
+		})
+	}
+
+	kb := &models.KnowledgeBase{}
+	if err := applyKnowledgeBase(kb, req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err := ctrl.knowledgeRepo.Create(kb); err != nil {
+		return knowledgeBaseSaveFailed(c, err)
+	}
+
+	return ctrl.respond(c, fiber.StatusCreated, kb)
+}
+
+// GetKnowledgeBase handles GET /api/knowledge-bases/:id (with its documents and their indexing status)
+func (ctrl *KnowledgeController) GetKnowledgeBase(c *fiber.Ctx) error {
+	kb, err := ctrl.knowledgeRepo.FindByID(c.Params("id"))
+	if err != nil {
+		return knowledgeBaseNotFound(c, err)
+	}
+	return ctrl.respond(c, fiber.StatusOK, kb)
+}
+
+// UpdateKnowledgeBase handles PATCH /api/knowledge-bases/:id
+func (ctrl *KnowledgeController) UpdateKnowledgeBase(c *fiber.Ctx) error {
+	kb, err := ctrl.knowledgeRepo.FindByID(c.Params("id"))
+	if err != nil {
+		return knowledgeBaseNotFound(c, err)
+	}
+
+	var req KnowledgeBaseRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if err := applyKnowledgeBase(kb, req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err := ctrl.knowledgeRepo.Update(kb); err != nil {
+		return knowledgeBaseSaveFailed(c, err)
+	}
+
+	return ctrl.respond(c, fiber.StatusOK, kb)
+}
+
+// DeleteKnowledgeBase handles DELETE /api/knowledge-bases/:id (documents and chunks; uploaded files are kept)
+func (ctrl *KnowledgeController) DeleteKnowledgeBase(c *fiber.Ctx) error {
+	kb, err := ctrl.knowledgeRepo.FindByID(c.Params("id"))
+	if err != nil {
+		return knowledgeBaseNotFound(c, err)
+	}
+
+	if err := ctrl.knowledgeRepo.Delete(kb.ID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete knowledge base",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Knowledge base deleted successfully",
+		"id":      kb.ID,
+	})
+}
+
+// AddDocuments handles POST /api/knowledge-bases/:id/documents
+// Files are indexed in the background; poll GET /api/knowledge-bases/:id for their status
+func (ctrl *KnowledgeController) AddDocuments(c *fiber.Ctx) error {
+	kb, err := ctrl.knowledgeRepo.FindByID(c.Params("id"))
+	if err != nil {
+		return knowledgeBaseNotFound(c, err)
+	}
+	if !ctrl.knowledge.Available() {
+		return knowledgeUnavailable(c)
+	}
+
+	var req KnowledgeDocumentsRequest
+	if err := c.BodyParser(&req); err != nil || len(req.FileIDs) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "file_ids is required",
+		})
+	}
+
+	docs, err := ctrl.knowledge.AddFiles(kb, req.FileIDs)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"documents": docs,
+	})
+}
+
+// ReindexDocument handles POST /api/knowledge-bases/:id/documents/:documentId/reindex
+func (ctrl *KnowledgeController) ReindexDocument(c *fiber.Ctx) error {
+	kb, err := ctrl.knowledgeRepo.FindByID(c.Params("id"))
+	if err != nil {
+		return knowledgeBaseNotFound(c, err)
+	}
+	doc, err := ctrl.knowledgeRepo.FindDocument(kb.ID, c.Params("documentId"))
+	if err != nil {
+		return knowledgeDocumentNotFound(c, err)
+	}
+	if !ctrl.knowledge.Available() {
+		return knowledgeUnavailable(c)
+	}
+
+	ctrl.knowledge.Index(*doc)
+	doc.Status = models.KnowledgeStatusPending
+	doc.Error = ""
+	return c.Status(fiber.StatusAccepted).JSON(doc)
+}
+
+// DeleteDocument handles DELETE /api/knowledge-bases/:id/documents/:documentId (the uploaded file is kept)
+func (ctrl *KnowledgeController) DeleteDocument(c *fiber.Ctx) error {
+	kb, err := ctrl.knowledgeRepo.FindByID(c.Params("id"))
+	if err != nil {
+		return knowledgeBaseNotFound(c, err)
+	}
+	doc, err := ctrl.knowledgeRepo.FindDocument(kb.ID, c.Params("documentId"))
+	if err != nil {
+		return knowledgeDocumentNotFound(c, err)
+	}
+
+	if err := ctrl.knowledgeRepo.DeleteDocument(doc.ID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete document",
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Document removed from knowledge base",
+		"id":      doc.ID,
+	})
+}
+
+// QueryKnowledgeBase handles POST /api/knowledge-bases/:id/query (the chunks a chat turn would retrieve)
+func (ctrl *KnowledgeController) QueryKnowledgeBase(c *fiber.Ctx) error {
+	kb, err := ctrl.knowledgeRepo.FindByID(c.Params("id"))
+	if err != nil {
+		return knowledgeBaseNotFound(c, err)
+	}
+	if !ctrl.knowledge.Available() {
+		return knowledgeUnavailable(c)
+	}
+
+	var req KnowledgeQueryRequest
+	if err := c.BodyParser(&req); err != nil || strings.TrimSpace(req.Query) == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "query is required",
+		})
+	}
+
+	sources, err := ctrl.knowledge.Retrieve(c.UserContext(), []string{kb.ID.String()}, req.Query, req.TopK)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to query knowledge base",
+			"details": err.Error(),
+		})
+	}
+
+	citations := make([]string, len(sources))
+	for i, source := range sources {
+		citations[i] = source.Citation()
+	}
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"query":     req.Query,
+		"results":   sources,
+		"citations": citations,
+	})
+}
+
+// respond sends a knowledge base with its stats and documents
+func (ctrl *KnowledgeController) respond(c *fiber.Ctx, status int, kb *models.KnowledgeBase) error {
+	summary, err := ctrl.knowledgeRepo.Summary(kb.ID)
+	if err != nil {
+		return knowledgeBaseNotFound(c, err)
+	}
+	docs, err := ctrl.knowledgeRepo.ListDocuments(kb.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve knowledge base documents",
+		})
+	}
+
+	response := knowledgeBaseResponse(summary)
+	response.Documents = docs
+	return c.Status(status).JSON(response)
+}
+
+// knowledgeBaseResponse converts a knowledge base summary to its API response
+func knowledgeBaseResponse(summary *repositories.KnowledgeBaseSummary) KnowledgeBaseResponse {
+	return KnowledgeBaseResponse{
+		ID:            summary.ID.String(),
+		Name:          summary.Name,
+		Description:   summary.Description,
+		DocumentCount: summary.DocumentCount,
+		ChunkCount:    summary.ChunkCount,
+		CreatedAt:     summary.CreatedAt,
+		UpdatedAt:     summary.UpdatedAt,
+	}
+}
+
+// applyKnowledgeBase validates a request and copies its fields onto the knowledge base
+func applyKnowledgeBase(kb *models.KnowledgeBase, req KnowledgeBaseRequest) error {
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" || len(name) > 100 {
+			return errors.New("name must be between 1 and 100 characters")
+		}
+		kb.Name = name
+	}
+	if req.Description != nil {
+		kb.Description = strings.TrimSpace(*req.Description)
+	}
+	return nil
+}
+
+func knowledgeBaseNotFound(c *fiber.Ctx, err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Knowledge base not found",
+		})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": "Failed to retrieve knowledge base",
+	})
+}
+
+func knowledgeDocumentNotFound(c *fiber.Ctx, err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Document not found",
+		})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": "Failed to retrieve document",
+	})
+}
+
+func knowledgeBaseSaveFailed(c *fiber.Ctx, err error) error {
+	if errors.Is(err, gorm.ErrDuplicatedKey) || strings.Contains(err.Error(), "duplicate key") {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "A knowledge base with this name already exists",
+		})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": "Failed to save knowledge base",
+	})
+}
+
+func knowledgeUnavailable(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+		"error": "Knowledge bases are not available (needs pgvector and OPENAI_API_KEY)",
+	})
+}
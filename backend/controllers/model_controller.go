@@ -0,0 +1,26 @@
+package controllers
+
+import (
+	"chatbot/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ModelController exposes the model catalog
+type ModelController struct {
+	catalog *services.ModelCatalog
+}
+
+// NewModelController creates a new model controller
+func NewModelController(catalog *services.ModelCatalog) *ModelController {
+	return &ModelController{
+		catalog: catalog,
+	}
+}
+
+// GetModels handles GET /api/models endpoint (optional ?provider= filter)
+func (ctrl *ModelController) GetModels(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"models": ctrl.catalog.List(c.Query("provider")),
+	})
+}
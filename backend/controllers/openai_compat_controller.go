@@ -0,0 +1,458 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"chatbot/models"
+	"chatbot/repositories"
+	"chatbot/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/sashabaranov/go-openai"
+)
+
+// OpenAICompatController serves an OpenAI-compatible API (/v1) on top of personas
+// A model is either "persona-<id>", "<provider>/<model>", a provider name or a catalogued model
+type OpenAICompatController struct {
+	personaRepo *repositories.PersonaRepository
+	providers   *services.ProviderRegistry
+	tools       *services.ToolOrchestrator
+	catalog     *services.ModelCatalog
+	knowledge   *services.KnowledgeService
+	streamer    *chatStreamer
+}
+
+// NewOpenAICompatController creates a new OpenAI-compatible controller
+func NewOpenAICompatController(
+	messageRepo *repositories.MessageRepository,
+	personaRepo *repositories.PersonaRepository,
+	contextService *services.ContextService,
+	providers *services.ProviderRegistry,
+	failover *services.FailoverStreamer,
+	usageService *services.UsageService,
+	tools *services.ToolOrchestrator,
+	catalog *services.ModelCatalog,
+	conversations *services.ConversationService,
+	knowledge *services.KnowledgeService,
+) *OpenAICompatController {
+	return &OpenAICompatController{
+		personaRepo: personaRepo,
+		providers:   providers,
+		tools:       tools,
+		catalog:     catalog,
+		knowledge:   knowledge,
+		streamer: &chatStreamer{
+			messageRepo:    messageRepo,
+			contextService: contextService,
+			failover:       failover,
+			usageService:   usageService,
+			tools:          tools,
+			conversations:  conversations,
+		},
+	}
+}
+
+const personaModelPrefix = "persona-"
+
+// OpenAIChatRequest is the subset of the OpenAI chat completions request the facade supports
+type OpenAIChatRequest struct {
+	Model               string                         `json:"model"`
+	Messages            []openai.ChatCompletionMessage `json:"messages"`
+	Temperature         *float64                       `json:"temperature,omitempty"` // Nil uses the persona's temperature
+	MaxTokens           int                            `json:"max_tokens,omitempty"`
+	MaxCompletionTokens int                            `json:"max_completion_tokens,omitempty"`
+	Stream              bool                           `json:"stream,omitempty"`
+	StreamOptions       *OpenAIStreamOptions           `json:"stream_options,omitempty"`
+	N                   int                            `json:"n,omitempty"`
+	ResponseFormat      *OpenAIResponseFormat          `json:"response_format,omitempty"`
+	Tools               json.RawMessage                `json:"tools,omitempty"`     // Rejected: tools are enabled per persona
+	Functions           json.RawMessage                `json:"functions,omitempty"` // Rejected: tools are enabled per persona
+}
+
+// OpenAIStreamOptions are the stream_options of a chat completions request
+type OpenAIStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// OpenAIResponseFormat is response_format; json_schema maps to a ResponseSchema
+type OpenAIResponseFormat struct {
+	Type       string                   `json:"type"` // "text", "json_object" or "json_schema"
+	JSONSchema *services.ResponseSchema `json:"json_schema,omitempty"`
+}
+
+// OpenAIChatCompletion is a chat.completion or chat.completion.chunk object
+type OpenAIChatCompletion struct {
+	ID      string         `json:"id"`
+	Object  string         `json:"object"`
+	Created int64          `json:"created"`
+	Model   string         `json:"model"`
+	Choices []OpenAIChoice `json:"choices"`
+	Usage   *OpenAIUsage   `json:"usage,omitempty"`
+}
+
+// OpenAIChoice is a completion choice (message) or a stream choice (delta)
+type OpenAIChoice struct {
+	Index        int            `json:"index"`
+	Message      *OpenAIMessage `json:"message,omitempty"`
+	Delta        *OpenAIMessage `json:"delta,omitempty"`
+	FinishReason *string        `json:"finish_reason"`
+}
+
+// OpenAIMessage is an assistant message or delta
+type OpenAIMessage struct {
+	Role             string `json:"role,omitempty"`
+	Content          string `json:"content"`
+	ReasoningContent string `json:"reasoning_content,omitempty"` // Reasoning of thinking models (DeepSeek-style field)
+}
+
+// OpenAIUsage is the token usage in OpenAI format
+type OpenAIUsage struct {
+	PromptTokens        int                       `json:"prompt_tokens"`
+	CompletionTokens    int                       `json:"completion_tokens"`
+	TotalTokens         int                       `json:"total_tokens"`
+	PromptTokensDetails *OpenAIPromptTokenDetails `json:"prompt_tokens_details,omitempty"`
+}
+
+// OpenAIPromptTokenDetails reports cached prompt tokens
+type OpenAIPromptTokenDetails struct {
+	CachedTokens int `json:"cached_tokens"`
+}
+
+// OpenAIModel is an entry of GET /v1/models
+type OpenAIModel struct {
+	ID          string `json:"id"`
+	Object      string `json:"object"`
+	Created     int64  `json:"created"`
+	OwnedBy     string `json:"owned_by"`
+	Name        string `json:"name,omitempty"`        // Persona name
+	Description string `json:"description,omitempty"` // Persona description
+}
+
+// openAITarget is what a request's model resolved to
+type openAITarget struct {
+	persona  *models.Persona
+	provider string // "" = auto-detect
+	model    string
+}
+
+// ListModels handles GET /v1/models
+func (ctrl *OpenAICompatController) ListModels(c *fiber.Ctx) error {
+	data := []OpenAIModel{}
+
+	personas, err := ctrl.personaRepo.FindActive()
+	if err != nil {
+		return openAIError(c, fiber.StatusInternalServerError, "api_error", "Failed to load personas")
+	}
+	for _, persona := range personas {
+		data = append(data, OpenAIModel{
+			ID:          fmt.Sprintf("%s%d", personaModelPrefix, persona.ID),
+			Object:      "model",
+			Created:     persona.CreatedAt.Unix(),
+			OwnedBy:     "persona",
+			Name:        persona.Name,
+			Description: persona.Description,
+		})
+	}
+
+	for _, provider := range ctrl.providers.List() {
+		if !provider.Available {
+			continue
+		}
+		data = append(data, OpenAIModel{ID: provider.Name, Object: "model", OwnedBy: provider.Name})
+
+		// The provider's allow-list, else its catalog models, else its default model
+		modelIDs := provider.AllowedModels
+		if len(modelIDs) == 0 {
+			for _, model := range ctrl.catalog.List(provider.Name) {
+				if !model.IsDeprecated {
+					modelIDs = append(modelIDs, model.ID)
+				}
+			}
+		}
+		if len(modelIDs) == 0 && provider.DefaultModel != "" {
+			modelIDs = []string{provider.DefaultModel}
+		}
+		for _, model := range modelIDs {
+			data = append(data, OpenAIModel{ID: provider.Name + "/" + model, Object: "model", OwnedBy: provider.Name})
+		}
+	}
+
+	return c.JSON(fiber.Map{
+		"object": "list",
+		"data":   data,
+	})
+}
+
+// ChatCompletions handles POST /v1/chat/completions
+func (ctrl *OpenAICompatController) ChatCompletions(c *fiber.Ctx) error {
+	// 1. Parse and validate request
+	var req OpenAIChatRequest
+	if err := json.Unmarshal(c.Body(), &req); err != nil {
+		return openAIError(c, fiber.StatusBadRequest, "invalid_request_error", "Invalid request body: "+err.Error())
+	}
+	if req.Model == "" {
+		return openAIError(c, fiber.StatusBadRequest, "invalid_request_error", "model is required")
+	}
+	if req.N > 1 {
+		return openAIError(c, fiber.StatusBadRequest, "invalid_request_error", "n > 1 is not supported")
+	}
+	if len(req.Tools) > 0 || len(req.Functions) > 0 {
+		return openAIError(c, fiber.StatusBadRequest, "invalid_request_error", "tools are not supported; enable tools on the persona instead")
+	}
+
+	var responseSchema *services.ResponseSchema
+	if req.ResponseFormat != nil && req.ResponseFormat.Type == "json_schema" {
+		responseSchema = req.ResponseFormat.JSONSchema
+		if responseSchema == nil {
+			return openAIError(c, fiber.StatusBadRequest, "invalid_request_error", "response_format.json_schema is required")
+		}
+		if err := responseSchema.Check(); err != nil {
+			return openAIError(c, fiber.StatusBadRequest, "invalid_request_error", "Invalid response_format: "+err.Error())
+		}
+	}
+
+	conversation, err := services.ConversationFromOpenAI(req.Messages)
+	if err != nil {
+		return openAIError(c, fiber.StatusBadRequest, "invalid_request_error", err.Error())
+	}
+	if len(conversation.Messages) == 0 || conversation.Messages[len(conversation.Messages)-1].Role != services.ConversationRoleUser {
+		return openAIError(c, fiber.StatusBadRequest, "invalid_request_error", "the last message must be from the user")
+	}
+
+	// 2. Map the model to a persona or provider
+	target, err := ctrl.resolveModel(req.Model)
+	if err != nil {
+		return openAIError(c, fiber.StatusNotFound, "invalid_request_error", err.Error())
+	}
+
+	// 3. Resolve generation settings (request → persona → provider default)
+	overrides := services.GenerationOverrides{
+		Provider:  target.provider,
+		Model:     target.model,
+		MaxTokens: req.MaxCompletionTokens,
+	}
+	if overrides.MaxTokens == 0 {
+		overrides.MaxTokens = req.MaxTokens
+	}
+	if req.Temperature != nil {
+		overrides.Temperature = *req.Temperature
+	}
+	settings, err := ctrl.catalog.ResolveGeneration(overrides, target.persona, "")
+	if err != nil {
+		return openAIError(c, fiber.StatusServiceUnavailable, "api_error", err.Error())
+	}
+
+	// 4. Apply the persona's system prompt, guardrails, knowledge bases and tools
+	ctrl.knowledge.Augment(c.UserContext(), conversation, target.persona)
+	chatReq := settings.Apply(services.StreamingChatRequest{
+		Messages:       conversation.Messages,
+		SystemPrompt:   conversation.SystemPrompt,
+		ResponseSchema: responseSchema,
+	})
+
+	var personaID *int
+	if persona := target.persona; persona != nil {
+		personaID = &persona.ID
+		chatReq.SystemPrompt = personaSystemPrompt(persona, conversation.SystemPrompt)
+		chatReq.Tools = ctrl.tools.Registry().Definitions(persona.GetTools())
+	}
+
+	// Fit the request to the model's limits (context window, max output, vision)
+	chatReq, err = ctrl.catalog.Prepare(settings.Provider, chatReq)
+	if err != nil {
+		return openAIError(c, fiber.StatusBadRequest, "invalid_request_error", err.Error())
+	}
+
+	// 5. Answer through the same pipeline as the native endpoints (failover, tools, history)
+	sessionID := c.Get("X-Session-ID")
+	if sessionID == "" {
+		sessionID = fmt.Sprintf("openai-%d", time.Now().UnixNano())
+	}
+	c.Set("X-Session-ID", sessionID)
+
+	// The client sends the whole history; the turn is saved after the session's latest message
+	parentID, err := ctrl.streamer.contextService.ActiveParent(sessionID, "")
+	if err != nil {
+		log.Printf("⚠️  Failed to find the latest message of %s: %v", sessionID, err)
+	}
+
+	turn := chatTurn{
+		SessionID: sessionID,
+		PersonaID: personaID,
+		Content:   conversation.Messages[len(conversation.Messages)-1].Text(),
+		Provider:  settings.Provider,
+		Request:   chatReq,
+		ParentID:  parentID,
+	}
+	completion := OpenAIChatCompletion{
+		ID:      "chatcmpl-" + uuid.NewString(),
+		Created: time.Now().Unix(),
+		Model:   req.Model,
+	}
+
+	log.Printf("📨 OpenAI-compatible request: model=%s, session=%s, stream=%v", req.Model, sessionID, req.Stream)
+
+	if req.Stream {
+		includeUsage := req.StreamOptions != nil && req.StreamOptions.IncludeUsage
+		return ctrl.streamCompletion(c, turn, completion, includeUsage)
+	}
+	return ctrl.completeCompletion(c, turn, completion)
+}
+
+// completeCompletion collects the streamed turn into a chat.completion object
+func (ctrl *OpenAICompatController) completeCompletion(c *fiber.Ctx, turn chatTurn, completion OpenAIChatCompletion) error {
+	var content, reasoning strings.Builder
+	var done WSResponse
+	err := ctrl.streamer.stream(c.UserContext(), turn, func(frame WSResponse) error {
+		switch {
+		case frame.Done:
+			done = frame
+		case frame.Type == "chunk":
+			content.WriteString(frame.Content)
+		case frame.Type == "reasoning":
+			reasoning.WriteString(frame.Content)
+		}
+		return nil
+	})
+	if err != nil {
+		var violation *services.SchemaViolationError
+		if errors.As(err, &violation) {
+			return openAIError(c, fiber.StatusUnprocessableEntity, "invalid_response_error", err.Error())
+		}
+		return openAIError(c, fiber.StatusBadGateway, "api_error", err.Error())
+	}
+
+	reply := content.String()
+	if len(done.Structured) > 0 {
+		reply = string(done.Structured)
+	}
+
+	stop := "stop"
+	completion.Object = "chat.completion"
+	completion.Choices = []OpenAIChoice{{
+		Message:      &OpenAIMessage{Role: openai.ChatMessageRoleAssistant, Content: reply, ReasoningContent: reasoning.String()},
+		FinishReason: &stop,
+	}}
+	completion.Usage = openAIUsageFromFrame(done)
+	return c.JSON(completion)
+}
+
+// streamCompletion streams the turn as chat.completion.chunk events ending with [DONE]
+func (ctrl *OpenAICompatController) streamCompletion(c *fiber.Ctx, turn chatTurn, completion OpenAIChatCompletion, includeUsage bool) error {
+	completion.Object = "chat.completion.chunk"
+	chunk := func(delta *OpenAIMessage, finishReason *string) OpenAIChatCompletion {
+		frame := completion
+		frame.Choices = []OpenAIChoice{{Delta: delta, FinishReason: finishReason}}
+		return frame
+	}
+
+	return serveSSE(c, func(ctx context.Context, out *sseWriter) {
+		if err := out.event("", chunk(&OpenAIMessage{Role: openai.ChatMessageRoleAssistant}, nil)); err != nil {
+			return
+		}
+
+		err := ctrl.streamer.stream(ctx, turn, func(frame WSResponse) error {
+			switch {
+			case frame.Done:
+				stop := "stop"
+				if err := out.event("", chunk(&OpenAIMessage{}, &stop)); err != nil {
+					return err
+				}
+				if includeUsage {
+					final := completion
+					final.Choices = []OpenAIChoice{}
+					final.Usage = openAIUsageFromFrame(frame)
+					return out.event("", final)
+				}
+			case frame.Type == "chunk" && frame.Content != "":
+				return out.event("", chunk(&OpenAIMessage{Content: frame.Content}, nil))
+			case frame.Type == "reasoning":
+				return out.event("", chunk(&OpenAIMessage{ReasoningContent: frame.Content}, nil))
+			}
+			// Tool calls run on the server and are not part of the OpenAI stream
+			return nil
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.Printf("Error streaming OpenAI-compatible completion: %v", err)
+			out.event("", fiber.Map{"error": fiber.Map{"message": err.Error(), "type": "api_error"}})
+			return
+		}
+		out.data("[DONE]")
+	})
+}
+
+// resolveModel maps a request model to a persona, a provider model, a provider or a catalogued model
+func (ctrl *OpenAICompatController) resolveModel(name string) (*openAITarget, error) {
+	if idText, ok := strings.CutPrefix(name, personaModelPrefix); ok {
+		id, err := strconv.Atoi(idText)
+		if err != nil {
+			return nil, fmt.Errorf("model %s does not exist", name)
+		}
+		persona, err := ctrl.personaRepo.FindByID(id)
+		if err != nil || !persona.IsActive {
+			return nil, fmt.Errorf("model %s does not exist", name)
+		}
+
+		// Provider and model come from the persona (see ResolveGeneration)
+		return &openAITarget{persona: persona}, nil
+	}
+
+	provider, model, _ := strings.Cut(name, "/")
+	if _, _, ok := ctrl.providers.Models(provider); !ok {
+		// A bare model name (e.g. "gpt-4o-mini") is sent to the provider the catalog lists it under
+		if owner := ctrl.catalog.ModelProvider(name); owner != "" {
+			provider, model = owner, name
+		}
+	}
+	model, err := ctrl.providers.ValidateModel(provider, model)
+	if err != nil {
+		return nil, fmt.Errorf("model %s does not exist: %w", name, err)
+	}
+	return &openAITarget{provider: provider, model: model}, nil
+}
+
+// personaSystemPrompt combines the persona's prompt, its guardrails and the client's system messages
+func personaSystemPrompt(persona *models.Persona, extra string) string {
+	systemPrompt := persona.SystemPrompt
+	if guardrails := services.GuardrailInstructions(persona.GetGuardrails()); guardrails != "" {
+		systemPrompt += "\n\n" + guardrails
+	}
+	if extra != "" {
+		systemPrompt += "\n\n--- Additional Instructions ---\n" + extra
+	}
+	return systemPrompt
+}
+
+// openAIUsageFromFrame converts the usage of a done frame
+func openAIUsageFromFrame(frame WSResponse) *OpenAIUsage {
+	usage := &OpenAIUsage{
+		PromptTokens:     frame.PromptTokens,
+		CompletionTokens: frame.CompletionTokens,
+		TotalTokens:      frame.TokensUsed,
+	}
+	if frame.CachedTokens > 0 {
+		usage.PromptTokensDetails = &OpenAIPromptTokenDetails{CachedTokens: frame.CachedTokens}
+	}
+	return usage
+}
+
+// openAIError sends an error in the OpenAI error format
+func openAIError(c *fiber.Ctx, status int, errorType, message string) error {
+	return c.Status(status).JSON(fiber.Map{
+		"error": fiber.Map{
+			"message": message,
+			"type":    errorType,
+		},
+	})
+}
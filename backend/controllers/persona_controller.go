@@ -2,11 +2,14 @@ package controllers
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"chatbot/models"
 	"chatbot/repositories"
+	"chatbot/services"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -15,32 +18,45 @@ import (
 type PersonaController struct {
 	personaRepo *repositories.PersonaRepository
 	messageRepo *repositories.MessageRepository
+	providers   *services.ProviderRegistry
+	tools       *services.ToolRegistry
+	catalog     *services.ModelCatalog
+	knowledge   *repositories.KnowledgeRepository
 }
 
 // NewPersonaController creates a new persona controller
-func NewPersonaController(personaRepo *repositories.PersonaRepository, messageRepo *repositories.MessageRepository) *PersonaController {
+func NewPersonaController(personaRepo *repositories.PersonaRepository, messageRepo *repositories.MessageRepository, providers *services.ProviderRegistry, tools *services.ToolRegistry, catalog *services.ModelCatalog, knowledge *repositories.KnowledgeRepository) *PersonaController {
 	return &PersonaController{
 		personaRepo: personaRepo,
 		messageRepo: messageRepo,
+		providers:   providers,
+		tools:       tools,
+		catalog:     catalog,
+		knowledge:   knowledge,
 	}
 }
 
 // PersonaResponse represents a persona in API response
 type PersonaResponse struct {
-	ID              int     `json:"id"`
-	Name            string  `json:"name"`
-	Description     string  `json:"description"`
-	SystemPrompt    string  `json:"system_prompt"`
-	Tone            string  `json:"tone"`
-	Style           string  `json:"style"`
-	Expertise       string  `json:"expertise"`
-	Temperature     float32 `json:"temperature"`
-	MaxTokens       int     `json:"max_tokens"`
-	Model           string  `json:"model"`
-	LanguageSetting string  `json:"language_setting"`
-	Guardrails      string  `json:"guardrails"`
-	Icon            string  `json:"icon"`
-	IsActive        bool    `json:"is_active"`
+	ID              int      `json:"id"`
+	Name            string   `json:"name"`
+	Description     string   `json:"description"`
+	SystemPrompt    string   `json:"system_prompt"`
+	Tone            string   `json:"tone"`
+	Style           string   `json:"style"`
+	Expertise       string   `json:"expertise"`
+	Temperature     float32  `json:"temperature"`
+	MaxTokens       int      `json:"max_tokens"`
+	ThinkingBudget  int      `json:"thinking_budget"`
+	PromptCaching   bool     `json:"prompt_caching"`
+	Model           string   `json:"model"`
+	Provider        string   `json:"provider"`
+	Tools           []string `json:"tools"`
+	KnowledgeBases  []string `json:"knowledge_bases"`
+	LanguageSetting string   `json:"language_setting"`
+	Guardrails      string   `json:"guardrails"`
+	Icon            string   `json:"icon"`
+	IsActive        bool     `json:"is_active"`
 }
 
 // PersonasListResponse represents the list of personas response
@@ -56,15 +72,17 @@ type PersonaStats struct {
 
 // PersonaDetailResponse represents a single persona with stats
 type PersonaDetailResponse struct {
-	ID           int          `json:"id"`
-	Name         string       `json:"name"`
-	SystemPrompt string       `json:"system_prompt"`
-	Expertise    string       `json:"expertise"`
-	Description  string       `json:"description"`
-	Icon         string       `json:"icon"`
-	IsActive     bool         `json:"is_active"`
-	CreatedAt    time.Time    `json:"created_at"`
-	Stats        PersonaStats `json:"stats"`
+	ID             int          `json:"id"`
+	Name           string       `json:"name"`
+	SystemPrompt   string       `json:"system_prompt"`
+	Expertise      string       `json:"expertise"`
+	Description    string       `json:"description"`
+	Icon           string       `json:"icon"`
+	Tools          []string     `json:"tools"`
+	KnowledgeBases []string     `json:"knowledge_bases"`
+	IsActive       bool         `json:"is_active"`
+	CreatedAt      time.Time    `json:"created_at"`
+	Stats          PersonaStats `json:"stats"`
 }
 
 // GetAllPersonas handles GET /api/personas endpoint
@@ -90,7 +108,12 @@ func (ctrl *PersonaController) GetAllPersonas(c *fiber.Ctx) error {
 			Expertise:       persona.Expertise,
 			Temperature:     persona.Temperature,
 			MaxTokens:       persona.MaxTokens,
+			ThinkingBudget:  persona.ThinkingBudget,
+			PromptCaching:   persona.PromptCaching,
 			Model:           persona.Model,
+			Provider:        persona.Provider,
+			Tools:           persona.GetTools(),
+			KnowledgeBases:  persona.GetKnowledgeBases(),
 			LanguageSetting: persona.LanguageSetting,
 			Guardrails:      persona.Guardrails,
 			Icon:            persona.Icon,
@@ -132,14 +155,16 @@ func (ctrl *PersonaController) GetPersonaByID(c *fiber.Ctx) error {
 
 	// Create response with stats
 	response := PersonaDetailResponse{
-		ID:           persona.ID,
-		Name:         persona.Name,
-		SystemPrompt: persona.SystemPrompt,
-		Expertise:    persona.Expertise,
-		Description:  persona.Description,
-		Icon:         persona.Icon,
-		IsActive:     persona.IsActive,
-		CreatedAt:    persona.CreatedAt,
+		ID:             persona.ID,
+		Name:           persona.Name,
+		SystemPrompt:   persona.SystemPrompt,
+		Expertise:      persona.Expertise,
+		Description:    persona.Description,
+		Icon:           persona.Icon,
+		Tools:          persona.GetTools(),
+		KnowledgeBases: persona.GetKnowledgeBases(),
+		IsActive:       persona.IsActive,
+		CreatedAt:      persona.CreatedAt,
 		Stats: PersonaStats{
 			TotalMessages:   messageCount,
 			AvgResponseTime: "2.3s", // Mock value for now
@@ -159,7 +184,12 @@ type CreatePersonaRequest struct {
 	Expertise       string                 `json:"expertise"`
 	Temperature     float32                `json:"temperature"`
 	MaxTokens       int                    `json:"max_tokens"`
+	ThinkingBudget  int                    `json:"thinking_budget"` // Optional reasoning tokens (0 = off, at least 1024)
+	PromptCaching   bool                   `json:"prompt_caching"`  // Cache the system prompt and file context between turns
 	Model           string                 `json:"model"`
+	Provider        string                 `json:"provider"`        // Optional provider name (e.g. "groq"); model is validated against it
+	Tools           []string               `json:"tools"`           // Optional tool names from the tool registry
+	KnowledgeBases  []string               `json:"knowledge_bases"` // Optional knowledge base IDs retrieved from on every turn
 	LanguageSetting LanguageSettingRequest `json:"language_setting"`
 	Guardrails      GuardrailsRequest      `json:"guardrails"`
 	Icon            string                 `json:"icon"`
@@ -233,7 +263,7 @@ func (ctrl *PersonaController) CreatePersona(c *fiber.Ctx) error {
 	if req.MaxTokens == 0 {
 		req.MaxTokens = 2000
 	}
-	if req.Model == "" {
+	if req.Model == "" && req.Provider == "" {
 		req.Model = "gpt-4o-mini"
 	}
 	if req.Icon == "" {
@@ -250,33 +280,17 @@ func (ctrl *PersonaController) CreatePersona(c *fiber.Ctx) error {
 	}
 
 	// Validate model name
-	validModels := map[string]bool{
-		// OpenAI Models
-		"gpt-4o-mini":   true,
-		"gpt-4o":        true,
-		"gpt-4":         true,
-		"gpt-3.5-turbo": true,
-		// AWS Bedrock - Cross-Region Inference (CRI) Models (Recommended)
-		"apac.anthropic.claude-sonnet-4-20250514-v1:0":   true,
-		"apac.anthropic.claude-3-5-sonnet-20241022-v2:0": true,
-		// "apac.amazon.titan-text-premier-v1:0":            true,
-		// "apac.amazon.titan-text-express-v1":              true,
-		// "apac.amazon.titan-text-lite-v1":                 true,
-		// AWS Bedrock - Standard Single-Region Models
-		"anthropic.claude-3-5-sonnet-20240620-v1:0": true,
-		// Legacy/Shorthand Names
-		"claude-sonnet-4": true,
-		"claude-3-opus":   true,
-		"claude-3-sonnet": true,
-	}
-
-	if !validModels[req.Model] {
+	// Validate model against the provider and the model catalog
+	model, err := ctrl.validatePersonaModel(req.Provider, req.Model)
+	if err == nil && model == "" {
+		err = fmt.Errorf("model is required")
+	}
+	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":        "Invalid model name",
-			"valid_models": []string{"gpt-4o-mini", "gpt-4o", "gpt-4", "gpt-3.5-turbo", "claude-sonnet-4", "claude-3-opus", "claude-3-sonnet"},
-			"received":     req.Model,
+			"error": err.Error(),
 		})
 	}
+	req.Model = model
 
 	// Validate temperature range
 	if req.Temperature < 0 || req.Temperature > 2.0 {
@@ -285,6 +299,28 @@ func (ctrl *PersonaController) CreatePersona(c *fiber.Ctx) error {
 		})
 	}
 
+	// Validate thinking budget
+	if err := validateThinkingBudget(req.ThinkingBudget); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	// Validate enabled tools
+	if err := ctrl.tools.Validate(req.Tools); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	// Validate attached knowledge bases
+	knowledgeBases, err := ctrl.validateKnowledgeBases(req.KnowledgeBases)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
 	// Marshal language setting to JSON
 	languageSettingJSON, err := json.Marshal(req.LanguageSetting)
 	if err != nil {
@@ -313,12 +349,25 @@ func (ctrl *PersonaController) CreatePersona(c *fiber.Ctx) error {
 		Expertise:       req.Expertise,
 		Temperature:     req.Temperature,
 		MaxTokens:       req.MaxTokens,
+		ThinkingBudget:  req.ThinkingBudget,
+		PromptCaching:   req.PromptCaching,
 		Model:           req.Model,
+		Provider:        req.Provider,
 		LanguageSetting: string(languageSettingJSON),
 		Guardrails:      string(guardrailsJSON),
 		Icon:            req.Icon,
 		IsActive:        true, // New personas are active by default
 	}
+	if err := persona.SetTools(req.Tools); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to process tools",
+		})
+	}
+	if err := persona.SetKnowledgeBases(knowledgeBases); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to process knowledge bases",
+		})
+	}
 
 	// Save to database
 	if err := ctrl.personaRepo.Create(persona); err != nil {
@@ -340,7 +389,12 @@ func (ctrl *PersonaController) CreatePersona(c *fiber.Ctx) error {
 		Expertise:       persona.Expertise,
 		Temperature:     persona.Temperature,
 		MaxTokens:       persona.MaxTokens,
+		ThinkingBudget:  persona.ThinkingBudget,
+		PromptCaching:   persona.PromptCaching,
 		Model:           persona.Model,
+		Provider:        persona.Provider,
+		Tools:           persona.GetTools(),
+		KnowledgeBases:  persona.GetKnowledgeBases(),
 		LanguageSetting: persona.LanguageSetting,
 		Guardrails:      persona.Guardrails,
 		Icon:            persona.Icon,
@@ -431,7 +485,12 @@ func (ctrl *PersonaController) UpdatePersona(c *fiber.Ctx) error {
 		Expertise       *string                 `json:"expertise"`
 		Temperature     *float32                `json:"temperature"`
 		MaxTokens       *int                    `json:"max_tokens"`
+		ThinkingBudget  *int                    `json:"thinking_budget"`
+		PromptCaching   *bool                   `json:"prompt_caching"`
 		Model           *string                 `json:"model"`
+		Provider        *string                 `json:"provider"`
+		Tools           *[]string               `json:"tools"`
+		KnowledgeBases  *[]string               `json:"knowledge_bases"`
 		LanguageSetting *LanguageSettingRequest `json:"language_setting"`
 		Guardrails      *GuardrailsRequest      `json:"guardrails"`
 		Icon            *string                 `json:"icon"`
@@ -513,42 +572,62 @@ func (ctrl *PersonaController) UpdatePersona(c *fiber.Ctx) error {
 		persona.MaxTokens = *req.MaxTokens
 	}
 
-	if req.Model != nil {
-		// Validate model name
-		validModels := map[string]bool{
-			// OpenAI Models
-			"gpt-4o-mini":   true,
-			"gpt-4o":        true,
-			"gpt-4":         true,
-			"gpt-3.5-turbo": true,
-			// AWS Bedrock - Cross-Region Inference (CRI) Models (Recommended)
-			"apac.anthropic.claude-sonnet-4-20250514-v1:0":   true,
-			"apac.anthropic.claude-3-5-sonnet-20241022-v2:0": true,
-			"apac.amazon.titan-text-premier-v1:0":            true,
-			"apac.amazon.titan-text-express-v1":              true,
-			"apac.amazon.titan-text-lite-v1":                 true,
-			// AWS Bedrock - Standard Single-Region Models
-			"anthropic.claude-3-5-sonnet-20241022-v2:0": true,
-			"anthropic.claude-3-5-sonnet-20240620-v1:0": true,
-			"anthropic.claude-3-sonnet-20240229-v1:0":   true,
-			"anthropic.claude-3-opus-20240229-v1:0":     true,
-			"amazon.titan-text-premier-v1:0":            true,
-			"amazon.titan-text-express-v1":              true,
-			"amazon.titan-text-lite-v1":                 true,
-			// Legacy/Shorthand Names
-			"claude-sonnet-4": true,
-			"claude-3-opus":   true,
-			"claude-3-sonnet": true,
+	if req.ThinkingBudget != nil {
+		if err := validateThinkingBudget(*req.ThinkingBudget); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		persona.ThinkingBudget = *req.ThinkingBudget
+	}
+
+	if req.PromptCaching != nil {
+		persona.PromptCaching = *req.PromptCaching
+	}
+
+	if req.Provider != nil {
+		persona.Provider = *req.Provider
+	}
+
+	if req.Model != nil || (persona.Provider != "" && req.Provider != nil) {
+		requested := persona.Model
+		if req.Model != nil {
+			requested = *req.Model
+		}
+		model, err := ctrl.validatePersonaModel(persona.Provider, requested)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		persona.Model = model
+	}
+
+	if req.Tools != nil {
+		if err := ctrl.tools.Validate(*req.Tools); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if err := persona.SetTools(*req.Tools); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to process tools",
+			})
 		}
+	}
 
-		if !validModels[*req.Model] {
+	if req.KnowledgeBases != nil {
+		knowledgeBases, err := ctrl.validateKnowledgeBases(*req.KnowledgeBases)
+		if err != nil {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error":        "Invalid model name",
-				"valid_models": []string{"gpt-4o-mini", "gpt-4o", "gpt-4", "gpt-3.5-turbo", "claude-sonnet-4", "claude-3-opus", "claude-3-sonnet"},
-				"received":     *req.Model,
+				"error": err.Error(),
+			})
+		}
+		if err := persona.SetKnowledgeBases(knowledgeBases); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to process knowledge bases",
 			})
 		}
-		persona.Model = *req.Model
 	}
 
 	if req.Icon != nil {
@@ -609,7 +688,12 @@ func (ctrl *PersonaController) UpdatePersona(c *fiber.Ctx) error {
 		Expertise:       persona.Expertise,
 		Temperature:     persona.Temperature,
 		MaxTokens:       persona.MaxTokens,
+		ThinkingBudget:  persona.ThinkingBudget,
+		PromptCaching:   persona.PromptCaching,
 		Model:           persona.Model,
+		Provider:        persona.Provider,
+		Tools:           persona.GetTools(),
+		KnowledgeBases:  persona.GetKnowledgeBases(),
 		LanguageSetting: persona.LanguageSetting,
 		Guardrails:      persona.Guardrails,
 		Icon:            persona.Icon,
@@ -618,3 +702,53 @@ func (ctrl *PersonaController) UpdatePersona(c *fiber.Ctx) error {
 
 	return c.Status(fiber.StatusOK).JSON(response)
 }
+
+// validateThinkingBudget checks a persona's reasoning budget (0 = off, Claude needs at least 1024)
+func validateThinkingBudget(budget int) error {
+	if budget != 0 && budget < 1024 {
+		return fmt.Errorf("thinking_budget must be 0 (off) or at least 1024")
+	}
+	return nil
+}
+
+// validatePersonaModel checks a persona's model against its provider and the model catalog
+// Returns the catalog ID of the model (aliases such as "claude-sonnet-4" are resolved)
+func (ctrl *PersonaController) validatePersonaModel(provider, model string) (string, error) {
+	if provider != "" {
+		// Personas bound to a provider are validated against that provider's models
+		var err error
+		if model, err = ctrl.providers.ValidateModel(provider, model); err != nil {
+			return "", err
+		}
+	}
+	return ctrl.catalog.Validate(provider, model)
+}
+
+// validateKnowledgeBases checks that every knowledge base ID exists and removes duplicates
+func (ctrl *PersonaController) validateKnowledgeBases(ids []string) ([]string, error) {
+	if len(ids) == 0 {
+		return []string{}, nil
+	}
+	kbs, err := ctrl.knowledge.FindByIDs(ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load knowledge bases: %w", err)
+	}
+	known := make(map[string]bool, len(kbs))
+	for _, kb := range kbs {
+		known[kb.ID.String()] = true
+	}
+
+	unique := make([]string, 0, len(ids))
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		id = strings.ToLower(strings.TrimSpace(id))
+		if !known[id] {
+			return nil, fmt.Errorf("knowledge base %q not found", id)
+		}
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique, nil
+}
@@ -0,0 +1,26 @@
+package controllers
+
+import (
+	"chatbot/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ProviderController exposes the registered chat providers
+type ProviderController struct {
+	providers *services.ProviderRegistry
+}
+
+// NewProviderController creates a new provider controller
+func NewProviderController(providers *services.ProviderRegistry) *ProviderController {
+	return &ProviderController{
+		providers: providers,
+	}
+}
+
+// GetProviders handles GET /api/providers endpoint
+func (ctrl *ProviderController) GetProviders(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"providers": ctrl.providers.List(),
+	})
+}
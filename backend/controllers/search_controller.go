@@ -0,0 +1,162 @@
+package controllers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"chatbot/models"
+	"chatbot/repositories"
+	"chatbot/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// SearchController handles searching chat history
+type SearchController struct {
+	search *services.SearchService
+}
+
+// NewSearchController creates a new search controller
+func NewSearchController(search *services.SearchService) *SearchController {
+	return &SearchController{search: search}
+}
+
+// SearchResultResponse is a found message in API responses
+type SearchResultResponse struct {
+	MessageID         string    `json:"message_id"`
+	SessionID         string    `json:"session_id"`
+	Role              string    `json:"role"`
+	PersonaID         *int      `json:"persona_id,omitempty"`
+	CreatedAt         time.Time `json:"created_at"`
+	Snippet           string    `json:"snippet"` // HTML-escaped, matches wrapped in <mark>
+	Score             float64   `json:"score"`
+	ConversationTitle string    `json:"conversation_title,omitempty"`
+	SessionLink       string    `json:"session_link"` // Branch of the session that ends at this message
+}
+
+// SearchResponse represents search results
+type SearchResponse struct {
+	Query   string                 `json:"query"`
+	Mode    string                 `json:"mode"`
+	Results []SearchResultResponse `json:"results"`
+	Total   int64                  `json:"total"`
+	Limit   int                    `json:"limit"`
+	Offset  int                    `json:"offset"`
+}
+
+// SearchMessages handles GET /api/chats/search
+// Query: q (required), mode (text|semantic|hybrid, default text), persona_id, session_id, role,
+// from, to (RFC3339 or YYYY-MM-DD; a date "to" includes that whole day), limit, offset
+func (ctrl *SearchController) SearchMessages(c *fiber.Ctx) error {
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "q is required",
+		})
+	}
+
+	mode := services.SearchMode(c.Query("mode", string(services.SearchModeText)))
+	switch mode {
+	case services.SearchModeText:
+	case services.SearchModeSemantic, services.SearchModeHybrid:
+		if !ctrl.search.SemanticAvailable() {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Semantic search is not available (needs pgvector, OPENAI_API_KEY and SEARCH_EMBED_MESSAGES=true)",
+			})
+		}
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "mode must be text, semantic or hybrid",
+		})
+	}
+
+	limit, offset := pagination(c)
+	filter := repositories.SearchFilter{
+		SessionID: c.Query("session_id"),
+		Role:      c.Query("role"),
+		Limit:     limit,
+		Offset:    offset,
+	}
+	if personaID := c.Query("persona_id"); personaID != "" {
+		id, err := strconv.Atoi(personaID)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid persona_id",
+			})
+		}
+		filter.PersonaID = &id
+	}
+	switch filter.Role {
+	case "", models.RoleUser, models.RoleAssistant, models.RoleSystem:
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "role must be user, assistant or system",
+		})
+	}
+	var err error
+	if filter.From, err = searchTime(c.Query("from"), false); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("Invalid from: %v", err),
+		})
+	}
+	if filter.To, err = searchTime(c.Query("to"), true); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("Invalid to: %v", err),
+		})
+	}
+
+	results, total, err := ctrl.search.Search(c.Context(), services.SearchRequest{
+		Query:  query,
+		Mode:   mode,
+		Filter: filter,
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to search messages",
+			"details": err.Error(),
+		})
+	}
+
+	items := make([]SearchResultResponse, len(results))
+	for i, result := range results {
+		items[i] = SearchResultResponse{
+			MessageID:         result.Message.ID.String(),
+			SessionID:         result.Message.SessionID,
+			Role:              result.Message.Role,
+			PersonaID:         result.Message.PersonaID,
+			CreatedAt:         result.Message.CreatedAt,
+			Snippet:           result.Snippet,
+			Score:             result.Score,
+			ConversationTitle: result.ConversationTitle,
+			SessionLink:       fmt.Sprintf("/api/chats/session/%s/branch?message_id=%s", result.Message.SessionID, result.Message.ID),
+		}
+	}
+	return c.Status(fiber.StatusOK).JSON(SearchResponse{
+		Query:   query,
+		Mode:    string(mode),
+		Results: items,
+		Total:   total,
+		Limit:   limit,
+		Offset:  offset,
+	})
+}
+
+// searchTime parses a from/to filter; a plain date as the end of a range means the end of that day
+func searchTime(value string, end bool) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, fmt.Errorf("use RFC3339 or YYYY-MM-DD")
+	}
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, nil
+}
@@ -0,0 +1,26 @@
+package controllers
+
+import (
+	"chatbot/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ToolController exposes the tools personas can enable
+type ToolController struct {
+	tools *services.ToolRegistry
+}
+
+// NewToolController creates a new tool controller
+func NewToolController(tools *services.ToolRegistry) *ToolController {
+	return &ToolController{
+		tools: tools,
+	}
+}
+
+// GetTools handles GET /api/tools endpoint
+func (ctrl *ToolController) GetTools(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"tools": ctrl.tools.List(),
+	})
+}
@@ -17,6 +17,7 @@ import (
 type TTSWebSocketController struct {
 	ttsService     *services.TTSService
 	personaRepo    *repositories.PersonaRepository
+	usageService   *services.UsageService
 	activeSessions sync.Map // session_id -> context.CancelFunc
 }
 
@@ -24,10 +25,12 @@ type TTSWebSocketController struct {
 func NewTTSWebSocketController(
 	ttsService *services.TTSService,
 	personaRepo *repositories.PersonaRepository,
+	usageService *services.UsageService,
 ) *TTSWebSocketController {
 	return &TTSWebSocketController{
-		ttsService:  ttsService,
-		personaRepo: personaRepo,
+		ttsService:   ttsService,
+		personaRepo:  personaRepo,
+		usageService: usageService,
 	}
 }
 
@@ -170,6 +173,7 @@ func (ctrl *TTSWebSocketController) handleTTS(c *websocket.Conn, req TTSWebSocke
 	if err != nil {
 		return fmt.Errorf("TTS generation failed: %w", err)
 	}
+	ctrl.usageService.RecordTTS(req.SessionID, req.PersonaID, "openai", model, ttsResp.CharactersUsed)
 
 	// 6. Split audio into chunks and stream
 	if err := ctrl.streamAudioChunks(c, ttsResp, req.SessionID); err != nil {
@@ -0,0 +1,120 @@
+package controllers
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"chatbot/repositories"
+	"chatbot/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// UsageController reports token usage and cost
+type UsageController struct {
+	usageService *services.UsageService
+}
+
+// NewUsageController creates a new usage controller
+func NewUsageController(usageService *services.UsageService) *UsageController {
+	return &UsageController{
+		usageService: usageService,
+	}
+}
+
+// GetUsage handles GET /api/usage endpoint
+// Query: group_by (day|persona|session|provider|model, default day), from, to (YYYY-MM-DD or RFC3339),
+// session_id, persona_id, provider, model
+func (ctrl *UsageController) GetUsage(c *fiber.Ctx) error {
+	filter := repositories.UsageFilter{
+		GroupBy:   c.Query("group_by", "day"),
+		SessionID: c.Query("session_id"),
+		Provider:  c.Query("provider"),
+		Model:     c.Query("model"),
+	}
+
+	if _, ok := repositories.UsageGroupKeys[filter.GroupBy]; !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "group_by must be one of: day, persona, session, provider, model",
+		})
+	}
+
+	if value := c.Query("persona_id"); value != "" {
+		personaID, err := strconv.Atoi(value)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "invalid persona_id",
+			})
+		}
+		filter.PersonaID = &personaID
+	}
+
+	var err error
+	if filter.From, err = parseUsageDate(c.Query("from"), false); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if filter.To, err = parseUsageDate(c.Query("to"), true); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	rows, err := ctrl.usageService.Summarize(filter)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to get usage",
+			"details": err.Error(),
+		})
+	}
+	if rows == nil {
+		rows = []repositories.UsageSummary{}
+	}
+
+	// Grand total across all groups
+	total := repositories.UsageSummary{Key: "total"}
+	for _, row := range rows {
+		total.Requests += row.Requests
+		total.ChatRequests += row.ChatRequests
+		total.TTSRequests += row.TTSRequests
+		total.STTRequests += row.STTRequests
+		total.PromptTokens += row.PromptTokens
+		total.CompletionTokens += row.CompletionTokens
+		total.CachedTokens += row.CachedTokens
+		total.Characters += row.Characters
+		total.AudioSeconds += row.AudioSeconds
+		total.ChatCost += row.ChatCost
+		total.TTSCost += row.TTSCost
+		total.STTCost += row.STTCost
+		total.Cost += row.Cost
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"group_by": filter.GroupBy,
+		"from":     filter.From,
+		"to":       filter.To,
+		"currency": "USD",
+		"usage":    rows,
+		"total":    total,
+	})
+}
+
+// parseUsageDate parses YYYY-MM-DD or RFC3339; a date-only "to" includes the whole day
+func parseUsageDate(value string, endOfDay bool) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date %q (use YYYY-MM-DD or RFC3339)", value)
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, nil
+}
@@ -2,18 +2,15 @@ package controllers
 
 import (
 	"context"
-	"encoding/base64"
+	"encoding/json"
 	"fmt"
-	"io"
 	"log"
-	"os"
 
 	"chatbot/models"
 	"chatbot/repositories"
 	"chatbot/services"
 
 	"github.com/gofiber/contrib/websocket"
-	"github.com/sashabaranov/go-openai"
 )
 
 // WebSocketController handles WebSocket connections for streaming chat
@@ -21,9 +18,15 @@ type WebSocketController struct {
 	messageRepo      *repositories.MessageRepository
 	personaRepo      *repositories.PersonaRepository
 	fileAnalysisRepo *repositories.FileAnalysisRepository
-	openaiService    *services.OpenAIService
-	bedrockService   *services.BedrockService
+	providers        *services.ProviderRegistry
+	failover         *services.FailoverStreamer
 	contextService   *services.ContextService
+	usageService     *services.UsageService
+	tools            *services.ToolOrchestrator
+	catalog          *services.ModelCatalog
+	knowledge        *services.KnowledgeService
+	streamer         *chatStreamer
+	turns            *branchTurns
 }
 
 // NewWebSocketController creates a new WebSocket controller
@@ -31,38 +34,86 @@ func NewWebSocketController(
 	messageRepo *repositories.MessageRepository,
 	personaRepo *repositories.PersonaRepository,
 	fileAnalysisRepo *repositories.FileAnalysisRepository,
-	openaiService *services.OpenAIService,
-	bedrockService *services.BedrockService,
+	contextService *services.ContextService,
+	providers *services.ProviderRegistry,
+	failover *services.FailoverStreamer,
+	usageService *services.UsageService,
+	tools *services.ToolOrchestrator,
+	catalog *services.ModelCatalog,
+	conversations *services.ConversationService,
+	knowledge *services.KnowledgeService,
 ) *WebSocketController {
 	return &WebSocketController{
 		messageRepo:      messageRepo,
 		personaRepo:      personaRepo,
 		fileAnalysisRepo: fileAnalysisRepo,
-		openaiService:    openaiService,
-		bedrockService:   bedrockService,
-		contextService:   services.NewContextService(messageRepo, fileAnalysisRepo),
+		providers:        providers,
+		failover:         failover,
+		contextService:   contextService,
+		usageService:     usageService,
+		tools:            tools,
+		catalog:          catalog,
+		knowledge:        knowledge,
+		streamer: &chatStreamer{
+			messageRepo:    messageRepo,
+			contextService: contextService,
+			failover:       failover,
+			usageService:   usageService,
+			tools:          tools,
+			conversations:  conversations,
+		},
+		turns: &branchTurns{
+			messageRepo:    messageRepo,
+			personaRepo:    personaRepo,
+			contextService: contextService,
+			catalog:        catalog,
+			tools:          tools,
+			knowledge:      knowledge,
+		},
 	}
 }
 
 // WSMessage represents incoming WebSocket messages
 type WSMessage struct {
-	Type         string   `json:"type"`          // "message"
-	Content      string   `json:"content"`       // User message
+	Type         string   `json:"type"`          // "message", "edit" or "regenerate"
+	Content      string   `json:"content"`       // User message (new text when editing)
 	PersonaID    *int     `json:"persona_id"`    // Persona ID
 	SystemPrompt string   `json:"system_prompt"` // Optional custom system prompt
 	SessionID    string   `json:"session_id"`    // Session ID for conversation history
 	FileIDs      []string `json:"file_ids"`      // File IDs for current message only
-	Provider     string   `json:"provider"`      // AI provider: "openai" or "bedrock" (optional, auto-detect if empty)
-	Model        string   `json:"model"`         // Model ID (optional, use provider default if empty)
+	Provider     string   `json:"provider"`      // AI provider name from the registry (optional, auto-detect if empty)
+	Model        string   `json:"model"`         // Model ID (optional, persona's model or provider default if empty)
+	Temperature  float64  `json:"temperature"`   // Optional, persona's temperature if 0
+	MaxTokens    int      `json:"max_tokens"`    // Optional, persona's max tokens if 0
+	ParentID     string   `json:"parent_id"`     // Message to reply after (optional, latest message of the session if empty)
+	MessageID    string   `json:"message_id"`    // Message to edit or regenerate ("edit", "regenerate")
 }
 
 // WSResponse represents outgoing WebSocket messages
 type WSResponse struct {
-	Type       string `json:"type"`                  // "chunk"
+	Type       string `json:"type"`                  // "chunk", "reasoning", "tool_call", "tool_result"
 	Content    string `json:"content"`               // Chunk content
 	Done       bool   `json:"done"`                  // Is streaming done?
 	MessageID  string `json:"message_id,omitempty"`  // Message ID (when done)
 	TokensUsed int    `json:"tokens_used,omitempty"` // Tokens used (when done)
+	Provider   string `json:"provider,omitempty"`    // Provider that answered (when done)
+	Model      string `json:"model,omitempty"`       // Model that answered (when done)
+	Attempts   int    `json:"attempts,omitempty"`    // Attempts including retries and failover (when done)
+
+	PromptTokens     int `json:"prompt_tokens,omitempty"`     // Prompt tokens (when done)
+	CompletionTokens int `json:"completion_tokens,omitempty"` // Completion tokens (when done)
+	CachedTokens     int `json:"cached_tokens,omitempty"`     // Cached prompt tokens (when done)
+
+	Cost *float64 `json:"cost,omitempty"` // USD cost from the pricing table (when done)
+
+	ParentID      string `json:"parent_id,omitempty"`       // Message the answer follows (when done)
+	UserMessageID string `json:"user_message_id,omitempty"` // Saved user message, empty when regenerating (when done)
+
+	ToolCall  *services.ToolCall `json:"tool_call,omitempty"`  // Tool requested by the model (tool_call, tool_result)
+	ToolError bool               `json:"tool_error,omitempty"` // Tool failed; content holds the error (tool_result)
+
+	Structured json.RawMessage          `json:"structured,omitempty"` // Reply parsed against response_schema (when done, SSE only)
+	Citations  []models.MessageCitation `json:"citations,omitempty"`  // Passages of the attached files the answer cites (when done)
 }
 
 // HandleStreamingChat handles WebSocket connections for streaming chat
@@ -93,14 +144,19 @@ func (ctrl *WebSocketController) HandleStreamingChat(c *websocket.Conn) {
 		}
 
 		// Handle message type
-		if msg.Type == "message" {
-			if err := ctrl.handleMessage(ctx, c, msg); err != nil {
-				log.Printf("Error handling message: %v", err)
-				ctrl.sendError(c, err.Error())
-			}
-		} else {
+		var err error
+		switch msg.Type {
+		case "message":
+			err = ctrl.handleMessage(ctx, c, msg)
+		case "edit", "regenerate":
+			err = ctrl.handleBranch(ctx, c, msg)
+		default:
 			ctrl.sendError(c, fmt.Sprintf("Unknown message type: %s", msg.Type))
 		}
+		if err != nil {
+			log.Printf("Error handling message: %v", err)
+			ctrl.sendError(c, err.Error())
+		}
 	}
 }
 
@@ -129,215 +185,101 @@ func (ctrl *WebSocketController) handleMessage(ctx context.Context, c *websocket
 		systemPrompt = systemPrompt + "\n\n--- Additional Instructions ---\n" + msg.SystemPrompt
 	}
 
-	// 4. Determine which AI provider to use
-	var streamingService services.StreamingChatService
-	var providerName string
-
-	// If provider is explicitly specified, use it
-	if msg.Provider != "" {
-		switch msg.Provider {
-		case "openai":
-			if ctrl.openaiService == nil || !ctrl.openaiService.IsAvailable() {
-				return fmt.Errorf("OpenAI provider not available (check OPENAI_API_KEY in .env)")
-			}
-			streamingService = ctrl.openaiService
-			providerName = "OpenAI"
-			log.Printf("🟢 Using OpenAI for WebSocket streaming (explicitly requested)")
-		case "bedrock":
-			if ctrl.bedrockService == nil || !ctrl.bedrockService.IsAvailable() {
-				return fmt.Errorf("bedrock provider not available (check AWS credentials in .env)")
-			}
-			streamingService = ctrl.bedrockService
-			providerName = "Bedrock"
-			log.Printf("🔵 Using AWS Bedrock for WebSocket streaming (explicitly requested)")
-		default:
-			return fmt.Errorf("invalid provider: %s (valid options: 'openai', 'bedrock')", msg.Provider)
-		}
-	} else {
-		// Auto-detect provider based on availability
-		if ctrl.openaiService != nil && ctrl.openaiService.IsAvailable() {
-			streamingService = ctrl.openaiService
-			providerName = "OpenAI"
-			log.Printf("🟢 Using OpenAI for WebSocket streaming (auto-detected)")
-		} else if ctrl.bedrockService != nil && ctrl.bedrockService.IsAvailable() {
-			streamingService = ctrl.bedrockService
-			providerName = "Bedrock"
-			log.Printf("🔵 Using AWS Bedrock for WebSocket streaming (auto-detected)")
-		} else {
-			return fmt.Errorf("no AI provider available (check OPENAI_API_KEY or AWS credentials in .env)")
-		}
-	}
-
-	// 5. Build context with history and files
-	var messages interface{}
-	if len(msg.FileIDs) > 0 {
-		if providerName == "Bedrock" {
-			// Bedrock: Build messages manually with file context
-			messages = ctrl.buildBedrockMessagesWithFiles(msg.SessionID, systemPrompt, msg.Content, msg.FileIDs)
-		} else {
-			// OpenAI: Use existing function that supports images
-			openaiMessages, err := ctrl.contextService.BuildContextWithHistoryAndFiles(
-				msg.SessionID,
-				systemPrompt,
-				msg.Content,
-				10, // history limit
-				msg.FileIDs,
-			)
-			if err != nil {
-				log.Printf("⚠️  Failed to build context with files: %v", err)
-				// Fallback to simple messages
-				openaiMessages = []openai.ChatCompletionMessage{
-					{Role: openai.ChatMessageRoleSystem, Content: systemPrompt},
-					{Role: openai.ChatMessageRoleUser, Content: msg.Content},
-				}
-			}
-			messages = openaiMessages
-		}
-	} else if msg.SessionID != "" {
-		// Use context service to build messages with history only
-		openaiMessages, err := ctrl.contextService.BuildContextWithHistory(
-			msg.SessionID,
-			systemPrompt,
-			msg.Content,
-			10, // history limit
-		)
-		if err != nil {
-			log.Printf("⚠️  Failed to build context with history: %v", err)
-			// Fallback to simple messages
-			openaiMessages = []openai.ChatCompletionMessage{
-				{Role: openai.ChatMessageRoleSystem, Content: systemPrompt},
-				{Role: openai.ChatMessageRoleUser, Content: msg.Content},
-			}
-		}
-
-		// Convert to provider-specific format
-		if providerName == "Bedrock" {
-			messages = ctrl.convertToClaudeMessages(openaiMessages, systemPrompt)
-		} else {
-			messages = openaiMessages
-		}
-	} else {
-		// No session and no files - simple messages
-		if providerName == "Bedrock" {
-			messages = []services.ClaudeMessage{
-				{Role: "user", Content: msg.Content},
-			}
-		} else {
-			messages = []openai.ChatCompletionMessage{
-				{Role: openai.ChatMessageRoleSystem, Content: systemPrompt},
-				{Role: openai.ChatMessageRoleUser, Content: msg.Content},
-			}
-		}
+	// 4. Resolve provider, model and generation settings: request → persona → provider default
+	settings, err := ctrl.catalog.ResolveGeneration(services.GenerationOverrides{
+		Provider:    msg.Provider,
+		Model:       msg.Model,
+		Temperature: msg.Temperature,
+		MaxTokens:   msg.MaxTokens,
+	}, persona, "")
+	if err != nil {
+		return err
 	}
 
-	// 6. Create streaming request using unified interface
-	streamReq := services.StreamingChatRequest{
-		Messages:     messages,
-		SystemPrompt: systemPrompt,
-		Temperature:  0.7,
-		MaxTokens:    2000,
-		Model:        msg.Model, // Use custom model if specified, otherwise service will use default
+	// 5. Build provider-neutral context with the branch history and files
+	parentID, err := ctrl.contextService.ActiveParent(msg.SessionID, msg.ParentID)
+	if err != nil {
+		return err
 	}
-
-	// Log model selection
-	if msg.Model != "" {
-		log.Printf("📝 Using custom model: %s (provider: %s)", msg.Model, providerName)
-	} else {
-		log.Printf("📝 Using default model for provider: %s", providerName)
+	historyLimit := 0
+	if msg.SessionID != "" {
+		historyLimit = services.DefaultHistoryLimit
 	}
-
-	stream, err := streamingService.CreateStreamingChat(ctx, streamReq)
+	conversation, err := ctrl.contextService.BuildConversation(
+		msg.SessionID,
+		parentID,
+		systemPrompt,
+		msg.Content,
+		historyLimit,
+		msg.FileIDs,
+	)
 	if err != nil {
-		return fmt.Errorf("failed to create streaming request: %w", err)
+		log.Printf("⚠️  Failed to build context: %v", err)
+		// Fallback to simple messages
+		conversation = services.NewSimpleConversation(systemPrompt, msg.Content)
 	}
-	defer stream.Close()
-
-	// 7. Stream the response to client
-	fullContent := ""
-
-	for {
-		select {
-		case <-ctx.Done():
-			// Request was cancelled
-			return nil
+	ctrl.knowledge.Augment(ctx, conversation, persona)
 
-		default:
-			chunk, err := stream.Recv()
-			if err == io.EOF {
-				// Stream completed
-				goto streamDone
-			}
-			if err != nil {
-				return fmt.Errorf("stream error: %w", err)
-			}
+	// 6. Create streaming request using unified interface
+	streamReq := settings.Apply(services.StreamingChatRequest{
+		Messages:     conversation.Messages,
+		SystemPrompt: conversation.SystemPrompt,
+		Tools:        ctrl.tools.Registry().Definitions(persona.GetTools()),
+	})
 
-			// Add chunk to full content
-			if chunk != "" {
-				fullContent += chunk
+	// Fit the request to the model's limits (context window, max output, vision)
+	streamReq, err = ctrl.catalog.Prepare(settings.Provider, streamReq)
+	if err != nil {
+		return err
+	}
 
-				// Send chunk to client
-				if err := ctrl.sendChunk(c, chunk, false); err != nil {
-					return err
-				}
-			}
-		}
+	// Log model selection
+	if streamReq.Model != "" {
+		log.Printf("📝 Using model: %s (provider: %q, temperature: %.2f, max_tokens: %d)", streamReq.Model, settings.Provider, streamReq.Temperature, streamReq.MaxTokens)
+	} else {
+		log.Printf("📝 Using default model (provider: %q)", settings.Provider)
 	}
 
-streamDone:
-	// 7. Save user message to database
-	userMessage := &models.Message{
+	// 7. Stream the response to client, then save the turn
+	return ctrl.streamer.stream(ctx, chatTurn{
 		SessionID: msg.SessionID,
-		Role:      models.RoleUser,
-		Content:   msg.Content,
 		PersonaID: &personaID,
-	}
-
-	if err := ctrl.messageRepo.Create(userMessage); err != nil {
-		log.Printf("Failed to save user message: %v", err)
-	}
-
-	// 8. Save assistant response to database
-	// Calculate tokens (simplified - in real app would get from OpenAI response)
-	tokensUsed := len(fullContent) / 4 // Rough estimate
+		Content:   msg.Content,
+		FileIDs:   msg.FileIDs,
+		Provider:  settings.Provider,
+		Request:   streamReq,
+		ParentID:  parentID,
+	}, func(frame WSResponse) error {
+		return c.WriteJSON(frame)
+	})
+}
 
-	assistantMessage := &models.Message{
-		SessionID:  msg.SessionID,
-		Role:       models.RoleAssistant,
-		Content:    fullContent,
-		PersonaID:  &personaID,
-		TokensUsed: &tokensUsed,
+// handleBranch answers an edited user message or regenerates a reply on a new branch
+func (ctrl *WebSocketController) handleBranch(ctx context.Context, c *websocket.Conn, msg WSMessage) error {
+	req := BranchRequest{
+		Content:      msg.Content,
+		FileIDs:      msg.FileIDs,
+		PersonaID:    msg.PersonaID,
+		SystemPrompt: msg.SystemPrompt,
+		Provider:     msg.Provider,
+		Model:        msg.Model,
+		Temperature:  msg.Temperature,
+		MaxTokens:    msg.MaxTokens,
 	}
 
-	if err := ctrl.messageRepo.Create(assistantMessage); err != nil {
-		log.Printf("Failed to save assistant message: %v", err)
+	var turn chatTurn
+	var err error
+	if msg.Type == "edit" {
+		turn, err = ctrl.turns.edit(msg.MessageID, req)
+	} else {
+		turn, err = ctrl.turns.regenerate(msg.MessageID, req)
 	}
-
-	// 9. Send completion message
-	if err := ctrl.sendDone(c, assistantMessage.ID.String(), tokensUsed); err != nil {
+	if err != nil {
 		return err
 	}
 
-	return nil
-}
-
-// sendChunk sends a content chunk to the client
-// ส่งข้อมูลเป็นส่วนๆ (chunk)
-func (ctrl *WebSocketController) sendChunk(c *websocket.Conn, content string, done bool) error {
-	return c.WriteJSON(WSResponse{
-		Type:    "chunk",
-		Content: content,
-		Done:    done,
-	})
-}
-
-// sendDone sends the final completion message
-func (ctrl *WebSocketController) sendDone(c *websocket.Conn, messageID string, tokensUsed int) error {
-	return c.WriteJSON(WSResponse{
-		Type:       "chunk",
-		Content:    "",
-		Done:       true,
-		MessageID:  messageID,
-		TokensUsed: tokensUsed,
+	return ctrl.streamer.stream(ctx, turn, func(frame WSResponse) error {
+		return c.WriteJSON(frame)
 	})
 }
 
@@ -348,225 +290,3 @@ func (ctrl *WebSocketController) sendError(c *websocket.Conn, errorMsg string) e
 		"error": errorMsg,
 	})
 }
-
-// convertToClaudeMessages converts OpenAI messages to Claude message format
-// Claude uses separate system prompt field, so we filter out system messages
-// Claude also requires alternating user/assistant messages, so we merge consecutive messages
-func (ctrl *WebSocketController) convertToClaudeMessages(openaiMessages []openai.ChatCompletionMessage, systemPrompt string) []services.ClaudeMessage {
-	claudeMessages := make([]services.ClaudeMessage, 0)
-	var lastRole string
-	var accumulatedContent string
-
-	for _, msg := range openaiMessages {
-		// Skip system messages (they're passed separately in systemPrompt)
-		if msg.Role == openai.ChatMessageRoleSystem {
-			continue
-		}
-
-		// Skip messages with empty content
-		if msg.Content == "" {
-			continue
-		}
-
-		// Convert role
-		role := "user"
-		if msg.Role == openai.ChatMessageRoleAssistant {
-			role = "assistant"
-		}
-
-		// If same role as last message, merge content
-		if lastRole == role {
-			accumulatedContent += "\n\n" + msg.Content
-		} else {
-			// Save previous accumulated message
-			if accumulatedContent != "" {
-				claudeMessages = append(claudeMessages, services.ClaudeMessage{
-					Role:    lastRole,
-					Content: accumulatedContent,
-				})
-			}
-
-			// Start new message
-			lastRole = role
-			accumulatedContent = msg.Content
-		}
-	}
-
-	// Add final accumulated message
-	if accumulatedContent != "" {
-		claudeMessages = append(claudeMessages, services.ClaudeMessage{
-			Role:    lastRole,
-			Content: accumulatedContent,
-		})
-	}
-
-	// Ensure alternating pattern and starts with user
-	claudeMessages = ctrl.ensureAlternatingMessages(claudeMessages)
-
-	return claudeMessages
-}
-
-// ensureAlternatingMessages ensures messages alternate between user and assistant
-// Claude API requires this pattern
-func (ctrl *WebSocketController) ensureAlternatingMessages(messages []services.ClaudeMessage) []services.ClaudeMessage {
-	if len(messages) == 0 {
-		return messages
-	}
-
-	// Must start with user message
-	if messages[0].Role != "user" {
-		log.Printf("⚠️  First message is not 'user', prepending empty user message")
-		messages = append([]services.ClaudeMessage{{Role: "user", Content: "[conversation started]"}}, messages...)
-	}
-
-	return messages
-}
-
-// isImageFile checks if a MIME type is an image
-func (ctrl *WebSocketController) isImageFile(mimeType string) bool {
-	switch mimeType {
-	case "image/jpeg", "image/jpg", "image/png", "image/gif", "image/webp":
-		return true
-	default:
-		return false
-	}
-}
-
-// readImageFile reads an image file and returns base64 encoded data
-func (ctrl *WebSocketController) readImageFile(filePath string, mimeType string) (string, string, error) {
-	fmt.Println(`filePath =>`, filePath)
-	// Read file
-	data, err := os.ReadFile(filePath)
-	if err != nil {
-		return "", "", fmt.Errorf("failed to read file: %w", err)
-	}
-
-	// Encode to base64
-	encoded := base64.StdEncoding.EncodeToString(data)
-
-	return encoded, mimeType, nil
-}
-
-// buildBedrockMessagesWithFiles builds Claude messages with file context (text files + images)
-func (ctrl *WebSocketController) buildBedrockMessagesWithFiles(
-	sessionID string,
-	systemPrompt string,
-	currentMessage string,
-	fileIDs []string,
-) []services.ClaudeMessage {
-	claudeMessages := make([]services.ClaudeMessage, 0)
-
-	// 1. Add conversation history
-	if sessionID != "" {
-		history, err := ctrl.messageRepo.GetRecentBySession(sessionID, 10)
-		if err == nil && len(history) > 0 {
-			for _, msg := range history {
-				role := "user"
-				if msg.Role == models.RoleAssistant {
-					role = "assistant"
-				}
-
-				// Skip empty messages
-				if msg.Content == "" {
-					continue
-				}
-
-				claudeMessages = append(claudeMessages, services.ClaudeMessage{
-					Role:    role,
-					Content: msg.Content,
-				})
-			}
-		}
-	}
-
-	// 2. Separate image files from text files
-	imageFileIDs := make([]string, 0)
-	textFileIDs := make([]string, 0)
-
-	for _, fileID := range fileIDs {
-		file, err := ctrl.fileAnalysisRepo.FindByID(fileID)
-		if err != nil {
-			log.Printf("⚠️  File not found: %s", fileID)
-			continue
-		}
-
-		if ctrl.isImageFile(file.MimeType) {
-			imageFileIDs = append(imageFileIDs, fileID)
-		} else {
-			textFileIDs = append(textFileIDs, fileID)
-		}
-	}
-
-	// 3. Build file context for text files
-	var fileContext string
-	if len(textFileIDs) > 0 {
-		var err error
-		fileContext, err = ctrl.contextService.BuildFileContext(textFileIDs)
-		if err != nil {
-			log.Printf("⚠️  Failed to build file context: %v", err)
-		}
-	}
-
-	// 4. Add current user message with file context
-	var userMessageContent string
-	if fileContext != "" {
-		userMessageContent = fileContext + "\n\n--- User Question ---\n" + currentMessage
-	} else {
-		userMessageContent = currentMessage
-	}
-
-	// 5. If there are images, create multimodal content blocks
-	if len(imageFileIDs) > 0 {
-		contentBlocks := make([]services.ClaudeContentBlock, 0)
-
-		// Add text content first
-		contentBlocks = append(contentBlocks, services.ClaudeContentBlock{
-			Type: "text",
-			Text: userMessageContent,
-		})
-
-		// Add image blocks
-		for _, fileID := range imageFileIDs {
-			file, err := ctrl.fileAnalysisRepo.FindByID(fileID)
-			if err != nil {
-				log.Printf("⚠️  File not found: %s", fileID)
-				continue
-			}
-
-			// Read image file and encode to base64
-			imageData, mediaType, err := ctrl.readImageFile(file.StoragePath, file.MimeType)
-			if err != nil {
-				log.Printf("⚠️  Failed to read image: %v", err)
-				continue
-			}
-
-			// Add image content block
-			contentBlocks = append(contentBlocks, services.ClaudeContentBlock{
-				Type: "image",
-				Source: &services.ClaudeImageSource{
-					Type:      "base64",
-					MediaType: mediaType,
-					Data:      imageData,
-				},
-			})
-
-			log.Printf("✓ Added image to content: %s (%s)", file.FileName, mediaType)
-		}
-
-		claudeMessages = append(claudeMessages, services.ClaudeMessage{
-			Role:    "user",
-			Content: contentBlocks,
-		})
-	} else {
-		// Simple text message
-		claudeMessages = append(claudeMessages, services.ClaudeMessage{
-			Role:    "user",
-			Content: userMessageContent,
-		})
-	}
-
-	// 6. Ensure alternating pattern and starts with user
-	claudeMessages = ctrl.ensureAlternatingMessages(claudeMessages)
-
-	return claudeMessages
-}
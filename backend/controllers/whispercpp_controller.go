@@ -14,12 +14,14 @@ import (
 // WhisperCppController handles Whisper.cpp STT HTTP requests
 type WhisperCppController struct {
 	whisperService *services.WhisperCppService
+	usageService   *services.UsageService
 }
 
 // NewWhisperCppController creates a new WhisperCpp controller
-func NewWhisperCppController(whisperService *services.WhisperCppService) *WhisperCppController {
+func NewWhisperCppController(whisperService *services.WhisperCppService, usageService *services.UsageService) *WhisperCppController {
 	return &WhisperCppController{
 		whisperService: whisperService,
+		usageService:   usageService,
 	}
 }
 
@@ -234,6 +236,7 @@ func (ctrl *WhisperCppController) TranscribeAudio(c *fiber.Ctx) error {
 		}
 
 		fmt.Printf("✅ Transcription with timestamps successful (%.2fs)\n", time.Since(startTime).Seconds())
+		ctrl.usageService.RecordSTT(c.FormValue("session_id"), nil, "whispercpp", actualModelName, duration)
 
 		// Create response with segments
 		response := WhisperCppTranscribeWithTimestampsResponse{
@@ -273,6 +276,8 @@ func (ctrl *WhisperCppController) TranscribeAudio(c *fiber.Ctx) error {
 	}
 
 	fmt.Printf("✅ Transcription successful (%.2fs): %s\n", time.Since(startTime).Seconds(), transcription)
+	// Audio duration is only known with timestamps; whisper.cpp runs locally so the cost is 0 anyway
+	ctrl.usageService.RecordSTT(c.FormValue("session_id"), nil, "whispercpp", actualModelName, 0)
 
 	// Create response
 	response := WhisperCppTranscribeResponse{
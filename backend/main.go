@@ -9,6 +9,7 @@ import (
 	"chatbot/config"
 	"chatbot/database"
 	"chatbot/models"
+	"chatbot/repositories"
 	"chatbot/routes"
 
 	"github.com/gofiber/fiber/v2"
@@ -31,19 +32,65 @@ func main() {
 	if err := db.Exec(`CREATE EXTENSION IF NOT EXISTS "uuid-ossp"`).Error; err != nil {
 		log.Fatal("Failed to create uuid-ossp extension:", err)
 	}
+	// pgvector is optional: without it search is text-only
+	vectors := db.Exec(`CREATE EXTENSION IF NOT EXISTS vector`).Error == nil
+	if !vectors {
+		log.Println("⚠️  pgvector extension is not available; semantic search and knowledge bases are disabled")
+	}
 	log.Println("✓ PostgreSQL extensions initialized")
 
+	// The messages role check now allows 'tool'; drop the old one so AutoMigrate recreates it
+	if db.Migrator().HasConstraint(&models.Message{}, "chk_messages_role") {
+		if err := db.Migrator().DropConstraint(&models.Message{}, "chk_messages_role"); err != nil {
+			log.Fatal("Failed to update messages role constraint:", err)
+		}
+	}
+
 	// Auto-migrate database models
 	err = db.AutoMigrate(
 		&models.Persona{},
 		&models.Message{},
 		&models.FileAnalysis{},
+		&models.UsageEvent{},
+		&models.Conversation{},
+		&models.SessionSummary{},
+		&models.KnowledgeBase{},
+		&models.KnowledgeDocument{},
 	)
 	if err != nil {
 		log.Fatal("Failed to migrate database:", err)
 	}
+	searchRepo := repositories.NewSearchRepository(db)
+	if vectors {
+		if err := db.AutoMigrate(&models.MessageEmbedding{}, &models.KnowledgeChunk{}); err != nil {
+			log.Fatal("Failed to migrate embeddings:", err)
+		}
+		if err := searchRepo.EnsureVectorIndex(); err != nil {
+			log.Printf("⚠️  Failed to create message embedding index: %v", err)
+		}
+		if err := repositories.NewKnowledgeRepository(db).EnsureVectorIndex(); err != nil {
+			log.Printf("⚠️  Failed to create knowledge chunk index: %v", err)
+		}
+	}
+	if err := searchRepo.EnsureSearchIndex(); err != nil {
+		log.Printf("⚠️  Failed to create message search index: %v", err)
+	}
 	log.Println("✓ Database migration completed")
 
+	// Messages saved before conversation branching have no parent; chain them in created_at order
+	if linked, err := repositories.NewMessageRepository(db).LinkLegacySessions(); err != nil {
+		log.Printf("⚠️  Failed to link legacy session messages: %v", err)
+	} else if linked > 0 {
+		log.Printf("✓ Linked %d legacy session messages into branches", linked)
+	}
+
+	// Sessions saved before conversations existed get a conversation titled by their first question
+	if created, err := repositories.NewConversationRepository(db).CreateFromMessages(); err != nil {
+		log.Printf("⚠️  Failed to create conversations for existing sessions: %v", err)
+	} else if created > 0 {
+		log.Printf("✓ Created %d conversations for existing sessions", created)
+	}
+
 	// Seed personas if empty
 	database.SeedPersonas(db)
 
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// Conversation is a chat session; its ID is the session_id of its messages
+type Conversation struct {
+	ID        string         `gorm:"type:varchar(100);primaryKey" json:"id"`
+	Title     string         `gorm:"type:varchar(200)" json:"title"`      // Generated from the first exchange unless set by the user
+	PersonaID *int           `gorm:"index" json:"persona_id,omitempty"`   // Persona the conversation was started with
+	Provider  string         `gorm:"type:varchar(50)" json:"provider"`    // Provider of the latest answer
+	Model     string         `gorm:"type:varchar(100)" json:"model"`      // Model of the latest answer
+	Archived  bool           `gorm:"default:false;index" json:"archived"` // Hidden from the default listing
+	Pinned    bool           `gorm:"default:false" json:"pinned"`         // Listed first
+	Tags      datatypes.JSON `gorm:"type:jsonb;default:'[]'" json:"tags"` // Array of strings
+	CreatedAt time.Time      `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt time.Time      `gorm:"default:CURRENT_TIMESTAMP;index" json:"updated_at"` // Bumped by every saved turn
+}
+
+// TableName specifies the table name for Conversation model
+func (Conversation) TableName() string {
+	return "conversations"
+}
+
+// GetTags parses the tags
+func (c *Conversation) GetTags() []string {
+	tags := []string{}
+	if len(c.Tags) == 0 {
+		return tags
+	}
+	if err := json.Unmarshal(c.Tags, &tags); err != nil || tags == nil {
+		return []string{}
+	}
+	return tags
+}
+
+// SetTags sets the tags
+func (c *Conversation) SetTags(tags []string) error {
+	if tags == nil {
+		tags = []string{}
+	}
+
+	data, err := json.Marshal(tags)
+	if err != nil {
+		return err
+	}
+
+	c.Tags = data
+	return nil
+}
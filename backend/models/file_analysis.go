@@ -1,12 +1,29 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
+	"gorm.io/datatypes"
 	"gorm.io/gorm"
 )
 
+// Text extraction statuses of an uploaded file
+const (
+	ExtractionStatusPending    = "pending"
+	ExtractionStatusProcessing = "processing"
+	ExtractionStatusReady      = "ready"
+	ExtractionStatusFailed     = "failed"
+	ExtractionStatusSkipped    = "skipped" // Images and formats without text
+)
+
+// OCRPage is a page of a file whose text was read with OCR
+type OCRPage struct {
+	Page       int     `json:"page"`       // PDF page (0 = the file is an image)
+	Confidence float64 `json:"confidence"` // Mean word confidence reported by the OCR engine (0-1)
+}
+
 // FileAnalysis represents a file upload record in the database
 type FileAnalysis struct {
 	ID          uuid.UUID      `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
@@ -17,6 +34,56 @@ type FileAnalysis struct {
 	UploadedAt  time.Time      `gorm:"autoCreateTime" json:"uploaded_at"`
 	UpdatedAt   time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
 	DeletedAt   gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+
+	// Text extracted once after upload (see services.FileExtractionService)
+	ExtractedText    string         `gorm:"type:text" json:"-"`
+	PageCount        int            `gorm:"not null;default:0" json:"page_count"`       // PDF pages or deck slides (0 = not paged)
+	Language         string         `gorm:"type:varchar(10)" json:"language,omitempty"` // Main language of the text: th, en
+	ExtractionStatus string         `gorm:"type:varchar(20);not null;default:'pending';index" json:"extraction_status"`
+	ExtractionError  string         `gorm:"type:text" json:"extraction_error,omitempty"`
+	ExtractedAt      *time.Time     `json:"extracted_at,omitempty"`
+	OCRPages         datatypes.JSON `gorm:"type:jsonb" json:"ocr_pages,omitempty"` // Array of OCRPage (scanned pages and images)
+}
+
+// Extracted reports whether extraction has finished (successfully or not)
+func (f *FileAnalysis) Extracted() bool {
+	switch f.ExtractionStatus {
+	case ExtractionStatusReady, ExtractionStatusFailed, ExtractionStatusSkipped:
+		return true
+	default:
+		return false
+	}
+}
+
+// GetOCRPages parses ocr_pages JSONB into OCRPage slice
+func (f *FileAnalysis) GetOCRPages() ([]OCRPage, error) {
+	var pages []OCRPage
+
+	if len(f.OCRPages) == 0 {
+		return pages, nil
+	}
+
+	if err := json.Unmarshal(f.OCRPages, &pages); err != nil {
+		return nil, err
+	}
+
+	return pages, nil
+}
+
+// SetOCRPages sets the pages read with OCR (nil or empty clears them)
+func (f *FileAnalysis) SetOCRPages(pages []OCRPage) error {
+	if len(pages) == 0 {
+		f.OCRPages = nil
+		return nil
+	}
+
+	data, err := json.Marshal(pages)
+	if err != nil {
+		return err
+	}
+
+	f.OCRPages = data
+	return nil
 }
 
 // TableName specifies the table name for FileAnalysis model
@@ -30,4 +97,4 @@ func (f *FileAnalysis) BeforeCreate(tx *gorm.DB) error {
 		f.ID = uuid.New()
 	}
 	return nil
-}
\ No newline at end of file
+}
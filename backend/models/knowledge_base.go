@@ -0,0 +1,98 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Knowledge document statuses
+const (
+	KnowledgeStatusPending    = "pending"    // Waiting to be indexed
+	KnowledgeStatusProcessing = "processing" // Being extracted, chunked and embedded
+	KnowledgeStatusReady      = "ready"      // Chunks are searchable
+	KnowledgeStatusFailed     = "failed"     // See Error
+)
+
+// KnowledgeBase is a named collection of documents that personas retrieve from
+type KnowledgeBase struct {
+	ID          uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
+	Name        string    `gorm:"type:varchar(100);not null;unique" json:"name"`
+	Description string    `gorm:"type:text" json:"description"`
+	CreatedAt   time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt   time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+// TableName specifies the table name for KnowledgeBase model
+func (KnowledgeBase) TableName() string {
+	return "knowledge_bases"
+}
+
+// BeforeCreate will set a UUID rather than numeric ID
+func (k *KnowledgeBase) BeforeCreate(tx *gorm.DB) error {
+	if k.ID == uuid.Nil {
+		k.ID = uuid.New()
+	}
+	return nil
+}
+
+// KnowledgeDocument is an uploaded file added to a knowledge base
+type KnowledgeDocument struct {
+	ID              uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
+	KnowledgeBaseID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_knowledge_documents_file" json:"knowledge_base_id"`
+	FileID          uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_knowledge_documents_file" json:"file_id"` // FileAnalysis the text is extracted from
+	FileName        string    `gorm:"type:varchar(255)" json:"file_name"`
+	Status          string    `gorm:"type:varchar(20);not null;default:'pending';index" json:"status"`
+	Error           string    `gorm:"type:text" json:"error,omitempty"` // Why indexing failed
+	ChunkCount      int       `gorm:"default:0" json:"chunk_count"`
+	CreatedAt       time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt       time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+
+	// Relationships (deleting the knowledge base or the file deletes the document)
+	KnowledgeBase *KnowledgeBase `gorm:"foreignKey:KnowledgeBaseID;constraint:OnDelete:CASCADE" json:"-"`
+	File          *FileAnalysis  `gorm:"foreignKey:FileID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// TableName specifies the table name for KnowledgeDocument model
+func (KnowledgeDocument) TableName() string {
+	return "knowledge_documents"
+}
+
+// BeforeCreate will set a UUID rather than numeric ID
+func (d *KnowledgeDocument) BeforeCreate(tx *gorm.DB) error {
+	if d.ID == uuid.Nil {
+		d.ID = uuid.New()
+	}
+	return nil
+}
+
+// KnowledgeChunk is an embedded passage of a knowledge document (needs pgvector)
+type KnowledgeChunk struct {
+	ID              uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
+	KnowledgeBaseID uuid.UUID `gorm:"type:uuid;not null;index" json:"knowledge_base_id"`
+	DocumentID      uuid.UUID `gorm:"type:uuid;not null;index" json:"document_id"`
+	FileID          uuid.UUID `gorm:"type:uuid;not null" json:"file_id"`
+	ChunkIndex      int       `gorm:"not null" json:"chunk_index"` // Position in the document
+	Page            int       `gorm:"default:0" json:"page"`       // PDF page or deck slide (0 = not paged)
+	Content         string    `gorm:"type:text;not null" json:"content"`
+	Model           string    `gorm:"type:varchar(100)" json:"model"`      // Embedding model
+	Embedding       Vector    `gorm:"type:vector(1536);not null" json:"-"` // EmbeddingDimensions
+	CreatedAt       time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+
+	// Relationships (deleting the document deletes its chunks)
+	Document *KnowledgeDocument `gorm:"foreignKey:DocumentID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// TableName specifies the table name for KnowledgeChunk model
+func (KnowledgeChunk) TableName() string {
+	return "knowledge_chunks"
+}
+
+// BeforeCreate will set a UUID rather than numeric ID
+func (c *KnowledgeChunk) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	return nil
+}
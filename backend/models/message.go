@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"time"
 
+	"chatbot/utils"
+
 	"github.com/google/uuid"
 	"gorm.io/datatypes"
 	"gorm.io/gorm"
@@ -17,17 +19,45 @@ type FileAttachment struct {
 	FileSize int64  `json:"file_size"`
 }
 
+// MessageToolCall is a tool call requested by the assistant
+type MessageToolCall struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"` // JSON-encoded arguments
+}
+
+// MessageCitation is a passage of a file attached to the question that an assistant answer cites
+type MessageCitation struct {
+	FileID   string `json:"file_id"`
+	FileName string `json:"file_name"`
+	Page     int    `json:"page,omitempty"`  // PDF page or deck slide (0 = not paged)
+	Sheet    string `json:"sheet,omitempty"` // Spreadsheet sheet
+	Quote    string `json:"quote"`           // Span of the file text the answer quotes
+	Verified bool   `json:"verified"`        // The quote was found in the file (page and sheet are where it was found)
+}
+
 // Message represents a chat message (simplified for learning project)
 type Message struct {
-	ID              uuid.UUID      `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
-	SessionID       string         `gorm:"type:varchar(100);index" json:"session_id"` // Session identifier for grouping conversations
-	Role            string         `gorm:"type:varchar(20);not null;check:role IN ('user', 'assistant', 'system')" json:"role"`
-	Content         string         `gorm:"type:text;not null" json:"content"`
-	PersonaID       *int           `json:"persona_id,omitempty"`
-	TokensUsed      *int           `json:"tokens_used,omitempty"`
-	FileAttachments datatypes.JSON `gorm:"type:jsonb;default:'[]'" json:"file_attachments"` // NEW - Array of FileAttachment
-	CreatedAt       time.Time      `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
-	Metadata        datatypes.JSON `gorm:"type:jsonb;default:'{}'" json:"metadata,omitempty"`
+	ID               uuid.UUID      `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
+	SessionID        string         `gorm:"type:varchar(100);index" json:"session_id"`  // Session identifier for grouping conversations
+	ParentID         *uuid.UUID     `gorm:"type:uuid;index" json:"parent_id,omitempty"` // Previous message in the branch (nil = first message of the session)
+	Role             string         `gorm:"type:varchar(20);not null;check:role IN ('user', 'assistant', 'system', 'tool')" json:"role"`
+	Content          string         `gorm:"type:text;not null" json:"content"`
+	PersonaID        *int           `json:"persona_id,omitempty"`
+	TokensUsed       *int           `json:"tokens_used,omitempty"`                            // prompt + completion
+	PromptTokens     *int           `json:"prompt_tokens,omitempty"`                          // Reported by the provider
+	CompletionTokens *int           `json:"completion_tokens,omitempty"`                      // Reported by the provider
+	CachedTokens     *int           `json:"cached_tokens,omitempty"`                          // Prompt tokens served from the provider cache
+	Provider         string         `gorm:"type:varchar(50);index" json:"provider,omitempty"` // Provider that generated the message
+	Model            string         `gorm:"type:varchar(100);index" json:"model,omitempty"`   // Model that generated the message
+	Cost             *float64       `gorm:"type:numeric(14,8)" json:"cost,omitempty"`         // USD, from the pricing table
+	ToolCalls        datatypes.JSON `gorm:"type:jsonb" json:"tool_calls,omitempty"`           // Assistant: array of MessageToolCall
+	ToolCallID       string         `gorm:"type:varchar(100)" json:"tool_call_id,omitempty"`  // Tool: the call this result answers
+	ToolName         string         `gorm:"type:varchar(100)" json:"tool_name,omitempty"`     // Tool: name of the tool that ran
+	FileAttachments  datatypes.JSON `gorm:"type:jsonb;default:'[]'" json:"file_attachments"`  // NEW - Array of FileAttachment
+	CreatedAt        time.Time      `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	Metadata         datatypes.JSON `gorm:"type:jsonb;default:'{}'" json:"metadata,omitempty"`
+	SearchText       *string        `gorm:"type:text" json:"-"` // Search terms of Content (see utils.SearchText), nil = not indexed yet
 
 	// Relationships
 	Persona *Persona `gorm:"foreignKey:PersonaID" json:"persona,omitempty"`
@@ -46,6 +76,22 @@ func (m *Message) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
+// BeforeSave hook to index the content for full-text search
+func (m *Message) BeforeSave(tx *gorm.DB) error {
+	searchText := utils.SearchText(m.Content)
+	m.SearchText = &searchText
+	return nil
+}
+
+// SetTokenUsage sets prompt, completion and cached tokens (TokensUsed becomes prompt + completion)
+func (m *Message) SetTokenUsage(promptTokens, completionTokens, cachedTokens int) {
+	total := promptTokens + completionTokens
+	m.TokensUsed = &total
+	m.PromptTokens = &promptTokens
+	m.CompletionTokens = &completionTokens
+	m.CachedTokens = &cachedTokens
+}
+
 // GetFileAttachments parses file_attachments JSONB into FileAttachment slice
 func (m *Message) GetFileAttachments() ([]FileAttachment, error) {
 	var attachments []FileAttachment
@@ -85,9 +131,58 @@ func (m *Message) HasFileAttachments() bool {
 	return len(attachments) > 0
 }
 
+// GetToolCalls parses tool_calls JSONB into MessageToolCall slice
+func (m *Message) GetToolCalls() ([]MessageToolCall, error) {
+	var calls []MessageToolCall
+
+	if len(m.ToolCalls) == 0 {
+		return calls, nil
+	}
+
+	if err := json.Unmarshal(m.ToolCalls, &calls); err != nil {
+		return nil, err
+	}
+
+	return calls, nil
+}
+
+// SetToolCalls sets tool calls from slice (nil or empty clears them)
+func (m *Message) SetToolCalls(calls []MessageToolCall) error {
+	if len(calls) == 0 {
+		m.ToolCalls = nil
+		return nil
+	}
+
+	data, err := json.Marshal(calls)
+	if err != nil {
+		return err
+	}
+
+	m.ToolCalls = data
+	return nil
+}
+
+// GetCitations parses the file citations stored in the metadata of an assistant message
+func (m *Message) GetCitations() ([]MessageCitation, error) {
+	var metadata struct {
+		Citations []MessageCitation `json:"citations"`
+	}
+
+	if len(m.Metadata) == 0 {
+		return nil, nil
+	}
+
+	if err := json.Unmarshal(m.Metadata, &metadata); err != nil {
+		return nil, err
+	}
+
+	return metadata.Citations, nil
+}
+
 // MessageRole constants
 const (
 	RoleUser      = "user"
 	RoleAssistant = "assistant"
 	RoleSystem    = "system"
+	RoleTool      = "tool"
 )
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MessageEmbedding is the embedding of a chat message used by semantic search (needs pgvector)
+type MessageEmbedding struct {
+	MessageID uuid.UUID `gorm:"type:uuid;primaryKey" json:"message_id"`
+	Model     string    `gorm:"type:varchar(100)" json:"model"`      // Embedding model
+	Embedding Vector    `gorm:"type:vector(1536);not null" json:"-"` // EmbeddingDimensions
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+
+	// Relationships (deleting the message deletes its embedding)
+	Message *Message `gorm:"foreignKey:MessageID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// TableName specifies the table name for MessageEmbedding model
+func (MessageEmbedding) TableName() string {
+	return "message_embeddings"
+}
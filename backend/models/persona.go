@@ -1,7 +1,10 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
+
+	"gorm.io/datatypes"
 )
 
 // LanguageSetting represents language configuration for a persona
@@ -32,7 +35,12 @@ type Persona struct {
 	Expertise      string     `gorm:"type:varchar(500)" json:"expertise"`        // e.g., "technology", "healthcare", "education"
 	Temperature    float32    `gorm:"type:decimal(3,2);default:0.7" json:"temperature"` // 0.0 - 2.0
 	MaxTokens      int        `gorm:"default:2000" json:"max_tokens"`
+	ThinkingBudget int        `gorm:"default:0" json:"thinking_budget"`              // Reasoning tokens for reasoning models (0 = off, min 1024 for Claude)
+	PromptCaching  bool       `gorm:"default:false" json:"prompt_caching"`           // Cache the system prompt and file context between turns
 	Model          string     `gorm:"type:varchar(50);default:'gpt-4o-mini'" json:"model"` // e.g., "gpt-4o-mini", "gpt-4"
+	Provider       string     `gorm:"type:varchar(50)" json:"provider"`                   // Provider name from the registry (empty = route default)
+	Tools          datatypes.JSON `gorm:"type:jsonb;default:'[]'" json:"tools"`          // Enabled tool names (array of strings)
+	KnowledgeBases datatypes.JSON `gorm:"type:jsonb;default:'[]'" json:"knowledge_bases"` // Attached knowledge base IDs (array of strings)
 	LanguageSetting string    `gorm:"type:jsonb" json:"language_setting"` // JSON field for language settings
 	Guardrails     string     `gorm:"type:jsonb" json:"guardrails"`       // JSON field for guardrails
 	Icon           string     `gorm:"type:varchar(50)" json:"icon"`
@@ -44,4 +52,70 @@ type Persona struct {
 // TableName specifies the table name for Persona model
 func (Persona) TableName() string {
 	return "personas"
-}
\ No newline at end of file
+}
+
+// GetTools parses the enabled tool names
+func (p *Persona) GetTools() []string {
+	tools := []string{}
+	if len(p.Tools) == 0 {
+		return tools
+	}
+	if err := json.Unmarshal(p.Tools, &tools); err != nil || tools == nil {
+		return []string{}
+	}
+	return tools
+}
+
+// SetTools sets the enabled tool names
+func (p *Persona) SetTools(tools []string) error {
+	if tools == nil {
+		tools = []string{}
+	}
+
+	data, err := json.Marshal(tools)
+	if err != nil {
+		return err
+	}
+
+	p.Tools = data
+	return nil
+}
+
+// GetKnowledgeBases parses the attached knowledge base IDs
+func (p *Persona) GetKnowledgeBases() []string {
+	ids := []string{}
+	if len(p.KnowledgeBases) == 0 {
+		return ids
+	}
+	if err := json.Unmarshal(p.KnowledgeBases, &ids); err != nil || ids == nil {
+		return []string{}
+	}
+	return ids
+}
+
+// SetKnowledgeBases sets the attached knowledge base IDs
+func (p *Persona) SetKnowledgeBases(ids []string) error {
+	if ids == nil {
+		ids = []string{}
+	}
+
+	data, err := json.Marshal(ids)
+	if err != nil {
+		return err
+	}
+
+	p.KnowledgeBases = data
+	return nil
+}
+
+// GetGuardrails parses the guardrails (zero value if unset or invalid)
+func (p *Persona) GetGuardrails() Guardrails {
+	var guardrails Guardrails
+	if p.Guardrails == "" {
+		return guardrails
+	}
+	if err := json.Unmarshal([]byte(p.Guardrails), &guardrails); err != nil {
+		return Guardrails{}
+	}
+	return guardrails
+}
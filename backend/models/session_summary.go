@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SessionSummary is a rolling summary of the older messages of a session branch
+// It stands in for the message it was made through and everything before it on that branch
+type SessionSummary struct {
+	ID           uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
+	SessionID    string    `gorm:"type:varchar(100);index" json:"session_id"`
+	ThroughID    uuid.UUID `gorm:"type:uuid;not null;index" json:"through_id"` // Last message covered by the summary
+	Content      string    `gorm:"type:text;not null" json:"content"`
+	MessageCount int       `json:"message_count"`                              // Messages covered, through ThroughID
+	Provider     string    `gorm:"type:varchar(50)" json:"provider,omitempty"` // Provider that wrote the summary
+	Model        string    `gorm:"type:varchar(100)" json:"model,omitempty"`   // Model that wrote the summary
+	CreatedAt    time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+
+	// Relationships (deleting the message deletes its summaries)
+	Through *Message `gorm:"foreignKey:ThroughID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// TableName specifies the table name for SessionSummary model
+func (SessionSummary) TableName() string {
+	return "session_summaries"
+}
+
+// BeforeCreate hook to generate UUID before creating
+func (s *SessionSummary) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return nil
+}
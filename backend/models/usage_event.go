@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// UsageEvent kinds
+const (
+	UsageKindTTS = "tts"
+	UsageKindSTT = "stt"
+)
+
+// UsageEvent records a billable TTS or STT call (chat usage is stored on Message)
+type UsageEvent struct {
+	ID              uint      `gorm:"primaryKey" json:"id"`
+	Kind            string    `gorm:"type:varchar(10);not null;index" json:"kind"` // tts or stt
+	Provider        string    `gorm:"type:varchar(50);index" json:"provider"`      // openai, elevenlabs, whispercpp
+	Model           string    `gorm:"type:varchar(100);index" json:"model"`
+	SessionID       string    `gorm:"type:varchar(100);index" json:"session_id,omitempty"`
+	PersonaID       *int      `json:"persona_id,omitempty"`
+	Characters      int       `json:"characters"`       // TTS input characters
+	DurationSeconds float64   `json:"duration_seconds"` // STT audio duration
+	Cost            *float64  `gorm:"type:numeric(14,8)" json:"cost,omitempty"`
+	CreatedAt       time.Time `gorm:"default:CURRENT_TIMESTAMP;index" json:"created_at"`
+}
+
+// TableName specifies the table name for UsageEvent model
+func (UsageEvent) TableName() string {
+	return "usage_events"
+}
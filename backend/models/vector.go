@@ -0,0 +1,64 @@
+package models
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// EmbeddingDimensions is the size of stored embeddings (vector(1536) columns)
+const EmbeddingDimensions = 1536
+
+// Vector is a pgvector column value, written and read in its text form "[1,2,3]"
+type Vector []float32
+
+// Value implements driver.Valuer
+func (v Vector) Value() (driver.Value, error) {
+	if v == nil {
+		return nil, nil
+	}
+	var text strings.Builder
+	text.WriteByte('[')
+	for i, x := range v {
+		if i > 0 {
+			text.WriteByte(',')
+		}
+		text.WriteString(strconv.FormatFloat(float64(x), 'f', -1, 32))
+	}
+	text.WriteByte(']')
+	return text.String(), nil
+}
+
+// Scan implements sql.Scanner
+func (v *Vector) Scan(src interface{}) error {
+	var text string
+	switch value := src.(type) {
+	case nil:
+		*v = nil
+		return nil
+	case string:
+		text = value
+	case []byte:
+		text = string(value)
+	default:
+		return fmt.Errorf("cannot scan %T into Vector", src)
+	}
+
+	text = strings.Trim(strings.TrimSpace(text), "[]")
+	if text == "" {
+		*v = Vector{}
+		return nil
+	}
+	fields := strings.Split(text, ",")
+	vector := make(Vector, len(fields))
+	for i, field := range fields {
+		x, err := strconv.ParseFloat(strings.TrimSpace(field), 32)
+		if err != nil {
+			return fmt.Errorf("invalid vector element %q: %w", field, err)
+		}
+		vector[i] = float32(x)
+	}
+	*v = vector
+	return nil
+}
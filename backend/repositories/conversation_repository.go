@@ -0,0 +1,172 @@
+package repositories
+
+import (
+	"encoding/json"
+	"time"
+
+	"chatbot/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ConversationFilter narrows the conversation listing
+type ConversationFilter struct {
+	Search    string // Matches the title or a tag (case-insensitive)
+	Archived  *bool  // nil = archived and active
+	Pinned    *bool
+	PersonaID *int
+	Tag       string
+	Limit     int
+	Offset    int
+}
+
+// ConversationSummary is a conversation with its message stats
+type ConversationSummary struct {
+	models.Conversation
+	MessageCount  int64      `json:"message_count"`
+	LastMessageAt *time.Time `json:"last_message_at,omitempty"`
+}
+
+// ConversationRepository handles database operations for conversations
+type ConversationRepository struct {
+	db *gorm.DB
+}
+
+// NewConversationRepository creates a new conversation repository
+func NewConversationRepository(db *gorm.DB) *ConversationRepository {
+	return &ConversationRepository{db: db}
+}
+
+// Create saves a new conversation
+func (r *ConversationRepository) Create(conversation *models.Conversation) error {
+	return r.db.Create(conversation).Error
+}
+
+// CreateIfMissing saves a conversation unless one with its ID exists, then loads the stored row
+func (r *ConversationRepository) CreateIfMissing(conversation *models.Conversation) error {
+	if err := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(conversation).Error; err != nil {
+		return err
+	}
+	return r.db.Where("id = ?", conversation.ID).First(conversation).Error
+}
+
+// FindByID retrieves a conversation by its ID
+func (r *ConversationRepository) FindByID(id string) (*models.Conversation, error) {
+	var conversation models.Conversation
+	err := r.db.Where("id = ?", id).First(&conversation).Error
+	if err != nil {
+		return nil, err
+	}
+	return &conversation, nil
+}
+
+// Summary retrieves a conversation with its message stats
+func (r *ConversationRepository) Summary(id string) (*ConversationSummary, error) {
+	var summary ConversationSummary
+	err := r.summaries().Where("conversations.id = ?", id).Take(&summary).Error
+	if err != nil {
+		return nil, err
+	}
+	return &summary, nil
+}
+
+// List retrieves conversations matching filter, pinned first then most recently updated
+func (r *ConversationRepository) List(filter ConversationFilter) ([]ConversationSummary, int64, error) {
+	query := r.db.Model(&models.Conversation{})
+	if filter.Search != "" {
+		pattern := "%" + filter.Search + "%"
+		query = query.Where("(title ILIKE ? OR CAST(tags AS text) ILIKE ?)", pattern, pattern)
+	}
+	if filter.Archived != nil {
+		query = query.Where("archived = ?", *filter.Archived)
+	}
+	if filter.Pinned != nil {
+		query = query.Where("pinned = ?", *filter.Pinned)
+	}
+	if filter.PersonaID != nil {
+		query = query.Where("persona_id = ?", *filter.PersonaID)
+	}
+	if filter.Tag != "" {
+		tag, _ := json.Marshal([]string{filter.Tag})
+		query = query.Where("tags @> ?", string(tag))
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var summaries []ConversationSummary
+	err := r.summaries().
+		Where("conversations.id IN (?)", query.Select("id")).
+		Order("conversations.pinned DESC, conversations.updated_at DESC").
+		Limit(filter.Limit).
+		Offset(filter.Offset).
+		Find(&summaries).Error
+	return summaries, total, err
+}
+
+// summaries selects conversations with their message count and last message time
+func (r *ConversationRepository) summaries() *gorm.DB {
+	return r.db.Model(&models.Conversation{}).
+		Select("conversations.*, COUNT(messages.id) AS message_count, MAX(messages.created_at) AS last_message_at").
+		Joins("LEFT JOIN messages ON messages.session_id = conversations.id").
+		Group("conversations.id")
+}
+
+// Update updates an existing conversation
+func (r *ConversationRepository) Update(conversation *models.Conversation) error {
+	return r.db.Save(conversation).Error
+}
+
+// Touch records a new turn: the provider and model that answered and the update time
+func (r *ConversationRepository) Touch(id, provider, model string) error {
+	return r.db.Model(&models.Conversation{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"provider":   provider,
+		"model":      model,
+		"updated_at": time.Now(),
+	}).Error
+}
+
+// SetTitle sets the title if the conversation has none yet (a user-set title is kept)
+func (r *ConversationRepository) SetTitle(id, title string, overwrite bool) error {
+	query := r.db.Model(&models.Conversation{}).Where("id = ?", id)
+	if !overwrite {
+		query = query.Where("title = ''")
+	}
+	return query.UpdateColumn("title", title).Error
+}
+
+// Delete removes a conversation and its messages
+func (r *ConversationRepository) Delete(id string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("session_id = ?", id).Delete(&models.Message{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Conversation{}, "id = ?", id).Error
+	})
+}
+
+// DeleteAll removes all conversations
+func (r *ConversationRepository) DeleteAll() error {
+	return r.db.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.Conversation{}).Error
+}
+
+// CreateFromMessages adds a conversation for every session that has messages but no conversation
+// (sessions saved before conversations existed); the title is the start of the first user message
+func (r *ConversationRepository) CreateFromMessages() (int64, error) {
+	result := r.db.Exec(`
+		INSERT INTO conversations (id, title, persona_id, provider, model, tags, created_at, updated_at)
+		SELECT session_id,
+			COALESCE(LEFT((array_agg(content ORDER BY created_at) FILTER (WHERE role = 'user'))[1], 50), ''),
+			(array_agg(persona_id ORDER BY created_at) FILTER (WHERE persona_id IS NOT NULL))[1],
+			COALESCE((array_agg(provider ORDER BY created_at DESC) FILTER (WHERE provider <> ''))[1], ''),
+			COALESCE((array_agg(model ORDER BY created_at DESC) FILTER (WHERE model <> ''))[1], ''),
+			'[]', MIN(created_at), MAX(created_at)
+		FROM messages
+		WHERE session_id <> ''
+		GROUP BY session_id
+		ON CONFLICT (id) DO NOTHING`)
+	return result.RowsAffected, result.Error
+}
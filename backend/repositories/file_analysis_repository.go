@@ -51,8 +51,8 @@ func (r *FileAnalysisRepository) GetAll(limit, offset int) ([]models.FileAnalysi
 		return nil, 0, err
 	}
 
-	// Get paginated records
-	err := r.db.Order("uploaded_at DESC").
+	// Get paginated records (without their extracted text)
+	err := r.db.Omit("extracted_text").Order("uploaded_at DESC").
 		Limit(limit).
 		Offset(offset).
 		Find(&analyses).Error
@@ -76,8 +76,8 @@ func (r *FileAnalysisRepository) GetByFileType(mimeType string, limit, offset in
 		return nil, 0, err
 	}
 
-	// Get paginated records
-	err := query.Order("uploaded_at DESC").
+	// Get paginated records (without their extracted text)
+	err := query.Omit("extracted_text").Order("uploaded_at DESC").
 		Limit(limit).
 		Offset(offset).
 		Find(&analyses).Error
@@ -94,6 +94,33 @@ func (r *FileAnalysisRepository) Update(analysis *models.FileAnalysis) error {
 	return r.db.Save(analysis).Error
 }
 
+// PendingExtraction retrieves the files whose text has not been extracted yet, oldest first
+func (r *FileAnalysisRepository) PendingExtraction() ([]models.FileAnalysis, error) {
+	var analyses []models.FileAnalysis
+	err := r.db.Where("extraction_status IN ?", []string{models.ExtractionStatusPending, models.ExtractionStatusProcessing}).
+		Order("uploaded_at").
+		Find(&analyses).Error
+	return analyses, err
+}
+
+// SetExtractionStatus updates the extraction status of a file
+func (r *FileAnalysisRepository) SetExtractionStatus(id uuid.UUID, status string) error {
+	return r.db.Model(&models.FileAnalysis{}).Where("id = ?", id).Update("extraction_status", status).Error
+}
+
+// SaveExtraction stores the outcome of extracting the text of a file
+func (r *FileAnalysisRepository) SaveExtraction(analysis *models.FileAnalysis) error {
+	return r.db.Model(&models.FileAnalysis{}).Where("id = ?", analysis.ID).Updates(map[string]interface{}{
+		"extracted_text":    analysis.ExtractedText,
+		"page_count":        analysis.PageCount,
+		"language":          analysis.Language,
+		"extraction_status": analysis.ExtractionStatus,
+		"extraction_error":  analysis.ExtractionError,
+		"extracted_at":      analysis.ExtractedAt,
+		"ocr_pages":         analysis.OCRPages,
+	}).Error
+}
+
 // Delete soft deletes a file analysis record
 func (r *FileAnalysisRepository) Delete(id uuid.UUID) error {
 	return r.db.Delete(&models.FileAnalysis{}, "id = ?", id).Error
@@ -0,0 +1,205 @@
+package repositories
+
+import (
+	"chatbot/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// KnowledgeBaseSummary is a knowledge base with its document stats
+type KnowledgeBaseSummary struct {
+	models.KnowledgeBase
+	DocumentCount int64 `json:"document_count"`
+	ChunkCount    int64 `json:"chunk_count"`
+}
+
+// KnowledgeHit is a chunk found by retrieval with its similarity and source names
+type KnowledgeHit struct {
+	models.KnowledgeChunk
+	Score             float64 // Cosine similarity (1 = identical)
+	FileName          string
+	KnowledgeBaseName string
+}
+
+// KnowledgeRepository handles database operations for knowledge bases, documents and chunks
+type KnowledgeRepository struct {
+	db *gorm.DB
+}
+
+// NewKnowledgeRepository creates a new knowledge repository
+func NewKnowledgeRepository(db *gorm.DB) *KnowledgeRepository {
+	return &KnowledgeRepository{db: db}
+}
+
+// EnsureVectorIndex creates the HNSW index used by retrieval (cosine distance)
+func (r *KnowledgeRepository) EnsureVectorIndex() error {
+	return r.db.Exec(`CREATE INDEX IF NOT EXISTS idx_knowledge_chunks_embedding ON knowledge_chunks USING hnsw (embedding vector_cosine_ops)`).Error
+}
+
+// VectorsEnabled reports whether chunks can be stored (pgvector installed and migrated)
+func (r *KnowledgeRepository) VectorsEnabled() bool {
+	return r.db.Migrator().HasTable(&models.KnowledgeChunk{})
+}
+
+// Create saves a new knowledge base
+func (r *KnowledgeRepository) Create(kb *models.KnowledgeBase) error {
+	return r.db.Create(kb).Error
+}
+
+// FindByID retrieves a knowledge base by its ID
+func (r *KnowledgeRepository) FindByID(id string) (*models.KnowledgeBase, error) {
+	kbID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	var kb models.KnowledgeBase
+	if err := r.db.Where("id = ?", kbID).First(&kb).Error; err != nil {
+		return nil, err
+	}
+	return &kb, nil
+}
+
+// FindByIDs retrieves the knowledge bases that exist among ids (invalid IDs are ignored)
+func (r *KnowledgeRepository) FindByIDs(ids []string) ([]models.KnowledgeBase, error) {
+	valid := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		if kbID, err := uuid.Parse(id); err == nil {
+			valid = append(valid, kbID)
+		}
+	}
+	var kbs []models.KnowledgeBase
+	if len(valid) == 0 {
+		return kbs, nil
+	}
+	err := r.db.Where("id IN ?", valid).Find(&kbs).Error
+	return kbs, err
+}
+
+// Summary retrieves a knowledge base with its document stats
+func (r *KnowledgeRepository) Summary(id uuid.UUID) (*KnowledgeBaseSummary, error) {
+	var summary KnowledgeBaseSummary
+	err := r.summaries().Where("knowledge_bases.id = ?", id).Take(&summary).Error
+	if err != nil {
+		return nil, err
+	}
+	return &summary, nil
+}
+
+// List retrieves all knowledge bases with their document stats, by name
+func (r *KnowledgeRepository) List() ([]KnowledgeBaseSummary, error) {
+	var summaries []KnowledgeBaseSummary
+	err := r.summaries().Order("knowledge_bases.name").Find(&summaries).Error
+	return summaries, err
+}
+
+// Update saves a knowledge base
+func (r *KnowledgeRepository) Update(kb *models.KnowledgeBase) error {
+	return r.db.Save(kb).Error
+}
+
+// Delete removes a knowledge base with its documents and chunks and detaches it from personas
+func (r *KnowledgeRepository) Delete(id uuid.UUID) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec(`UPDATE personas SET knowledge_bases = knowledge_bases - CAST(? AS text)
+			WHERE knowledge_bases @> jsonb_build_array(CAST(? AS text))`, id.String(), id.String()).Error
+		if err != nil {
+			return err
+		}
+		return tx.Delete(&models.KnowledgeBase{}, "id = ?", id).Error
+	})
+}
+
+// summaries selects knowledge bases with their document and chunk counts
+func (r *KnowledgeRepository) summaries() *gorm.DB {
+	return r.db.Model(&models.KnowledgeBase{}).
+		Select("knowledge_bases.*, " +
+			"(SELECT COUNT(*) FROM knowledge_documents d WHERE d.knowledge_base_id = knowledge_bases.id) AS document_count, " +
+			"(SELECT COALESCE(SUM(d.chunk_count), 0) FROM knowledge_documents d WHERE d.knowledge_base_id = knowledge_bases.id) AS chunk_count")
+}
+
+// AddDocument saves a document unless the file is already in the knowledge base, then loads the stored row
+// Returns whether the document was created
+func (r *KnowledgeRepository) AddDocument(doc *models.KnowledgeDocument) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(doc)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	err := r.db.Where("knowledge_base_id = ? AND file_id = ?", doc.KnowledgeBaseID, doc.FileID).First(doc).Error
+	return result.RowsAffected > 0, err
+}
+
+// FindDocument retrieves a document of a knowledge base
+func (r *KnowledgeRepository) FindDocument(kbID uuid.UUID, id string) (*models.KnowledgeDocument, error) {
+	docID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	var doc models.KnowledgeDocument
+	if err := r.db.Where("id = ? AND knowledge_base_id = ?", docID, kbID).First(&doc).Error; err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// ListDocuments retrieves the documents of a knowledge base, oldest first
+func (r *KnowledgeRepository) ListDocuments(kbID uuid.UUID) ([]models.KnowledgeDocument, error) {
+	var docs []models.KnowledgeDocument
+	err := r.db.Where("knowledge_base_id = ?", kbID).Order("created_at").Find(&docs).Error
+	return docs, err
+}
+
+// DocumentsWithStatus retrieves documents in any of the statuses (every knowledge base)
+func (r *KnowledgeRepository) DocumentsWithStatus(statuses ...string) ([]models.KnowledgeDocument, error) {
+	var docs []models.KnowledgeDocument
+	err := r.db.Where("status IN ?", statuses).Order("created_at").Find(&docs).Error
+	return docs, err
+}
+
+// SetDocumentStatus updates the indexing status of a document
+func (r *KnowledgeRepository) SetDocumentStatus(id uuid.UUID, status, errorMessage string, chunkCount int) error {
+	return r.db.Model(&models.KnowledgeDocument{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":      status,
+		"error":       errorMessage,
+		"chunk_count": chunkCount,
+	}).Error
+}
+
+// DeleteDocument removes a document and its chunks
+func (r *KnowledgeRepository) DeleteDocument(id uuid.UUID) error {
+	return r.db.Delete(&models.KnowledgeDocument{}, "id = ?", id).Error
+}
+
+// ReplaceChunks stores the chunks of a document in place of its previous ones
+func (r *KnowledgeRepository) ReplaceChunks(docID uuid.UUID, chunks []models.KnowledgeChunk) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("document_id = ?", docID).Delete(&models.KnowledgeChunk{}).Error; err != nil {
+			return err
+		}
+		if len(chunks) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(&chunks, 100).Error
+	})
+}
+
+// SearchChunks finds the chunks of the knowledge bases closest to embedding (cosine similarity)
+// Only chunks with a similarity of at least minScore are returned
+func (r *KnowledgeRepository) SearchChunks(kbIDs []uuid.UUID, embedding models.Vector, minScore float64, limit int) ([]KnowledgeHit, error) {
+	var hits []KnowledgeHit
+	if len(kbIDs) == 0 {
+		return hits, nil
+	}
+	err := r.db.Model(&models.KnowledgeChunk{}).
+		Joins("JOIN knowledge_documents ON knowledge_documents.id = knowledge_chunks.document_id").
+		Joins("JOIN knowledge_bases ON knowledge_bases.id = knowledge_chunks.knowledge_base_id").
+		Select("knowledge_chunks.*, knowledge_documents.file_name AS file_name, knowledge_bases.name AS knowledge_base_name, "+
+			"1 - (knowledge_chunks.embedding <=> ?::vector) AS score", embedding).
+		Where("knowledge_chunks.knowledge_base_id IN ?", kbIDs).
+		Where("1 - (knowledge_chunks.embedding <=> ?::vector) >= ?", embedding, minScore).
+		Order(clause.Expr{SQL: "knowledge_chunks.embedding <=> ?::vector", Vars: []interface{}{embedding}}).
+		Limit(limit).
+		Find(&hits).Error
+	return hits, err
+}
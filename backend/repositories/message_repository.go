@@ -2,6 +2,8 @@ package repositories
 
 import (
 	"chatbot/models"
+
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
@@ -20,6 +22,37 @@ func (r *MessageRepository) Create(message *models.Message) error {
 	return r.db.Create(message).Error
 }
 
+// CreateBranch saves messages in order as a chain after parentID (nil starts the session):
+// each message's parent is the one saved before it
+func (r *MessageRepository) CreateBranch(parentID *uuid.UUID, messages ...*models.Message) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for _, message := range messages {
+			message.ParentID = parentID
+			if err := tx.Create(message).Error; err != nil {
+				return err
+			}
+			parentID = &message.ID
+		}
+		return nil
+	})
+}
+
+// LinkLegacySessions chains the messages of sessions saved before branching in created_at order
+// Sessions that already have parent links are left alone, so running it again changes nothing
+func (r *MessageRepository) LinkLegacySessions() (int64, error) {
+	result := r.db.Exec(`
+		UPDATE messages AS m SET parent_id = chain.prev_id
+		FROM (
+			SELECT id, LAG(id) OVER (PARTITION BY session_id ORDER BY created_at, id) AS prev_id
+			FROM messages
+			WHERE session_id <> '' AND session_id NOT IN (
+				SELECT DISTINCT session_id FROM messages WHERE parent_id IS NOT NULL AND session_id IS NOT NULL
+			)
+		) AS chain
+		WHERE m.id = chain.id AND chain.prev_id IS NOT NULL`)
+	return result.RowsAffected, result.Error
+}
+
 // FindByID retrieves a message by its ID
 func (r *MessageRepository) FindByID(id string) (*models.Message, error) {
 	var message models.Message
@@ -100,6 +133,34 @@ func (r *MessageRepository) GetAllBySession(sessionID string) ([]models.Message,
 	return messages, err
 }
 
+// FindByConversation retrieves a page of a conversation's messages, oldest first
+func (r *MessageRepository) FindByConversation(conversationID string, limit, offset int) ([]models.Message, int64, error) {
+	var messages []models.Message
+	var total int64
+
+	query := r.db.Model(&models.Message{}).Where("session_id = ?", conversationID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Order("created_at ASC").Limit(limit).Offset(offset).Find(&messages).Error
+	return messages, total, err
+}
+
+// CreateSummary saves a rolling summary of a session branch
+func (r *MessageRepository) CreateSummary(summary *models.SessionSummary) error {
+	return r.db.Create(summary).Error
+}
+
+// FindSummariesBySession retrieves the summaries of all branches of a session, newest first
+func (r *MessageRepository) FindSummariesBySession(sessionID string) ([]models.SessionSummary, error) {
+	var summaries []models.SessionSummary
+	err := r.db.Where("session_id = ?", sessionID).
+		Order("created_at DESC").
+		Find(&summaries).Error
+	return summaries, err
+}
+
 // DeleteSession removes all messages from a specific session
 func (r *MessageRepository) DeleteSession(sessionID string) error {
 	return r.db.Where("session_id = ?", sessionID).Delete(&models.Message{}).Error
@@ -0,0 +1,167 @@
+package repositories
+
+import (
+	"time"
+
+	"chatbot/models"
+	"chatbot/utils"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// searchVector is the tsvector of a message's search text; it matches the expression of the
+// idx_messages_search GIN index (see EnsureSearchIndex), so the index is used
+const searchVector = "array_to_tsvector(string_to_array(messages.search_text, ' '))"
+
+// SearchFilter narrows a message search
+type SearchFilter struct {
+	PersonaID *int
+	SessionID string
+	Role      string
+	From      *time.Time // Messages created at or after
+	To        *time.Time // Messages created before
+	Limit     int
+	Offset    int
+}
+
+// SearchHit is a message found by a search with its relevance and conversation title
+type SearchHit struct {
+	models.Message
+	Score             float64
+	ConversationTitle string
+}
+
+// SearchRepository handles full-text and vector search over messages
+type SearchRepository struct {
+	db *gorm.DB
+}
+
+// NewSearchRepository creates a new search repository
+func NewSearchRepository(db *gorm.DB) *SearchRepository {
+	return &SearchRepository{db: db}
+}
+
+// EnsureSearchIndex creates the GIN index used by full-text search
+func (r *SearchRepository) EnsureSearchIndex() error {
+	return r.db.Exec(`CREATE INDEX IF NOT EXISTS idx_messages_search ON messages USING GIN (array_to_tsvector(string_to_array(search_text, ' ')))`).Error
+}
+
+// EnsureVectorIndex creates the HNSW index used by semantic search (cosine distance)
+func (r *SearchRepository) EnsureVectorIndex() error {
+	return r.db.Exec(`CREATE INDEX IF NOT EXISTS idx_message_embeddings_embedding ON message_embeddings USING hnsw (embedding vector_cosine_ops)`).Error
+}
+
+// VectorsEnabled reports whether semantic search is available (pgvector installed and migrated)
+func (r *SearchRepository) VectorsEnabled() bool {
+	return r.db.Migrator().HasTable(&models.MessageEmbedding{})
+}
+
+// IndexSearchText fills the search text of messages saved before full-text search, batch by batch
+func (r *SearchRepository) IndexSearchText(batchSize int) (int64, error) {
+	var indexed int64
+	for {
+		var messages []models.Message
+		err := r.db.Select("id", "content").
+			Where("search_text IS NULL").
+			Limit(batchSize).
+			Find(&messages).Error
+		if err != nil || len(messages) == 0 {
+			return indexed, err
+		}
+
+		err = r.db.Transaction(func(tx *gorm.DB) error {
+			for _, msg := range messages {
+				searchText := utils.SearchText(msg.Content)
+				if err := tx.Model(&models.Message{}).Where("id = ?", msg.ID).UpdateColumn("search_text", searchText).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return indexed, err
+		}
+		indexed += int64(len(messages))
+	}
+}
+
+// TextSearch finds messages matching a tsquery (see utils.SearchQuery), most relevant first
+func (r *SearchRepository) TextSearch(tsquery string, filter SearchFilter) ([]SearchHit, int64, error) {
+	query := r.filtered(filter).Where(searchVector+" @@ ?::tsquery", tsquery).Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var hits []SearchHit
+	err := query.
+		Select("messages.*, COALESCE(conversations.title, '') AS conversation_title, ts_rank("+searchVector+", ?::tsquery) AS score", tsquery).
+		Order("score DESC, messages.created_at DESC").
+		Limit(filter.Limit).
+		Offset(filter.Offset).
+		Find(&hits).Error
+	return hits, total, err
+}
+
+// VectorSearch finds the messages whose embeddings are closest to embedding (cosine similarity)
+func (r *SearchRepository) VectorSearch(embedding models.Vector, filter SearchFilter) ([]SearchHit, error) {
+	var hits []SearchHit
+	err := r.filtered(filter).
+		Joins("JOIN message_embeddings ON message_embeddings.message_id = messages.id").
+		Select("messages.*, COALESCE(conversations.title, '') AS conversation_title, 1 - (message_embeddings.embedding <=> ?::vector) AS score", embedding).
+		Order(clause.Expr{SQL: "message_embeddings.embedding <=> ?::vector", Vars: []interface{}{embedding}}).
+		Limit(filter.Limit).
+		Offset(filter.Offset).
+		Find(&hits).Error
+	return hits, err
+}
+
+// MissingEmbeddings lists user and assistant messages without an embedding, oldest first
+// (sessionID "" = every session)
+func (r *SearchRepository) MissingEmbeddings(sessionID string, limit int) ([]models.Message, error) {
+	query := r.db.Model(&models.Message{}).
+		Select("messages.*").
+		Joins("LEFT JOIN message_embeddings ON message_embeddings.message_id = messages.id").
+		Where("message_embeddings.message_id IS NULL").
+		Where("messages.role IN ?", []string{models.RoleUser, models.RoleAssistant}).
+		Where("messages.content <> ''")
+	if sessionID != "" {
+		query = query.Where("messages.session_id = ?", sessionID)
+	}
+
+	var messages []models.Message
+	err := query.Order("messages.created_at").Limit(limit).Find(&messages).Error
+	return messages, err
+}
+
+// SaveEmbeddings stores message embeddings (messages that already have one are skipped)
+func (r *SearchRepository) SaveEmbeddings(embeddings []models.MessageEmbedding) error {
+	if len(embeddings) == 0 {
+		return nil
+	}
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&embeddings).Error
+}
+
+// filtered selects messages matching the filter, joined with their conversation
+func (r *SearchRepository) filtered(filter SearchFilter) *gorm.DB {
+	query := r.db.Model(&models.Message{}).
+		Joins("LEFT JOIN conversations ON conversations.id = messages.session_id")
+	if filter.PersonaID != nil {
+		query = query.Where("messages.persona_id = ?", *filter.PersonaID)
+	}
+	if filter.SessionID != "" {
+		query = query.Where("messages.session_id = ?", filter.SessionID)
+	}
+	if filter.Role != "" {
+		query = query.Where("messages.role = ?", filter.Role)
+	}
+	if filter.From != nil {
+		query = query.Where("messages.created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("messages.created_at < ?", *filter.To)
+	}
+	return query
+}
@@ -0,0 +1,135 @@
+package repositories
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"chatbot/models"
+	"gorm.io/gorm"
+)
+
+// UsageGroupKeys maps a group_by value to its SQL key expression
+var UsageGroupKeys = map[string]string{
+	"day":      "to_char(date_trunc('day', created_at), 'YYYY-MM-DD')",
+	"persona":  "COALESCE(CAST(persona_id AS text), '')",
+	"session":  "COALESCE(session_id, '')",
+	"provider": "COALESCE(provider, '')",
+	"model":    "COALESCE(model, '')",
+}
+
+// UsageFilter narrows the usage report
+type UsageFilter struct {
+	GroupBy   string // One of UsageGroupKeys
+	From      *time.Time
+	To        *time.Time // Exclusive
+	SessionID string
+	PersonaID *int
+	Provider  string
+	Model     string
+}
+
+// UsageSummary is one aggregated row of the usage report
+type UsageSummary struct {
+	Key              string  `json:"key"`
+	Requests         int64   `json:"requests"`
+	ChatRequests     int64   `json:"chat_requests"`
+	TTSRequests      int64   `json:"tts_requests"`
+	STTRequests      int64   `json:"stt_requests"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	CachedTokens     int64   `json:"cached_tokens"`
+	Characters       int64   `json:"characters"`
+	AudioSeconds     float64 `json:"audio_seconds"`
+	ChatCost         float64 `json:"chat_cost"`
+	TTSCost          float64 `json:"tts_cost"`
+	STTCost          float64 `json:"stt_cost"`
+	Cost             float64 `json:"cost"`
+}
+
+// usageSource combines assistant messages (chat) and usage events (TTS/STT) into one row set
+const usageSource = `
+	SELECT created_at, session_id, persona_id, provider, model, 'chat' AS kind,
+		COALESCE(prompt_tokens, 0) AS prompt_tokens,
+		COALESCE(completion_tokens, 0) AS completion_tokens,
+		COALESCE(cached_tokens, 0) AS cached_tokens,
+		0 AS characters, 0 AS duration_seconds, COALESCE(cost, 0) AS cost
+	FROM messages WHERE role = 'assistant'
+	UNION ALL
+	SELECT created_at, session_id, persona_id, provider, model, kind,
+		0, 0, 0, characters, duration_seconds, COALESCE(cost, 0)
+	FROM usage_events`
+
+// UsageRepository handles database operations for usage events and reports
+type UsageRepository struct {
+	db *gorm.DB
+}
+
+// NewUsageRepository creates a new usage repository
+func NewUsageRepository(db *gorm.DB) *UsageRepository {
+	return &UsageRepository{db: db}
+}
+
+// Create saves a new usage event to database
+func (r *UsageRepository) Create(event *models.UsageEvent) error {
+	return r.db.Create(event).Error
+}
+
+// Summarize aggregates chat, TTS and STT usage grouped by filter.GroupBy
+func (r *UsageRepository) Summarize(filter UsageFilter) ([]UsageSummary, error) {
+	keyExpr, ok := UsageGroupKeys[filter.GroupBy]
+	if !ok {
+		return nil, fmt.Errorf("invalid group_by: %s", filter.GroupBy)
+	}
+
+	conditions := []string{"1 = 1"}
+	args := []interface{}{}
+	if filter.From != nil {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, *filter.From)
+	}
+	if filter.To != nil {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, *filter.To)
+	}
+	if filter.SessionID != "" {
+		conditions = append(conditions, "session_id = ?")
+		args = append(args, filter.SessionID)
+	}
+	if filter.PersonaID != nil {
+		conditions = append(conditions, "persona_id = ?")
+		args = append(args, *filter.PersonaID)
+	}
+	if filter.Provider != "" {
+		conditions = append(conditions, "provider = ?")
+		args = append(args, filter.Provider)
+	}
+	if filter.Model != "" {
+		conditions = append(conditions, "model = ?")
+		args = append(args, filter.Model)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s AS key,
+			COUNT(*) AS requests,
+			COUNT(*) FILTER (WHERE kind = 'chat') AS chat_requests,
+			COUNT(*) FILTER (WHERE kind = 'tts') AS tts_requests,
+			COUNT(*) FILTER (WHERE kind = 'stt') AS stt_requests,
+			SUM(prompt_tokens) AS prompt_tokens,
+			SUM(completion_tokens) AS completion_tokens,
+			SUM(cached_tokens) AS cached_tokens,
+			SUM(characters) AS characters,
+			SUM(duration_seconds) AS audio_seconds,
+			COALESCE(SUM(cost) FILTER (WHERE kind = 'chat'), 0) AS chat_cost,
+			COALESCE(SUM(cost) FILTER (WHERE kind = 'tts'), 0) AS tts_cost,
+			COALESCE(SUM(cost) FILTER (WHERE kind = 'stt'), 0) AS stt_cost,
+			SUM(cost) AS cost
+		FROM (%s) AS usage
+		WHERE %s
+		GROUP BY 1
+		ORDER BY 1`, keyExpr, usageSource, strings.Join(conditions, " AND "))
+
+	var rows []UsageSummary
+	err := r.db.Raw(query, args...).Scan(&rows).Error
+	return rows, err
+}
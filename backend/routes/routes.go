@@ -1,6 +1,7 @@
 package routes
 
 import (
+	"context"
 	"log"
 
 	"chatbot/config"
@@ -19,21 +20,53 @@ func SetupRoutes(app *fiber.App, db *gorm.DB, cfg *config.Config) {
 	messageRepo := repositories.NewMessageRepository(db)
 	personaRepo := repositories.NewPersonaRepository(db)
 	fileAnalysisRepo := repositories.NewFileAnalysisRepository(db)
+	usageRepo := repositories.NewUsageRepository(db)
+	conversationRepo := repositories.NewConversationRepository(db)
+	searchRepo := repositories.NewSearchRepository(db)
+	knowledgeRepo := repositories.NewKnowledgeRepository(db)
 
 	// Initialize services
 	openaiService := services.NewOpenAIService(cfg)
 	ttsService := services.NewTTSService(cfg)
 	elevenLabsService := services.NewElevenLabsService(cfg)
-	contextService := services.NewContextService(messageRepo, fileAnalysisRepo)
-	fileService := services.NewFileService(openaiService.GetClient(), contextService)
-
-	// Initialize Bedrock service
-	bedrockService, err := services.NewBedrockService(cfg)
-	if err != nil {
-		log.Printf("⚠️ Warning: Failed to initialize Bedrock service: %v", err)
-		log.Printf("   Bedrock endpoints will not be available")
+	fileExtraction := services.NewFileExtractionService(fileAnalysisRepo)
+	contextService := services.NewContextService(messageRepo, fileAnalysisRepo, fileExtraction)
+	structuredOutput := services.NewStructuredOutputService(cfg.StructuredOutputMaxRetries)
+	pricingService := services.NewPricingService(cfg.Pricing)
+	usageService := services.NewUsageService(pricingService, usageRepo)
+
+	// Initialize streaming chat providers (each provider registers itself in services)
+	providerRegistry := services.NewProviderRegistryFromConfig(cfg)
+	log.Printf("✓ Chat providers registered: %v", providerRegistry.Names())
+	modelCatalog := services.NewModelCatalog(cfg.ModelCatalog, pricingService, providerRegistry)
+
+	// Initialize OCR (Tesseract) for scanned PDFs and images before any text is extracted
+	if cfg.OCREnabled {
+		ocrService, err := services.NewTesseractService(cfg)
+		if err != nil {
+			log.Printf("⚠️ Warning: Failed to initialize Tesseract OCR: %v", err)
+			log.Printf("   Scanned PDFs and images will not be read with OCR")
+		} else {
+			services.UseOCR(ocrService, cfg)
+		}
 	}
 
+	fileExtraction.ResumeExtraction()
+	fileService := services.NewFileService(openaiService, contextService, fileAnalysisRepo, fileExtraction, structuredOutput, modelCatalog)
+	failoverStreamer := services.NewFailoverStreamer(providerRegistry, services.NewFailoverPolicyFromConfig(cfg))
+	summarizer := services.NewSummarizer(messageRepo, providerRegistry, cfg)
+	embeddingService := services.NewEmbeddingService(cfg)
+	searchService := services.NewSearchService(searchRepo, embeddingService, cfg)
+	go searchService.IndexAll(context.Background())
+	knowledgeService := services.NewKnowledgeService(knowledgeRepo, fileAnalysisRepo, fileExtraction, embeddingService, cfg)
+	knowledgeService.ResumeIndexing()
+	conversationService := services.NewConversationService(conversationRepo, messageRepo, providerRegistry, summarizer, searchService, cfg)
+
+	// Initialize tools personas can enable (each tool registers itself in services)
+	toolRegistry := services.NewToolRegistry()
+	log.Printf("✓ Tools registered: %v", toolRegistry.Names())
+	toolOrchestrator := services.NewToolOrchestrator(toolRegistry, cfg.ToolMaxRounds)
+
 	// Initialize Whisper.cpp service
 	whisperService, err := services.NewWhisperCppService(cfg)
 	if err != nil {
@@ -42,25 +75,36 @@ func SetupRoutes(app *fiber.App, db *gorm.DB, cfg *config.Config) {
 	}
 
 	// Initialize controllers
-	chatCtrl := controllers.NewChatController(messageRepo, personaRepo, fileAnalysisRepo, openaiService)
-	personaCtrl := controllers.NewPersonaController(personaRepo, messageRepo)
-	audioCtrl := controllers.NewAudioController(openaiService, ttsService)
-	elevenLabsCtrl := controllers.NewElevenLabsController(elevenLabsService)
-	wsCtrl := controllers.NewWebSocketController(messageRepo, personaRepo, fileAnalysisRepo, openaiService, bedrockService)
-	ttsWSCtrl := controllers.NewTTSWebSocketController(ttsService, personaRepo)
-	elevenLabsWSCtrl := controllers.NewElevenLabsWSController(elevenLabsService)
-	fileCtrl := controllers.NewFileController(fileService, fileAnalysisRepo, messageRepo)
+	chatCtrl := controllers.NewChatController(messageRepo, personaRepo, fileAnalysisRepo, contextService, providerRegistry, usageService, toolOrchestrator, structuredOutput, failoverStreamer, modelCatalog, conversationService, knowledgeService)
+	personaCtrl := controllers.NewPersonaController(personaRepo, messageRepo, providerRegistry, toolRegistry, modelCatalog, knowledgeRepo)
+	audioCtrl := controllers.NewAudioController(openaiService, ttsService, usageService)
+	elevenLabsCtrl := controllers.NewElevenLabsController(elevenLabsService, usageService)
+	wsCtrl := controllers.NewWebSocketController(messageRepo, personaRepo, fileAnalysisRepo, contextService, providerRegistry, failoverStreamer, usageService, toolOrchestrator, modelCatalog, conversationService, knowledgeService)
+	ttsWSCtrl := controllers.NewTTSWebSocketController(ttsService, personaRepo, usageService)
+	elevenLabsWSCtrl := controllers.NewElevenLabsWSController(elevenLabsService, usageService)
+	fileCtrl := controllers.NewFileController(fileService, fileAnalysisRepo, messageRepo, fileExtraction)
+	providerCtrl := controllers.NewProviderController(providerRegistry)
+	modelCtrl := controllers.NewModelController(modelCatalog)
+	toolCtrl := controllers.NewToolController(toolRegistry)
+	usageCtrl := controllers.NewUsageController(usageService)
+	searchCtrl := controllers.NewSearchController(searchService)
+	knowledgeCtrl := controllers.NewKnowledgeController(knowledgeRepo, knowledgeService)
+	conversationCtrl := controllers.NewConversationController(conversationRepo, messageRepo, personaRepo, providerRegistry, conversationService)
+	branchCtrl := controllers.NewBranchController(messageRepo, personaRepo, contextService, failoverStreamer, usageService, toolOrchestrator, modelCatalog, conversationService, knowledgeService)
+	openAICompatCtrl := controllers.NewOpenAICompatController(messageRepo, personaRepo, contextService, providerRegistry, failoverStreamer, usageService, toolOrchestrator, modelCatalog, conversationService, knowledgeService)
 
 	// Initialize Bedrock controller
 	var bedrockCtrl *controllers.BedrockController
-	if bedrockService != nil {
-		bedrockCtrl = controllers.NewBedrockController(bedrockService, personaRepo, messageRepo, contextService, fileAnalysisRepo)
+	if _, ok := providerRegistry.Capabilities("bedrock"); ok {
+		bedrockCtrl = controllers.NewBedrockController(providerRegistry, personaRepo, messageRepo, contextService, fileAnalysisRepo, usageService, toolOrchestrator, structuredOutput, failoverStreamer, modelCatalog, conversationService, knowledgeService)
+	} else {
+		log.Printf("   Bedrock endpoints will not be available")
 	}
 
 	// Initialize Whisper.cpp controller
 	var whisperCtrl *controllers.WhisperCppController
 	if whisperService != nil {
-		whisperCtrl = controllers.NewWhisperCppController(whisperService)
+		whisperCtrl = controllers.NewWhisperCppController(whisperService, usageService)
 	}
 
 	// API group
@@ -75,6 +119,18 @@ func SetupRoutes(app *fiber.App, db *gorm.DB, cfg *config.Config) {
 		})
 	})
 
+	// Chat providers endpoint
+	api.Get("/providers", providerCtrl.GetProviders)
+
+	// Model catalog
+	api.Get("/models", modelCtrl.GetModels)
+
+	// Tools personas can enable
+	api.Get("/tools", toolCtrl.GetTools)
+
+	// Usage and cost report
+	api.Get("/usage", usageCtrl.GetUsage)
+
 	// Personas endpoints
 	api.Get("/personas", personaCtrl.GetAllPersonas)
 	api.Get("/personas/:id", personaCtrl.GetPersonaByID)
@@ -85,10 +141,36 @@ func SetupRoutes(app *fiber.App, db *gorm.DB, cfg *config.Config) {
 	// Chat endpoints (OpenAI)
 	api.Post("/chat", chatCtrl.HandleChat)
 	api.Get("/chats", chatCtrl.GetChatHistory)
+	api.Get("/chats/search", searchCtrl.SearchMessages)
 	api.Get("/chats/session/:sessionId", chatCtrl.GetChatHistoryBySession)
 	api.Delete("/chats", chatCtrl.DeleteAllMessages)
 	api.Delete("/chats/session/:sessionId", chatCtrl.DeleteMessagesBySession)
 
+	// Conversations (chat sessions with title and metadata)
+	api.Get("/conversations", conversationCtrl.ListConversations)
+	api.Post("/conversations", conversationCtrl.CreateConversation)
+	api.Get("/conversations/:id", conversationCtrl.GetConversation)
+	api.Patch("/conversations/:id", conversationCtrl.UpdateConversation)
+	api.Delete("/conversations/:id", conversationCtrl.DeleteConversation)
+	api.Post("/conversations/:id/title", conversationCtrl.GenerateTitle)
+	api.Get("/conversations/:id/messages", conversationCtrl.GetConversationMessages)
+
+	// Conversation branching (edit a user message, regenerate a reply, switch branches)
+	api.Get("/chats/session/:sessionId/branch", branchCtrl.GetBranch)
+	api.Post("/chats/messages/:id/edit", branchCtrl.EditMessage)
+	api.Post("/chats/messages/:id/regenerate", branchCtrl.RegenerateMessage)
+
+	// Knowledge bases (documents retrieved from by personas that attach them)
+	api.Get("/knowledge-bases", knowledgeCtrl.ListKnowledgeBases)
+	api.Post("/knowledge-bases", knowledgeCtrl.CreateKnowledgeBase)
+	api.Get("/knowledge-bases/:id", knowledgeCtrl.GetKnowledgeBase)
+	api.Patch("/knowledge-bases/:id", knowledgeCtrl.UpdateKnowledgeBase)
+	api.Delete("/knowledge-bases/:id", knowledgeCtrl.DeleteKnowledgeBase)
+	api.Post("/knowledge-bases/:id/documents", knowledgeCtrl.AddDocuments)
+	api.Post("/knowledge-bases/:id/documents/:documentId/reindex", knowledgeCtrl.ReindexDocument)
+	api.Delete("/knowledge-bases/:id/documents/:documentId", knowledgeCtrl.DeleteDocument)
+	api.Post("/knowledge-bases/:id/query", knowledgeCtrl.QueryKnowledgeBase)
+
 	// Bedrock endpoints (AWS Bedrock)
 	if bedrockCtrl != nil {
 		api.Post("/chat/bedrock", bedrockCtrl.SendBedrockMessage)
@@ -122,6 +204,11 @@ func SetupRoutes(app *fiber.App, db *gorm.DB, cfg *config.Config) {
 	api.Get("/file/history", fileCtrl.GetFileHistory)
 	api.Delete("/file/uploads", fileCtrl.DeleteAllFiles)
 
+	// OpenAI-compatible API (models are personas or provider models)
+	v1 := app.Group("/v1")
+	v1.Get("/models", openAICompatCtrl.ListModels)
+	v1.Post("/chat/completions", openAICompatCtrl.ChatCompletions)
+
 	// WebSocket upgrade middleware: ตรวจสอบ request จาก client
 	app.Use("/api/chat/stream", func(c *fiber.Ctx) error {
 		// IsWebSocketUpgrade returns true if the client requested upgrade to the WebSocket protocol
@@ -2,10 +2,12 @@ package services
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
+	"strings"
 
 	"chatbot/config"
 
@@ -22,6 +24,16 @@ type BedrockService struct {
 	config *config.Config
 }
 
+func init() {
+	RegisterProvider("bedrock", 20, ProviderCapabilities{
+		Vision:     true,
+		Streaming:  true,
+		MaxContext: 200000,
+	}, func(cfg *config.Config) (StreamingChatService, error) {
+		return NewBedrockService(cfg)
+	})
+}
+
 // NewBedrockService creates a new Bedrock service
 func NewBedrockService(cfg *config.Config) (*BedrockService, error) {
 	// Load AWS config with explicit credentials
@@ -59,68 +71,155 @@ func NewBedrockService(cfg *config.Config) (*BedrockService, error) {
 // ClaudeMessage represents a single message in the conversation
 // Content can be either string (simple text) or []ClaudeContentBlock (multimodal)
 type ClaudeMessage struct {
-	Role    string      `json:"role"` // "user" or "assistant"
+	Role    string      `json:"role"`    // "user" or "assistant"
 	Content interface{} `json:"content"` // string or []ClaudeContentBlock
 }
 
-// ClaudeContentBlock represents a content block (text or image)
+// ClaudeContentBlock represents a content block (text, image, tool_use, tool_result, thinking or redacted_thinking)
 type ClaudeContentBlock struct {
-	Type   string                `json:"type"` // "text" or "image"
-	Text   string                `json:"text,omitempty"`
-	Source *ClaudeImageSource    `json:"source,omitempty"`
+	Type      string             `json:"type"` // "text", "image", "tool_use", "tool_result", "thinking" or "redacted_thinking"
+	Text      string             `json:"text,omitempty"`
+	Source    *ClaudeImageSource `json:"source,omitempty"`
+	ID        string             `json:"id,omitempty"`          // tool_use: call ID
+	Name      string             `json:"name,omitempty"`        // tool_use: tool name
+	Input     json.RawMessage    `json:"input,omitempty"`       // tool_use: JSON arguments
+	ToolUseID string             `json:"tool_use_id,omitempty"` // tool_result: the call it answers
+	Content   string             `json:"content,omitempty"`     // tool_result: result text
+	Thinking  string             `json:"thinking,omitempty"`    // thinking: reasoning text
+	Signature string             `json:"signature,omitempty"`   // thinking: signature to send the block back
+	Data      string             `json:"data,omitempty"`        // redacted_thinking: encrypted reasoning
+
+	CacheControl *ClaudeCacheControl `json:"cache_control,omitempty"` // Prompt cache breakpoint after this block
+}
+
+// ClaudeCacheControl marks a prompt cache breakpoint
+type ClaudeCacheControl struct {
+	Type string `json:"type"` // "ephemeral"
 }
 
 // ClaudeImageSource represents an image source for Claude
 type ClaudeImageSource struct {
-	Type      string `json:"type"` // "base64"
+	Type      string `json:"type"`       // "base64"
 	MediaType string `json:"media_type"` // "image/jpeg", "image/png", etc.
-	Data      string `json:"data"` // base64 encoded image
+	Data      string `json:"data"`       // base64 encoded image
 }
 
 // ClaudeRequest represents the request body for Claude on Bedrock
 type ClaudeRequest struct {
-	AnthropicVersion string          `json:"anthropic_version"`
-	MaxTokens        int             `json:"max_tokens"`
-	Messages         []ClaudeMessage `json:"messages"`
-	Temperature      float64         `json:"temperature,omitempty"`
-	SystemPrompt     string          `json:"system,omitempty"`
+	AnthropicVersion string            `json:"anthropic_version"`
+	MaxTokens        int               `json:"max_tokens"`
+	Messages         []ClaudeMessage   `json:"messages"`
+	Temperature      float64           `json:"temperature,omitempty"`
+	SystemPrompt     interface{}       `json:"system,omitempty"` // string or []ClaudeContentBlock (prompt caching)
+	Tools            []ClaudeTool      `json:"tools,omitempty"`
+	ToolChoice       *ClaudeToolChoice `json:"tool_choice,omitempty"`
+	Thinking         *ClaudeThinking   `json:"thinking,omitempty"`
+}
+
+// ClaudeThinking enables extended thinking
+type ClaudeThinking struct {
+	Type         string `json:"type"` // "enabled"
+	BudgetTokens int    `json:"budget_tokens"`
+}
+
+// minThinkingBudget is the smallest thinking budget Claude accepts
+const minThinkingBudget = 1024
+
+// claudeThinking enables extended thinking when the request has a budget
+// Claude needs max_tokens above the budget and its default temperature, and cannot think while a tool is forced
+func claudeThinking(budget, maxTokens int, temperature float64, toolChoice *ClaudeToolChoice) (*ClaudeThinking, int, float64) {
+	if budget <= 0 {
+		return nil, maxTokens, temperature
+	}
+	if toolChoice != nil && toolChoice.Type != "auto" {
+		log.Printf("⚠️  Extended thinking is not available with a forced tool (structured output), skipping")
+		return nil, maxTokens, temperature
+	}
+	budget = max(budget, minThinkingBudget)
+	if maxTokens <= budget {
+		maxTokens += budget
+	}
+	return &ClaudeThinking{Type: "enabled", BudgetTokens: budget}, maxTokens, 0
+}
+
+// claudeReasoning converts thinking and redacted_thinking blocks to reasoning blocks
+func claudeReasoning(block ClaudeContentBlock) (ReasoningBlock, bool) {
+	switch block.Type {
+	case "thinking":
+		return ReasoningBlock{Text: block.Thinking, Signature: block.Signature}, true
+	case "redacted_thinking":
+		return ReasoningBlock{Redacted: block.Data}, true
+	}
+	return ReasoningBlock{}, false
+}
+
+// ClaudeTool is a tool definition in the Claude Messages API
+type ClaudeTool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"input_schema"`
+}
+
+// ClaudeToolChoice forces Claude to call a specific tool
+type ClaudeToolChoice struct {
+	Type string `json:"type"` // "auto", "any" or "tool"
+	Name string `json:"name,omitempty"`
 }
 
 // ClaudeResponse represents the response from Claude on Bedrock
 type ClaudeResponse struct {
-	ID      string `json:"id"`
-	Type    string `json:"type"`
-	Role    string `json:"role"`
-	Content []struct {
-		Type string `json:"type"`
-		Text string `json:"text"`
-	} `json:"content"`
-	Model      string `json:"model"`
-	StopReason string `json:"stop_reason"`
-	Usage      struct {
-		InputTokens  int `json:"input_tokens"`
-		OutputTokens int `json:"output_tokens"`
-	} `json:"usage"`
+	ID         string               `json:"id"`
+	Type       string               `json:"type"`
+	Role       string               `json:"role"`
+	Content    []ClaudeContentBlock `json:"content"` // text and tool_use blocks
+	Model      string               `json:"model"`
+	StopReason string               `json:"stop_reason"`
+	Usage      claudeUsage          `json:"usage"`
+}
+
+// claudeUsage is the usage block in Claude responses and stream events
+type claudeUsage struct {
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+}
+
+// promptTokens returns all prompt tokens (Claude reports cached tokens separately from input_tokens)
+func (u claudeUsage) promptTokens() int {
+	return u.InputTokens + u.CacheReadInputTokens + u.CacheCreationInputTokens
 }
 
 // BedrockChatRequest represents a chat request with all options
 type BedrockChatRequest struct {
-	Messages     []ClaudeMessage
-	SystemPrompt string
-	Temperature  float64
-	MaxTokens    int
+	Messages       []ClaudeMessage
+	SystemPrompt   interface{} // string or []ClaudeContentBlock (see claudePrompt)
+	Temperature    float64
+	MaxTokens      int
+	Model          string // Optional, uses BedrockModelID if empty
+	Tools          []ClaudeTool
+	ToolChoice     *ClaudeToolChoice
+	ThinkingBudget int // Extended thinking tokens (0 = off)
 }
 
 // BedrockChatResponse represents the response from Bedrock
 type BedrockChatResponse struct {
 	Content    string
 	TokensUsed int
+	Usage      TokenUsage
 	Model      string
 	StopReason string
+	ToolCalls  []ToolCall       // tool_use blocks (StopReason "tool_use")
+	Reasoning  []ReasoningBlock // thinking blocks (extended thinking)
 }
 
 // SendChatRequest sends a chat request to AWS Bedrock (Claude)
 func (s *BedrockService) SendChatRequest(req BedrockChatRequest) (*BedrockChatResponse, error) {
+	return s.sendChatRequest(context.Background(), req)
+}
+
+// sendChatRequest sends a chat request to AWS Bedrock (Claude) using the given context
+func (s *BedrockService) sendChatRequest(ctx context.Context, req BedrockChatRequest) (*BedrockChatResponse, error) {
 	// Use default values if not specified
 	if req.Temperature == 0 {
 		req.Temperature = s.config.BedrockTemperature
@@ -128,17 +227,24 @@ func (s *BedrockService) SendChatRequest(req BedrockChatRequest) (*BedrockChatRe
 	if req.MaxTokens == 0 {
 		req.MaxTokens = s.config.BedrockMaxTokens
 	}
+	if req.Model == "" {
+		req.Model = s.config.BedrockModelID
+	}
 
 	// Build Claude request
+	thinking, maxTokens, temperature := claudeThinking(req.ThinkingBudget, req.MaxTokens, req.Temperature, req.ToolChoice)
 	claudeReq := ClaudeRequest{
 		AnthropicVersion: "bedrock-2023-05-31",
-		MaxTokens:        req.MaxTokens,
+		MaxTokens:        maxTokens,
 		Messages:         req.Messages,
-		Temperature:      req.Temperature,
+		Temperature:      temperature,
+		Tools:            req.Tools,
+		ToolChoice:       req.ToolChoice,
+		Thinking:         thinking,
 	}
 
 	// Add system prompt if provided
-	if req.SystemPrompt != "" {
+	if req.SystemPrompt != nil && req.SystemPrompt != "" {
 		claudeReq.SystemPrompt = req.SystemPrompt
 	}
 
@@ -149,11 +255,11 @@ func (s *BedrockService) SendChatRequest(req BedrockChatRequest) (*BedrockChatRe
 	}
 
 	log.Printf("🔵 Bedrock Request: model=%s, messages=%d, max_tokens=%d",
-		s.config.BedrockModelID, len(req.Messages), req.MaxTokens)
+		req.Model, len(req.Messages), req.MaxTokens)
 
 	// Call Bedrock API
-	output, err := s.client.InvokeModel(context.Background(), &bedrockruntime.InvokeModelInput{
-		ModelId:     aws.String(s.config.BedrockModelID),
+	output, err := s.client.InvokeModel(ctx, &bedrockruntime.InvokeModelInput{
+		ModelId:     aws.String(req.Model),
 		ContentType: aws.String("application/json"),
 		Accept:      aws.String("application/json"),
 		Body:        requestBody,
@@ -169,72 +275,189 @@ func (s *BedrockService) SendChatRequest(req BedrockChatRequest) (*BedrockChatRe
 		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
 
-	// Extract text from content
-	var responseText string
-	if len(claudeResp.Content) > 0 {
-		responseText = claudeResp.Content[0].Text
+	// Extract text, tool calls and reasoning from content blocks
+	var responseText strings.Builder
+	toolCalls := make([]ToolCall, 0)
+	var reasoning []ReasoningBlock
+	for _, block := range claudeResp.Content {
+		if thought, ok := claudeReasoning(block); ok {
+			reasoning = append(reasoning, thought)
+			continue
+		}
+		switch block.Type {
+		case "text":
+			responseText.WriteString(block.Text)
+		case "tool_use":
+			toolCalls = append(toolCalls, ToolCall{
+				ID:        block.ID,
+				Name:      block.Name,
+				Arguments: string(block.Input),
+			})
+		}
 	}
 
-	totalTokens := claudeResp.Usage.InputTokens + claudeResp.Usage.OutputTokens
+	usage := TokenUsage{
+		PromptTokens:     claudeResp.Usage.promptTokens(),
+		CompletionTokens: claudeResp.Usage.OutputTokens,
+		CachedTokens:     claudeResp.Usage.CacheReadInputTokens,
+		CacheWriteTokens: claudeResp.Usage.CacheCreationInputTokens,
+	}
+	totalTokens := usage.Total()
 
-	log.Printf("✓ Bedrock Response: tokens=%d (in=%d, out=%d), stop_reason=%s",
-		totalTokens, claudeResp.Usage.InputTokens, claudeResp.Usage.OutputTokens,
+	log.Printf("✓ Bedrock Response: tokens=%d (in=%d, out=%d, cached=%d, cache_write=%d), stop_reason=%s",
+		totalTokens, usage.PromptTokens, usage.CompletionTokens, usage.CachedTokens, usage.CacheWriteTokens,
 		claudeResp.StopReason)
 
 	return &BedrockChatResponse{
-		Content:    responseText,
+		Content:    responseText.String(),
 		TokensUsed: totalTokens,
-		Model:      s.config.BedrockModelID,
+		Usage:      usage,
+		Model:      req.Model,
 		StopReason: claudeResp.StopReason,
+		ToolCalls:  toolCalls,
+		Reasoning:  reasoning,
 	}, nil
 }
 
-// BuildMessages builds message array from user input (simple version)
-func (s *BedrockService) BuildMessages(userMessage string, systemPrompt string) []ClaudeMessage {
-	return []ClaudeMessage{
-		{
-			Role:    "user",
-			Content: userMessage,
-		},
-	}
-}
+// toClaudeMessages translates a provider-neutral conversation to Claude messages
+// Claude takes the system prompt separately, requires alternating user/assistant turns
+// and the first turn must be from the user, so the conversation is normalized here
+func toClaudeMessages(systemPrompt string, messages []ConversationMessage) (string, []ClaudeMessage) {
+	claudeMessages := make([]ClaudeMessage, 0, len(messages))
+
+	for _, msg := range messages {
+		var role string
+		var blocks []ClaudeContentBlock
+
+		switch msg.Role {
+		case ConversationRoleSystem:
+			// System messages are folded into the system prompt
+			if text := msg.Text(); text != "" {
+				systemPrompt = strings.TrimSpace(systemPrompt + "\n\n" + text)
+			}
+			continue
+
+		case ConversationRoleTool:
+			role = "user"
+			blocks = append(blocks, ClaudeContentBlock{
+				Type:      "tool_result",
+				ToolUseID: msg.ToolCallID,
+				Content:   msg.Text(),
+			})
+
+		case ConversationRoleAssistant:
+			role = "assistant"
+			// Thinking must come first and unchanged; blocks without a signature cannot be sent back
+			for _, thought := range msg.Reasoning {
+				if thought.Redacted != "" {
+					blocks = append(blocks, ClaudeContentBlock{Type: "redacted_thinking", Data: thought.Redacted})
+				} else if thought.Signature != "" {
+					blocks = append(blocks, ClaudeContentBlock{Type: "thinking", Thinking: thought.Text, Signature: thought.Signature})
+				}
+			}
+			if text := msg.Text(); text != "" {
+				blocks = append(blocks, ClaudeContentBlock{Type: "text", Text: text})
+			}
+			for _, call := range msg.ToolCalls {
+				input := json.RawMessage(call.Arguments)
+				if len(input) == 0 {
+					input = json.RawMessage("{}")
+				}
+				blocks = append(blocks, ClaudeContentBlock{
+					Type:  "tool_use",
+					ID:    call.ID,
+					Name:  call.Name,
+					Input: input,
+				})
+			}
 
-// BuildMessagesWithHistory builds message array with conversation history
-func (s *BedrockService) BuildMessagesWithHistory(
-	userMessage string,
-	history []ClaudeMessage,
-) []ClaudeMessage {
-	messages := make([]ClaudeMessage, 0, len(history)+1)
+		default:
+			role = "user"
+			text := msg.Text()
+			if fileParts := msg.PartsOfType(ContentPartFile); len(fileParts) > 0 {
+				text = FormatFileContext(fileParts) + "\n\n--- User Question ---\n" + text
+			}
+			if text != "" {
+				blocks = append(blocks, ClaudeContentBlock{Type: "text", Text: text})
+			}
+			for _, image := range msg.PartsOfType(ContentPartImage) {
+				blocks = append(blocks, ClaudeContentBlock{
+					Type: "image",
+					Source: &ClaudeImageSource{
+						Type:      "base64",
+						MediaType: image.MimeType,
+						Data:      base64.StdEncoding.EncodeToString(image.Data),
+					},
+				})
+			}
+		}
+
+		// Skip messages with empty content
+		if len(blocks) == 0 {
+			continue
+		}
 
-	// Add conversation history
-	messages = append(messages, history...)
+		// If same role as last message, merge content
+		if last := len(claudeMessages) - 1; last >= 0 && claudeMessages[last].Role == role {
+			existing := claudeMessages[last].Content.([]ClaudeContentBlock)
+			claudeMessages[last].Content = append(existing, blocks...)
+			continue
+		}
 
-	// Add current user message
-	messages = append(messages, ClaudeMessage{
-		Role:    "user",
-		Content: userMessage,
-	})
+		claudeMessages = append(claudeMessages, ClaudeMessage{
+			Role:    role,
+			Content: blocks,
+		})
+	}
 
-	return messages
+	// Must start with user message
+	if len(claudeMessages) > 0 && claudeMessages[0].Role != "user" {
+		log.Printf("⚠️  First message is not 'user', prepending placeholder user message")
+		claudeMessages = append([]ClaudeMessage{{
+			Role:    "user",
+			Content: []ClaudeContentBlock{{Type: "text", Text: "[conversation started]"}},
+		}}, claudeMessages...)
+	}
+
+	return systemPrompt, claudeMessages
 }
 
-// BuildMessagesWithContext builds message array with file context
-func (s *BedrockService) BuildMessagesWithContext(
-	userMessage string,
-	fileContext string,
-) []ClaudeMessage {
-	// If there's file context, prepend it to the user message
-	fullMessage := userMessage
+// claudeCacheBreakpoint is the cache_control Claude accepts
+var claudeCacheBreakpoint = &ClaudeCacheControl{Type: "ephemeral"}
+
+// claudePrompt translates a request's conversation to the Claude system prompt and messages
+// With prompt caching, the file context moves into the system prompt and cache breakpoints are
+// set on the system prompt, the file context and the last user turn (Claude allows four), so
+// the persona prompt, the documents and the conversation so far are read from the cache next time
+func claudePrompt(req StreamingChatRequest) (interface{}, []ClaudeMessage) {
+	if !req.PromptCaching {
+		systemPrompt, messages := toClaudeMessages(req.SystemPrompt, req.Messages)
+		if systemPrompt == "" {
+			return nil, messages
+		}
+		return systemPrompt, messages
+	}
+
+	fileContext, conversation := HoistFileContext(req.Messages)
+	systemPrompt, messages := toClaudeMessages(req.SystemPrompt, conversation)
+
+	system := make([]ClaudeContentBlock, 0, 2)
+	if systemPrompt != "" {
+		system = append(system, ClaudeContentBlock{Type: "text", Text: systemPrompt, CacheControl: claudeCacheBreakpoint})
+	}
 	if fileContext != "" {
-		fullMessage = fileContext + "\n\n" + userMessage
+		system = append(system, ClaudeContentBlock{Type: "text", Text: fileContext, CacheControl: claudeCacheBreakpoint})
 	}
 
-	return []ClaudeMessage{
-		{
-			Role:    "user",
-			Content: fullMessage,
-		},
+	if last := len(messages) - 1; last >= 0 && messages[last].Role == "user" {
+		blocks := messages[last].Content.([]ClaudeContentBlock)
+		blocks[len(blocks)-1].CacheControl = claudeCacheBreakpoint
+	}
+
+	if len(system) == 0 {
+		return nil, messages
 	}
+	return system, messages
 }
 
 // ========================================
@@ -246,18 +469,23 @@ type BedrockStreamReader struct {
 	stream *bedrockruntime.InvokeModelWithResponseStreamEventStream
 	ctx    context.Context
 	closed bool
+	usage  *TokenUsage // Filled from message_start / message_delta / message_stop events
+
+	toolCalls     []ToolCall  // Assembled from tool_use content blocks
+	toolCallIndex map[int]int // Content block index → position in toolCalls
+
+	reasoning        []ReasoningBlock // Assembled from thinking content blocks
+	reasoningIndex   map[int]int      // Content block index → position in reasoning
+	pendingReasoning strings.Builder  // Thinking text not yet taken by TakeReasoning
+
+	structuredTool  string // Forced tool carrying the structured output (ResponseSchema)
+	structuredIndex int    // Content block of the structured tool (-1 until it starts)
 }
 
 // CreateStreamingChat implements StreamingChatService interface
 func (s *BedrockService) CreateStreamingChat(ctx context.Context, req StreamingChatRequest) (StreamReader, error) {
 	// Convert messages to Claude format
-	var messages []ClaudeMessage
-	switch v := req.Messages.(type) {
-	case []ClaudeMessage:
-		messages = v
-	default:
-		return nil, fmt.Errorf("unsupported message type for Bedrock")
-	}
+	systemPrompt, messages := claudePrompt(req)
 
 	// Use defaults if not specified
 	temperature := req.Temperature
@@ -276,12 +504,17 @@ func (s *BedrockService) CreateStreamingChat(ctx context.Context, req StreamingC
 	}
 
 	// Build Claude request
+	tools, toolChoice := claudeResponseSchemaTool(claudeTools(req.Tools), req.ResponseSchema)
+	thinking, maxTokens, temperature := claudeThinking(req.ThinkingBudget, maxTokens, temperature, toolChoice)
 	claudeReq := ClaudeRequest{
 		AnthropicVersion: "bedrock-2023-05-31",
 		MaxTokens:        maxTokens,
 		Messages:         messages,
 		Temperature:      temperature,
-		SystemPrompt:     req.SystemPrompt,
+		SystemPrompt:     systemPrompt,
+		Tools:            tools,
+		ToolChoice:       toolChoice,
+		Thinking:         thinking,
 	}
 
 	requestBody, err := json.Marshal(claudeReq)
@@ -307,11 +540,16 @@ func (s *BedrockService) CreateStreamingChat(ctx context.Context, req StreamingC
 		return nil, fmt.Errorf("failed to invoke streaming: %w", err)
 	}
 
-	return &BedrockStreamReader{
-		stream: output.GetStream(),
-		ctx:    ctx,
-		closed: false,
-	}, nil
+	reader := &BedrockStreamReader{
+		stream:          output.GetStream(),
+		ctx:             ctx,
+		closed:          false,
+		structuredIndex: -1,
+	}
+	if req.ResponseSchema != nil {
+		reader.structuredTool = req.ResponseSchema.Name
+	}
+	return reader, nil
 }
 
 // Recv receives the next chunk from the Bedrock stream
@@ -327,6 +565,10 @@ func (r *BedrockStreamReader) Recv() (string, error) {
 
 		case event, ok := <-r.stream.Events():
 			if !ok {
+				// Stream errors (e.g. throttling) are reported when the channel closes
+				if err := r.stream.Err(); err != nil {
+					return "", err
+				}
 				return "", io.EOF
 			}
 
@@ -337,9 +579,21 @@ func (r *BedrockStreamReader) Recv() (string, error) {
 					Type  string `json:"type"`
 					Index int    `json:"index"`
 					Delta struct {
-						Type string `json:"type"`
-						Text string `json:"text"`
+						Type        string `json:"type"`
+						Text        string `json:"text"`
+						PartialJSON string `json:"partial_json"` // input_json_delta
+						Thinking    string `json:"thinking"`     // thinking_delta
+						Signature   string `json:"signature"`    // signature_delta
 					} `json:"delta"`
+					ContentBlock ClaudeContentBlock `json:"content_block"` // content_block_start
+					Message      struct {
+						Usage claudeUsage `json:"usage"`
+					} `json:"message"` // message_start
+					Usage   claudeUsage `json:"usage"` // message_delta
+					Metrics *struct {
+						InputTokenCount  int `json:"inputTokenCount"`
+						OutputTokenCount int `json:"outputTokenCount"`
+					} `json:"amazon-bedrock-invocationMetrics"` // message_stop
 				}
 
 				if err := json.Unmarshal(v.Value.Bytes, &chunkData); err != nil {
@@ -347,11 +601,36 @@ func (r *BedrockStreamReader) Recv() (string, error) {
 					continue
 				}
 
+				r.recordUsage(chunkData.Type, chunkData.Message.Usage, chunkData.Usage)
+				structuredJSON := r.recordToolUse(chunkData.Type, chunkData.Index, chunkData.ContentBlock, chunkData.Delta.PartialJSON)
+				thinking := r.recordThinking(chunkData.Type, chunkData.Index, chunkData.ContentBlock, chunkData.Delta.Thinking, chunkData.Delta.Signature)
+				if metrics := chunkData.Metrics; metrics != nil {
+					// Bedrock invocation metrics are the fallback when Claude usage events are missing
+					if r.usage == nil {
+						r.usage = &TokenUsage{
+							PromptTokens:     metrics.InputTokenCount,
+							CompletionTokens: metrics.OutputTokenCount,
+						}
+					} else if r.usage.CompletionTokens == 0 {
+						r.usage.CompletionTokens = metrics.OutputTokenCount
+					}
+				}
+
 				// Return the text content if available
 				if chunkData.Delta.Text != "" {
 					return chunkData.Delta.Text, nil
 				}
 
+				// Structured output streams as the forced tool's input JSON
+				if structuredJSON != "" {
+					return structuredJSON, nil
+				}
+
+				// Reasoning is taken separately (TakeReasoning)
+				if thinking {
+					return "", nil
+				}
+
 				// If no text, continue to next event
 				continue
 
@@ -364,12 +643,197 @@ func (r *BedrockStreamReader) Recv() (string, error) {
 	}
 }
 
+// recordUsage updates usage from message_start (prompt tokens) and message_delta (output tokens)
+func (r *BedrockStreamReader) recordUsage(eventType string, startUsage, deltaUsage claudeUsage) {
+	switch eventType {
+	case "message_start":
+		r.usage = &TokenUsage{
+			PromptTokens:     startUsage.promptTokens(),
+			CompletionTokens: startUsage.OutputTokens,
+			CachedTokens:     startUsage.CacheReadInputTokens,
+			CacheWriteTokens: startUsage.CacheCreationInputTokens,
+		}
+	case "message_delta":
+		if r.usage == nil {
+			r.usage = &TokenUsage{}
+		}
+		if deltaUsage.OutputTokens > 0 {
+			r.usage.CompletionTokens = deltaUsage.OutputTokens
+		}
+	}
+}
+
+// recordToolUse starts a tool call on content_block_start (tool_use) and appends its input_json_delta fragments
+// Fragments of the structured output tool are returned instead, to be streamed as content
+func (r *BedrockStreamReader) recordToolUse(eventType string, index int, block ClaudeContentBlock, partialJSON string) string {
+	switch eventType {
+	case "content_block_start":
+		if block.Type != "tool_use" {
+			return ""
+		}
+		if r.structuredTool != "" && block.Name == r.structuredTool {
+			r.structuredIndex = index
+			return ""
+		}
+		if r.toolCallIndex == nil {
+			r.toolCallIndex = make(map[int]int)
+		}
+		r.toolCallIndex[index] = len(r.toolCalls)
+		r.toolCalls = append(r.toolCalls, ToolCall{ID: block.ID, Name: block.Name})
+	case "content_block_delta":
+		if index == r.structuredIndex {
+			return partialJSON
+		}
+		if position, ok := r.toolCallIndex[index]; ok {
+			r.toolCalls[position].Arguments += partialJSON
+		}
+	}
+	return ""
+}
+
+// recordThinking collects thinking and redacted_thinking blocks, reporting whether new thinking text arrived
+func (r *BedrockStreamReader) recordThinking(eventType string, index int, block ClaudeContentBlock, thinking, signature string) bool {
+	switch eventType {
+	case "content_block_start":
+		thought, ok := claudeReasoning(block)
+		if !ok {
+			return false
+		}
+		if r.reasoningIndex == nil {
+			r.reasoningIndex = make(map[int]int)
+		}
+		r.reasoningIndex[index] = len(r.reasoning)
+		r.reasoning = append(r.reasoning, thought)
+	case "content_block_delta":
+		position, ok := r.reasoningIndex[index]
+		if !ok {
+			return false
+		}
+		r.reasoning[position].Signature += signature
+		if thinking != "" {
+			r.reasoning[position].Text += thinking
+			r.pendingReasoning.WriteString(thinking)
+			return true
+		}
+	}
+	return false
+}
+
+// TakeReasoning returns the thinking text received since the last call
+func (r *BedrockStreamReader) TakeReasoning() string {
+	text := r.pendingReasoning.String()
+	r.pendingReasoning.Reset()
+	return text
+}
+
+// Reasoning returns the thinking blocks of the stream
+func (r *BedrockStreamReader) Reasoning() []ReasoningBlock {
+	return r.reasoning
+}
+
+// ToolCalls returns the tool calls requested in the stream
+func (r *BedrockStreamReader) ToolCalls() []ToolCall {
+	return r.toolCalls
+}
+
+// Usage returns the token usage reported by the stream events
+func (r *BedrockStreamReader) Usage() *TokenUsage {
+	return r.usage
+}
+
 // Close closes the Bedrock stream
 func (r *BedrockStreamReader) Close() error {
 	r.closed = true
 	return nil
 }
 
+// CompleteChat implements ChatCompleter using the non-streaming InvokeModel API
+func (s *BedrockService) CompleteChat(ctx context.Context, req StreamingChatRequest) (*ChatCompletion, error) {
+	systemPrompt, messages := claudePrompt(req)
+
+	tools, toolChoice := claudeResponseSchemaTool(claudeTools(req.Tools), req.ResponseSchema)
+	resp, err := s.sendChatRequest(ctx, BedrockChatRequest{
+		Messages:       messages,
+		SystemPrompt:   systemPrompt,
+		Temperature:    req.Temperature,
+		MaxTokens:      req.MaxTokens,
+		Model:          req.Model,
+		Tools:          tools,
+		ToolChoice:     toolChoice,
+		ThinkingBudget: req.ThinkingBudget,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	completion := &ChatCompletion{
+		Content:      resp.Content,
+		TokensUsed:   resp.TokensUsed,
+		Usage:        &resp.Usage,
+		Model:        resp.Model,
+		FinishReason: resp.StopReason,
+		ToolCalls:    resp.ToolCalls,
+		Reasoning:    resp.Reasoning,
+	}
+
+	// The structured output arrives as the input of the forced tool
+	if req.ResponseSchema != nil {
+		completion.ToolCalls = nil
+		for _, call := range resp.ToolCalls {
+			if call.Name == req.ResponseSchema.Name {
+				completion.Content = call.Arguments
+				continue
+			}
+			completion.ToolCalls = append(completion.ToolCalls, call)
+		}
+	}
+
+	return completion, nil
+}
+
+// claudeResponseSchemaTool adds a tool whose input is the response schema and forces Claude to call it
+func claudeResponseSchemaTool(tools []ClaudeTool, schema *ResponseSchema) ([]ClaudeTool, *ClaudeToolChoice) {
+	if schema == nil {
+		return tools, nil
+	}
+	description := schema.Description
+	if description == "" {
+		description = "Return the response in this structure."
+	}
+	tools = append(tools, ClaudeTool{
+		Name:        schema.Name,
+		Description: description,
+		InputSchema: schema.Schema,
+	})
+	return tools, &ClaudeToolChoice{Type: "tool", Name: schema.Name}
+}
+
+// claudeTools translates tool definitions to Claude tools
+func claudeTools(definitions []ToolDefinition) []ClaudeTool {
+	if len(definitions) == 0 {
+		return nil
+	}
+	tools := make([]ClaudeTool, 0, len(definitions))
+	for _, definition := range definitions {
+		tools = append(tools, ClaudeTool{
+			Name:        definition.Name,
+			Description: definition.Description,
+			InputSchema: definition.Parameters,
+		})
+	}
+	return tools
+}
+
+// DefaultModel returns the configured Bedrock model ID
+func (s *BedrockService) DefaultModel() string {
+	return s.config.BedrockModelID
+}
+
+// AllowedModels returns nil (any Bedrock model ID is accepted)
+func (s *BedrockService) AllowedModels() []string {
+	return nil
+}
+
 // GetProviderName returns "bedrock"
 func (s *BedrockService) GetProviderName() string {
 	return "bedrock"
@@ -0,0 +1,217 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"chatbot/config"
+)
+
+// ========================================
+// Context budget
+// ========================================
+// fitContext makes a request fit the context window of its model. Space for
+// the reply (MaxTokens) is reserved first, then the system prompt, tools and
+// the current user question are counted; they are never trimmed. Whatever
+// does not fit is trimmed by priority, least important first:
+//
+//   1. history, oldest message first
+//   2. file excerpts, largest first, cut to a common size
+//   3. system additions (the rolling summary, knowledge excerpts), dropped last
+//
+// A request whose required parts alone exceed the window is rejected.
+
+// Context budget limits
+const (
+	messageFramingTokens = 4   // Role and separators of each message
+	minFileExcerptTokens = 200 // Smallest excerpt kept of a trimmed file
+)
+
+// fileTruncatedNote ends a file excerpt cut to fit the context window
+const fileTruncatedNote = "\n\n[... file truncated to fit the model's context window]"
+
+// contextFit reports what fitContext trimmed
+type contextFit struct {
+	Budget          int // Prompt tokens available after the output reserve
+	Tokens          int // Prompt tokens after trimming
+	DroppedMessages int // History messages left out
+	TruncatedFiles  int // File excerpts shortened
+	DroppedSystem   int // System additions left out
+}
+
+// fitContext trims a request's messages to fit the model's context window (see above)
+func fitContext(req StreamingChatRequest, entry config.CatalogModel, tokenizer Tokenizer) ([]ConversationMessage, contextFit, error) {
+	reserve := req.MaxTokens
+	if reserve == 0 {
+		reserve = min(defaultOutputReserve, entry.MaxOutputTokens)
+	}
+	fit := contextFit{Budget: entry.ContextWindow - reserve}
+
+	fixed := tokenizer.CountTokens(req.SystemPrompt) + toolTokens(tokenizer, req)
+	if fixed >= fit.Budget {
+		return req.Messages, fit, fmt.Errorf("system prompt and tools need %d tokens, more than the %d-token context window of %s allows", fixed, entry.ContextWindow, entry.ID)
+	}
+
+	messages := append([]ConversationMessage(nil), req.Messages...)
+	counts := make([]int, len(messages))
+	tokens := fixed
+	for i, message := range messages {
+		counts[i] = countMessageTokens(tokenizer, message)
+		tokens += counts[i]
+	}
+	if tokens <= fit.Budget || len(messages) == 0 {
+		fit.Tokens = tokens
+		return req.Messages, fit, nil
+	}
+
+	current := len(messages) - 1
+	dropped := make([]bool, len(messages))
+
+	// 1. History, oldest first
+	for i := 0; i < current && tokens > fit.Budget; i++ {
+		if messages[i].Role == ConversationRoleSystem {
+			continue
+		}
+		dropped[i] = true
+		tokens -= counts[i]
+		fit.DroppedMessages++
+	}
+
+	// 2. File excerpts of the remaining messages
+	if tokens > fit.Budget {
+		tokens -= truncateFileParts(tokenizer, messages, dropped, tokens-fit.Budget, &fit)
+	}
+
+	// 3. System additions
+	for i := 0; i < current && tokens > fit.Budget; i++ {
+		if dropped[i] || messages[i].Role != ConversationRoleSystem {
+			continue
+		}
+		dropped[i] = true
+		tokens -= counts[i]
+		fit.DroppedSystem++
+	}
+
+	fit.Tokens = tokens
+	if tokens > fit.Budget {
+		return req.Messages, fit, fmt.Errorf("message needs %d tokens, more than the %d-token context window of %s allows (with %d reserved for the reply)", tokens, entry.ContextWindow, entry.ID, reserve)
+	}
+
+	kept := make([]ConversationMessage, 0, len(messages))
+	for i, message := range messages {
+		if !dropped[i] {
+			kept = append(kept, message)
+		}
+	}
+	log.Printf("✂️  Fit %s context window (%d tokens): dropped %d messages, truncated %d files, dropped %d system additions",
+		entry.ID, fit.Budget, fit.DroppedMessages, fit.TruncatedFiles, fit.DroppedSystem)
+	return NormalizeToolMessages(kept), fit, nil
+}
+
+// truncateFileParts cuts the file excerpts of the kept messages by at least excess tokens
+// All excerpts larger than a common cap are cut to it, so the largest files shrink first
+// Returns the tokens saved; messages are updated in place (parts are copied, not shared)
+func truncateFileParts(tokenizer Tokenizer, messages []ConversationMessage, dropped []bool, excess int, fit *contextFit) int {
+	type excerpt struct {
+		message, part int
+		tokens        int
+	}
+	var excerpts []excerpt
+	total := 0
+	for i, message := range messages {
+		if dropped[i] {
+			continue
+		}
+		for j, part := range message.Parts {
+			if part.Type == ContentPartFile && part.Text != "" {
+				tokens := tokenizer.CountTokens(part.Text)
+				excerpts = append(excerpts, excerpt{i, j, tokens})
+				total += tokens
+			}
+		}
+	}
+	if len(excerpts) == 0 {
+		return 0
+	}
+
+	// Find the largest cap that saves enough tokens (never below the minimum excerpt)
+	target := total - excess
+	limit := minFileExcerptTokens
+	for low, high := minFileExcerptTokens, total; low <= high; {
+		mid := (low + high) / 2
+		sum := 0
+		for _, e := range excerpts {
+			sum += min(e.tokens, mid)
+		}
+		if sum <= target {
+			limit = mid
+			low = mid + 1
+		} else {
+			high = mid - 1
+		}
+	}
+
+	saved := 0
+	for _, e := range excerpts {
+		if e.tokens <= limit {
+			continue
+		}
+		message := &messages[e.message]
+		parts := append([]ContentPart(nil), message.Parts...)
+		text := truncateToTokens(tokenizer, parts[e.part].Text, limit)
+		parts[e.part].Text = text
+		message.Parts = parts
+
+		saved += e.tokens - tokenizer.CountTokens(text)
+		fit.TruncatedFiles++
+	}
+	return saved
+}
+
+// truncateToTokens keeps the start of text within limit tokens (including the truncation note)
+func truncateToTokens(tokenizer Tokenizer, text string, limit int) string {
+	runes := []rune(text)
+	keep := len(runes) * limit / max(tokenizer.CountTokens(text), 1)
+	for keep > 0 {
+		cut := strings.TrimSpace(string(runes[:keep])) + fileTruncatedNote
+		if tokenizer.CountTokens(cut) <= limit {
+			return cut
+		}
+		keep = keep * 9 / 10
+	}
+	return strings.TrimSpace(fileTruncatedNote)
+}
+
+// countMessageTokens counts a message's prompt tokens including images and tool calls
+func countMessageTokens(tokenizer Tokenizer, message ConversationMessage) int {
+	tokens := messageFramingTokens
+	for _, part := range message.Parts {
+		if part.Type == ContentPartImage {
+			tokens += imageTokenEstimate
+			continue
+		}
+		tokens += tokenizer.CountTokens(part.Text)
+		if part.Type == ContentPartFile {
+			tokens += tokenizer.CountTokens(part.FileName) + messageFramingTokens
+		}
+	}
+	for _, call := range message.ToolCalls {
+		tokens += tokenizer.CountTokens(call.Name) + tokenizer.CountTokens(call.Arguments)
+	}
+	return tokens
+}
+
+// toolTokens counts the tool definitions and response schema sent with a request
+func toolTokens(tokenizer Tokenizer, req StreamingChatRequest) int {
+	tokens := 0
+	for _, tool := range req.Tools {
+		tokens += tokenizer.CountTokens(tool.Name) + tokenizer.CountTokens(tool.Description) + tokenizer.CountTokens(string(tool.Parameters))
+	}
+	if req.ResponseSchema != nil {
+		schema, _ := json.Marshal(req.ResponseSchema)
+		tokens += tokenizer.CountTokens(string(schema))
+	}
+	return tokens
+}
@@ -3,366 +3,407 @@ package services
 import (
 	"chatbot/models"
 	"chatbot/repositories"
-	"encoding/base64"
+	"errors"
 	"fmt"
+	"log"
 	"os"
 	"strings"
 
-	"github.com/ledongthuc/pdf"
-	"github.com/nguyenthenguyen/docx"
-	"github.com/sashabaranov/go-openai"
+	"github.com/google/uuid"
 )
 
+// DefaultHistoryLimit is the most recent messages sent with each turn; how many of them fit is
+// decided by the model's context window (see fitContext), and older messages of long sessions
+// reach the model through their summary (see Summarizer)
+const DefaultHistoryLimit = 50
+
 // ContextService handles conversation context management
 type ContextService struct {
 	messageRepo      *repositories.MessageRepository
 	fileAnalysisRepo *repositories.FileAnalysisRepository
+	extraction       *FileExtractionService
 }
 
+// pendingFileText stands in for the content of a file whose text is still being extracted
+const pendingFileText = "(This file is still being processed and its content is not available yet. Tell the user to ask again in a moment.)"
+
 // NewContextService creates a new context service
 func NewContextService(
 	messageRepo *repositories.MessageRepository,
 	fileAnalysisRepo *repositories.FileAnalysisRepository,
+	extraction *FileExtractionService,
 ) *ContextService {
 	return &ContextService{
 		messageRepo:      messageRepo,
 		fileAnalysisRepo: fileAnalysisRepo,
+		extraction:       extraction,
 	}
 }
 
-// BuildContextWithHistory builds OpenAI messages array with conversation history
-func (s *ContextService) BuildContextWithHistory(
-	sessionID string,
-	systemPrompt string,
-	currentMessage string,
-	historyLimit int,
-) ([]openai.ChatCompletionMessage, error) {
-
-	messages := []openai.ChatCompletionMessage{}
+// SessionTree loads the message tree of a session
+func (s *ContextService) SessionTree(sessionID string) (*MessageTree, error) {
+	messages, err := s.messageRepo.GetAllBySession(sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load session: %w", err)
+	}
+	return NewMessageTree(messages), nil
+}
 
-	// 1. Add system prompt if provided
-	if systemPrompt != "" {
-		messages = append(messages, openai.ChatCompletionMessage{
-			Role:    openai.ChatMessageRoleSystem,
-			Content: systemPrompt,
-		})
+// ActiveParent returns the message a new user message follows: parentID when given (it must
+// belong to the session), otherwise the latest message of the session; nil starts the session
+func (s *ContextService) ActiveParent(sessionID, parentID string) (*uuid.UUID, error) {
+	if sessionID == "" {
+		return nil, nil
+	}
+	tree, err := s.SessionTree(sessionID)
+	if err != nil {
+		return nil, err
 	}
 
-	// 2. Add conversation history
-	if sessionID != "" && historyLimit > 0 {
-		history, err := s.messageRepo.GetRecentBySession(sessionID, historyLimit)
-		if err == nil && len(history) > 0 {
-			for _, msg := range history {
-				role := openai.ChatMessageRoleUser
-				if msg.Role == models.RoleAssistant {
-					role = openai.ChatMessageRoleAssistant
-				}
-
-				messages = append(messages, openai.ChatCompletionMessage{
-					Role:    role,
-					Content: msg.Content,
-				})
-			}
+	if parentID == "" {
+		if latest := tree.Latest(); latest != nil {
+			return &latest.ID, nil
 		}
+		return nil, nil
 	}
 
-	// 3. Add current user message
-	messages = append(messages, openai.ChatCompletionMessage{
-		Role:    openai.ChatMessageRoleUser,
-		Content: currentMessage,
-	})
-
-	return messages, nil
+	id, err := uuid.Parse(parentID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid parent_id: %s", parentID)
+	}
+	if _, ok := tree.Get(id); !ok {
+		return nil, fmt.Errorf("parent_id %s is not a message of session %s", parentID, sessionID)
+	}
+	return &id, nil
 }
 
-// BuildContextWithHistoryAndFiles builds OpenAI messages with file support (including images)
-func (s *ContextService) BuildContextWithHistoryAndFiles(
+// BuildConversation builds a provider-neutral conversation with history and current-turn files
+// History is the branch ending at parentID (the message the current one follows, see ActiveParent),
+// so edited and regenerated alternatives on other branches are left out
+// When the branch has a rolling summary, history is the summary followed by the messages after it
+// Text files are attached as file parts and images as image parts of the current user message
+func (s *ContextService) BuildConversation(
 	sessionID string,
+	parentID *uuid.UUID,
 	systemPrompt string,
 	currentMessage string,
 	historyLimit int,
 	fileIDs []string,
-) ([]openai.ChatCompletionMessage, error) {
+) (*Conversation, error) {
+	conversation := &Conversation{
+		SystemPrompt: systemPrompt,
+		Messages:     []ConversationMessage{},
+	}
 
-	messages := []openai.ChatCompletionMessage{}
+	// 1. Add conversation history
+	if sessionID != "" && parentID != nil && historyLimit > 0 {
+		tree, err := s.SessionTree(sessionID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load history: %w", err)
+		}
+		summary, history := s.summarizedPath(sessionID, tree.Path(*parentID))
+		if len(history) > historyLimit {
+			history = history[len(history)-historyLimit:]
+		}
+		if summary != nil {
+			conversation.Messages = append(conversation.Messages, SummaryMessage(summary))
+		}
+		for _, msg := range history {
+			historyMessage := s.historyToConversationMessage(msg)
+			if historyMessage.IsEmpty() {
+				continue
+			}
+			conversation.Messages = append(conversation.Messages, historyMessage)
+		}
+		conversation.Messages = NormalizeToolMessages(conversation.Messages)
+		conversation.HistoryCount = len(conversation.Messages)
+		if summary != nil {
+			conversation.HistoryCount--
+		}
+	}
 
-	// 1. Add system prompt if provided
-	if systemPrompt != "" {
-		messages = append(messages, openai.ChatCompletionMessage{
-			Role:    openai.ChatMessageRoleSystem,
-			Content: systemPrompt,
-		})
+	// 2. Add current user message with file and image parts
+	current := NewTextMessage(ConversationRoleUser, currentMessage)
+	current.Parts = append(current.Parts, s.LoadFileParts(fileIDs)...)
+	conversation.Messages = append(conversation.Messages, current)
+
+	return conversation, nil
+}
+
+// summarizedPath splits a branch path into its latest summary and the messages after it
+func (s *ContextService) summarizedPath(sessionID string, path []models.Message) (*models.SessionSummary, []models.Message) {
+	summaries, err := s.messageRepo.FindSummariesBySession(sessionID)
+	if err != nil {
+		log.Printf("⚠️  Failed to load summaries of session %s: %v", sessionID, err)
+		return nil, path
+	}
+	return SummaryOnPath(path, summaries)
+}
+
+// historyToConversationMessage converts a stored message to a conversation message
+// Images attached to earlier user turns are replayed so multimodal history works on every provider
+func (s *ContextService) historyToConversationMessage(msg models.Message) ConversationMessage {
+	role := ConversationRoleUser
+	switch msg.Role {
+	case models.RoleAssistant:
+		role = ConversationRoleAssistant
+	case models.RoleSystem:
+		role = ConversationRoleSystem
+	case models.RoleTool:
+		role = ConversationRoleTool
 	}
 
-	// 2. Add conversation history
-	if sessionID != "" && historyLimit > 0 {
-		history, err := s.messageRepo.GetRecentBySession(sessionID, historyLimit)
-		if err == nil && len(history) > 0 {
-			for _, msg := range history {
-				role := openai.ChatMessageRoleUser
-				if msg.Role == models.RoleAssistant {
-					role = openai.ChatMessageRoleAssistant
-				}
-
-				messages = append(messages, openai.ChatCompletionMessage{
-					Role:    role,
-					Content: msg.Content,
-				})
+	message := NewTextMessage(role, msg.Content)
+	switch role {
+	case ConversationRoleTool:
+		message.ToolCallID = msg.ToolCallID
+		return message
+	case ConversationRoleAssistant:
+		if calls, err := msg.GetToolCalls(); err == nil {
+			for _, call := range calls {
+				message.ToolCalls = append(message.ToolCalls, ToolCall{ID: call.ID, Name: call.Name, Arguments: call.Arguments})
 			}
 		}
+		return message
+	case ConversationRoleSystem:
+		return message
 	}
 
-	// 3. Add file context as system message if files exist
-	if len(fileIDs) > 0 {
-		fileContext, err := s.BuildFileContext(fileIDs)
-		if err == nil && fileContext != "" {
-			messages = append(messages, openai.ChatCompletionMessage{
-				Role:    openai.ChatMessageRoleSystem,
-				Content: fileContext,
-			})
+	attachments, err := msg.GetFileAttachments()
+	if err != nil {
+		return message
+	}
+	for _, attachment := range attachments {
+		if !IsImageMimeType(attachment.FileType) {
+			continue
+		}
+		fileRecord, err := s.fileAnalysisRepo.FindByID(attachment.FileID)
+		if err != nil {
+			continue
+		}
+		if part, err := loadImagePart(fileRecord); err == nil {
+			message.Parts = append(message.Parts, part)
 		}
 	}
 
-	// 4. Add current user message with image support
-	if len(fileIDs) > 0 && s.hasImageFiles(fileIDs) {
-		// Build multipart message with text and images
-		multiContent := []openai.ChatMessagePart{
-			{
-				Type: openai.ChatMessagePartTypeText,
-				Text: currentMessage,
-			},
+	return message
+}
+
+// ToolStepRecords converts the tool-call turns and tool results of a tool loop to messages for storage
+func ToolStepRecords(steps []ConversationMessage, sessionID string, personaID *int) []*models.Message {
+	toolNames := make(map[string]string)
+	records := make([]*models.Message, 0, len(steps))
+
+	for _, step := range steps {
+		record := &models.Message{
+			SessionID: sessionID,
+			Content:   step.Text(),
+			PersonaID: personaID,
 		}
 
-		// Add images
-		for _, fileID := range fileIDs {
-			fileRecord, err := s.fileAnalysisRepo.FindByID(fileID)
-			if err != nil {
-				continue
+		if step.Role == ConversationRoleTool {
+			record.Role = models.RoleTool
+			record.ToolCallID = step.ToolCallID
+			record.ToolName = toolNames[step.ToolCallID]
+		} else {
+			record.Role = models.RoleAssistant
+			calls := make([]models.MessageToolCall, 0, len(step.ToolCalls))
+			for _, call := range step.ToolCalls {
+				toolNames[call.ID] = call.Name
+				calls = append(calls, models.MessageToolCall{ID: call.ID, Name: call.Name, Arguments: call.Arguments})
 			}
-
-			// Check if it's an image
-			if strings.HasPrefix(fileRecord.MimeType, "image/") {
-				// Read and encode image to base64
-				imageData, err := os.ReadFile(fileRecord.StoragePath)
-				if err != nil {
-					continue
-				}
-
-				base64Image := base64.StdEncoding.EncodeToString(imageData)
-				dataURL := fmt.Sprintf("data:%s;base64,%s", fileRecord.MimeType, base64Image)
-
-				multiContent = append(multiContent, openai.ChatMessagePart{
-					Type: openai.ChatMessagePartTypeImageURL,
-					ImageURL: &openai.ChatMessageImageURL{
-						URL:    dataURL,
-						Detail: openai.ImageURLDetailAuto,
-					},
-				})
+			if err := record.SetToolCalls(calls); err != nil {
+				log.Printf("⚠️  Failed to set tool calls: %v", err)
 			}
 		}
 
-		messages = append(messages, openai.ChatCompletionMessage{
-			Role:         openai.ChatMessageRoleUser,
-			MultiContent: multiContent,
-		})
-	} else {
-		// Simple text message
-		messages = append(messages, openai.ChatCompletionMessage{
-			Role:    openai.ChatMessageRoleUser,
-			Content: currentMessage,
-		})
+		records = append(records, record)
 	}
 
-	return messages, nil
+	return records
 }
 
-// hasImageFiles checks if any of the files are images
-func (s *ContextService) hasImageFiles(fileIDs []string) bool {
-	for _, fileID := range fileIDs {
-		fileRecord, err := s.fileAnalysisRepo.FindByID(fileID)
-		if err != nil {
-			continue
-		}
-		if strings.HasPrefix(fileRecord.MimeType, "image/") {
-			return true
-		}
+// ExecutedToolCalls lists the tool calls made in the steps of a tool loop
+func ExecutedToolCalls(steps []ConversationMessage) []ToolCall {
+	var calls []ToolCall
+	for _, step := range steps {
+		calls = append(calls, step.ToolCalls...)
 	}
-	return false
+	return calls
 }
 
-// BuildFileContext builds file context string from file IDs (for current message only)
-func (s *ContextService) BuildFileContext(fileIDs []string) (string, error) {
-	if len(fileIDs) == 0 {
-		return "", nil
-	}
-
-	var contextParts []string
-	contextParts = append(contextParts,
-		fmt.Sprintf("📎 The user has provided %d file(s) for you to analyze:", len(fileIDs)))
-	contextParts = append(contextParts, "")
+// LoadFileParts loads file IDs as content parts (image parts for images, file parts with extracted text otherwise)
+func (s *ContextService) LoadFileParts(fileIDs []string) []ContentPart {
+	parts := make([]ContentPart, 0, len(fileIDs))
 
-	successCount := 0
-	for i, fileID := range fileIDs {
+	for _, fileID := range fileIDs {
 		fileRecord, err := s.fileAnalysisRepo.FindByID(fileID)
 		if err != nil {
-			contextParts = append(contextParts,
-				fmt.Sprintf("⚠️  File %d: [Error: File not found - ID: %s]", i+1, fileID))
+			log.Printf("⚠️  File not found: %s", fileID)
 			continue
 		}
 
-		successCount++
-		contextParts = append(contextParts,
-			fmt.Sprintf("--- 📄 File %d: %s ---", i+1, fileRecord.FileName))
-		contextParts = append(contextParts,
-			fmt.Sprintf("MIME Type: %s", fileRecord.MimeType))
-		contextParts = append(contextParts,
-			fmt.Sprintf("Size: %s", formatFileSize(fileRecord.FileSize)))
-		contextParts = append(contextParts, "")
+		if IsImageMimeType(fileRecord.MimeType) {
+			part, err := loadImagePart(fileRecord)
+			if err != nil {
+				log.Printf("⚠️  Failed to read image: %v", err)
+				continue
+			}
+			parts = append(parts, part)
+			continue
+		}
 
-		// Read and include file content for supported text-based files
-		fileContent, err := s.readFileContent(fileRecord.StoragePath, fileRecord.MimeType)
-		if err == nil && fileContent != "" {
-			contextParts = append(contextParts, "📝 File Content:")
-			contextParts = append(contextParts, "```")
-			contextParts = append(contextParts, fileContent)
-			contextParts = append(contextParts, "```")
+		// Include the text extracted at upload
+		// A file still being extracted keeps its place (and number) but not its content
+		fileContent, err := s.extraction.Text(fileRecord)
+		if errors.Is(err, ErrExtractionPending) {
+			fileContent = pendingFileText
 		} else if err != nil {
-			contextParts = append(contextParts, fmt.Sprintf("⚠️  Could not read file content: %v", err))
-		} else {
-			contextParts = append(contextParts, "Note: File is stored on server (binary/unsupported format)")
+			log.Printf("⚠️  Could not read file content of %s: %v", fileRecord.FileName, err)
 		}
 
-		contextParts = append(contextParts, "")
-		contextParts = append(contextParts, strings.Repeat("-", 60))
-		contextParts = append(contextParts, "")
-	}
-
-	if successCount == 0 {
-		return "", fmt.Errorf("no valid files found")
+		parts = append(parts, ContentPart{
+			Type:     ContentPartFile,
+			Text:     fileContent,
+			MimeType: fileRecord.MimeType,
+			FileID:   fileID,
+			FileName: fileRecord.FileName,
+			FileSize: fileRecord.FileSize,
+		})
 	}
 
-	contextParts = append(contextParts,
-		"📌 Instructions: Please analyze the file content above and provide insights based on what you see. "+
-			"Answer the user's question using the information from these files.")
-
-	return strings.Join(contextParts, "\n"), nil
+	return parts
 }
 
-// readFileContent reads the content of a file based on its MIME type
-func (s *ContextService) readFileContent(filePath string, mimeType string) (string, error) {
-	// Maximum file size to read (5MB for documents, 1MB for text)
-	maxFileSize := int64(1024 * 1024) // 1MB default
-
-	// Check file size
-	fileInfo, err := os.Stat(filePath)
-	if err != nil {
-		return "", fmt.Errorf("failed to get file info: %w", err)
-	}
-
-	// Handle different file types
-	switch mimeType {
-	case "application/pdf":
-		maxFileSize = 5 * 1024 * 1024 // 5MB for PDF
-		if fileInfo.Size() > maxFileSize {
-			return "", fmt.Errorf("PDF file too large (max 5MB)")
-		}
-		return s.extractPDFText(filePath)
-
-	case "application/vnd.openxmlformats-officedocument.wordprocessingml.document":
-		maxFileSize = 5 * 1024 * 1024 // 5MB for DOCX
-		if fileInfo.Size() > maxFileSize {
-			return "", fmt.Errorf("DOCX file too large (max 5MB)")
-		}
-		return s.extractDOCXText(filePath)
-
-	case "text/plain", "text/markdown", "text/csv", "application/json",
-		"text/html", "text/css", "text/javascript", "application/xml", "text/xml":
-		// Text-based files
-		if fileInfo.Size() > maxFileSize {
-			return "", fmt.Errorf("file too large (max 1MB)")
-		}
-		content, err := os.ReadFile(filePath)
+// FileAttachments returns attachment records for the given file IDs (stored on the user message)
+func (s *ContextService) FileAttachments(fileIDs []string) []models.FileAttachment {
+	attachments := make([]models.FileAttachment, 0, len(fileIDs))
+	for _, fileID := range fileIDs {
+		fileRecord, err := s.fileAnalysisRepo.FindByID(fileID)
 		if err != nil {
-			return "", fmt.Errorf("failed to read file: %w", err)
+			continue
 		}
-		return string(content), nil
+		attachments = append(attachments, models.FileAttachment{
+			FileID:   fileID,
+			Filename: fileRecord.FileName,
+			FileType: fileRecord.MimeType,
+			FileSize: fileRecord.FileSize,
+		})
+	}
+	return attachments
+}
 
-	default:
-		// Check if it starts with "text/"
-		if strings.HasPrefix(mimeType, "text/") {
-			if fileInfo.Size() > maxFileSize {
-				return "", fmt.Errorf("file too large (max 1MB)")
-			}
-			content, err := os.ReadFile(filePath)
-			if err != nil {
-				return "", fmt.Errorf("failed to read file: %w", err)
-			}
-			return string(content), nil
+// FileCitations returns the citations of the files attached to a question found in its answer
+// Files are numbered as in the file context (images left out), see FileCitations
+func (s *ContextService) FileCitations(fileIDs []string, answer string) []models.MessageCitation {
+	if len(fileIDs) == 0 || !citationMarker.MatchString(answer) {
+		return nil
+	}
+
+	var fileParts []ContentPart
+	for _, fileID := range fileIDs {
+		fileRecord, err := s.fileAnalysisRepo.FindByID(fileID)
+		if err != nil || IsImageMimeType(fileRecord.MimeType) {
+			continue
 		}
-		return "", nil // Not supported, not an error
+		text, _ := s.extraction.Text(fileRecord)
+		fileParts = append(fileParts, ContentPart{
+			Type:     ContentPartFile,
+			Text:     text,
+			FileID:   fileID,
+			FileName: fileRecord.FileName,
+		})
 	}
+	return FileCitations(fileParts, answer)
 }
 
-// extractPDFText extracts text content from a PDF file
-func (s *ContextService) extractPDFText(filePath string) (string, error) {
-	f, r, err := pdf.Open(filePath)
+// loadImagePart reads an image file from storage into an image content part
+func loadImagePart(fileRecord *models.FileAnalysis) (ContentPart, error) {
+	data, err := os.ReadFile(fileRecord.StoragePath)
 	if err != nil {
-		return "", fmt.Errorf("failed to open PDF: %w", err)
+		return ContentPart{}, fmt.Errorf("failed to read file: %w", err)
 	}
-	defer f.Close()
 
-	var textContent strings.Builder
-	totalPages := r.NumPage()
+	return ContentPart{
+		Type:     ContentPartImage,
+		MimeType: fileRecord.MimeType,
+		Data:     data,
+		FileID:   fileRecord.ID.String(),
+		FileName: fileRecord.FileName,
+		FileSize: fileRecord.FileSize,
+	}, nil
+}
 
-	// Limit to first 50 pages to avoid huge content
-	maxPages := totalPages
-	if maxPages > 50 {
-		maxPages = 50
+// IsImageMimeType checks if a MIME type is an image supported by vision models
+func IsImageMimeType(mimeType string) bool {
+	switch mimeType {
+	case "image/jpeg", "image/jpg", "image/png", "image/gif", "image/webp":
+		return true
+	default:
+		return false
 	}
+}
 
-	for pageNum := 1; pageNum <= maxPages; pageNum++ {
-		p := r.Page(pageNum)
-		if p.V.IsNull() {
-			continue
-		}
-
-		text, err := p.GetPlainText(nil)
-		if err != nil {
-			continue // Skip pages with errors
-		}
-
-		textContent.WriteString(fmt.Sprintf("\n--- Page %d ---\n", pageNum))
-		textContent.WriteString(text)
+// BuildFileContext builds file context string from file IDs (for current message only)
+func (s *ContextService) BuildFileContext(fileIDs []string) (string, error) {
+	if len(fileIDs) == 0 {
+		return "", nil
 	}
 
-	if totalPages > maxPages {
-		textContent.WriteString(fmt.Sprintf("\n\n[Note: Only first %d of %d pages shown]", maxPages, totalPages))
+	fileParts := make([]ContentPart, 0, len(fileIDs))
+	for _, part := range s.LoadFileParts(fileIDs) {
+		if part.Type == ContentPartFile {
+			fileParts = append(fileParts, part)
+		}
 	}
 
-	result := textContent.String()
-	if result == "" {
-		return "", fmt.Errorf("no text content extracted from PDF")
+	if len(fileParts) == 0 {
+		return "", fmt.Errorf("no valid files found")
 	}
 
-	return result, nil
+	return FormatFileContext(fileParts), nil
 }
 
-// extractDOCXText extracts text content from a DOCX file
-func (s *ContextService) extractDOCXText(filePath string) (string, error) {
-	doc, err := docx.ReadDocxFile(filePath)
-	if err != nil {
-		return "", fmt.Errorf("failed to open DOCX: %w", err)
+// FormatFileContext renders file parts as a text block that is placed in front of the user question
+func FormatFileContext(fileParts []ContentPart) string {
+	if len(fileParts) == 0 {
+		return ""
 	}
-	defer doc.Close()
 
-	docx := doc.Editable()
-	text := docx.GetContent()
+	var contextParts []string
+	contextParts = append(contextParts,
+		fmt.Sprintf("📎 The user has provided %d file(s) for you to analyze:", len(fileParts)))
+	contextParts = append(contextParts, "")
+
+	for i, part := range fileParts {
+		contextParts = append(contextParts,
+			fmt.Sprintf("--- 📄 File %d: %s ---", i+1, part.FileName))
+		contextParts = append(contextParts,
+			fmt.Sprintf("MIME Type: %s", part.MimeType))
+		contextParts = append(contextParts,
+			fmt.Sprintf("Size: %s", formatFileSize(part.FileSize)))
+		contextParts = append(contextParts, "")
+
+		if part.Text != "" {
+			contextParts = append(contextParts, "📝 File Content:")
+			contextParts = append(contextParts, "```")
+			contextParts = append(contextParts, part.Text)
+			contextParts = append(contextParts, "```")
+		} else {
+			contextParts = append(contextParts, "Note: File is stored on server (binary/unsupported format)")
+		}
 
-	if text == "" {
-		return "", fmt.Errorf("no text content extracted from DOCX")
+		contextParts = append(contextParts, "")
+		contextParts = append(contextParts, strings.Repeat("-", 60))
+		contextParts = append(contextParts, "")
 	}
 
-	return text, nil
+	contextParts = append(contextParts,
+		"📌 Instructions: Please analyze the file content above and provide insights based on what you see. "+
+			"Answer the user's question using the information from these files.")
+	contextParts = append(contextParts, citationInstructions)
+
+	return strings.Join(contextParts, "\n")
 }
 
 // formatFileSize formats bytes to human-readable size
@@ -385,7 +426,8 @@ func formatFileSize(bytes int64) string {
 	}
 }
 
-// GetConversationSummary returns a summary of the conversation
+// GetConversationSummary returns a summary of the conversation: the rolling summary of its
+// latest branch when the session has grown long enough to have one, otherwise message counts
 func (s *ContextService) GetConversationSummary(sessionID string) (string, error) {
 	messages, err := s.messageRepo.GetAllBySession(sessionID)
 	if err != nil {
@@ -396,6 +438,13 @@ func (s *ContextService) GetConversationSummary(sessionID string) (string, error
 		return "New conversation", nil
 	}
 
+	tree := NewMessageTree(messages)
+	if latest := tree.Latest(); latest != nil {
+		if summary, _ := s.summarizedPath(sessionID, tree.Path(latest.ID)); summary != nil {
+			return summary.Content, nil
+		}
+	}
+
 	var summary strings.Builder
 	summary.WriteString(fmt.Sprintf("Conversation with %d messages\n", len(messages)))
 
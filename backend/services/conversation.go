@@ -0,0 +1,213 @@
+package services
+
+import (
+	"strings"
+)
+
+// ========================================
+// Provider-neutral Conversation Model
+// ========================================
+// ContextService builds conversations in this format and every provider
+// adapter (OpenAI, Claude on Bedrock, ...) translates it to its own wire format.
+
+// ConversationRole is the author of a conversation message
+type ConversationRole string
+
+// Conversation roles
+const (
+	ConversationRoleSystem    ConversationRole = "system"
+	ConversationRoleUser      ConversationRole = "user"
+	ConversationRoleAssistant ConversationRole = "assistant"
+	ConversationRoleTool      ConversationRole = "tool"
+)
+
+// ContentPartType is the kind of content carried by a ContentPart
+type ContentPartType string
+
+// Content part types
+const (
+	ContentPartText  ContentPartType = "text"
+	ContentPartImage ContentPartType = "image"
+	ContentPartFile  ContentPartType = "file"
+)
+
+// ContentPart is a single piece of message content (text, image or extracted file)
+type ContentPart struct {
+	Type     ContentPartType `json:"type"`
+	Text     string          `json:"text,omitempty"`      // Text content, or extracted text for file parts
+	MimeType string          `json:"mime_type,omitempty"` // Image/file MIME type
+	Data     []byte          `json:"-"`                   // Raw image bytes (adapters encode as needed)
+	FileID   string          `json:"file_id,omitempty"`   // Source file ID for image/file parts
+	FileName string          `json:"file_name,omitempty"` // Source file name for image/file parts
+	FileSize int64           `json:"file_size,omitempty"` // Source file size in bytes
+}
+
+// ToolCall is a tool invocation requested by the assistant
+type ToolCall struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"` // JSON-encoded arguments
+}
+
+// ReasoningBlock is reasoning a model produced before answering (extended thinking)
+type ReasoningBlock struct {
+	Text      string `json:"text,omitempty"`
+	Signature string `json:"signature,omitempty"` // Provider signature required to send the block back (Claude)
+	Redacted  string `json:"redacted,omitempty"`  // Encrypted reasoning the provider did not reveal
+}
+
+// ReasoningText returns the readable text of reasoning blocks
+func ReasoningText(blocks []ReasoningBlock) string {
+	texts := make([]string, 0, len(blocks))
+	for _, block := range blocks {
+		if block.Text != "" {
+			texts = append(texts, block.Text)
+		}
+	}
+	return strings.Join(texts, "\n\n")
+}
+
+// ConversationMessage is a single turn in a provider-neutral conversation
+type ConversationMessage struct {
+	Role       ConversationRole `json:"role"`
+	Parts      []ContentPart    `json:"parts"`
+	ToolCalls  []ToolCall       `json:"tool_calls,omitempty"`   // Assistant tool calls
+	ToolCallID string           `json:"tool_call_id,omitempty"` // Tool result: the call it answers
+	Reasoning  []ReasoningBlock `json:"reasoning,omitempty"`    // Assistant reasoning, sent back during a tool loop
+}
+
+// Conversation is a system prompt plus ordered messages
+type Conversation struct {
+	SystemPrompt string
+	Messages     []ConversationMessage
+	HistoryCount int // Messages replayed from the session's history (a summary or knowledge excerpts are not counted)
+}
+
+// NewTextMessage creates a message with a single text part
+func NewTextMessage(role ConversationRole, text string) ConversationMessage {
+	return ConversationMessage{
+		Role:  role,
+		Parts: []ContentPart{{Type: ContentPartText, Text: text}},
+	}
+}
+
+// NewSimpleConversation creates a conversation with only the current user message
+func NewSimpleConversation(systemPrompt, userMessage string) *Conversation {
+	return &Conversation{
+		SystemPrompt: systemPrompt,
+		Messages:     []ConversationMessage{NewTextMessage(ConversationRoleUser, userMessage)},
+	}
+}
+
+// Text returns the concatenated text parts of a message
+func (m ConversationMessage) Text() string {
+	texts := make([]string, 0, len(m.Parts))
+	for _, part := range m.Parts {
+		if part.Type == ContentPartText && part.Text != "" {
+			texts = append(texts, part.Text)
+		}
+	}
+	return strings.Join(texts, "\n\n")
+}
+
+// PartsOfType returns the parts of a message with the given type
+func (m ConversationMessage) PartsOfType(partType ContentPartType) []ContentPart {
+	parts := make([]ContentPart, 0)
+	for _, part := range m.Parts {
+		if part.Type == partType {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
+
+// IsEmpty reports whether a message has no usable content
+func (m ConversationMessage) IsEmpty() bool {
+	if len(m.ToolCalls) > 0 || m.ToolCallID != "" {
+		return false
+	}
+	for _, part := range m.Parts {
+		if part.Type != ContentPartText || strings.TrimSpace(part.Text) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// HasImages reports whether any message in the conversation carries an image
+func (c *Conversation) HasImages() bool {
+	for _, msg := range c.Messages {
+		if len(msg.PartsOfType(ContentPartImage)) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// HoistFileContext takes the file parts out of the messages and returns them rendered as one
+// context block, so providers can place it right after the system prompt (a prefix that stays
+// the same while the user keeps asking about the same files, which is what prompt caches need)
+func HoistFileContext(messages []ConversationMessage) (string, []ConversationMessage) {
+	var fileParts []ContentPart
+	result := make([]ConversationMessage, 0, len(messages))
+	for _, msg := range messages {
+		files := msg.PartsOfType(ContentPartFile)
+		if len(files) == 0 {
+			result = append(result, msg)
+			continue
+		}
+		fileParts = append(fileParts, files...)
+
+		parts := make([]ContentPart, 0, len(msg.Parts)-len(files))
+		for _, part := range msg.Parts {
+			if part.Type != ContentPartFile {
+				parts = append(parts, part)
+			}
+		}
+		msg.Parts = parts
+		result = append(result, msg)
+	}
+	return FormatFileContext(fileParts), result
+}
+
+// NormalizeToolMessages drops tool turns that lost their partner when history was cut:
+// tool results whose call is not in the window, and assistant tool calls without every result
+// (providers reject both). Assistant text of a dropped tool-call turn is kept.
+func NormalizeToolMessages(messages []ConversationMessage) []ConversationMessage {
+	answered := make(map[string]bool)
+	for _, msg := range messages {
+		if msg.Role == ConversationRoleTool {
+			answered[msg.ToolCallID] = true
+		}
+	}
+
+	result := make([]ConversationMessage, 0, len(messages))
+	called := make(map[string]bool)
+	for _, msg := range messages {
+		switch {
+		case msg.Role == ConversationRoleTool:
+			if !called[msg.ToolCallID] {
+				continue
+			}
+		case len(msg.ToolCalls) > 0:
+			complete := true
+			for _, call := range msg.ToolCalls {
+				if !answered[call.ID] {
+					complete = false
+				}
+			}
+			if !complete {
+				msg.ToolCalls = nil
+				if msg.IsEmpty() {
+					continue
+				}
+				break
+			}
+			for _, call := range msg.ToolCalls {
+				called[call.ID] = true
+			}
+		}
+		result = append(result, msg)
+	}
+	return result
+}
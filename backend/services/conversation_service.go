@@ -0,0 +1,207 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"chatbot/config"
+	"chatbot/models"
+	"chatbot/repositories"
+
+	"github.com/google/uuid"
+)
+
+// titleSystemPrompt asks for a short title in the language of the conversation
+const titleSystemPrompt = "Write a short title (at most 6 words) for the conversation below, " +
+	"in the same language as the user. Reply with the title only, without quotes or punctuation at the end."
+
+// Title lengths
+const (
+	titleMaxLength    = 80  // Longest generated title (characters)
+	titlePromptLength = 500 // Characters of the question and the answer sent to the title model
+	titleFallback     = 50  // Characters of the first question used when no title can be generated
+)
+
+// ConversationService keeps the conversation of each chat session and titles new ones
+type ConversationService struct {
+	repo          *repositories.ConversationRepository
+	messageRepo   *repositories.MessageRepository
+	providers     *ProviderRegistry
+	summarizer    *Summarizer
+	search        *SearchService
+	titleProvider string
+	titleModel    string
+}
+
+// NewConversationService creates a new conversation service
+func NewConversationService(
+	repo *repositories.ConversationRepository,
+	messageRepo *repositories.MessageRepository,
+	providers *ProviderRegistry,
+	summarizer *Summarizer,
+	search *SearchService,
+	cfg *config.Config,
+) *ConversationService {
+	return &ConversationService{
+		repo:          repo,
+		messageRepo:   messageRepo,
+		providers:     providers,
+		summarizer:    summarizer,
+		search:        search,
+		titleProvider: cfg.TitleProvider,
+		titleModel:    cfg.TitleModel,
+	}
+}
+
+// ConversationTurn is a saved question and answer of a session
+type ConversationTurn struct {
+	SessionID string
+	LeafID    uuid.UUID // Last saved message of the turn (the assistant answer)
+	PersonaID *int
+	Provider  string // Provider and model that answered
+	Model     string
+	Question  string
+	Answer    string
+}
+
+// RecordTurn creates the conversation of a saved turn if needed and bumps it; a conversation
+// without a title gets one generated from this exchange in the background, a branch that
+// has grown too long gets its older turns summarized, and new messages are embedded for search
+func (s *ConversationService) RecordTurn(turn ConversationTurn) {
+	if s == nil || turn.SessionID == "" {
+		return
+	}
+
+	conversation := &models.Conversation{
+		ID:        turn.SessionID,
+		PersonaID: turn.PersonaID,
+		Provider:  turn.Provider,
+		Model:     turn.Model,
+	}
+	if err := s.repo.CreateIfMissing(conversation); err != nil {
+		log.Printf("⚠️  Failed to save conversation %s: %v", turn.SessionID, err)
+		return
+	}
+	if err := s.repo.Touch(turn.SessionID, turn.Provider, turn.Model); err != nil {
+		log.Printf("⚠️  Failed to update conversation %s: %v", turn.SessionID, err)
+	}
+	s.summarizer.Schedule(turn.SessionID, turn.LeafID, turn.Provider)
+	s.search.IndexSession(turn.SessionID)
+
+	if conversation.Title == "" {
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+
+			title := s.GenerateTitle(ctx, turn.Provider, turn.Question, turn.Answer)
+			if err := s.repo.SetTitle(turn.SessionID, title, false); err != nil {
+				log.Printf("⚠️  Failed to save title of %s: %v", turn.SessionID, err)
+			}
+		}()
+	}
+}
+
+// Retitle generates a new title from the first exchange of a conversation, replacing the current one
+func (s *ConversationService) Retitle(ctx context.Context, conversation *models.Conversation) (string, error) {
+	messages, err := s.messageRepo.GetAllBySession(conversation.ID)
+	if err != nil {
+		return "", err
+	}
+
+	var question, answer *models.Message
+	for i := range messages {
+		msg := &messages[i]
+		if question == nil && msg.Role == models.RoleUser {
+			question = msg
+		} else if question != nil && msg.Role == models.RoleAssistant && msg.Content != "" {
+			answer = msg
+			break
+		}
+	}
+	if question == nil {
+		return "", fmt.Errorf("conversation %s has no messages to title", conversation.ID)
+	}
+
+	provider := conversation.Provider
+	answerText := ""
+	if answer != nil {
+		provider = answer.Provider
+		answerText = answer.Content
+	}
+	title := s.GenerateTitle(ctx, provider, question.Content, answerText)
+	if err := s.repo.SetTitle(conversation.ID, title, true); err != nil {
+		return "", err
+	}
+	return title, nil
+}
+
+// Delete removes a conversation and its messages
+func (s *ConversationService) Delete(id string) error {
+	return s.repo.Delete(id)
+}
+
+// DeleteAll removes all conversations and messages
+func (s *ConversationService) DeleteAll() error {
+	if err := s.messageRepo.DeleteAll(); err != nil {
+		return err
+	}
+	return s.repo.DeleteAll()
+}
+
+// GenerateTitle asks the title model for a title of an exchange
+// Falls back to the start of the question if the model is unavailable
+func (s *ConversationService) GenerateTitle(ctx context.Context, provider, question, answer string) string {
+	fallback := truncateRunes(firstLine(question), titleFallback)
+
+	if s.titleProvider != "" {
+		provider = s.titleProvider
+	}
+	service, err := s.providers.Get(provider)
+	if err != nil {
+		log.Printf("⚠️  No provider for conversation titles (%q): %v", provider, err)
+		return fallback
+	}
+
+	exchange := fmt.Sprintf("User: %s\n\nAssistant: %s",
+		truncateRunes(question, titlePromptLength), truncateRunes(answer, titlePromptLength))
+	completion, err := CompleteChat(ctx, service, StreamingChatRequest{
+		Messages:     []ConversationMessage{NewTextMessage(ConversationRoleUser, exchange)},
+		SystemPrompt: titleSystemPrompt,
+		Model:        s.titleModel,
+		Temperature:  0.3,
+		MaxTokens:    30,
+	})
+	if err != nil {
+		log.Printf("⚠️  Failed to generate conversation title: %v", err)
+		return fallback
+	}
+
+	title := strings.Trim(firstLine(completion.Content), " \"'`*#.")
+	if title == "" {
+		return fallback
+	}
+	return truncateRunes(title, titleMaxLength)
+}
+
+// firstLine returns the first non-empty line of text, trimmed
+func firstLine(text string) string {
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
+// truncateRunes shortens text to at most max characters, adding "..." when cut
+func truncateRunes(text string, max int) string {
+	if utf8.RuneCountInString(text) <= max {
+		return text
+	}
+	runes := []rune(text)
+	return strings.TrimSpace(string(runes[:max])) + "..."
+}
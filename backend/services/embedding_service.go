@@ -0,0 +1,74 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"chatbot/config"
+	"chatbot/models"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// Embedding limits
+const (
+	embeddingBatchSize  = 100  // Texts per embeddings request
+	embeddingTextLength = 4000 // Characters of a text that are embedded (Thai takes ~1-2 tokens per character)
+)
+
+// EmbeddingService turns text into vectors for semantic search (OpenAI embeddings API)
+type EmbeddingService struct {
+	client *openai.Client
+	model  string
+}
+
+// NewEmbeddingService creates an embedding service; without an OpenAI API key it is unavailable
+func NewEmbeddingService(cfg *config.Config) *EmbeddingService {
+	service := &EmbeddingService{model: cfg.EmbeddingModel}
+	if cfg.OpenAIAPIKey != "" {
+		service.client = openai.NewClient(cfg.OpenAIAPIKey)
+	}
+	return service
+}
+
+// Available reports whether embeddings can be created
+func (s *EmbeddingService) Available() bool {
+	return s != nil && s.client != nil
+}
+
+// Model returns the embedding model
+func (s *EmbeddingService) Model() string {
+	return s.model
+}
+
+// Embed returns one EmbeddingDimensions-long vector per text, in order
+func (s *EmbeddingService) Embed(ctx context.Context, texts []string) ([]models.Vector, error) {
+	if !s.Available() {
+		return nil, fmt.Errorf("embeddings need OPENAI_API_KEY")
+	}
+
+	vectors := make([]models.Vector, 0, len(texts))
+	for start := 0; start < len(texts); start += embeddingBatchSize {
+		end := min(start+embeddingBatchSize, len(texts))
+		batch := make([]string, end-start)
+		for i, text := range texts[start:end] {
+			batch[i] = truncateRunes(text, embeddingTextLength)
+		}
+
+		resp, err := s.client.CreateEmbeddings(ctx, openai.EmbeddingRequestStrings{
+			Input:      batch,
+			Model:      openai.EmbeddingModel(s.model),
+			Dimensions: models.EmbeddingDimensions,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create embeddings: %w", err)
+		}
+		if len(resp.Data) != len(batch) {
+			return nil, fmt.Errorf("embeddings API returned %d vectors for %d texts", len(resp.Data), len(batch))
+		}
+		for _, data := range resp.Data {
+			vectors = append(vectors, models.Vector(data.Embedding))
+		}
+	}
+	return vectors, nil
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"chatbot/config"
+	"chatbot/models"
+)
+
+// ========================================
+// OCR Fallback
+// ========================================
+// Scanned documents have no text layer: their PDF pages extract as empty
+// text and images have no text at all. With an OCR engine in use,
+// ExtractTextWithOCR reads images with it, and the PDF pages with less text than
+// OCR_MIN_PAGE_CHARS are rendered and read with it instead (up to
+// OCR_MAX_PAGES pages per document). Each page read with OCR is reported with
+// the engine's confidence.
+
+// OCREngine reads the text of images and of PDF pages
+type OCREngine interface {
+	RecognizeImage(ctx context.Context, data []byte) (*OCRResult, error)
+	RecognizePDFPages(ctx context.Context, data []byte, pages []int) (map[int]*OCRResult, error) // By 1-based page number
+}
+
+// OCRResult is text read with OCR
+type OCRResult struct {
+	Text       string
+	Confidence float64 // Mean word confidence (0-1)
+}
+
+var (
+	ocrMu           sync.RWMutex
+	ocrEngine       OCREngine
+	ocrMinPageChars int
+	ocrMaxPages     int
+)
+
+// UseOCR makes ExtractTextWithOCR read images and scanned PDF pages with engine (nil turns OCR off)
+func UseOCR(engine OCREngine, cfg *config.Config) {
+	ocrMu.Lock()
+	defer ocrMu.Unlock()
+
+	ocrEngine = engine
+	ocrMinPageChars = max(cfg.OCRMinPageChars, 1)
+	ocrMaxPages = max(cfg.OCRMaxPages, 1)
+}
+
+// OCRAvailable reports whether images and scanned PDF pages are read with OCR
+func OCRAvailable() bool {
+	ocrMu.RLock()
+	defer ocrMu.RUnlock()
+	return ocrEngine != nil
+}
+
+// readImage reads the text of an image with OCR
+// Returns nil (and no error) without an OCR engine or when the image has no text
+func readImage(ctx context.Context, data []byte) (*ExtractedText, error) {
+	ocrMu.RLock()
+	engine := ocrEngine
+	ocrMu.RUnlock()
+	if engine == nil {
+		return nil, nil
+	}
+
+	recognized, err := engine.RecognizeImage(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+	text := strings.TrimSpace(recognized.Text)
+	if text == "" {
+		return nil, nil
+	}
+	return &ExtractedText{
+		Text:     text,
+		OCRPages: []models.OCRPage{{Page: 0, Confidence: recognized.Confidence}},
+	}, nil
+}
+
+// readScannedPages reads the pages of a PDF without enough text with OCR and puts their text in
+// The extracted text is returned as it is without an OCR engine; when OCR fails the error is
+// returned only if the document has no text at all
+func readScannedPages(ctx context.Context, data []byte, extracted *ExtractedText) (*ExtractedText, error) {
+	ocrMu.RLock()
+	engine, minPageChars, maxPages := ocrEngine, ocrMinPageChars, ocrMaxPages
+	ocrMu.RUnlock()
+	if engine == nil {
+		return extracted, nil
+	}
+
+	pages := make(map[int]string)
+	for _, page := range SplitPages(extracted.Text) {
+		if page.Number > 0 {
+			pages[page.Number] = page.Text
+		}
+	}
+	var scanned []int
+	for number := 1; number <= extracted.PageCount && len(scanned) < maxPages; number++ {
+		if utf8.RuneCountInString(pages[number]) < minPageChars {
+			scanned = append(scanned, number)
+		}
+	}
+	if len(scanned) == 0 {
+		return extracted, nil
+	}
+
+	recognized, err := engine.RecognizePDFPages(ctx, data, scanned)
+	if err != nil {
+		if len(pages) == 0 {
+			return nil, fmt.Errorf("scanned document could not be read with OCR: %w", err)
+		}
+		log.Printf("⚠️  OCR of %d scanned pages failed, keeping the text layer: %v", len(scanned), err)
+		return extracted, nil
+	}
+
+	// Rebuild the text page by page, taking the OCR text of pages where it reads more
+	result := &ExtractedText{PageCount: extracted.PageCount}
+	var text strings.Builder
+	for number := 1; number <= extracted.PageCount; number++ {
+		pageText, ok := pages[number]
+		if page := recognized[number]; page != nil {
+			ocrText := strings.TrimSpace(page.Text)
+			if utf8.RuneCountInString(ocrText) > utf8.RuneCountInString(pageText) {
+				pageText, ok = ocrText, true
+				result.OCRPages = append(result.OCRPages, models.OCRPage{Page: number, Confidence: page.Confidence})
+			}
+		}
+		if ok {
+			fmt.Fprintf(&text, "\n--- Page %d ---\n%s\n", number, pageText)
+		}
+	}
+	if len(result.OCRPages) == 0 {
+		return extracted, nil
+	}
+	result.Text = text.String()
+	return result, nil
+}
@@ -0,0 +1,187 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"chatbot/models"
+	"chatbot/utils"
+)
+
+// ========================================
+// Document Extractor Registry
+// ========================================
+// Extractors register themselves from init() with RegisterExtractor for the
+// MIME types and file name extensions of their format. ExtractText picks the
+// extractor of a file by its extension first (browsers often send a generic
+// or wrong MIME type), then by its MIME type; any other text/* file is read
+// as plain text. Paged formats mark their pages ("--- Page N ---") or slides
+// ("--- Slide N ---") and tables come out as Markdown tables. When an OCR
+// engine is in use (see UseOCR), ExtractTextWithOCR also reads images and the
+// scanned pages of PDFs with OCR; it can take minutes, so only the background
+// extraction of uploads uses it.
+
+// maxExtractedTextBytes is the most text stored for a file; longer text is cut
+const maxExtractedTextBytes = 5 * 1024 * 1024
+
+// ExtractedText is the text of a file with what is known about it
+type ExtractedText struct {
+	Text      string
+	PageCount int              // PDF pages or deck slides (0 = not paged)
+	Language  string           // See utils.DetectLanguage
+	OCRPages  []models.OCRPage // Pages read with OCR and their confidence
+}
+
+// Extractor extracts the text of the documents of one format
+type Extractor interface {
+	Extract(data []byte) (*ExtractedText, error)
+}
+
+// ExtractorFunc adapts a function to an Extractor
+type ExtractorFunc func(data []byte) (*ExtractedText, error)
+
+// Extract calls f(data)
+func (f ExtractorFunc) Extract(data []byte) (*ExtractedText, error) {
+	return f(data)
+}
+
+// ExtractorFormat is a document format and the MIME types and extensions it is recognized by
+type ExtractorFormat struct {
+	Name       string   `json:"name"`
+	MimeTypes  []string `json:"mime_types"`
+	Extensions []string `json:"extensions"` // With the dot, e.g. ".pdf"
+}
+
+// registeredExtractor is an extractor with the format it was registered for
+type registeredExtractor struct {
+	format    ExtractorFormat
+	extractor Extractor
+}
+
+var (
+	registeredExtractorsMu sync.RWMutex
+	extractorsByMimeType   = map[string]registeredExtractor{}
+	extractorsByExtension  = map[string]registeredExtractor{}
+	extractorFormats       []ExtractorFormat
+)
+
+// RegisterExtractor registers the extractor of a document format
+// Extractors call this from init(); a MIME type or extension registered twice panics
+func RegisterExtractor(format ExtractorFormat, extractor Extractor) {
+	registeredExtractorsMu.Lock()
+	defer registeredExtractorsMu.Unlock()
+
+	entry := registeredExtractor{format: format, extractor: extractor}
+	for _, mimeType := range format.MimeTypes {
+		mimeType = strings.ToLower(mimeType)
+		if existing, exists := extractorsByMimeType[mimeType]; exists {
+			panic(fmt.Sprintf("MIME type %q registered by extractors %q and %q", mimeType, existing.format.Name, format.Name))
+		}
+		extractorsByMimeType[mimeType] = entry
+	}
+	for _, ext := range format.Extensions {
+		ext = strings.ToLower(ext)
+		if existing, exists := extractorsByExtension[ext]; exists {
+			panic(fmt.Sprintf("extension %q registered by extractors %q and %q", ext, existing.format.Name, format.Name))
+		}
+		extractorsByExtension[ext] = entry
+	}
+	extractorFormats = append(extractorFormats, format)
+}
+
+// ExtractorFormats lists the registered document formats by name
+func ExtractorFormats() []ExtractorFormat {
+	registeredExtractorsMu.RLock()
+	defer registeredExtractorsMu.RUnlock()
+
+	formats := append([]ExtractorFormat(nil), extractorFormats...)
+	sort.Slice(formats, func(i, j int) bool { return formats[i].Name < formats[j].Name })
+	return formats
+}
+
+// ExtractorFor returns the extractor of a file by its extension, then its MIME type
+// Other text/* files get the plain text extractor
+func ExtractorFor(mimeType, fileName string) (ExtractorFormat, Extractor, bool) {
+	registeredExtractorsMu.RLock()
+	defer registeredExtractorsMu.RUnlock()
+
+	if entry, ok := extractorsByExtension[getFileExtension(fileName)]; ok {
+		return entry.format, entry.extractor, true
+	}
+
+	mediaType := strings.ToLower(strings.TrimSpace(mimeType))
+	if parsed, _, err := mime.ParseMediaType(mimeType); err == nil {
+		mediaType = parsed
+	}
+	if entry, ok := extractorsByMimeType[mediaType]; ok {
+		return entry.format, entry.extractor, true
+	}
+	if strings.HasPrefix(mediaType, "text/") {
+		if entry, ok := extractorsByMimeType["text/plain"]; ok {
+			return entry.format, entry.extractor, true
+		}
+	}
+	return ExtractorFormat{}, nil, false
+}
+
+// ExtractText extracts the text of file data with the extractor of its format, without OCR
+// Returns nil (and no error) for formats without an extractor and images
+func ExtractText(data []byte, mimeType, fileName string) (*ExtractedText, error) {
+	return extractText(context.Background(), data, mimeType, fileName, false)
+}
+
+// ExtractTextWithOCR extracts text like ExtractText, also reading images and scanned PDF pages
+// with OCR when an OCR engine is in use; ctx cancels the OCR
+// Returns nil (and no error) for formats without an extractor and images without text
+func ExtractTextWithOCR(ctx context.Context, data []byte, mimeType, fileName string) (*ExtractedText, error) {
+	return extractText(ctx, data, mimeType, fileName, true)
+}
+
+// extractText extracts the text of file data, with OCR when ocr is set
+func extractText(ctx context.Context, data []byte, mimeType, fileName string, ocr bool) (*ExtractedText, error) {
+	format, extractor, ok := ExtractorFor(mimeType, fileName)
+	var result *ExtractedText
+	var err error
+	switch {
+	case ok:
+		if result, err = extractor.Extract(data); err != nil {
+			return nil, fmt.Errorf("%s: %w", format.Name, err)
+		}
+		if ocr && format.Name == "pdf" && result != nil {
+			if result, err = readScannedPages(ctx, data, result); err != nil {
+				return nil, fmt.Errorf("%s: %w", format.Name, err)
+			}
+		}
+	case ocr && (IsImageMimeType(mimeType) || IsImageMimeType(detectContentType(fileName))):
+		if result, err = readImage(ctx, data); err != nil {
+			return nil, fmt.Errorf("ocr: %w", err)
+		}
+	}
+	if result == nil {
+		return nil, nil
+	}
+
+	result.Text = cleanExtractedText(result.Text)
+	result.Language = utils.DetectLanguage(result.Text)
+	return result, nil
+}
+
+// cleanExtractedText makes text storable in Postgres (valid UTF-8 without NUL) and cuts it to maxExtractedTextBytes
+func cleanExtractedText(text string) string {
+	text = strings.ToValidUTF8(text, "")
+	text = strings.ReplaceAll(text, "\x00", "")
+	if len(text) <= maxExtractedTextBytes {
+		return text
+	}
+
+	cut := maxExtractedTextBytes
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	return text[:cut] + "\n\n[Note: text truncated]"
+}
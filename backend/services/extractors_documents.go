@@ -0,0 +1,502 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ledongthuc/pdf"
+	"github.com/xuri/excelize/v2"
+)
+
+// ========================================
+// Document Format Extractors
+// ========================================
+// Office (OOXML), OpenDocument and EPUB files are zip archives of XML parts;
+// their text is read straight from the parts, paragraph by paragraph, with
+// tables rendered as Markdown tables.
+
+// maxDocumentPartBytes is the largest zip part read (guards against zip bombs)
+const maxDocumentPartBytes = 64 * 1024 * 1024
+
+func init() {
+	RegisterExtractor(ExtractorFormat{
+		Name:       "pdf",
+		MimeTypes:  []string{"application/pdf"},
+		Extensions: []string{".pdf"},
+	}, ExtractorFunc(extractPDFText))
+
+	RegisterExtractor(ExtractorFormat{
+		Name:       "docx",
+		MimeTypes:  []string{"application/vnd.openxmlformats-officedocument.wordprocessingml.document"},
+		Extensions: []string{".docx"},
+	}, ExtractorFunc(extractDOCXText))
+
+	RegisterExtractor(ExtractorFormat{
+		Name:       "xlsx",
+		MimeTypes:  []string{"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"},
+		Extensions: []string{".xlsx"},
+	}, ExtractorFunc(extractExcelText))
+
+	RegisterExtractor(ExtractorFormat{
+		Name:       "pptx",
+		MimeTypes:  []string{"application/vnd.openxmlformats-officedocument.presentationml.presentation"},
+		Extensions: []string{".pptx"},
+	}, ExtractorFunc(extractPPTXText))
+
+	RegisterExtractor(ExtractorFormat{
+		Name:       "odt",
+		MimeTypes:  []string{"application/vnd.oasis.opendocument.text"},
+		Extensions: []string{".odt"},
+	}, ExtractorFunc(extractODTText))
+
+	RegisterExtractor(ExtractorFormat{
+		Name:       "epub",
+		MimeTypes:  []string{"application/epub+zip"},
+		Extensions: []string{".epub"},
+	}, ExtractorFunc(extractEPUBText))
+}
+
+// extractPDFText extracts the text of every page of a PDF under "--- Page N ---" headers
+func extractPDFText(data []byte) (*ExtractedText, error) {
+	r, err := pdf.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open PDF: %w", err)
+	}
+
+	var textContent strings.Builder
+	totalPages := r.NumPage()
+
+	for pageNum := 1; pageNum <= totalPages; pageNum++ {
+		p := r.Page(pageNum)
+		if p.V.IsNull() {
+			continue
+		}
+
+		text, err := p.GetPlainText(nil)
+		if err != nil {
+			continue // Skip pages with errors
+		}
+
+		textContent.WriteString(fmt.Sprintf("\n--- Page %d ---\n", pageNum))
+		textContent.WriteString(text)
+	}
+
+	if textContent.Len() == 0 {
+		return nil, fmt.Errorf("no text content extracted from PDF")
+	}
+
+	return &ExtractedText{Text: textContent.String(), PageCount: totalPages}, nil
+}
+
+// markupLayout names the elements of an XML document format that hold its text
+type markupLayout struct {
+	paragraphs []string // Elements ending a line
+	text       string   // Element whose character data is text ("" = any character data in a paragraph)
+	tab        string
+	lineBreak  string
+	spaces     string // Element standing for spaces, as many as its "c" attribute (OpenDocument)
+	table      string
+	row        string
+	cell       string
+	skipped    []string // Elements whose content is not document text
+}
+
+// Layouts of WordprocessingML (.docx), DrawingML (.pptx slides) and OpenDocument text (.odt)
+var (
+	wordLayout = markupLayout{
+		paragraphs: []string{"p"}, text: "t", tab: "tab", lineBreak: "br",
+		table: "tbl", row: "tr", cell: "tc", skipped: []string{"pPr", "rPr"},
+	}
+	drawingLayout = markupLayout{
+		paragraphs: []string{"p"}, text: "t", lineBreak: "br",
+		table: "tbl", row: "tr", cell: "tc",
+	}
+	openDocumentLayout = markupLayout{
+		paragraphs: []string{"p", "h"}, tab: "tab", lineBreak: "line-break", spaces: "s",
+		table: "table", row: "table-row", cell: "table-cell",
+		skipped: []string{"tracked-changes", "annotation", "note", "sequence-decls", "forms"},
+	}
+)
+
+// markupTable is a table being read by markupText
+type markupTable struct {
+	rows [][]string
+	row  []string
+	cell []string // Lines of the current cell
+}
+
+// markupText extracts the text of an XML document part: one line per paragraph, tables as Markdown tables
+func markupText(data []byte, layout markupLayout) (string, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	var blocks []string
+	var tables []*markupTable
+	var line strings.Builder
+	inParagraph, inText, skipped := 0, 0, 0
+
+	endLine := func() {
+		text := strings.TrimSpace(line.String())
+		line.Reset()
+		if len(tables) > 0 {
+			if text != "" {
+				top := tables[len(tables)-1]
+				top.cell = append(top.cell, text)
+			}
+			return
+		}
+		blocks = append(blocks, text)
+	}
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to parse document XML: %w", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			name := t.Name.Local
+			switch {
+			case skipped > 0 || slices.Contains(layout.skipped, name):
+				skipped++
+			case slices.Contains(layout.paragraphs, name):
+				inParagraph++
+			case name == layout.text:
+				inText++
+			case name == layout.tab:
+				line.WriteString("\t")
+			case name == layout.lineBreak:
+				line.WriteString("\n")
+			case name == layout.spaces:
+				count := 1
+				for _, attr := range t.Attr {
+					if attr.Name.Local == "c" {
+						if n, err := strconv.Atoi(attr.Value); err == nil && n > 0 && n < 1000 {
+							count = n
+						}
+					}
+				}
+				line.WriteString(strings.Repeat(" ", count))
+			case name == layout.table:
+				tables = append(tables, &markupTable{})
+			case name == layout.row && len(tables) > 0:
+				tables[len(tables)-1].row = nil
+			case name == layout.cell && len(tables) > 0:
+				tables[len(tables)-1].cell = nil
+			}
+
+		case xml.EndElement:
+			name := t.Name.Local
+			switch {
+			case skipped > 0:
+				skipped--
+			case slices.Contains(layout.paragraphs, name):
+				inParagraph = max(inParagraph-1, 0)
+				endLine()
+			case name == layout.text:
+				inText = max(inText-1, 0)
+			case name == layout.table && len(tables) > 0:
+				top := tables[len(tables)-1]
+				tables = tables[:len(tables)-1]
+				rendered := renderTable(top.rows)
+				if len(tables) > 0 {
+					// A nested table becomes part of the cell it is in
+					parent := tables[len(tables)-1]
+					parent.cell = append(parent.cell, collapseSpaces(rendered))
+				} else {
+					blocks = append(blocks, "", rendered)
+				}
+			case name == layout.row && len(tables) > 0:
+				top := tables[len(tables)-1]
+				top.rows = append(top.rows, top.row)
+			case name == layout.cell && len(tables) > 0:
+				top := tables[len(tables)-1]
+				top.row = append(top.row, strings.Join(top.cell, " "))
+			}
+
+		case xml.CharData:
+			if skipped == 0 && (inText > 0 || layout.text == "" && inParagraph > 0) {
+				line.Write(t)
+			}
+		}
+	}
+
+	return tidyLines(strings.Join(blocks, "\n")), nil
+}
+
+// extractDOCXText extracts the paragraphs and tables of a Word (.docx) document
+func extractDOCXText(data []byte) (*ExtractedText, error) {
+	archive, err := openZip(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open DOCX: %w", err)
+	}
+	part, err := readZipPart(archive, "word/document.xml")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open DOCX: %w", err)
+	}
+
+	text, err := markupText(part, wordLayout)
+	if err != nil {
+		return nil, err
+	}
+	if text == "" {
+		return nil, fmt.Errorf("no text content extracted from DOCX")
+	}
+	return &ExtractedText{Text: text}, nil
+}
+
+// extractExcelText extracts every sheet of an Excel (.xlsx) workbook as a Markdown table
+func extractExcelText(data []byte) (*ExtractedText, error) {
+	f, err := excelize.OpenReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open Excel: %w", err)
+	}
+	defer f.Close()
+
+	var text strings.Builder
+	for _, sheetName := range f.GetSheetList() {
+		rows, err := f.GetRows(sheetName)
+		if err != nil {
+			continue
+		}
+		table := renderTable(rows)
+		if table == "" {
+			continue
+		}
+
+		text.WriteString(fmt.Sprintf("=== Sheet: %s ===\n", sheetName))
+		text.WriteString(table)
+		text.WriteString("\n")
+	}
+
+	return &ExtractedText{Text: text.String()}, nil
+}
+
+// partRelationships are the relationships of an OOXML part (its _rels file)
+type partRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Type   string `xml:"Type,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+// pptxSlideFile matches slide parts, for decks without a readable slide list
+var pptxSlideFile = regexp.MustCompile(`^ppt/slides/slide(\d+)\.xml$`)
+
+// extractPPTXText extracts the text, tables and speaker notes of every slide of a PowerPoint
+// (.pptx) deck under "--- Slide N ---" headers, in presentation order
+func extractPPTXText(data []byte) (*ExtractedText, error) {
+	archive, err := openZip(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open PPTX: %w", err)
+	}
+
+	slides := pptxSlidePaths(archive)
+	if len(slides) == 0 {
+		return nil, fmt.Errorf("no slides found in PPTX")
+	}
+
+	var text strings.Builder
+	for i, slidePath := range slides {
+		text.WriteString(fmt.Sprintf("\n--- Slide %d ---\n", i+1))
+
+		part, err := readZipPart(archive, slidePath)
+		if err != nil {
+			continue
+		}
+		if slideText, err := markupText(part, drawingLayout); err == nil {
+			text.WriteString(slideText)
+			text.WriteString("\n")
+		}
+
+		if notesPath := pptxNotesPath(archive, slidePath); notesPath != "" {
+			if part, err := readZipPart(archive, notesPath); err == nil {
+				if notes, err := markupText(part, drawingLayout); err == nil && notes != "" {
+					text.WriteString("Notes: ")
+					text.WriteString(notes)
+					text.WriteString("\n")
+				}
+			}
+		}
+	}
+
+	return &ExtractedText{Text: text.String(), PageCount: len(slides)}, nil
+}
+
+// pptxSlidePaths lists the slide parts of a deck in presentation order
+func pptxSlidePaths(archive *zip.Reader) []string {
+	var presentation struct {
+		Slides []struct {
+			RelID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sldIdLst>sldId"`
+	}
+	relationships := readRelationships(archive, "ppt/presentation.xml")
+	if part, err := readZipPart(archive, "ppt/presentation.xml"); err == nil && xml.Unmarshal(part, &presentation) == nil {
+		targets := make(map[string]string)
+		for _, rel := range relationships.Relationships {
+			targets[rel.ID] = path.Join("ppt", rel.Target)
+		}
+		var slides []string
+		for _, slide := range presentation.Slides {
+			if target, ok := targets[slide.RelID]; ok {
+				slides = append(slides, target)
+			}
+		}
+		if len(slides) > 0 {
+			return slides
+		}
+	}
+
+	// Fall back to the slide file numbers
+	numbers := make(map[string]int)
+	var slides []string
+	for _, file := range archive.File {
+		if match := pptxSlideFile.FindStringSubmatch(file.Name); match != nil {
+			numbers[file.Name], _ = strconv.Atoi(match[1])
+			slides = append(slides, file.Name)
+		}
+	}
+	sort.Slice(slides, func(i, j int) bool { return numbers[slides[i]] < numbers[slides[j]] })
+	return slides
+}
+
+// pptxNotesPath returns the speaker notes part of a slide ("" when it has none)
+func pptxNotesPath(archive *zip.Reader, slidePath string) string {
+	for _, rel := range readRelationships(archive, slidePath).Relationships {
+		if strings.HasSuffix(rel.Type, "/notesSlide") {
+			return path.Join(path.Dir(slidePath), rel.Target)
+		}
+	}
+	return ""
+}
+
+// readRelationships reads the relationships of an OOXML part (none when it has no _rels file)
+func readRelationships(archive *zip.Reader, partPath string) partRelationships {
+	var relationships partRelationships
+	relsPath := path.Join(path.Dir(partPath), "_rels", path.Base(partPath)+".rels")
+	if part, err := readZipPart(archive, relsPath); err == nil {
+		_ = xml.Unmarshal(part, &relationships)
+	}
+	return relationships
+}
+
+// extractODTText extracts the paragraphs and tables of an OpenDocument text (.odt) document
+func extractODTText(data []byte) (*ExtractedText, error) {
+	archive, err := openZip(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ODT: %w", err)
+	}
+	part, err := readZipPart(archive, "content.xml")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ODT: %w", err)
+	}
+
+	text, err := markupText(part, openDocumentLayout)
+	if err != nil {
+		return nil, err
+	}
+	if text == "" {
+		return nil, fmt.Errorf("no text content extracted from ODT")
+	}
+	return &ExtractedText{Text: text}, nil
+}
+
+// extractEPUBText extracts the chapters of an EPUB book in reading (spine) order
+func extractEPUBText(data []byte) (*ExtractedText, error) {
+	archive, err := openZip(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open EPUB: %w", err)
+	}
+
+	var container struct {
+		Rootfiles []struct {
+			FullPath string `xml:"full-path,attr"`
+		} `xml:"rootfiles>rootfile"`
+	}
+	part, err := readZipPart(archive, "META-INF/container.xml")
+	if err != nil || xml.Unmarshal(part, &container) != nil || len(container.Rootfiles) == 0 {
+		return nil, fmt.Errorf("failed to open EPUB: missing container")
+	}
+	packagePath := container.Rootfiles[0].FullPath
+
+	var pkg struct {
+		Manifest []struct {
+			ID   string `xml:"id,attr"`
+			Href string `xml:"href,attr"`
+		} `xml:"manifest>item"`
+		Spine []struct {
+			IDRef string `xml:"idref,attr"`
+		} `xml:"spine>itemref"`
+	}
+	part, err = readZipPart(archive, packagePath)
+	if err != nil || xml.Unmarshal(part, &pkg) != nil {
+		return nil, fmt.Errorf("failed to open EPUB: missing package document")
+	}
+
+	hrefs := make(map[string]string, len(pkg.Manifest))
+	for _, item := range pkg.Manifest {
+		hrefs[item.ID] = item.Href
+	}
+
+	var chapters []string
+	for _, item := range pkg.Spine {
+		href, err := url.PathUnescape(hrefs[item.IDRef])
+		if err != nil || href == "" {
+			continue
+		}
+		part, err := readZipPart(archive, path.Join(path.Dir(packagePath), href))
+		if err != nil {
+			continue
+		}
+		if chapter := htmlText(part); chapter != "" {
+			chapters = append(chapters, chapter)
+		}
+	}
+	if len(chapters) == 0 {
+		return nil, fmt.Errorf("no text content extracted from EPUB")
+	}
+
+	return &ExtractedText{Text: strings.Join(chapters, "\n\n")}, nil
+}
+
+// openZip opens a zip archive held in memory
+func openZip(data []byte) (*zip.Reader, error) {
+	return zip.NewReader(bytes.NewReader(data), int64(len(data)))
+}
+
+// readZipPart reads a part of a zip archive by its path
+func readZipPart(archive *zip.Reader, name string) ([]byte, error) {
+	for _, file := range archive.File {
+		if file.Name != name {
+			continue
+		}
+		r, err := file.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+
+		data, err := io.ReadAll(io.LimitReader(r, maxDocumentPartBytes+1))
+		if err != nil {
+			return nil, err
+		}
+		if len(data) > maxDocumentPartBytes {
+			return nil, fmt.Errorf("%s is larger than %d MB", name, maxDocumentPartBytes/(1024*1024))
+		}
+		return data, nil
+	}
+	return nil, fmt.Errorf("%s not found", name)
+}
@@ -0,0 +1,425 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/beevik/etree"
+	"golang.org/x/net/html"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+)
+
+// ========================================
+// Text Format Extractors
+// ========================================
+
+func init() {
+	RegisterExtractor(ExtractorFormat{
+		Name: "text",
+		MimeTypes: []string{
+			"text/plain", "application/javascript", "text/javascript", "text/css",
+			"text/x-python", "text/x-go", "text/x-java", "application/x-yaml", "text/yaml",
+		},
+		Extensions: []string{
+			".txt", ".log", ".js", ".ts", ".py", ".go", ".java", ".c", ".cpp", ".h", ".cs",
+			".rb", ".php", ".rs", ".kt", ".swift", ".sql", ".sh", ".css", ".yaml", ".yml", ".toml", ".ini",
+		},
+	}, ExtractorFunc(extractPlainText))
+
+	RegisterExtractor(ExtractorFormat{
+		Name:       "markdown",
+		MimeTypes:  []string{"text/markdown", "text/x-markdown"},
+		Extensions: []string{".md", ".markdown"},
+	}, ExtractorFunc(extractPlainText))
+
+	RegisterExtractor(ExtractorFormat{
+		Name:       "csv",
+		MimeTypes:  []string{"text/csv", "application/csv"},
+		Extensions: []string{".csv"},
+	}, delimitedExtractor(','))
+
+	RegisterExtractor(ExtractorFormat{
+		Name:       "tsv",
+		MimeTypes:  []string{"text/tab-separated-values"},
+		Extensions: []string{".tsv"},
+	}, delimitedExtractor('\t'))
+
+	RegisterExtractor(ExtractorFormat{
+		Name:       "json",
+		MimeTypes:  []string{"application/json"},
+		Extensions: []string{".json"},
+	}, ExtractorFunc(extractPlainText)) // As sent: compact JSON costs fewer tokens
+
+	RegisterExtractor(ExtractorFormat{
+		Name:       "xml",
+		MimeTypes:  []string{"application/xml", "text/xml"},
+		Extensions: []string{".xml"},
+	}, ExtractorFunc(extractXMLText))
+
+	RegisterExtractor(ExtractorFormat{
+		Name:       "html",
+		MimeTypes:  []string{"text/html", "application/xhtml+xml"},
+		Extensions: []string{".html", ".htm", ".xhtml"},
+	}, ExtractorFunc(extractHTMLText))
+
+	RegisterExtractor(ExtractorFormat{
+		Name:       "rtf",
+		MimeTypes:  []string{"application/rtf", "text/rtf"},
+		Extensions: []string{".rtf"},
+	}, ExtractorFunc(extractRTFText))
+}
+
+// extractPlainText returns the data as it is
+func extractPlainText(data []byte) (*ExtractedText, error) {
+	return &ExtractedText{Text: string(data)}, nil
+}
+
+// delimitedExtractor extracts comma- or tab-separated rows as a Markdown table
+// Data that does not parse is returned as it is
+func delimitedExtractor(delimiter rune) Extractor {
+	return ExtractorFunc(func(data []byte) (*ExtractedText, error) {
+		reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+		reader.Comma = delimiter
+		reader.FieldsPerRecord = -1
+		reader.LazyQuotes = true
+
+		rows, err := reader.ReadAll()
+		if err != nil {
+			return &ExtractedText{Text: string(data)}, nil
+		}
+		return &ExtractedText{Text: renderTable(rows)}, nil
+	})
+}
+
+// extractXMLText pretty-prints an XML document (malformed XML is returned as it is)
+func extractXMLText(data []byte) (*ExtractedText, error) {
+	doc := etree.NewDocument()
+	if err := doc.ReadFromBytes(data); err != nil {
+		return &ExtractedText{Text: string(data)}, nil
+	}
+
+	doc.Indent(2)
+	xmlString, err := doc.WriteToString()
+	if err != nil {
+		return &ExtractedText{Text: string(data)}, nil
+	}
+	return &ExtractedText{Text: xmlString}, nil
+}
+
+// extractHTMLText extracts the visible text of an HTML page
+func extractHTMLText(data []byte) (*ExtractedText, error) {
+	return &ExtractedText{Text: htmlText(data)}, nil
+}
+
+// htmlSkippedElements hold no visible text
+var htmlSkippedElements = map[string]bool{
+	"head": true, "script": true, "style": true, "noscript": true, "template": true, "svg": true,
+}
+
+// htmlBlockElements start a new line
+var htmlBlockElements = map[string]bool{
+	"p": true, "div": true, "br": true, "li": true, "tr": true, "table": true, "ul": true, "ol": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true, "pre": true, "hr": true,
+	"blockquote": true, "section": true, "article": true, "header": true, "footer": true, "nav": true,
+	"aside": true, "main": true, "dt": true, "dd": true, "figcaption": true, "caption": true,
+}
+
+// htmlText extracts the visible text of HTML: one line per block, table cells separated by |
+func htmlText(data []byte) string {
+	tokenizer := html.NewTokenizer(bytes.NewReader(data))
+	var text strings.Builder
+	skipped, pre := 0, 0
+
+	// newLine ends the current line unless it is empty
+	newLine := func() {
+		if content := text.String(); content != "" && !strings.HasSuffix(content, "\n") {
+			text.WriteString("\n")
+		}
+	}
+
+	for {
+		token := tokenizer.Next()
+		switch token {
+		case html.ErrorToken:
+			return tidyLines(text.String())
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, _ := tokenizer.TagName()
+			tag := string(name)
+			switch {
+			case tag == "body":
+				skipped = 0 // An unclosed <head> ends here
+			case htmlSkippedElements[tag]:
+				if token == html.StartTagToken {
+					skipped++
+				}
+			case tag == "pre":
+				pre++
+				newLine()
+			case tag == "td" || tag == "th":
+				text.WriteString(" | ")
+			case htmlBlockElements[tag]:
+				newLine()
+			}
+
+		case html.EndTagToken:
+			name, _ := tokenizer.TagName()
+			tag := string(name)
+			switch {
+			case htmlSkippedElements[tag]:
+				skipped = max(skipped-1, 0)
+			case tag == "pre":
+				pre = max(pre-1, 0)
+				newLine()
+			case htmlBlockElements[tag]:
+				newLine()
+			}
+
+		case html.TextToken:
+			if skipped > 0 {
+				continue
+			}
+			content := html.UnescapeString(string(tokenizer.Text()))
+			if pre == 0 {
+				content = collapseSpaces(content)
+			}
+			text.WriteString(content)
+		}
+	}
+}
+
+var (
+	spaceRun      = regexp.MustCompile(`[ \t\r\n\f]+`)
+	blankLineRuns = regexp.MustCompile(`\n{3,}`)
+)
+
+// collapseSpaces turns every run of whitespace into one space, as browsers render it
+func collapseSpaces(text string) string {
+	return spaceRun.ReplaceAllString(text, " ")
+}
+
+// tidyLines trims the lines of text and keeps at most one blank line between paragraphs
+func tidyLines(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRightFunc(strings.TrimPrefix(line, " "), unicode.IsSpace)
+	}
+	return strings.TrimSpace(blankLineRuns.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
+
+// renderTable renders rows as a Markdown table; the first row is the header
+func renderTable(rows [][]string) string {
+	columns := 0
+	kept := make([][]string, 0, len(rows))
+	for _, row := range rows {
+		empty := true
+		for _, cell := range row {
+			if strings.TrimSpace(cell) != "" {
+				empty = false
+				break
+			}
+		}
+		if !empty {
+			kept = append(kept, row)
+			columns = max(columns, len(row))
+		}
+	}
+	if len(kept) == 0 {
+		return ""
+	}
+
+	var table strings.Builder
+	for i, row := range kept {
+		table.WriteString("|")
+		for col := 0; col < columns; col++ {
+			cell := ""
+			if col < len(row) {
+				cell = strings.ReplaceAll(collapseSpaces(strings.TrimSpace(row[col])), "|", `\|`)
+			}
+			table.WriteString(" " + cell + " |")
+		}
+		table.WriteString("\n")
+		if i == 0 {
+			table.WriteString("|" + strings.Repeat(" --- |", columns) + "\n")
+		}
+	}
+	return table.String()
+}
+
+// rtfSkippedDestinations are RTF groups without document text
+var rtfSkippedDestinations = map[string]bool{
+	"fonttbl": true, "colortbl": true, "stylesheet": true, "info": true, "pict": true, "object": true,
+	"header": true, "headerl": true, "headerr": true, "headerf": true,
+	"footer": true, "footerl": true, "footerr": true, "footerf": true,
+	"listtable": true, "listoverridetable": true, "rsidtbl": true, "generator": true,
+	"themedata": true, "colorschememapping": true, "latentstyles": true, "datastore": true,
+	"xmlnstbl": true, "fldinst": true, "filetbl": true, "revtbl": true,
+}
+
+// rtfCodePages are the ANSI code pages of \ansicpgN (default Windows-1252)
+var rtfCodePages = map[int]encoding.Encoding{
+	874:  charmap.Windows874, // Thai
+	1250: charmap.Windows1250,
+	1251: charmap.Windows1251,
+	1252: charmap.Windows1252,
+	1253: charmap.Windows1253,
+	1254: charmap.Windows1254,
+	1257: charmap.Windows1257,
+}
+
+// extractRTFText extracts the text of an RTF document
+// Characters come from \uN escapes or, in the document's ANSI code page, from \'hh escapes
+func extractRTFText(data []byte) (*ExtractedText, error) {
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte(`{\rtf`)) {
+		return nil, fmt.Errorf("not an RTF document")
+	}
+
+	type group struct {
+		skip        bool
+		unicodeSkip int // Fallback characters after \uN (\ucN)
+	}
+
+	var text strings.Builder
+	var pending []byte // Code page bytes not decoded yet
+	codePage := encoding.Encoding(charmap.Windows1252)
+	state := group{unicodeSkip: 1}
+	var stack []group
+	fallback := 0 // Fallback characters of the last \uN still to skip
+
+	flush := func() {
+		if len(pending) == 0 {
+			return
+		}
+		decoded, err := codePage.NewDecoder().Bytes(pending)
+		if err != nil {
+			decoded = pending
+		}
+		text.Write(decoded)
+		pending = pending[:0]
+	}
+	write := func(s string) {
+		if state.skip {
+			return
+		}
+		flush()
+		text.WriteString(s)
+	}
+	writeByte := func(b byte) {
+		if fallback > 0 {
+			fallback--
+			return
+		}
+		if !state.skip {
+			pending = append(pending, b)
+		}
+	}
+
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		switch c {
+		case '{':
+			stack = append(stack, state)
+			fallback = 0
+		case '}':
+			flush()
+			if len(stack) > 0 {
+				state = stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+			}
+			fallback = 0
+		case '\r', '\n':
+		case '\\':
+			if i+1 >= len(data) {
+				break
+			}
+			next := data[i+1]
+			switch {
+			case next == '*':
+				state.skip = true
+				i++
+			case next == '\'':
+				if i+3 < len(data) {
+					if b, err := strconv.ParseUint(string(data[i+2:i+4]), 16, 8); err == nil {
+						writeByte(byte(b))
+					}
+				}
+				i += 3
+			case next == '\\' || next == '{' || next == '}':
+				writeByte(next)
+				i++
+			case next == '~':
+				write(" ")
+				i++
+			case next == '_':
+				write("-")
+				i++
+			case next == '\r' || next == '\n':
+				write("\n")
+				i++
+			case isASCIILetter(next):
+				j := i + 1
+				for j < len(data) && isASCIILetter(data[j]) {
+					j++
+				}
+				word := string(data[i+1 : j])
+				k := j
+				if k < len(data) && data[k] == '-' {
+					k++
+				}
+				for k < len(data) && data[k] >= '0' && data[k] <= '9' {
+					k++
+				}
+				param, err := strconv.Atoi(string(data[j:k]))
+				hasParam := err == nil
+				if k < len(data) && data[k] == ' ' {
+					k++
+				}
+				i = k - 1
+
+				switch {
+				case rtfSkippedDestinations[word]:
+					state.skip = true
+				case word == "par" || word == "line" || word == "row" || word == "sect" || word == "page":
+					write("\n")
+				case word == "tab":
+					write("\t")
+				case word == "cell":
+					write(" | ")
+				case word == "ansicpg":
+					if enc, ok := rtfCodePages[param]; ok {
+						flush()
+						codePage = enc
+					}
+				case word == "uc" && hasParam:
+					state.unicodeSkip = param
+				case word == "u" && hasParam:
+					if param < 0 {
+						param += 65536
+					}
+					write(string(rune(param)))
+					fallback = state.unicodeSkip
+				case word == "bin" && hasParam:
+					i += param
+				}
+			default:
+				i++
+			}
+		default:
+			writeByte(c)
+		}
+	}
+	flush()
+
+	return &ExtractedText{Text: tidyLines(text.String())}, nil
+}
+
+// isASCIILetter reports whether b is an ASCII letter (RTF control words are made of them)
+func isASCIILetter(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z'
+}
@@ -0,0 +1,330 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net"
+	"strings"
+	"time"
+
+	"chatbot/config"
+
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"github.com/sashabaranov/go-openai"
+)
+
+// ========================================
+// Provider Failover and Retry
+// ========================================
+// FailoverStreamer wraps StreamingChatService.CreateStreamingChat with retries
+// (exponential backoff + jitter) and an ordered list of fallback providers.
+// Fallbacks only replace a provider picked by the persona or the endpoint: a
+// provider or model the client asked for itself (StreamingChatRequest.PinProvider)
+// is retried but never swapped, and neither is the local LLM, whose requests
+// must not leave the machine. A stream only counts
+// as opened once its first chunk or reasoning arrives, so failover happens
+// before anything has been sent to the client.
+
+// ErrorClass tells the failover policy how to react to a provider error
+type ErrorClass int
+
+const (
+	// ErrorClassFatal stops immediately (bad request, cancelled context)
+	ErrorClassFatal ErrorClass = iota
+	// ErrorClassRetryable retries the same provider with backoff (429, 5xx, throttling, network)
+	ErrorClassRetryable
+	// ErrorClassFailover skips straight to the next provider (auth, unknown model, unknown error)
+	ErrorClassFailover
+)
+
+// String returns the error class name for logging
+func (c ErrorClass) String() string {
+	switch c {
+	case ErrorClassFatal:
+		return "fatal"
+	case ErrorClassRetryable:
+		return "retryable"
+	default:
+		return "failover"
+	}
+}
+
+// ClassifyProviderError classifies OpenAI, Bedrock and network errors
+func ClassifyProviderError(err error) ErrorClass {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return ErrorClassFatal
+	}
+
+	// OpenAI errors carry the HTTP status code
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) {
+		return classifyHTTPStatus(apiErr.HTTPStatusCode)
+	}
+	var reqErr *openai.RequestError
+	if errors.As(err, &reqErr) {
+		return classifyHTTPStatus(reqErr.HTTPStatusCode)
+	}
+
+	// Local LLM server errors
+	var localErr *LocalLLMError
+	if errors.As(err, &localErr) {
+		return classifyHTTPStatus(localErr.StatusCode)
+	}
+
+	// Bedrock modeled exceptions
+	var (
+		throttling   *types.ThrottlingException
+		unavailable  *types.ServiceUnavailableException
+		internal     *types.InternalServerException
+		timeout      *types.ModelTimeoutException
+		notReady     *types.ModelNotReadyException
+		streamErr    *types.ModelStreamErrorException
+		validation   *types.ValidationException
+		accessDenied *types.AccessDeniedException
+		notFound     *types.ResourceNotFoundException
+	)
+	switch {
+	case errors.As(err, &throttling), errors.As(err, &unavailable), errors.As(err, &internal),
+		errors.As(err, &timeout), errors.As(err, &notReady), errors.As(err, &streamErr):
+		return ErrorClassRetryable
+	case errors.As(err, &validation):
+		return ErrorClassFatal
+	case errors.As(err, &accessDenied), errors.As(err, &notFound):
+		return ErrorClassFailover
+	}
+
+	// Other AWS HTTP errors
+	var respErr *awshttp.ResponseError
+	if errors.As(err, &respErr) {
+		return classifyHTTPStatus(respErr.HTTPStatusCode())
+	}
+
+	// Network errors (connection reset, timeout, DNS)
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return ErrorClassRetryable
+	}
+
+	return ErrorClassFailover
+}
+
+// classifyHTTPStatus maps an HTTP status code to an error class
+func classifyHTTPStatus(status int) ErrorClass {
+	switch {
+	case status == 408 || status == 409 || status == 429 || status >= 500:
+		return ErrorClassRetryable
+	case status == 400 || status == 422:
+		return ErrorClassFatal
+	default:
+		return ErrorClassFailover
+	}
+}
+
+// FailoverPolicy configures retries and fallback providers
+type FailoverPolicy struct {
+	Providers  []string      // Fallback providers, tried in order after the requested one
+	MaxRetries int           // Retries per provider for retryable errors
+	BaseDelay  time.Duration // First backoff delay
+	MaxDelay   time.Duration // Backoff cap
+}
+
+// NewFailoverPolicyFromConfig builds the failover policy from config
+func NewFailoverPolicyFromConfig(cfg *config.Config) FailoverPolicy {
+	providers := make([]string, 0)
+	for _, name := range strings.Split(cfg.FailoverProviders, ",") {
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+			providers = append(providers, name)
+		}
+	}
+
+	return FailoverPolicy{
+		Providers:  providers,
+		MaxRetries: cfg.FailoverMaxRetries,
+		BaseDelay:  time.Duration(cfg.FailoverBaseDelayMs) * time.Millisecond,
+		MaxDelay:   time.Duration(cfg.FailoverMaxDelayMs) * time.Millisecond,
+	}
+}
+
+// backoff returns the delay before retry number n (0-based) with jitter in [d/2, d]
+func (p FailoverPolicy) backoff(n int) time.Duration {
+	delay := p.BaseDelay << n
+	if p.MaxDelay > 0 && (delay > p.MaxDelay || delay <= 0) {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// FailoverResult reports which provider and model answered and how many attempts it took
+type FailoverResult struct {
+	Provider string
+	Model    string
+	Attempts int
+}
+
+// FailoverStreamer opens streams with retry and provider failover
+type FailoverStreamer struct {
+	registry *ProviderRegistry
+	policy   FailoverPolicy
+}
+
+// NewFailoverStreamer creates a new failover streamer
+func NewFailoverStreamer(registry *ProviderRegistry, policy FailoverPolicy) *FailoverStreamer {
+	return &FailoverStreamer{
+		registry: registry,
+		policy:   policy,
+	}
+}
+
+// CreateStreamingChat opens a stream on the requested provider (auto-detect if empty),
+// retrying it until it produces a first chunk. Unless req pins the provider or the provider
+// is registered as Local, it then fails over to the policy's providers with their default model.
+func (f *FailoverStreamer) CreateStreamingChat(ctx context.Context, provider string, req StreamingChatRequest) (StreamReader, *FailoverResult, error) {
+	primary, err := f.registry.Resolve(provider)
+	if err != nil {
+		return nil, &FailoverResult{}, err
+	}
+
+	candidates := []string{primary.GetProviderName()}
+	capabilities, _ := f.registry.Capabilities(primary.GetProviderName())
+	if !req.PinProvider && !capabilities.Local {
+		candidates = f.candidates(primary.GetProviderName())
+	}
+	result := &FailoverResult{}
+	var lastErr error
+
+	for i, name := range candidates {
+		service, err := f.registry.Get(name)
+		if err != nil {
+			continue
+		}
+
+		// The persona's model belongs to the primary provider; fallbacks use their default
+		attemptReq := req
+		if i > 0 {
+			attemptReq.Model = ""
+			log.Printf("⚠️  Failing over to provider %s", name)
+		}
+
+		for retry := 0; retry <= f.policy.MaxRetries; retry++ {
+			result.Attempts++
+			result.Provider = name
+			result.Model = ResolvedModel(service, attemptReq.Model)
+
+			stream, err := openStream(ctx, service, attemptReq)
+			if err == nil {
+				return stream, result, nil
+			}
+			lastErr = err
+
+			class := ClassifyProviderError(err)
+			log.Printf("⚠️  Provider %s attempt %d failed (%s): %v", name, result.Attempts, class, err)
+
+			if class == ErrorClassFatal {
+				return nil, result, err
+			}
+			if class == ErrorClassFailover || retry == f.policy.MaxRetries {
+				break
+			}
+
+			select {
+			case <-ctx.Done():
+				return nil, result, ctx.Err()
+			case <-time.After(f.policy.backoff(retry)):
+			}
+		}
+	}
+
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no provider available")
+	}
+	return nil, result, fmt.Errorf("all providers failed after %d attempts: %w", result.Attempts, lastErr)
+}
+
+// candidates returns the primary provider followed by the fallbacks, without duplicates
+func (f *FailoverStreamer) candidates(primary string) []string {
+	names := []string{primary}
+	seen := map[string]bool{primary: true}
+	for _, name := range f.policy.Providers {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// openStream creates a stream and waits for its first output (a non-empty chunk or reasoning),
+// so errors that only surface on the first Recv can still be retried
+func openStream(ctx context.Context, service StreamingChatService, req StreamingChatRequest) (StreamReader, error) {
+	stream, err := service.CreateStreamingChat(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			return &peekedStream{StreamReader: stream, eof: true}, nil
+		}
+		if err != nil {
+			stream.Close()
+			return nil, err
+		}
+		reasoning := TakeStreamReasoning(stream)
+		if chunk != "" || reasoning != "" {
+			return &peekedStream{StreamReader: stream, first: chunk, reasoning: reasoning, peeked: true}, nil
+		}
+	}
+}
+
+// peekedStream replays the output read by openStream before reading from the underlying stream
+type peekedStream struct {
+	StreamReader
+	first     string
+	reasoning string // Reasoning received with first, returned by the next TakeReasoning
+	peeked    bool   // first has not been returned yet
+	eof       bool
+}
+
+// Recv returns the peeked chunk first, then delegates to the underlying stream
+func (s *peekedStream) Recv() (string, error) {
+	if s.peeked {
+		s.peeked = false
+		return s.first, nil
+	}
+	if s.eof {
+		return "", io.EOF
+	}
+	return s.StreamReader.Recv()
+}
+
+// Usage delegates to the underlying stream
+func (s *peekedStream) Usage() *TokenUsage {
+	return StreamUsage(s.StreamReader)
+}
+
+// ToolCalls delegates to the underlying stream
+func (s *peekedStream) ToolCalls() []ToolCall {
+	return StreamToolCalls(s.StreamReader)
+}
+
+// TakeReasoning returns the peeked reasoning, then delegates to the underlying stream
+func (s *peekedStream) TakeReasoning() string {
+	reasoning := s.reasoning
+	s.reasoning = ""
+	return reasoning + TakeStreamReasoning(s.StreamReader)
+}
+
+// Reasoning delegates to the underlying stream
+func (s *peekedStream) Reasoning() []ReasoningBlock {
+	return StreamReasoning(s.StreamReader)
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"chatbot/models"
+)
+
+// ========================================
+// File Citations
+// ========================================
+// Files attached to a question are numbered in the file context ("File 1")
+// and their text keeps the page, slide and sheet headers written by the
+// extractors. The model is asked to cite the passages it relies on as
+// [File 1, page 3: "quote"]; FileCitations finds those citations in the
+// answer and looks each quote up in the file text, so the page or sheet of a
+// quote that is found comes from the document rather than from the model.
+
+// citationInstructions tells the model how to cite the files of the file context
+const citationInstructions = `📌 Citations: Cite the passages you rely on right after the sentence they support, as [File N, page P: "exact quote"]. ` +
+	`Use "slide S" for slides and "sheet NAME" for spreadsheet sheets (from the "--- Page N ---", "--- Slide N ---" and "=== Sheet: NAME ===" headers), ` +
+	`and leave the page out for files without them: [File N: "exact quote"]. Quote a short span copied word for word from the file.`
+
+var (
+	// citationMarker matches a citation of the file context in an answer
+	citationMarker = regexp.MustCompile(`(?i)\[File (\d+)(?:,\s*(page|slide|sheet)\s+([^:\]]+?))?:\s*["“]([^"”\]]+)["”]\]`)
+	// sheetMarker matches the sheet headers of spreadsheets written by their extractor
+	sheetMarker = regexp.MustCompile(`(?m)^=== Sheet: (.+) ===$`)
+)
+
+// FileCitations returns the citations of the file parts (numbered from 1 as in FormatFileContext) found in an answer
+// A file cited more than once with the same quote is returned once
+func FileCitations(fileParts []ContentPart, answer string) []models.MessageCitation {
+	var citations []models.MessageCitation
+	seen := make(map[string]bool)
+
+	for _, match := range citationMarker.FindAllStringSubmatch(answer, -1) {
+		number, _ := strconv.Atoi(match[1])
+		if number < 1 || number > len(fileParts) {
+			continue
+		}
+		part := fileParts[number-1]
+		quote := strings.TrimSpace(match[4])
+		key := part.FileID + "\x00" + quote
+		if quote == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		citation := models.MessageCitation{
+			FileID:   part.FileID,
+			FileName: part.FileName,
+			Quote:    quote,
+		}
+		if offset := findQuote(part.Text, quote); offset >= 0 {
+			citation.Verified = true
+			citation.Page = pageAt(part.Text, offset)
+			citation.Sheet = sheetAt(part.Text, offset)
+		} else if strings.EqualFold(match[2], "sheet") {
+			citation.Sheet = strings.TrimSpace(match[3])
+		} else {
+			citation.Page, _ = strconv.Atoi(strings.TrimSpace(match[3]))
+		}
+		citations = append(citations, citation)
+	}
+	return citations
+}
+
+// findQuote returns the byte offset of quote in text ignoring case and whitespace differences (-1 = not found)
+func findQuote(text, quote string) int {
+	needle := strings.ToLower(strings.Join(strings.Fields(quote), " "))
+	if needle == "" {
+		return -1
+	}
+
+	// Fold text the same way, remembering where each folded byte came from
+	var folded strings.Builder
+	offsets := make([]int, 0, len(text))
+	space := false
+	for i, r := range text {
+		if unicode.IsSpace(r) {
+			space = true
+			continue
+		}
+		if space && folded.Len() > 0 {
+			folded.WriteByte(' ')
+			offsets = append(offsets, i)
+		}
+		space = false
+
+		lower := string(unicode.ToLower(r))
+		folded.WriteString(lower)
+		for range len(lower) {
+			offsets = append(offsets, i)
+		}
+	}
+
+	at := strings.Index(folded.String(), needle)
+	if at < 0 {
+		return -1
+	}
+	return offsets[at]
+}
+
+// pageAt returns the page or slide of the header before offset (0 = not paged)
+func pageAt(text string, offset int) int {
+	page := 0
+	for _, marker := range pageMarker.FindAllStringSubmatchIndex(text, -1) {
+		if marker[0] > offset {
+			break
+		}
+		page, _ = strconv.Atoi(text[marker[2]:marker[3]])
+	}
+	return page
+}
+
+// sheetAt returns the sheet of the header before offset ("" = not a spreadsheet)
+func sheetAt(text string, offset int) string {
+	sheet := ""
+	for _, marker := range sheetMarker.FindAllStringSubmatchIndex(text, -1) {
+		if marker[0] > offset {
+			break
+		}
+		sheet = text[marker[2]:marker[3]]
+	}
+	return sheet
+}
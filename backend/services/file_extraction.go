@@ -0,0 +1,167 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"chatbot/models"
+	"chatbot/repositories"
+)
+
+// ========================================
+// File Text Extraction
+// ========================================
+// The text of an uploaded file is extracted once, in the background right
+// after upload, and stored on its file_analyses row together with its page
+// count, language and extraction status. Chat turns (ContextService),
+// knowledge base indexing and document analysis (FileService) read the
+// stored text instead of parsing the file again; a file whose extraction has
+// not finished yet is waited for (and extracted then if it never started).
+// Formats are read by the extractors of the extractor registry (see
+// RegisterExtractor), scanned pages and images with OCR (see UseOCR) — here,
+// and for files sent directly to document analysis, bounded by that request.
+
+// extractionTimeout is the longest the extraction of one file may take, OCR included
+const extractionTimeout = 10 * time.Minute
+
+// extractionWait is how long Text waits for a file being extracted before leaving it out
+const extractionWait = 15 * time.Second
+
+// ErrExtractionPending is returned by Text for a file whose extraction has not finished in time
+var ErrExtractionPending = errors.New("file is still being processed")
+
+// FileExtractionService extracts the text of uploaded files and stores it on their records
+// One service is shared by every caller so a file is never extracted twice at the same time
+type FileExtractionService struct {
+	repo    *repositories.FileAnalysisRepository
+	running sync.Map // File ID -> *extractionJob being extracted in the background
+}
+
+// extractionJob is the background extraction of one file
+type extractionJob struct {
+	file *models.FileAnalysis // Copy of the record the job extracts
+	done chan struct{}        // Closed once file holds the result
+}
+
+// NewFileExtractionService creates a new file extraction service
+func NewFileExtractionService(repo *repositories.FileAnalysisRepository) *FileExtractionService {
+	return &FileExtractionService{repo: repo}
+}
+
+// Extract extracts the text of an uploaded file in the background
+func (s *FileExtractionService) Extract(file models.FileAnalysis) {
+	s.start(&file)
+}
+
+// ResumeExtraction extracts, one after another in the background, the files left pending by a
+// restart or uploaded before extracted text was stored
+func (s *FileExtractionService) ResumeExtraction() {
+	files, err := s.repo.PendingExtraction()
+	if err != nil {
+		log.Printf("⚠️  Failed to load files to extract: %v", err)
+		return
+	}
+	if len(files) == 0 {
+		return
+	}
+
+	go func() {
+		for i := range files {
+			<-s.start(&files[i]).done
+		}
+		log.Printf("📄 Extracted the text of %d earlier uploads", len(files))
+	}()
+}
+
+// Text returns the extracted text of a file, waiting for its extraction when that has not finished yet
+// Returns ErrExtractionPending when it takes longer than extractionWait, and the extraction error
+// of files whose text could not be extracted
+func (s *FileExtractionService) Text(file *models.FileAnalysis) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), extractionWait)
+	defer cancel()
+
+	text, err := s.Wait(ctx, file)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return "", ErrExtractionPending
+	}
+	return text, err
+}
+
+// Wait is Text for background work: it waits for the extraction of a file for as long as ctx allows
+// and returns the context's error when that ends first
+func (s *FileExtractionService) Wait(ctx context.Context, file *models.FileAnalysis) (string, error) {
+	if !file.Extracted() {
+		job := s.start(file)
+		select {
+		case <-job.done:
+			*file = *job.file
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+	if file.ExtractionStatus == models.ExtractionStatusFailed {
+		return "", fmt.Errorf("%s", file.ExtractionError)
+	}
+	return file.ExtractedText, nil
+}
+
+// start extracts a copy of file in the background, or returns the job already extracting it
+func (s *FileExtractionService) start(file *models.FileAnalysis) *extractionJob {
+	record := *file
+	job := &extractionJob{file: &record, done: make(chan struct{})}
+	if running, loaded := s.running.LoadOrStore(file.ID, job); loaded {
+		return running.(*extractionJob)
+	}
+
+	go func() {
+		defer close(job.done)
+		defer s.running.Delete(record.ID)
+		s.extract(job.file)
+	}()
+	return job
+}
+
+// extract reads and extracts a file, storing the text or the error on the file and its record
+func (s *FileExtractionService) extract(file *models.FileAnalysis) {
+	if err := s.repo.SetExtractionStatus(file.ID, models.ExtractionStatusProcessing); err != nil {
+		log.Printf("⚠️  Failed to update extraction status of %s: %v", file.FileName, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), extractionTimeout)
+	defer cancel()
+
+	data, err := os.ReadFile(file.StoragePath)
+	var result *ExtractedText
+	if err == nil {
+		result, err = ExtractTextWithOCR(ctx, data, file.MimeType, file.FileName)
+	}
+
+	now := time.Now()
+	file.ExtractedAt = &now
+	file.ExtractedText, file.PageCount, file.Language, file.ExtractionError, file.OCRPages = "", 0, "", "", nil
+	switch {
+	case err != nil:
+		file.ExtractionStatus = models.ExtractionStatusFailed
+		file.ExtractionError = err.Error()
+		log.Printf("⚠️  Failed to extract text of %s: %v", file.FileName, err)
+	case result == nil:
+		file.ExtractionStatus = models.ExtractionStatusSkipped
+	default:
+		file.ExtractionStatus = models.ExtractionStatusReady
+		file.ExtractedText = result.Text
+		file.PageCount = result.PageCount
+		file.Language = result.Language
+		if err := file.SetOCRPages(result.OCRPages); err != nil {
+			log.Printf("⚠️  Failed to set OCR pages of %s: %v", file.FileName, err)
+		}
+	}
+
+	if err := s.repo.SaveExtraction(file); err != nil {
+		log.Printf("⚠️  Failed to save extracted text of %s: %v", file.FileName, err)
+	}
+}
@@ -1,62 +1,101 @@
 package services
 
 import (
-	"bytes"
 	"context"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"io"
 	"mime/multipart"
-	"os"
 	"strings"
 	"time"
 
-	"github.com/beevik/etree"
-	"github.com/ledongthuc/pdf"
-	"github.com/nguyenthenguyen/docx"
+	"chatbot/repositories"
+
 	"github.com/sashabaranov/go-openai"
-	"github.com/xuri/excelize/v2"
 )
 
 // FileService handles file analysis operations
 type FileService struct {
 	openaiClient   *openai.Client
+	provider       StreamingChatService // Provider for document analysis
 	contextService *ContextService
+	files          *repositories.FileAnalysisRepository
+	extraction     *FileExtractionService
+	structured     *StructuredOutputService
+	catalog        *ModelCatalog
+	model          string // Analysis model (the OpenAI provider's default)
 }
 
 // NewFileService creates a new file service
-func NewFileService(client *openai.Client, contextService *ContextService) *FileService {
+func NewFileService(openaiService *OpenAIService, contextService *ContextService, files *repositories.FileAnalysisRepository, extraction *FileExtractionService, structured *StructuredOutputService, catalog *ModelCatalog) *FileService {
 	return &FileService{
-		openaiClient:   client,
+		openaiClient:   openaiService.GetClient(),
+		provider:       openaiService,
 		contextService: contextService,
+		files:          files,
+		extraction:     extraction,
+		structured:     structured,
+		catalog:        catalog,
+		model:          openaiService.DefaultModel(),
 	}
 }
 
 // FileAnalysisRequest represents a file analysis request
 type FileAnalysisRequest struct {
 	File         *multipart.FileHeader
+	FileID       string // Uploaded file to analyze instead of File (reads its extracted text)
 	AnalysisType string // summary, detail, qa, extract
 	Prompt       string
 	Language     string // th, en
 	SystemPrompt string // Optional custom system prompt
 	SessionID    string // Session ID for conversation history
 	UseHistory   bool   // Include conversation history in analysis
+
+	ResponseSchema *ResponseSchema // Optional custom output schema (replaces the default analysis fields)
 }
 
 // FileAnalysisResponse represents the analysis response
 type FileAnalysisResponse struct {
-	FileID      string    `json:"file_id"`
-	FileName    string    `json:"filename"`
-	FileType    string    `json:"file_type"`
-	FileSize    int64     `json:"file_size"`
-	Analysis    string    `json:"analysis"`
-	KeyPoints   []string  `json:"key_points"`
-	Entities    []string  `json:"entities,omitempty"`
-	Sentiment   string    `json:"sentiment,omitempty"`
-	Language    string    `json:"language"`
-	TokensUsed  int       `json:"tokens_used"`
-	ProcessTime float64   `json:"process_time_ms"`
-	Timestamp   time.Time `json:"timestamp"`
+	FileID      string          `json:"file_id"`
+	FileName    string          `json:"filename"`
+	FileType    string          `json:"file_type"`
+	FileSize    int64           `json:"file_size"`
+	Analysis    string          `json:"analysis"`
+	KeyPoints   []string        `json:"key_points"`
+	Entities    []string        `json:"entities,omitempty"`
+	Sentiment   string          `json:"sentiment,omitempty"`
+	Structured  json.RawMessage `json:"structured,omitempty"` // Output of a custom ResponseSchema
+	Language    string          `json:"language"`
+	TokensUsed  int             `json:"tokens_used"`
+	ProcessTime float64         `json:"process_time_ms"`
+	Timestamp   time.Time       `json:"timestamp"`
+}
+
+// fileAnalysisSchema is the structured output of a default file analysis
+var fileAnalysisSchema = ResponseSchema{
+	Name:        "file_analysis",
+	Description: "Analysis of the document",
+	Strict:      true,
+	Schema: json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"analysis": {"type": "string", "description": "The requested analysis, in the requested language"},
+			"key_points": {"type": "array", "items": {"type": "string"}, "description": "Up to 5 key points"},
+			"entities": {"type": "array", "items": {"type": "string"}, "description": "Named entities: people, organizations, places, dates, amounts"},
+			"sentiment": {"type": "string", "enum": ["positive", "neutral", "negative", "mixed"]}
+		},
+		"required": ["analysis", "key_points", "entities", "sentiment"],
+		"additionalProperties": false
+	}`),
+}
+
+// fileAnalysisOutput mirrors fileAnalysisSchema
+type fileAnalysisOutput struct {
+	Analysis  string   `json:"analysis"`
+	KeyPoints []string `json:"key_points"`
+	Entities  []string `json:"entities"`
+	Sentiment string   `json:"sentiment"`
 }
 
 // Supported file types with their MIME types and max sizes
@@ -139,8 +178,16 @@ func (s *FileService) ValidateFile(file *multipart.FileHeader) error {
 		}
 	}
 
+	// Any other format with a registered extractor (epub, odt, rtf, ...)
+	if !found {
+		if _, _, ok := ExtractorFor(contentType, file.Filename); ok {
+			maxSize = 25 * 1024 * 1024
+			found = true
+		}
+	}
+
 	if !found {
-		return fmt.Errorf("unsupported file type: %s (allowed: pdf, docx, xlsx, txt, png, jpg, json, etc)", contentType)
+		return fmt.Errorf("unsupported file type: %s (allowed: pdf, docx, xlsx, pptx, txt, png, jpg, json, etc)", contentType)
 	}
 
 	if file.Size > maxSize {
@@ -154,175 +201,175 @@ func (s *FileService) ValidateFile(file *multipart.FileHeader) error {
 func (s *FileService) AnalyzeFile(ctx context.Context, req FileAnalysisRequest) (*FileAnalysisResponse, error) {
 	startTime := time.Now()
 
-	// Debug: Log request parameters
-	fmt.Printf("\n🔍 === FILE ANALYSIS REQUEST ===\n")
-	fmt.Printf("   Filename: %s\n", req.File.Filename)
-	fmt.Printf("   Analysis Type: %s\n", req.AnalysisType)
-	fmt.Printf("   Language: %s\n", req.Language)
-	fmt.Printf("   Session ID: %s\n", req.SessionID)
-	fmt.Printf("   Use History: %v\n", req.UseHistory)
-	fmt.Printf("   Has System Prompt: %v\n", req.SystemPrompt != "")
-	fmt.Printf("   Has Custom Prompt: %v\n", req.Prompt != "")
-	fmt.Printf("================================\n\n")
-
-	// Validate file
-	if err := s.ValidateFile(req.File); err != nil {
-		return nil, err
-	}
-
-	// Open file
-	fileData, err := req.File.Open()
+	// Get the document text: stored at upload, or extracted from the file sent
+	doc, err := s.documentText(ctx, req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open file: %w", err)
-	}
-	defer fileData.Close()
-
-	// Extract text from file based on type
-	text, err := s.extractText(fileData, req.File)
-	if err != nil {
-		return nil, fmt.Errorf("failed to extract text from file: %w", err)
+		return nil, err
 	}
-
-	// Debug: Log extracted text length
-	fmt.Printf("📄 File: %s (Size: %d bytes)\n", req.File.Filename, req.File.Size)
-	fmt.Printf("📝 Extracted text length: %d characters\n", len(text))
-	if len(text) > 0 {
-		// Show first 100 chars of extracted text
-		preview := text
-		if len(preview) > 100 {
-			preview = preview[:100] + "..."
-		}
-		fmt.Printf("📋 Text preview: %s\n", preview)
-	} else {
-		fmt.Printf("⚠️  WARNING: Extracted text is EMPTY!\n")
+	text := doc.text
+	if strings.TrimSpace(text) == "" {
+		return nil, fmt.Errorf("no text could be extracted from %s", doc.fileName)
 	}
 
 	// If text is too long, chunk it
 	if len(text) > 15000 {
 		text = text[:15000] + "\n\n... (truncated)"
-		fmt.Printf("✂️  Text truncated to 15000 characters\n")
 	}
 
 	// Build analysis prompt
 	prompt := s.buildAnalysisPrompt(req.AnalysisType, req.Prompt, req.Language, text)
-	fmt.Printf("📤 Final prompt length: %d characters\n", len(prompt))
 
-	// Build messages array with optional system prompt
-	var messages []openai.ChatCompletionMessage
+	// Build system prompt (use custom or default)
+	systemPrompt := req.SystemPrompt
+	if systemPrompt == "" {
+		systemPrompt = "You are a document analysis expert. Provide clear, accurate, and structured analysis."
+	}
 
 	// If UseHistory is enabled and SessionID is provided, build context with conversation history
+	conversation := NewSimpleConversation(systemPrompt, prompt)
 	if req.UseHistory && req.SessionID != "" && s.contextService != nil {
-		// Build system prompt (use custom or default)
-		systemPrompt := req.SystemPrompt
-		if systemPrompt == "" {
-			systemPrompt = "You are a document analysis expert. Provide clear, accurate, and structured analysis."
+		var historyConversation *Conversation
+		parentID, err := s.contextService.ActiveParent(req.SessionID, "")
+		if err == nil {
+			historyConversation, err = s.contextService.BuildConversation(
+				req.SessionID,
+				parentID,
+				systemPrompt,
+				prompt,
+				DefaultHistoryLimit,
+				nil,
+			)
 		}
-
-		// Use context service to build messages with history
-		var err error
-		messages, err = s.contextService.BuildContextWithHistory(
-			req.SessionID,
-			systemPrompt,
-			prompt,
-			10, // last 10 messages
-		)
 		if err != nil {
 			fmt.Printf("⚠️  Failed to build context with history: %v, falling back to simple context\n", err)
-			// Fallback to simple context
-			messages = s.buildSimpleContext(req.SystemPrompt, prompt)
+		} else {
+			conversation = historyConversation
 		}
-	} else {
-		// Build simple context without history
-		messages = s.buildSimpleContext(req.SystemPrompt, prompt)
 	}
 
-	// Call OpenAI API
-	chatResp, err := s.openaiClient.CreateChatCompletion(
-		ctx,
-		openai.ChatCompletionRequest{
-			Model:       openai.GPT4oMini,
-			Messages:    messages,
-			Temperature: 0.7,
-			MaxTokens:   2000,
-		},
-	)
-
+	// Ask for structured output (custom schema or the default analysis schema)
+	schema := fileAnalysisSchema
+	if req.ResponseSchema != nil {
+		schema = *req.ResponseSchema
+	}
+	chatReq, err := s.catalog.Prepare(s.provider.GetProviderName(), StreamingChatRequest{
+		Messages:       conversation.Messages,
+		SystemPrompt:   conversation.SystemPrompt,
+		Model:          s.model,
+		Temperature:    0.7,
+		MaxTokens:      2000,
+		ResponseSchema: &schema,
+	})
+	if err != nil {
+		return nil, err
+	}
+	result, err := s.structured.Complete(ctx, s.provider, chatReq)
 	if err != nil {
 		return nil, fmt.Errorf("failed to analyze with OpenAI: %w", err)
 	}
 
-	// Parse response
-	analysis := chatResp.Choices[0].Message.Content
-
-	// Extract key points (simple parsing)
-	keyPoints := extractKeyPoints(analysis)
-
 	// Calculate process time
 	processTime := float64(time.Since(startTime).Milliseconds())
 
 	// Build response
 	response := &FileAnalysisResponse{
-		FileID:      generateFileID(),
-		FileName:    req.File.Filename,
-		FileType:    req.File.Header.Get("Content-Type"),
-		FileSize:    req.File.Size,
-		Analysis:    analysis,
-		KeyPoints:   keyPoints,
+		FileID:      doc.fileID,
+		FileName:    doc.fileName,
+		FileType:    doc.mimeType,
+		FileSize:    doc.fileSize,
+		KeyPoints:   []string{},
 		Language:    req.Language,
-		TokensUsed:  chatResp.Usage.TotalTokens,
+		TokensUsed:  result.Completion.TokensUsed,
 		ProcessTime: processTime,
 		Timestamp:   time.Now(),
 	}
 
-	return response, nil
-}
-
-// extractText extracts text from different file types
-func (s *FileService) extractText(file io.Reader, fileHeader *multipart.FileHeader) (string, error) {
-	contentType := fileHeader.Header.Get("Content-Type")
-	ext := getFileExtension(fileHeader.Filename)
-
-	// Read file data into memory for multiple parsing attempts
-	fileData, err := io.ReadAll(file)
-	if err != nil {
-		return "", fmt.Errorf("failed to read file data: %w", err)
+	if req.ResponseSchema != nil {
+		response.Analysis = string(result.Data)
+		response.Structured = result.Data
+		return response, nil
 	}
 
-	// Handle different file types
-	switch {
-	case strings.Contains(contentType, "application/pdf") || ext == ".pdf":
-		return s.extractPDFText(bytes.NewReader(fileData), int64(len(fileData)))
-
-	case strings.Contains(contentType, "application/vnd.openxmlformats-officedocument.wordprocessingml.document") || ext == ".docx":
-		return s.extractDocxText(bytes.NewReader(fileData), int64(len(fileData)))
+	var output fileAnalysisOutput
+	if err := json.Unmarshal(result.Data, &output); err != nil {
+		return nil, fmt.Errorf("failed to parse analysis: %w", err)
+	}
+	response.Analysis = output.Analysis
+	response.Entities = output.Entities
+	response.Sentiment = output.Sentiment
+	if output.KeyPoints != nil {
+		response.KeyPoints = output.KeyPoints
+	}
 
-	case strings.Contains(contentType, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet") || ext == ".xlsx":
-		return s.extractExcelText(bytes.NewReader(fileData))
+	return response, nil
+}
 
-	case strings.Contains(contentType, "application/vnd.openxmlformats-officedocument.presentationml.presentation") || ext == ".pptx":
-		return s.extractPptxText(bytes.NewReader(fileData))
+// analyzedDocument is the file an analysis is about and its text
+type analyzedDocument struct {
+	fileID   string
+	fileName string
+	mimeType string
+	fileSize int64
+	text     string
+}
 
-	case strings.Contains(contentType, "application/xml") || ext == ".xml":
-		return s.extractXMLText(fileData)
+// documentText returns the text of the file to analyze: the text extracted at upload for FileID,
+// otherwise the text extracted from File, with OCR for as long as ctx allows
+func (s *FileService) documentText(ctx context.Context, req FileAnalysisRequest) (*analyzedDocument, error) {
+	if req.FileID != "" {
+		file, err := s.files.FindByID(req.FileID)
+		if err != nil {
+			return nil, fmt.Errorf("file %s not found", req.FileID)
+		}
+		text, err := s.extraction.Text(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to extract text from file: %w", err)
+		}
+		return &analyzedDocument{
+			fileID:   file.ID.String(),
+			fileName: file.FileName,
+			mimeType: file.MimeType,
+			fileSize: file.FileSize,
+			text:     text,
+		}, nil
+	}
 
-	case strings.Contains(contentType, "text/csv") || ext == ".csv":
-		return string(fileData), nil
+	// Validate file
+	if err := s.ValidateFile(req.File); err != nil {
+		return nil, err
+	}
 
-	case strings.Contains(contentType, "application/json") || ext == ".json":
-		return string(fileData), nil
+	// Open file
+	fileData, err := req.File.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer fileData.Close()
 
-	case strings.Contains(contentType, "image/"):
-		// Images should be handled separately by Vision API
-		return fmt.Sprintf("[Image file: %s - use Vision API for analysis]", fileHeader.Filename), nil
+	data, err := io.ReadAll(fileData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file data: %w", err)
+	}
 
-	case strings.Contains(contentType, "text/") || isTextFile(ext):
-		// Plain text files
-		return string(fileData), nil
+	contentType := req.File.Header.Get("Content-Type")
+	doc := &analyzedDocument{
+		fileID:   generateFileID(),
+		fileName: req.File.Filename,
+		mimeType: contentType,
+		fileSize: req.File.Size,
+	}
 
-	default:
-		// Try to read as text
-		return string(fileData), nil
+	// Extract text from file based on type, reading scanned pages and images with OCR
+	ctx, cancel := context.WithTimeout(ctx, extractionTimeout)
+	defer cancel()
+	extracted, err := ExtractTextWithOCR(ctx, data, contentType, req.File.Filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract text from file: %w", err)
+	}
+	if extracted != nil {
+		doc.text = extracted.Text
 	}
+	return doc, nil
 }
 
 // AnalyzeImage analyzes an image file using OpenAI Vision API
@@ -383,7 +430,7 @@ func (s *FileService) AnalyzeImage(ctx context.Context, file io.Reader, filename
 	resp, err := s.openaiClient.CreateChatCompletion(
 		ctx,
 		openai.ChatCompletionRequest{
-			Model:     openai.GPT4oMini,
+			Model:     s.catalog.VisionModel(s.provider.GetProviderName(), s.model),
 			Messages:  messages,
 			MaxTokens: 1000,
 		},
@@ -450,28 +497,6 @@ func (s *FileService) buildAnalysisPrompt(analysisType, customPrompt, language,
 	return prompt.String()
 }
 
-// buildSimpleContext builds a simple message context without history
-func (s *FileService) buildSimpleContext(systemPrompt, userPrompt string) []openai.ChatCompletionMessage {
-	messages := []openai.ChatCompletionMessage{}
-
-	// Add system prompt (use custom or default)
-	if systemPrompt == "" {
-		systemPrompt = "You are a document analysis expert. Provide clear, accurate, and structured analysis."
-	}
-	messages = append(messages, openai.ChatCompletionMessage{
-		Role:    openai.ChatMessageRoleSystem,
-		Content: systemPrompt,
-	})
-
-	// Add user message with analysis prompt
-	messages = append(messages, openai.ChatCompletionMessage{
-		Role:    openai.ChatMessageRoleUser,
-		Content: userPrompt,
-	})
-
-	return messages
-}
-
 // Helper functions
 
 func generateFileID() string {
@@ -487,6 +512,13 @@ func detectContentType(filename string) string {
 		".xml":  "application/xml",
 		".csv":  "text/csv",
 		".pdf":  "application/pdf",
+		".docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+		".xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+		".pptx": "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+		".odt":  "application/vnd.oasis.opendocument.text",
+		".epub": "application/epub+zip",
+		".rtf":  "application/rtf",
+		".html": "text/html",
 		".jpg":  "image/jpeg",
 		".jpeg": "image/jpeg",
 		".png":  "image/png",
@@ -521,200 +553,3 @@ func isTextFile(ext string) bool {
 	}
 	return false
 }
-
-func extractKeyPoints(text string) []string {
-	keyPoints := []string{}
-
-	// Simple extraction: look for bullet points or numbered lists
-	lines := strings.Split(text, "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "-") || strings.HasPrefix(line, "•") || strings.HasPrefix(line, "*") {
-			point := strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(strings.TrimPrefix(line, "-"), "•"), "*"))
-			if point != "" && len(point) > 10 {
-				keyPoints = append(keyPoints, point)
-			}
-		}
-	}
-
-	// Limit to 5 key points
-	if len(keyPoints) > 5 {
-		keyPoints = keyPoints[:5]
-	}
-
-	return keyPoints
-}
-
-// extractPDFText extracts text from PDF files
-func (s *FileService) extractPDFText(reader io.ReaderAt, size int64) (string, error) {
-	pdfReader, err := pdf.NewReader(reader, size)
-	if err != nil {
-		fmt.Printf("❌ Failed to open PDF: %v\n", err)
-		return "", fmt.Errorf("failed to open PDF: %w", err)
-	}
-
-	var text strings.Builder
-	numPages := pdfReader.NumPage()
-
-	fmt.Printf("📖 PDF has %d pages\n", numPages)
-
-	// Limit to first 50 pages to avoid excessive processing
-	maxPages := numPages
-	if maxPages > 50 {
-		maxPages = 50
-	}
-
-	successfulPages := 0
-	failedPages := 0
-
-	for pageNum := 1; pageNum <= maxPages; pageNum++ {
-		page := pdfReader.Page(pageNum)
-		if page.V.IsNull() {
-			fmt.Printf("⚠️  Page %d: V is null, skipping\n", pageNum)
-			failedPages++
-			continue
-		}
-
-		pageText, err := page.GetPlainText(nil)
-		if err != nil {
-			fmt.Printf("⚠️  Page %d: GetPlainText error: %v\n", pageNum, err)
-			failedPages++
-			continue
-		}
-
-		if len(pageText) > 0 {
-			text.WriteString(pageText)
-			text.WriteString("\n\n")
-			successfulPages++
-			fmt.Printf("✅ Page %d: Extracted %d characters\n", pageNum, len(pageText))
-		} else {
-			fmt.Printf("⚠️  Page %d: No text content\n", pageNum)
-			failedPages++
-		}
-	}
-
-	fmt.Printf("📊 PDF extraction summary: %d successful, %d failed out of %d pages\n", successfulPages, failedPages, maxPages)
-
-	if text.Len() == 0 {
-		fmt.Printf("❌ WARNING: No text extracted from PDF! Returning placeholder.\n")
-		return "[PDF file - no text content extracted]", nil
-	}
-
-	return text.String(), nil
-}
-
-// extractDocxText extracts text from Word (.docx) files
-func (s *FileService) extractDocxText(reader io.ReaderAt, size int64) (string, error) {
-	// Read data into memory and save to temp file (docx library needs file path)
-	data := make([]byte, size)
-	_, err := reader.ReadAt(data, 0)
-	if err != nil {
-		return "", fmt.Errorf("failed to read DOCX data: %w", err)
-	}
-
-	// Create a temporary file
-	tmpFile := fmt.Sprintf("temp_docx_%d.docx", time.Now().UnixNano())
-	defer func() {
-		// Clean up temp file (best effort)
-		_ = os.Remove(tmpFile)
-	}()
-
-	// Write to temp file
-	if err := os.WriteFile(tmpFile, data, 0644); err != nil {
-		return "", fmt.Errorf("failed to write temp file: %w", err)
-	}
-
-	// Read docx file
-	doc, err := docx.ReadDocxFile(tmpFile)
-	if err != nil {
-		return "", fmt.Errorf("failed to open DOCX: %w", err)
-	}
-	defer doc.Close()
-
-	var text strings.Builder
-
-	// Extract text content
-	editable := doc.Editable()
-	text.WriteString(editable.GetContent())
-
-	if text.Len() == 0 {
-		return "[Word document - no text content extracted]", nil
-	}
-
-	return text.String(), nil
-}
-
-// extractExcelText extracts text from Excel (.xlsx) files
-func (s *FileService) extractExcelText(reader io.Reader) (string, error) {
-	f, err := excelize.OpenReader(reader)
-	if err != nil {
-		return "", fmt.Errorf("failed to open Excel: %w", err)
-	}
-	defer f.Close()
-
-	var text strings.Builder
-
-	// Get all sheet names
-	sheets := f.GetSheetList()
-	for _, sheetName := range sheets {
-		text.WriteString(fmt.Sprintf("=== Sheet: %s ===\n", sheetName))
-
-		// Get all rows
-		rows, err := f.GetRows(sheetName)
-		if err != nil {
-			continue
-		}
-
-		for _, row := range rows {
-			if len(row) > 0 {
-				text.WriteString(strings.Join(row, " | "))
-				text.WriteString("\n")
-			}
-		}
-
-		text.WriteString("\n")
-	}
-
-	if text.Len() == 0 {
-		return "[Excel file - no data extracted]", nil
-	}
-
-	return text.String(), nil
-}
-
-// extractPptxText extracts text from PowerPoint (.pptx) files
-func (s *FileService) extractPptxText(reader io.Reader) (string, error) {
-	// PowerPoint files are zip archives containing XML files
-	// We'll read the archive and extract text from slide XML files
-	data, err := io.ReadAll(reader)
-	if err != nil {
-		return "", fmt.Errorf("failed to read PPTX data: %w", err)
-	}
-
-	// For now, return a placeholder
-	// Full PPTX parsing requires unzipping and parsing multiple XML files
-	return fmt.Sprintf("[PowerPoint file - %d bytes - advanced parsing not yet implemented]", len(data)), nil
-}
-
-// extractXMLText extracts and formats XML content
-func (s *FileService) extractXMLText(data []byte) (string, error) {
-	doc := etree.NewDocument()
-	if err := doc.ReadFromBytes(data); err != nil {
-		// If XML parsing fails, return as plain text
-		return string(data), nil
-	}
-
-	var text strings.Builder
-
-	// Pretty print XML
-	doc.Indent(2)
-	xmlString, err := doc.WriteToString()
-	if err != nil {
-		return string(data), nil
-	}
-
-	text.WriteString("=== XML Content ===\n")
-	text.WriteString(xmlString)
-
-	return text.String(), nil
-}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"math"
+
+	"chatbot/models"
+)
+
+// ========================================
+// Generation Settings Resolution
+// ========================================
+// Every chat endpoint resolves provider, model, temperature and max tokens the
+// same way: request override → persona → provider default. A model without a
+// provider selects the provider the catalog lists it under (e.g. a Claude
+// model ID routes to Bedrock). Zero temperature / max tokens leave the
+// provider's configured default in place. A provider or model the request
+// sets itself pins the request to its provider (no failover); one that comes
+// from the persona or the endpoint's fallback may fail over.
+
+// GenerationOverrides are the settings a chat request may set (zero values = not set)
+type GenerationOverrides struct {
+	Provider    string
+	Model       string
+	Temperature float64
+	MaxTokens   int
+}
+
+// GenerationSettings are the resolved settings for one chat request
+type GenerationSettings struct {
+	Provider    string  // Provider name ("" = auto-detect)
+	Model       string  // Model ID ("" = provider default)
+	Temperature float64 // 0 = provider default
+	MaxTokens   int     // 0 = provider default
+
+	ThinkingBudget int  // Persona's reasoning budget (0 = off)
+	PromptCaching  bool // Persona's prompt caching switch
+
+	Pinned bool // The request set the provider or model itself (see StreamingChatRequest.PinProvider)
+}
+
+// Apply copies the settings into a chat request
+func (s GenerationSettings) Apply(req StreamingChatRequest) StreamingChatRequest {
+	req.Model = s.Model
+	req.Temperature = s.Temperature
+	req.MaxTokens = s.MaxTokens
+	req.ThinkingBudget = s.ThinkingBudget
+	req.PromptCaching = s.PromptCaching
+	req.PinProvider = s.Pinned
+	return req
+}
+
+// ResolveGeneration resolves the settings of a chat request for persona (nil = no persona)
+// fallbackProvider is used when neither the request, the persona nor the model picks one
+func (c *ModelCatalog) ResolveGeneration(overrides GenerationOverrides, persona *models.Persona, fallbackProvider string) (GenerationSettings, error) {
+	settings := GenerationSettings{
+		Provider:    overrides.Provider,
+		Model:       overrides.Model,
+		Temperature: overrides.Temperature,
+		MaxTokens:   overrides.MaxTokens,
+		Pinned:      overrides.Provider != "" || overrides.Model != "",
+	}
+
+	// Provider: request → request model → persona → persona model → endpoint fallback
+	if settings.Provider == "" {
+		settings.Provider = c.ModelProvider(settings.Model)
+	}
+	if persona != nil {
+		if settings.Provider == "" {
+			settings.Provider = persona.Provider
+		}
+		if settings.Provider == "" {
+			settings.Provider = c.ModelProvider(persona.Model)
+		}
+	}
+	if settings.Provider == "" {
+		settings.Provider = fallbackProvider
+	}
+
+	// Model, temperature and max tokens: request → persona → provider default
+	// The persona's model is only used with a provider it belongs to
+	if persona != nil {
+		if settings.Model == "" && c.personaModelFits(persona, settings.Provider) {
+			settings.Model = persona.Model
+		}
+		if settings.Temperature == 0 {
+			settings.Temperature = math.Round(float64(persona.Temperature)*100) / 100
+		}
+		if settings.MaxTokens == 0 {
+			settings.MaxTokens = persona.MaxTokens
+		}
+		settings.ThinkingBudget = persona.ThinkingBudget
+		settings.PromptCaching = persona.PromptCaching
+	}
+
+	if settings.Provider != "" && c.providers != nil {
+		if _, err := c.providers.Resolve(settings.Provider); err != nil {
+			return settings, err
+		}
+	}
+	return settings, nil
+}
+
+// ModelProvider returns the provider the catalog lists model under ("" = unknown or not catalogued)
+func (c *ModelCatalog) ModelProvider(model string) string {
+	if model == "" {
+		return ""
+	}
+	if entry, ok := c.Find("", model); ok {
+		return entry.Provider
+	}
+	return ""
+}
+
+// personaModelFits reports whether the persona's model can be sent to provider
+func (c *ModelCatalog) personaModelFits(persona *models.Persona, provider string) bool {
+	if persona.Model == "" {
+		return false
+	}
+	if provider == "" {
+		return true
+	}
+	if owner := c.ModelProvider(persona.Model); owner != "" {
+		return owner == provider
+	}
+	// Uncatalogued model: only for the persona's own provider, or a provider the catalog does not cover
+	return persona.Provider == provider || (persona.Provider == "" && !c.covers(provider))
+}
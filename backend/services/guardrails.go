@@ -0,0 +1,34 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"chatbot/models"
+)
+
+// GuardrailInstructions renders a persona's guardrails as system prompt rules
+// Returns an empty string when no rule is enabled
+func GuardrailInstructions(guardrails models.Guardrails) string {
+	var rules []string
+	if len(guardrails.AllowedTopics) > 0 {
+		rules = append(rules, "Only discuss these topics: "+strings.Join(guardrails.AllowedTopics, ", ")+". Politely decline anything else.")
+	}
+	if len(guardrails.BlockedTopics) > 0 {
+		rules = append(rules, "Do not discuss these topics: "+strings.Join(guardrails.BlockedTopics, ", ")+".")
+	}
+	if guardrails.BlockProfanity {
+		rules = append(rules, "Never use profanity or offensive language.")
+	}
+	if guardrails.BlockSensitive {
+		rules = append(rules, "Do not ask for, repeat or reveal sensitive personal information (ID numbers, passwords, financial or health details).")
+	}
+	if guardrails.MaxResponseLength > 0 {
+		rules = append(rules, fmt.Sprintf("Keep every reply under %d characters.", guardrails.MaxResponseLength))
+	}
+	if len(rules) == 0 {
+		return ""
+	}
+
+	return "--- Guardrails ---\n- " + strings.Join(rules, "\n- ")
+}
@@ -0,0 +1,293 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// ========================================
+// Structured Output Schema
+// ========================================
+// ResponseSchema asks a provider for JSON that matches a JSON Schema:
+// OpenAI uses response_format json_schema, Claude a forced tool and Ollama
+// the format field. Replies are checked with ValidateJSONSchema, which
+// covers the subset of JSON Schema the providers themselves support.
+
+// ResponseSchema describes the structured output expected from the model
+type ResponseSchema struct {
+	Name        string          `json:"name"`                  // Identifier sent to the provider (letters, digits, _ and -)
+	Description string          `json:"description,omitempty"` // Optional hint for the model
+	Schema      json.RawMessage `json:"schema"`                // JSON Schema of the reply (object at the top level)
+	Strict      bool            `json:"strict,omitempty"`      // OpenAI strict mode (every property required, no additional properties)
+}
+
+var schemaNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// Check validates the schema definition itself; an empty name defaults to "response"
+func (s *ResponseSchema) Check() error {
+	if s.Name == "" {
+		s.Name = "response"
+	}
+	if !schemaNamePattern.MatchString(s.Name) {
+		return fmt.Errorf("schema name must be 1-64 letters, digits, _ or -")
+	}
+
+	var schema map[string]interface{}
+	if err := json.Unmarshal(s.Schema, &schema); err != nil || schema == nil {
+		return fmt.Errorf("schema must be a JSON object")
+	}
+	if t, ok := schema["type"]; ok && t != "object" {
+		return fmt.Errorf("schema type must be object")
+	}
+	return nil
+}
+
+// SchemaViolationError lists the places where a value does not match its schema
+type SchemaViolationError struct {
+	Problems []string
+}
+
+func (e *SchemaViolationError) Error() string {
+	return "JSON does not match schema: " + strings.Join(e.Problems, "; ")
+}
+
+// ValidateJSONSchema checks data against a JSON Schema
+// Supported keywords: type, properties, required, additionalProperties, items,
+// enum, const, anyOf, minItems, maxItems, minLength, maxLength, minimum, maximum
+func ValidateJSONSchema(schema json.RawMessage, data []byte) error {
+	var schemaValue map[string]interface{}
+	if err := json.Unmarshal(schema, &schemaValue); err != nil {
+		return fmt.Errorf("invalid schema: %w", err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return &SchemaViolationError{Problems: []string{"invalid JSON: " + err.Error()}}
+	}
+	if decoder.More() {
+		return &SchemaViolationError{Problems: []string{"invalid JSON: unexpected data after the value"}}
+	}
+
+	var problems []string
+	validateSchemaValue(schemaValue, value, "$", &problems)
+	if len(problems) > 0 {
+		return &SchemaViolationError{Problems: problems}
+	}
+	return nil
+}
+
+// validateSchemaValue appends the violations of value at path to problems
+func validateSchemaValue(schema map[string]interface{}, value interface{}, path string, problems *[]string) {
+	if branches, ok := schema["anyOf"].([]interface{}); ok {
+		matched := false
+		for _, branch := range branches {
+			branchSchema, _ := branch.(map[string]interface{})
+			var branchProblems []string
+			validateSchemaValue(branchSchema, value, path, &branchProblems)
+			if len(branchProblems) == 0 {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			*problems = append(*problems, fmt.Sprintf("%s does not match any allowed schema", path))
+			return
+		}
+	}
+
+	if types := schemaTypes(schema["type"]); len(types) > 0 && !matchesSchemaType(types, value) {
+		*problems = append(*problems, fmt.Sprintf("%s must be %s, got %s", path, strings.Join(types, " or "), jsonTypeName(value)))
+		return
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		allowed := false
+		for _, option := range enum {
+			if jsonEqual(option, value) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			*problems = append(*problems, fmt.Sprintf("%s must be one of %s", path, compactJSON(enum)))
+		}
+	}
+	if constant, ok := schema["const"]; ok && !jsonEqual(constant, value) {
+		*problems = append(*problems, fmt.Sprintf("%s must be %s", path, compactJSON(constant)))
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		validateSchemaObject(schema, v, path, problems)
+	case []interface{}:
+		if min, ok := schemaNumber(schema["minItems"]); ok && float64(len(v)) < min {
+			*problems = append(*problems, fmt.Sprintf("%s must have at least %v items", path, min))
+		}
+		if max, ok := schemaNumber(schema["maxItems"]); ok && float64(len(v)) > max {
+			*problems = append(*problems, fmt.Sprintf("%s must have at most %v items", path, max))
+		}
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				validateSchemaValue(items, item, fmt.Sprintf("%s[%d]", path, i), problems)
+			}
+		}
+	case string:
+		length := float64(utf8.RuneCountInString(v))
+		if min, ok := schemaNumber(schema["minLength"]); ok && length < min {
+			*problems = append(*problems, fmt.Sprintf("%s must be at least %v characters", path, min))
+		}
+		if max, ok := schemaNumber(schema["maxLength"]); ok && length > max {
+			*problems = append(*problems, fmt.Sprintf("%s must be at most %v characters", path, max))
+		}
+	case json.Number:
+		number, _ := v.Float64()
+		if min, ok := schemaNumber(schema["minimum"]); ok && number < min {
+			*problems = append(*problems, fmt.Sprintf("%s must be >= %v", path, min))
+		}
+		if max, ok := schemaNumber(schema["maximum"]); ok && number > max {
+			*problems = append(*problems, fmt.Sprintf("%s must be <= %v", path, max))
+		}
+	}
+}
+
+// validateSchemaObject checks required, properties and additionalProperties
+func validateSchemaObject(schema map[string]interface{}, object map[string]interface{}, path string, problems *[]string) {
+	if required, ok := schema["required"].([]interface{}); ok {
+		for _, name := range required {
+			key, _ := name.(string)
+			if _, present := object[key]; !present {
+				*problems = append(*problems, fmt.Sprintf("%s.%s is required", path, key))
+			}
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]interface{})
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if propertySchema, ok := properties[key].(map[string]interface{}); ok {
+			validateSchemaValue(propertySchema, object[key], path+"."+key, problems)
+			continue
+		}
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				*problems = append(*problems, fmt.Sprintf("%s.%s is not allowed", path, key))
+			}
+		case map[string]interface{}:
+			validateSchemaValue(additional, object[key], path+"."+key, problems)
+		}
+	}
+}
+
+// schemaTypes normalizes the type keyword ("string" or ["string", "null"])
+func schemaTypes(value interface{}) []string {
+	switch t := value.(type) {
+	case string:
+		return []string{t}
+	case []interface{}:
+		types := make([]string, 0, len(t))
+		for _, item := range t {
+			if name, ok := item.(string); ok {
+				types = append(types, name)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+func matchesSchemaType(types []string, value interface{}) bool {
+	actual := jsonTypeName(value)
+	for _, t := range types {
+		if t == actual {
+			return true
+		}
+		if t == "number" && actual == "integer" {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonTypeName returns the JSON Schema type of a decoded value (numbers decoded with UseNumber)
+func jsonTypeName(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		if f, err := v.Float64(); err == nil && f == math.Trunc(f) && !strings.ContainsAny(v.String(), ".eE") {
+			return "integer"
+		}
+		return "number"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// schemaNumber reads a numeric keyword from a schema decoded without UseNumber
+func schemaNumber(value interface{}) (float64, bool) {
+	number, ok := value.(float64)
+	return number, ok
+}
+
+// jsonEqual compares a schema value with a data value (numbers compared numerically)
+func jsonEqual(schemaValue, value interface{}) bool {
+	if number, ok := value.(json.Number); ok {
+		expected, isNumber := schemaValue.(float64)
+		actual, err := number.Float64()
+		return isNumber && err == nil && expected == actual
+	}
+	return compactJSON(schemaValue) == compactJSON(value)
+}
+
+func compactJSON(value interface{}) string {
+	data, _ := json.Marshal(value)
+	return string(data)
+}
+
+// ExtractJSON returns the JSON value in a model reply, dropping code fences or text around it
+func ExtractJSON(content string) string {
+	content = strings.TrimSpace(content)
+	if strings.HasPrefix(content, "```") {
+		content = strings.TrimPrefix(content, "```json")
+		content = strings.TrimPrefix(content, "```")
+		content = strings.TrimSuffix(strings.TrimSpace(content), "```")
+		content = strings.TrimSpace(content)
+	}
+	if json.Valid([]byte(content)) {
+		return content
+	}
+
+	// Fall back to the outermost object in the text
+	start := strings.Index(content, "{")
+	end := strings.LastIndex(content, "}")
+	if start >= 0 && end > start && json.Valid([]byte(content[start:end+1])) {
+		return content[start : end+1]
+	}
+	return content
+}
@@ -0,0 +1,370 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"chatbot/config"
+	"chatbot/models"
+	"chatbot/repositories"
+	"chatbot/utils"
+
+	"github.com/google/uuid"
+)
+
+// ========================================
+// Knowledge Bases (retrieval-augmented generation)
+// ========================================
+// A file added to a knowledge base is indexed once in the background: its
+// text is extracted, split into overlapping chunks (PDF and deck chunks
+// never span pages or slides, so each keeps its page number) and every chunk is embedded into
+// pgvector. On each chat turn with a persona that has knowledge bases
+// attached, the chunks closest to the question are placed in front of it as
+// numbered excerpts the model cites as [1], [2], ...
+
+// Knowledge limits
+const (
+	maxKnowledgeTopK      = 20
+	knowledgeIndexTimeout = extractionTimeout + 5*time.Minute // Extraction of the file, then embedding
+	knowledgeRetrieveTime = 10 * time.Second
+)
+
+// pageMarker matches the page headers of PDFs and the slide headers of decks written by their extractors
+var pageMarker = regexp.MustCompile(`(?m)^--- (?:Page|Slide) (\d+) ---$`)
+
+// KnowledgeSource is a retrieved excerpt and the citation the model refers to it by
+type KnowledgeSource struct {
+	Index             int       `json:"index"` // Citation number ([1], [2], ...)
+	KnowledgeBaseID   uuid.UUID `json:"knowledge_base_id"`
+	KnowledgeBaseName string    `json:"knowledge_base_name"`
+	DocumentID        uuid.UUID `json:"document_id"`
+	FileID            uuid.UUID `json:"file_id"`
+	FileName          string    `json:"file_name"`
+	Page              int       `json:"page,omitempty"` // PDF page or deck slide (0 = not paged)
+	ChunkIndex        int       `json:"chunk_index"`
+	Content           string    `json:"content"`
+	Score             float64   `json:"score"` // Cosine similarity to the question
+}
+
+// Citation returns how the source is named in the prompt, e.g. "report.pdf, page 3" or "pitch.pptx, slide 2"
+func (s KnowledgeSource) Citation() string {
+	if s.Page > 0 && getFileExtension(s.FileName) == ".pptx" {
+		return fmt.Sprintf("%s, slide %d", s.FileName, s.Page)
+	}
+	if s.Page > 0 {
+		return fmt.Sprintf("%s, page %d", s.FileName, s.Page)
+	}
+	return s.FileName
+}
+
+// KnowledgeService indexes knowledge base documents and retrieves their chunks
+type KnowledgeService struct {
+	repo         *repositories.KnowledgeRepository
+	fileRepo     *repositories.FileAnalysisRepository
+	extraction   *FileExtractionService
+	embeddings   *EmbeddingService
+	vectors      bool // pgvector is installed
+	chunkSize    int
+	chunkOverlap int
+	topK         int
+	minScore     float64
+	running      sync.Map // Document IDs being indexed
+}
+
+// NewKnowledgeService creates a new knowledge service
+func NewKnowledgeService(
+	repo *repositories.KnowledgeRepository,
+	fileRepo *repositories.FileAnalysisRepository,
+	extraction *FileExtractionService,
+	embeddings *EmbeddingService,
+	cfg *config.Config,
+) *KnowledgeService {
+	topK := cfg.KnowledgeTopK
+	if topK <= 0 || topK > maxKnowledgeTopK {
+		topK = 5
+	}
+	return &KnowledgeService{
+		repo:         repo,
+		fileRepo:     fileRepo,
+		extraction:   extraction,
+		embeddings:   embeddings,
+		vectors:      repo.VectorsEnabled(),
+		chunkSize:    max(cfg.KnowledgeChunkSize, 200),
+		chunkOverlap: cfg.KnowledgeChunkOverlap,
+		topK:         topK,
+		minScore:     cfg.KnowledgeMinScore,
+	}
+}
+
+// Available reports whether documents can be indexed and retrieved
+func (s *KnowledgeService) Available() bool {
+	return s != nil && s.vectors && s.embeddings.Available()
+}
+
+// AddFiles adds uploaded files to a knowledge base and indexes them in the background
+// Files already in the knowledge base are returned as they are
+func (s *KnowledgeService) AddFiles(kb *models.KnowledgeBase, fileIDs []string) ([]models.KnowledgeDocument, error) {
+	if !s.Available() {
+		return nil, fmt.Errorf("knowledge bases are not available (needs pgvector and OPENAI_API_KEY)")
+	}
+
+	files := make([]*models.FileAnalysis, 0, len(fileIDs))
+	for _, fileID := range fileIDs {
+		file, err := s.fileRepo.FindByID(fileID)
+		if err != nil {
+			return nil, fmt.Errorf("file %s not found", fileID)
+		}
+		if IsImageMimeType(file.MimeType) && !OCRAvailable() {
+			return nil, fmt.Errorf("file %s is an image; only documents can be added to a knowledge base (images need OCR)", file.FileName)
+		}
+		files = append(files, file)
+	}
+
+	docs := make([]models.KnowledgeDocument, 0, len(files))
+	for _, file := range files {
+		doc := models.KnowledgeDocument{
+			KnowledgeBaseID: kb.ID,
+			FileID:          file.ID,
+			FileName:        file.FileName,
+			Status:          models.KnowledgeStatusPending,
+		}
+		created, err := s.repo.AddDocument(&doc)
+		if err != nil {
+			return nil, fmt.Errorf("failed to add %s: %w", file.FileName, err)
+		}
+		if created {
+			s.Index(doc)
+		}
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
+
+// Index (re)indexes a document in the background; a document already being indexed is skipped
+func (s *KnowledgeService) Index(doc models.KnowledgeDocument) {
+	if _, busy := s.running.LoadOrStore(doc.ID, true); busy {
+		return
+	}
+	go func() {
+		defer s.running.Delete(doc.ID)
+		ctx, cancel := context.WithTimeout(context.Background(), knowledgeIndexTimeout)
+		defer cancel()
+		s.index(ctx, doc)
+	}()
+}
+
+// ResumeIndexing indexes the documents left pending or half-indexed by a restart
+func (s *KnowledgeService) ResumeIndexing() {
+	if !s.Available() {
+		return
+	}
+	docs, err := s.repo.DocumentsWithStatus(models.KnowledgeStatusPending, models.KnowledgeStatusProcessing)
+	if err != nil {
+		log.Printf("⚠️  Failed to load knowledge documents to index: %v", err)
+		return
+	}
+	for _, doc := range docs {
+		s.Index(doc)
+	}
+}
+
+// index extracts, chunks and embeds a document, recording the outcome on its status
+func (s *KnowledgeService) index(ctx context.Context, doc models.KnowledgeDocument) {
+	if err := s.repo.SetDocumentStatus(doc.ID, models.KnowledgeStatusProcessing, "", 0); err != nil {
+		log.Printf("⚠️  Failed to update knowledge document %s: %v", doc.ID, err)
+		return
+	}
+
+	count, err := s.indexChunks(ctx, doc)
+	if err != nil {
+		log.Printf("⚠️  Failed to index %s into knowledge base %s: %v", doc.FileName, doc.KnowledgeBaseID, err)
+		if err := s.repo.SetDocumentStatus(doc.ID, models.KnowledgeStatusFailed, err.Error(), 0); err != nil {
+			log.Printf("⚠️  Failed to update knowledge document %s: %v", doc.ID, err)
+		}
+		return
+	}
+
+	if err := s.repo.SetDocumentStatus(doc.ID, models.KnowledgeStatusReady, "", count); err != nil {
+		log.Printf("⚠️  Failed to update knowledge document %s: %v", doc.ID, err)
+		return
+	}
+	log.Printf("📚 Indexed %s into knowledge base %s (%d chunks)", doc.FileName, doc.KnowledgeBaseID, count)
+}
+
+// indexChunks stores the embedded chunks of a document and returns how many there are
+func (s *KnowledgeService) indexChunks(ctx context.Context, doc models.KnowledgeDocument) (int, error) {
+	file, err := s.fileRepo.FindByID(doc.FileID.String())
+	if err != nil {
+		return 0, fmt.Errorf("file not found")
+	}
+	text, err := s.extraction.Wait(ctx, file)
+	if err != nil {
+		return 0, err
+	}
+	if strings.TrimSpace(text) == "" {
+		return 0, fmt.Errorf("no text could be extracted from %s", file.MimeType)
+	}
+
+	var chunks []models.KnowledgeChunk
+	for _, page := range SplitPages(text) {
+		for _, content := range utils.ChunkDocument(page.Text, s.chunkSize, s.chunkOverlap) {
+			chunks = append(chunks, models.KnowledgeChunk{
+				KnowledgeBaseID: doc.KnowledgeBaseID,
+				DocumentID:      doc.ID,
+				FileID:          doc.FileID,
+				ChunkIndex:      len(chunks),
+				Page:            page.Number,
+				Content:         content,
+				Model:           s.embeddings.Model(),
+			})
+		}
+	}
+	if len(chunks) == 0 {
+		return 0, fmt.Errorf("no text could be extracted from %s", file.MimeType)
+	}
+
+	texts := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		texts[i] = chunk.Content
+	}
+	vectors, err := s.embeddings.Embed(ctx, texts)
+	if err != nil {
+		return 0, err
+	}
+	for i := range chunks {
+		chunks[i].Embedding = vectors[i]
+	}
+
+	if err := s.repo.ReplaceChunks(doc.ID, chunks); err != nil {
+		return 0, fmt.Errorf("failed to save chunks: %w", err)
+	}
+	return len(chunks), nil
+}
+
+// Retrieve finds the chunks of the knowledge bases most relevant to query (topK 0 = configured default)
+func (s *KnowledgeService) Retrieve(ctx context.Context, kbIDs []string, query string, topK int) ([]KnowledgeSource, error) {
+	if !s.Available() {
+		return nil, fmt.Errorf("knowledge bases are not available (needs pgvector and OPENAI_API_KEY)")
+	}
+	if topK <= 0 {
+		topK = s.topK
+	}
+	topK = min(topK, maxKnowledgeTopK)
+
+	ids := make([]uuid.UUID, 0, len(kbIDs))
+	for _, id := range kbIDs {
+		if kbID, err := uuid.Parse(id); err == nil {
+			ids = append(ids, kbID)
+		}
+	}
+	if len(ids) == 0 || strings.TrimSpace(query) == "" {
+		return []KnowledgeSource{}, nil
+	}
+
+	vectors, err := s.embeddings.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, err
+	}
+	hits, err := s.repo.SearchChunks(ids, vectors[0], s.minScore, topK)
+	if err != nil {
+		return nil, err
+	}
+
+	sources := make([]KnowledgeSource, len(hits))
+	for i, hit := range hits {
+		sources[i] = KnowledgeSource{
+			Index:             i + 1,
+			KnowledgeBaseID:   hit.KnowledgeBaseID,
+			KnowledgeBaseName: hit.KnowledgeBaseName,
+			DocumentID:        hit.DocumentID,
+			FileID:            hit.FileID,
+			FileName:          hit.FileName,
+			Page:              hit.Page,
+			ChunkIndex:        hit.ChunkIndex,
+			Content:           hit.Content,
+			Score:             hit.Score,
+		}
+	}
+	return sources, nil
+}
+
+// Augment places the excerpts of the persona's knowledge bases that are relevant to the current
+// question in front of it (as a system message) and returns them; retrieval errors are logged and
+// the turn goes on without excerpts
+func (s *KnowledgeService) Augment(ctx context.Context, conversation *Conversation, persona *models.Persona) []KnowledgeSource {
+	if persona == nil || conversation == nil || len(conversation.Messages) == 0 || !s.Available() {
+		return nil
+	}
+	kbIDs := persona.GetKnowledgeBases()
+	if len(kbIDs) == 0 {
+		return nil
+	}
+
+	current := len(conversation.Messages) - 1
+	ctx, cancel := context.WithTimeout(ctx, knowledgeRetrieveTime)
+	defer cancel()
+	sources, err := s.Retrieve(ctx, kbIDs, conversation.Messages[current].Text(), 0)
+	if err != nil {
+		log.Printf("⚠️  Failed to retrieve knowledge for persona %d: %v", persona.ID, err)
+		return nil
+	}
+	if len(sources) == 0 {
+		return nil
+	}
+
+	messages := make([]ConversationMessage, 0, len(conversation.Messages)+1)
+	messages = append(messages, conversation.Messages[:current]...)
+	messages = append(messages, KnowledgeMessage(sources), conversation.Messages[current])
+	conversation.Messages = messages
+	log.Printf("📚 Retrieved %d knowledge excerpts for persona %d", len(sources), persona.ID)
+	return sources
+}
+
+// KnowledgeMessage renders retrieved excerpts as a system message asking the model to cite them
+func KnowledgeMessage(sources []KnowledgeSource) ConversationMessage {
+	var text strings.Builder
+	text.WriteString("📚 Excerpts from the knowledge base that may help answer the next question. " +
+		"Use them when they are relevant and cite every excerpt you rely on by its number, e.g. [1]. " +
+		"If they do not contain the answer, say so instead of guessing.\n")
+	for _, source := range sources {
+		fmt.Fprintf(&text, "\n[%d] %s\n%s\n", source.Index, source.Citation(), source.Content)
+	}
+	return NewTextMessage(ConversationRoleSystem, strings.TrimSpace(text.String()))
+}
+
+// DocumentPage is the text of one page of an extracted document
+type DocumentPage struct {
+	Number int // 1-based page or slide (0 = the document is not paged)
+	Text   string
+}
+
+// SplitPages splits extracted text at the page headers of PDFs and the slide headers of decks;
+// other documents are one unpaged page
+func SplitPages(text string) []DocumentPage {
+	markers := pageMarker.FindAllStringSubmatchIndex(text, -1)
+	if len(markers) == 0 {
+		return []DocumentPage{{Text: text}}
+	}
+
+	var pages []DocumentPage
+	if preface := strings.TrimSpace(text[:markers[0][0]]); preface != "" {
+		pages = append(pages, DocumentPage{Text: preface})
+	}
+	for i, marker := range markers {
+		number, _ := strconv.Atoi(text[marker[2]:marker[3]])
+		end := len(text)
+		if i+1 < len(markers) {
+			end = markers[i+1][0]
+		}
+		if body := strings.TrimSpace(text[marker[1]:end]); body != "" {
+			pages = append(pages, DocumentPage{Number: number, Text: body})
+		}
+	}
+	return pages
+}
@@ -0,0 +1,410 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"chatbot/config"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// LocalLLMService handles chat with a local LLM server (Ollama or llama.cpp server)
+// Like WhisperCppService for STT, it lets the chatbot run fully offline
+type LocalLLMService struct {
+	baseURL      string
+	api          string // "ollama" or "openai"
+	config       *config.Config
+	httpClient   *http.Client
+	openaiClient *openai.Client // Only set for OpenAI-compatible servers
+}
+
+func init() {
+	RegisterProvider("local", 30, ProviderCapabilities{
+		Vision:     false,
+		Streaming:  true,
+		MaxContext: 8192,
+		Local:      true,
+	}, func(cfg *config.Config) (StreamingChatService, error) {
+		return NewLocalLLMService(cfg)
+	})
+}
+
+// NewLocalLLMService creates a new local LLM service
+func NewLocalLLMService(cfg *config.Config) (*LocalLLMService, error) {
+	service := &LocalLLMService{
+		baseURL:    strings.TrimSuffix(cfg.LocalLLMBaseURL, "/"),
+		api:        cfg.LocalLLMAPI,
+		config:     cfg,
+		httpClient: &http.Client{}, // No timeout: streams are cancelled through the context
+	}
+	if service.api == "" {
+		service.api = "ollama"
+	}
+
+	switch service.api {
+	case "ollama":
+	case "openai":
+		// llama.cpp server (and Ollama) expose an OpenAI-compatible API under /v1
+		clientConfig := openai.DefaultConfig("local")
+		clientConfig.BaseURL = service.baseURL + "/v1"
+		clientConfig.HTTPClient = service.httpClient
+		service.openaiClient = openai.NewClientWithConfig(clientConfig)
+	default:
+		return nil, fmt.Errorf("unsupported LOCAL_LLM_API: %s (valid options: ollama, openai)", service.api)
+	}
+
+	return service, nil
+}
+
+// LocalLLMError is a non-200 response from the local LLM server
+type LocalLLMError struct {
+	StatusCode int
+	Message    string
+}
+
+// Error implements the error interface
+func (e *LocalLLMError) Error() string {
+	return fmt.Sprintf("local LLM error (status %d): %s", e.StatusCode, e.Message)
+}
+
+// ollamaMessage is a message in the Ollama /api/chat format
+type ollamaMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	Images    []string         `json:"images,omitempty"` // Base64 encoded images (multimodal models)
+	ToolCalls []ollamaToolCall `json:"tool_calls,omitempty"`
+}
+
+// ollamaToolCall is a tool call in the Ollama format
+type ollamaToolCall struct {
+	Function struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	} `json:"function"`
+}
+
+// ollamaChatRequest is the request body for Ollama /api/chat
+type ollamaChatRequest struct {
+	Model    string          `json:"model"`
+	Messages []ollamaMessage `json:"messages"`
+	Stream   bool            `json:"stream"`
+	Format   json.RawMessage `json:"format,omitempty"` // JSON schema for structured output
+	Options  struct {
+		Temperature float64 `json:"temperature,omitempty"`
+		NumPredict  int     `json:"num_predict,omitempty"`
+	} `json:"options"`
+}
+
+// ollamaChatResponse is a response (or one NDJSON stream line) from Ollama /api/chat
+type ollamaChatResponse struct {
+	Model           string        `json:"model"`
+	Message         ollamaMessage `json:"message"`
+	Done            bool          `json:"done"`
+	DoneReason      string        `json:"done_reason"`
+	PromptEvalCount int           `json:"prompt_eval_count"`
+	EvalCount       int           `json:"eval_count"`
+	Error           string        `json:"error"`
+}
+
+// toOllamaMessages translates a provider-neutral conversation to Ollama messages
+// File parts become a system message in front of the user turn (same as OpenAI)
+func toOllamaMessages(systemPrompt string, messages []ConversationMessage) []ollamaMessage {
+	result := make([]ollamaMessage, 0, len(messages)+1)
+
+	if systemPrompt != "" {
+		result = append(result, ollamaMessage{Role: "system", Content: systemPrompt})
+	}
+
+	for _, msg := range messages {
+		switch msg.Role {
+		case ConversationRoleSystem, ConversationRoleTool:
+			result = append(result, ollamaMessage{Role: string(msg.Role), Content: msg.Text()})
+
+		case ConversationRoleAssistant:
+			assistant := ollamaMessage{Role: "assistant", Content: msg.Text()}
+			for _, call := range msg.ToolCalls {
+				var toolCall ollamaToolCall
+				toolCall.Function.Name = call.Name
+				toolCall.Function.Arguments = json.RawMessage(call.Arguments)
+				if len(toolCall.Function.Arguments) == 0 {
+					toolCall.Function.Arguments = json.RawMessage("{}")
+				}
+				assistant.ToolCalls = append(assistant.ToolCalls, toolCall)
+			}
+			result = append(result, assistant)
+
+		default:
+			if fileParts := msg.PartsOfType(ContentPartFile); len(fileParts) > 0 {
+				result = append(result, ollamaMessage{Role: "system", Content: FormatFileContext(fileParts)})
+			}
+
+			user := ollamaMessage{Role: "user", Content: msg.Text()}
+			for _, image := range msg.PartsOfType(ContentPartImage) {
+				user.Images = append(user.Images, base64.StdEncoding.EncodeToString(image.Data))
+			}
+			result = append(result, user)
+		}
+	}
+
+	return result
+}
+
+// buildOllamaRequest applies defaults and builds the Ollama request body
+func (s *LocalLLMService) buildOllamaRequest(req StreamingChatRequest, stream bool) ollamaChatRequest {
+	body := ollamaChatRequest{
+		Model:    s.model(req.Model),
+		Messages: toOllamaMessages(req.SystemPrompt, req.Messages),
+		Stream:   stream,
+	}
+	if req.ResponseSchema != nil {
+		body.Format = req.ResponseSchema.Schema
+	}
+
+	body.Options.Temperature, body.Options.NumPredict = s.generationDefaults(req)
+
+	return body
+}
+
+// buildOpenAIRequest applies defaults and builds the request for OpenAI-compatible servers
+func (s *LocalLLMService) buildOpenAIRequest(req StreamingChatRequest, stream bool) openai.ChatCompletionRequest {
+	temperature, maxTokens := s.generationDefaults(req)
+	request := openai.ChatCompletionRequest{
+		Model:          s.model(req.Model),
+		Messages:       toOpenAIMessages(req.SystemPrompt, req.Messages),
+		Temperature:    float32(temperature),
+		MaxTokens:      maxTokens,
+		Stream:         stream,
+		ResponseFormat: openAIResponseFormat(req.ResponseSchema),
+	}
+	if stream {
+		request.StreamOptions = &openai.StreamOptions{IncludeUsage: true}
+	}
+	return request
+}
+
+// warnToolsUnsupported logs the tools of a request, which are not sent to the local LLM
+// The model answers without calling them
+func warnToolsUnsupported(req StreamingChatRequest) {
+	if len(req.Tools) > 0 {
+		log.Printf("⚠️  Local LLM does not support tools, answering without %d tools", len(req.Tools))
+	}
+}
+
+// model returns the requested model or the configured default
+func (s *LocalLLMService) model(requested string) string {
+	if requested != "" {
+		return requested
+	}
+	return s.config.LocalLLMModel
+}
+
+// generationDefaults returns temperature and max tokens, falling back to config
+func (s *LocalLLMService) generationDefaults(req StreamingChatRequest) (float64, int) {
+	temperature := req.Temperature
+	if temperature == 0 {
+		temperature = s.config.LocalLLMTemperature
+	}
+	maxTokens := req.MaxTokens
+	if maxTokens == 0 {
+		maxTokens = s.config.LocalLLMMaxTokens
+	}
+	return temperature, maxTokens
+}
+
+// postOllamaChat sends a request to Ollama /api/chat and returns the response body
+func (s *LocalLLMService) postOllamaChat(ctx context.Context, body ollamaChatRequest) (io.ReadCloser, error) {
+	requestBody, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, s.baseURL+"/api/chat", bytes.NewReader(requestBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call local LLM: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		errorBody, _ := io.ReadAll(resp.Body)
+
+		// Ollama returns {"error": "..."} on failure
+		var errResp struct {
+			Error string `json:"error"`
+		}
+		message := strings.TrimSpace(string(errorBody))
+		if json.Unmarshal(errorBody, &errResp) == nil && errResp.Error != "" {
+			message = errResp.Error
+		}
+		return nil, &LocalLLMError{StatusCode: resp.StatusCode, Message: message}
+	}
+
+	return resp.Body, nil
+}
+
+// ========================================
+// StreamingChatService Implementation
+// ========================================
+
+// LocalLLMStreamReader reads the Ollama NDJSON stream
+type LocalLLMStreamReader struct {
+	body    io.ReadCloser
+	scanner *bufio.Scanner
+	closed  bool
+	usage   *TokenUsage // Set by the final line (done: true)
+}
+
+// CreateStreamingChat implements StreamingChatService interface
+func (s *LocalLLMService) CreateStreamingChat(ctx context.Context, req StreamingChatRequest) (StreamReader, error) {
+	warnToolsUnsupported(req)
+	if s.api == "openai" {
+		stream, err := s.openaiClient.CreateChatCompletionStream(ctx, s.buildOpenAIRequest(req, true))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create streaming: %w", err)
+		}
+		return &OpenAIStreamReader{stream: stream}, nil
+	}
+
+	body := s.buildOllamaRequest(req, true)
+	log.Printf("🔵 Local LLM Streaming Request: model=%s, messages=%d", body.Model, len(body.Messages))
+
+	respBody, err := s.postOllamaChat(ctx, body)
+	if err != nil {
+		return nil, err
+	}
+
+	scanner := bufio.NewScanner(respBody)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	return &LocalLLMStreamReader{
+		body:    respBody,
+		scanner: scanner,
+	}, nil
+}
+
+// Recv receives the next chunk from the local LLM stream
+func (r *LocalLLMStreamReader) Recv() (string, error) {
+	if r.closed {
+		return "", io.EOF
+	}
+
+	for r.scanner.Scan() {
+		line := bytes.TrimSpace(r.scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var chunk ollamaChatResponse
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			log.Printf("⚠️ Failed to unmarshal local LLM chunk: %v", err)
+			continue
+		}
+
+		if chunk.Error != "" {
+			return "", &LocalLLMError{StatusCode: http.StatusInternalServerError, Message: chunk.Error}
+		}
+		if chunk.Done {
+			r.usage = &TokenUsage{
+				PromptTokens:     chunk.PromptEvalCount,
+				CompletionTokens: chunk.EvalCount,
+			}
+			return "", io.EOF
+		}
+		if chunk.Message.Content != "" {
+			return chunk.Message.Content, nil
+		}
+	}
+
+	if err := r.scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", io.EOF
+}
+
+// Usage returns the token usage reported at the end of the stream
+func (r *LocalLLMStreamReader) Usage() *TokenUsage {
+	return r.usage
+}
+
+// Close closes the local LLM stream
+func (r *LocalLLMStreamReader) Close() error {
+	r.closed = true
+	return r.body.Close()
+}
+
+// CompleteChat implements ChatCompleter using a non-streaming request
+func (s *LocalLLMService) CompleteChat(ctx context.Context, req StreamingChatRequest) (*ChatCompletion, error) {
+	warnToolsUnsupported(req)
+	if s.api == "openai" {
+		resp, err := s.openaiClient.CreateChatCompletion(ctx, s.buildOpenAIRequest(req, false))
+		if err != nil {
+			return nil, fmt.Errorf("local LLM API error: %w", err)
+		}
+		if len(resp.Choices) == 0 {
+			return nil, fmt.Errorf("no response from local LLM")
+		}
+		return &ChatCompletion{
+			Content:      resp.Choices[0].Message.Content,
+			TokensUsed:   resp.Usage.TotalTokens,
+			Usage:        openAIUsage(&resp.Usage),
+			Model:        resp.Model,
+			FinishReason: string(resp.Choices[0].FinishReason),
+		}, nil
+	}
+
+	respBody, err := s.postOllamaChat(ctx, s.buildOllamaRequest(req, false))
+	if err != nil {
+		return nil, err
+	}
+	defer respBody.Close()
+
+	var resp ollamaChatResponse
+	if err := json.NewDecoder(respBody).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if resp.Error != "" {
+		return nil, &LocalLLMError{StatusCode: http.StatusInternalServerError, Message: resp.Error}
+	}
+
+	return &ChatCompletion{
+		Content:      resp.Message.Content,
+		TokensUsed:   resp.PromptEvalCount + resp.EvalCount,
+		Usage:        &TokenUsage{PromptTokens: resp.PromptEvalCount, CompletionTokens: resp.EvalCount},
+		Model:        resp.Model,
+		FinishReason: resp.DoneReason,
+	}, nil
+}
+
+// DefaultModel returns the configured local model
+func (s *LocalLLMService) DefaultModel() string {
+	return s.config.LocalLLMModel
+}
+
+// AllowedModels returns nil (any model pulled on the local server is accepted)
+func (s *LocalLLMService) AllowedModels() []string {
+	return nil
+}
+
+// GetProviderName returns "local"
+func (s *LocalLLMService) GetProviderName() string {
+	return "local"
+}
+
+// IsAvailable checks if a local LLM server is configured
+func (s *LocalLLMService) IsAvailable() bool {
+	return s.baseURL != ""
+}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"sort"
+	"time"
+
+	"chatbot/models"
+
+	"github.com/google/uuid"
+)
+
+// ========================================
+// Conversation Branching
+// ========================================
+// Messages form a tree through ParentID: editing a user message or regenerating
+// a reply adds a sibling, and the conversation the model sees is the path from
+// the first message to the tip of one branch.
+
+// MessageTree is the parent/child structure of a session's messages
+type MessageTree struct {
+	byID     map[uuid.UUID]*models.Message
+	children map[uuid.UUID][]*models.Message // Key uuid.Nil holds the first messages of the session
+	latest   *models.Message
+}
+
+// NewMessageTree builds the tree of a session's messages (in created_at order)
+func NewMessageTree(messages []models.Message) *MessageTree {
+	tree := &MessageTree{
+		byID:     make(map[uuid.UUID]*models.Message, len(messages)),
+		children: make(map[uuid.UUID][]*models.Message),
+	}
+	for i := range messages {
+		msg := &messages[i]
+		tree.byID[msg.ID] = msg
+		if tree.latest == nil || !msg.CreatedAt.Before(tree.latest.CreatedAt) {
+			tree.latest = msg
+		}
+	}
+	for _, msg := range tree.byID {
+		parent := uuid.Nil
+		if msg.ParentID != nil {
+			if _, ok := tree.byID[*msg.ParentID]; ok {
+				parent = *msg.ParentID
+			}
+		}
+		tree.children[parent] = append(tree.children[parent], msg)
+	}
+	for _, siblings := range tree.children {
+		sort.SliceStable(siblings, func(i, j int) bool {
+			return siblings[i].CreatedAt.Before(siblings[j].CreatedAt)
+		})
+	}
+	return tree
+}
+
+// Get returns a message of the tree
+func (t *MessageTree) Get(id uuid.UUID) (*models.Message, bool) {
+	msg, ok := t.byID[id]
+	return msg, ok
+}
+
+// Latest returns the most recently created message (nil for an empty session)
+func (t *MessageTree) Latest() *models.Message {
+	return t.latest
+}
+
+// Path returns the branch from the first message down to id, oldest first
+func (t *MessageTree) Path(id uuid.UUID) []models.Message {
+	var path []models.Message
+	seen := make(map[uuid.UUID]bool)
+	for msg, ok := t.byID[id]; ok && !seen[msg.ID]; {
+		seen[msg.ID] = true
+		path = append(path, *msg)
+		if msg.ParentID == nil {
+			break
+		}
+		msg, ok = t.byID[*msg.ParentID]
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+// LatestLeaf returns the tip of the most recent branch below id (id itself if it has no replies)
+func (t *MessageTree) LatestLeaf(id uuid.UUID) *models.Message {
+	msg, ok := t.byID[id]
+	if !ok {
+		return nil
+	}
+	for {
+		children := t.children[msg.ID]
+		if len(children) == 0 {
+			return msg
+		}
+		msg = t.newestBranch(children)
+	}
+}
+
+// newestBranch picks the sibling whose branch holds the most recent message
+func (t *MessageTree) newestBranch(siblings []*models.Message) *models.Message {
+	best := siblings[0]
+	bestActivity := t.lastActivity(best)
+	for _, sibling := range siblings[1:] {
+		if activity := t.lastActivity(sibling); !activity.Before(bestActivity) {
+			best, bestActivity = sibling, activity
+		}
+	}
+	return best
+}
+
+// lastActivity returns the creation time of the newest message below and including msg
+func (t *MessageTree) lastActivity(msg *models.Message) time.Time {
+	latest := msg.CreatedAt
+	for _, child := range t.children[msg.ID] {
+		if activity := t.lastActivity(child); activity.After(latest) {
+			latest = activity
+		}
+	}
+	return latest
+}
+
+// Siblings returns the alternatives of a message (itself included), oldest first
+func (t *MessageTree) Siblings(id uuid.UUID) []*models.Message {
+	msg, ok := t.byID[id]
+	if !ok {
+		return nil
+	}
+	parent := uuid.Nil
+	if msg.ParentID != nil {
+		if _, ok := t.byID[*msg.ParentID]; ok {
+			parent = *msg.ParentID
+		}
+	}
+	return t.children[parent]
+}
+
+// PromptOf returns the user message a reply answers (walking up past tool turns)
+func (t *MessageTree) PromptOf(id uuid.UUID) (*models.Message, bool) {
+	path := t.Path(id)
+	for i := len(path) - 1; i >= 0; i-- {
+		if path[i].Role == models.RoleUser {
+			return t.byID[path[i].ID], true
+		}
+	}
+	return nil, false
+}
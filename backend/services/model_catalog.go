@@ -0,0 +1,252 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"chatbot/config"
+)
+
+// ========================================
+// Model Catalog
+// ========================================
+// The catalog lists the chat models personas and requests may use, with
+// their limits (context window, max output tokens, vision). Providers that
+// have no catalog entries (e.g. local or OpenAI-compatible providers) accept
+// any model their own configuration allows.
+
+// imageTokenEstimate is the rough prompt cost of one image
+const imageTokenEstimate = 1000
+
+// defaultOutputReserve is kept free for the reply when a request sets no max tokens
+const defaultOutputReserve = 4096
+
+// ModelInfo is a catalog entry as returned by GET /api/models
+type ModelInfo struct {
+	config.CatalogModel
+	Pricing      *config.ModelPrice `json:"pricing,omitempty"` // USD per 1M tokens from the pricing table
+	IsDeprecated bool               `json:"is_deprecated"`     // Deprecation date has passed
+	IsDefault    bool               `json:"is_default"`        // Model the provider uses when none is requested
+	Available    bool               `json:"available"`         // Provider is configured
+}
+
+// ModelCatalog validates models and applies their limits to requests
+type ModelCatalog struct {
+	models    []config.CatalogModel
+	pricing   *PricingService
+	providers *ProviderRegistry
+	now       func() time.Time
+}
+
+// NewModelCatalog creates a model catalog
+func NewModelCatalog(models []config.CatalogModel, pricing *PricingService, providers *ProviderRegistry) *ModelCatalog {
+	return &ModelCatalog{
+		models:    models,
+		pricing:   pricing,
+		providers: providers,
+		now:       time.Now,
+	}
+}
+
+// List returns the catalog sorted by provider and ID (provider "" = every provider)
+func (c *ModelCatalog) List(provider string) []ModelInfo {
+	available := map[string]bool{}
+	defaults := map[string]string{}
+	if c.providers != nil {
+		for _, info := range c.providers.List() {
+			available[info.Name] = info.Available
+			defaults[info.Name] = info.DefaultModel
+		}
+	}
+
+	models := make([]ModelInfo, 0, len(c.models))
+	for _, model := range c.models {
+		if provider != "" && !strings.EqualFold(model.Provider, provider) {
+			continue
+		}
+		info := ModelInfo{
+			CatalogModel: model,
+			IsDeprecated: c.isDeprecated(model),
+			IsDefault:    defaults[model.Provider] == model.ID,
+			Available:    available[model.Provider],
+		}
+		if c.pricing != nil {
+			if price, ok := c.pricing.ModelPrice(model.ID); ok {
+				info.Pricing = &price
+			}
+		}
+		models = append(models, info)
+	}
+
+	sort.SliceStable(models, func(i, j int) bool {
+		if models[i].Provider != models[j].Provider {
+			return models[i].Provider < models[j].Provider
+		}
+		return models[i].ID < models[j].ID
+	})
+	return models
+}
+
+// Find looks up a model by ID or alias (provider "" searches every provider)
+func (c *ModelCatalog) Find(provider, model string) (config.CatalogModel, bool) {
+	for _, entry := range c.models {
+		if provider != "" && !strings.EqualFold(entry.Provider, provider) {
+			continue
+		}
+		if entry.ID == model {
+			return entry, true
+		}
+		for _, alias := range entry.Aliases {
+			if alias == model {
+				return entry, true
+			}
+		}
+	}
+	return config.CatalogModel{}, false
+}
+
+// Validate checks that a model may be used with the provider and returns its catalog ID
+// An empty model (provider default) and models of providers without catalog entries pass unchanged
+func (c *ModelCatalog) Validate(provider, model string) (string, error) {
+	if model == "" {
+		return "", nil
+	}
+
+	entry, ok := c.Find(provider, model)
+	if !ok {
+		if provider != "" && !c.covers(provider) {
+			return model, nil
+		}
+		if provider == "" {
+			return "", fmt.Errorf("unknown model %s (see GET /api/models)", model)
+		}
+		return "", fmt.Errorf("unknown model %s for provider %s (see GET /api/models)", model, provider)
+	}
+	if c.isDeprecated(entry) {
+		return "", fmt.Errorf("model %s was deprecated on %s", entry.ID, entry.Deprecated)
+	}
+	return entry.ID, nil
+}
+
+// Prepare validates the request's model and fits the request to its limits:
+// images need a vision model, a thinking budget needs a reasoning model (and is added
+// to MaxTokens), MaxTokens is capped at the model's max output and history, file excerpts
+// and system additions are trimmed when the prompt exceeds the context window (see fitContext)
+func (c *ModelCatalog) Prepare(provider string, req StreamingChatRequest) (StreamingChatRequest, error) {
+	model, err := c.Validate(provider, req.Model)
+	if err != nil {
+		return req, err
+	}
+	req.Model = model
+	if model == "" {
+		model = c.defaultModel(provider)
+	}
+
+	entry, ok := c.Find(provider, model)
+	if !ok {
+		return req, nil
+	}
+
+	if !entry.Vision && messagesHaveImages(req.Messages) {
+		return req, fmt.Errorf("model %s does not support images", entry.ID)
+	}
+	if req.ThinkingBudget > 0 {
+		req = fitThinkingBudget(req, entry)
+	}
+	if entry.MaxOutputTokens > 0 && req.MaxTokens > entry.MaxOutputTokens {
+		req.MaxTokens = entry.MaxOutputTokens
+	}
+	if entry.ContextWindow > 0 {
+		req.Messages, _, err = fitContext(req, entry, TokenizerFor(entry.Provider, entry.ID))
+		if err != nil {
+			return req, err
+		}
+	}
+	return req, nil
+}
+
+// VisionModel returns preferred if it accepts images, otherwise the first vision model of the provider
+func (c *ModelCatalog) VisionModel(provider, preferred string) string {
+	if entry, ok := c.Find(provider, preferred); ok && entry.Vision && !c.isDeprecated(entry) {
+		return entry.ID
+	}
+	for _, entry := range c.List(provider) {
+		if entry.Vision && !entry.IsDeprecated {
+			return entry.ID
+		}
+	}
+	return preferred
+}
+
+// covers reports whether the catalog has entries for the provider
+func (c *ModelCatalog) covers(provider string) bool {
+	for _, entry := range c.models {
+		if strings.EqualFold(entry.Provider, provider) {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultModel returns the model a provider uses when the request sets none
+func (c *ModelCatalog) defaultModel(provider string) string {
+	if c.providers == nil {
+		return ""
+	}
+	if provider == "" {
+		service, err := c.providers.Resolve("")
+		if err != nil {
+			return ""
+		}
+		provider = service.GetProviderName()
+	}
+	model, _, _ := c.providers.Models(provider)
+	return model
+}
+
+func (c *ModelCatalog) isDeprecated(entry config.CatalogModel) bool {
+	if entry.Deprecated == "" {
+		return false
+	}
+	date, err := time.Parse("2006-01-02", entry.Deprecated)
+	if err != nil {
+		return false
+	}
+	return !c.now().Before(date)
+}
+
+// fitThinkingBudget drops the budget for models that cannot reason, otherwise keeps it
+// below half the max output and adds it to MaxTokens (which covers reasoning and answer)
+func fitThinkingBudget(req StreamingChatRequest, entry config.CatalogModel) StreamingChatRequest {
+	if !entry.Reasoning {
+		log.Printf("💭 Model %s does not support reasoning, thinking budget ignored", entry.ID)
+		req.ThinkingBudget = 0
+		return req
+	}
+	if entry.MaxOutputTokens > 0 && req.ThinkingBudget > entry.MaxOutputTokens/2 {
+		req.ThinkingBudget = entry.MaxOutputTokens / 2
+	}
+	if req.MaxTokens > 0 {
+		req.MaxTokens += req.ThinkingBudget
+	}
+	return req
+}
+
+// EstimateTokens roughly estimates the token count of text (about 4 bytes per token)
+// Context assembly counts with the model's Tokenizer instead
+func EstimateTokens(text string) int {
+	return (len(text) + 3) / 4
+}
+
+// messagesHaveImages reports whether any message carries an image
+func messagesHaveImages(messages []ConversationMessage) bool {
+	for _, message := range messages {
+		if len(message.PartsOfType(ContentPartImage)) > 0 {
+			return true
+		}
+	}
+	return false
+}
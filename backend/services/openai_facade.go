@@ -0,0 +1,116 @@
+package services
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// ========================================
+// OpenAI-compatible API (/v1) helpers
+// ========================================
+// Clients of the /v1 facade send the whole conversation in OpenAI format;
+// it is translated to the provider-neutral format used by every adapter.
+
+// ConversationFromOpenAI converts OpenAI chat messages to a neutral conversation
+// System and developer messages are joined into the returned system prompt
+func ConversationFromOpenAI(messages []openai.ChatCompletionMessage) (*Conversation, error) {
+	conversation := &Conversation{}
+	var system []string
+
+	for i, message := range messages {
+		switch message.Role {
+		case openai.ChatMessageRoleSystem, openai.ChatMessageRoleDeveloper:
+			if text := openAIMessageText(message); text != "" {
+				system = append(system, text)
+			}
+		case openai.ChatMessageRoleUser:
+			parts, err := openAIUserParts(message)
+			if err != nil {
+				return nil, fmt.Errorf("messages[%d]: %w", i, err)
+			}
+			conversation.Messages = append(conversation.Messages, ConversationMessage{
+				Role:  ConversationRoleUser,
+				Parts: parts,
+			})
+		case openai.ChatMessageRoleAssistant:
+			converted := NewTextMessage(ConversationRoleAssistant, openAIMessageText(message))
+			for _, call := range message.ToolCalls {
+				converted.ToolCalls = append(converted.ToolCalls, ToolCall{
+					ID:        call.ID,
+					Name:      call.Function.Name,
+					Arguments: call.Function.Arguments,
+				})
+			}
+			conversation.Messages = append(conversation.Messages, converted)
+		case openai.ChatMessageRoleTool:
+			converted := NewTextMessage(ConversationRoleTool, openAIMessageText(message))
+			converted.ToolCallID = message.ToolCallID
+			conversation.Messages = append(conversation.Messages, converted)
+		default:
+			return nil, fmt.Errorf("messages[%d]: unsupported role %q", i, message.Role)
+		}
+	}
+
+	conversation.SystemPrompt = strings.Join(system, "\n\n")
+	conversation.Messages = NormalizeToolMessages(conversation.Messages)
+	return conversation, nil
+}
+
+// openAIMessageText returns the text of a message sent as a string or as content parts
+func openAIMessageText(message openai.ChatCompletionMessage) string {
+	if len(message.MultiContent) == 0 {
+		return message.Content
+	}
+
+	var texts []string
+	for _, part := range message.MultiContent {
+		if part.Type == openai.ChatMessagePartTypeText {
+			texts = append(texts, part.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// openAIUserParts converts user content; images must be base64 data URLs
+func openAIUserParts(message openai.ChatCompletionMessage) ([]ContentPart, error) {
+	if len(message.MultiContent) == 0 {
+		return []ContentPart{{Type: ContentPartText, Text: message.Content}}, nil
+	}
+
+	parts := make([]ContentPart, 0, len(message.MultiContent))
+	for _, part := range message.MultiContent {
+		switch part.Type {
+		case openai.ChatMessagePartTypeText:
+			parts = append(parts, ContentPart{Type: ContentPartText, Text: part.Text})
+		case openai.ChatMessagePartTypeImageURL:
+			if part.ImageURL == nil {
+				return nil, fmt.Errorf("image_url part without url")
+			}
+			mimeType, data, err := decodeDataURL(part.ImageURL.URL)
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, ContentPart{Type: ContentPartImage, MimeType: mimeType, Data: data})
+		default:
+			return nil, fmt.Errorf("unsupported content part type %q", part.Type)
+		}
+	}
+	return parts, nil
+}
+
+// decodeDataURL decodes a data:<mime>;base64,<data> URL
+func decodeDataURL(url string) (string, []byte, error) {
+	header, encoded, found := strings.Cut(strings.TrimPrefix(url, "data:"), ",")
+	if !strings.HasPrefix(url, "data:") || !found || !strings.HasSuffix(header, ";base64") {
+		return "", nil, fmt.Errorf("only base64 data URLs are supported for images")
+	}
+
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid base64 image: %w", err)
+	}
+	return strings.TrimSuffix(header, ";base64"), data, nil
+}
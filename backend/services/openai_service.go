@@ -2,8 +2,10 @@ package services
 
 import (
 	"context"
+	"encoding/base64"
 	"fmt"
 	"io"
+	"strings"
 
 	"chatbot/config"
 
@@ -11,43 +13,143 @@ import (
 )
 
 // OpenAIService handles OpenAI API interactions
+// The same service also serves named OpenAI-compatible providers (Azure OpenAI, vLLM, Groq, ...)
 type OpenAIService struct {
-	client *openai.Client
-	config *config.Config
+	client        *openai.Client
+	config        *config.Config
+	name          string
+	apiKey        string
+	requiresKey   bool // Self-hosted compatible servers may not need a key
+	defaultModel  string
+	allowedModels []string // Empty = any model
+}
+
+func init() {
+	RegisterProvider("openai", 10, ProviderCapabilities{
+		Vision:     true,
+		Streaming:  true,
+		MaxContext: 128000,
+	}, func(cfg *config.Config) (StreamingChatService, error) {
+		return NewOpenAIService(cfg), nil
+	})
 }
 
 // NewOpenAIService creates a new OpenAI service
 func NewOpenAIService(cfg *config.Config) *OpenAIService {
 	client := openai.NewClient(cfg.OpenAIAPIKey)
 	return &OpenAIService{
-		client: client,
-		config: cfg,
+		client:       client,
+		config:       cfg,
+		name:         "openai",
+		apiKey:       cfg.OpenAIAPIKey,
+		requiresKey:  true,
+		defaultModel: cfg.OpenAIModel,
+	}
+}
+
+// NewOpenAICompatibleService creates a service for a named OpenAI-compatible provider
+func NewOpenAICompatibleService(cfg *config.Config, provider config.OpenAICompatibleProvider) (*OpenAIService, error) {
+	if provider.BaseURL == "" {
+		return nil, fmt.Errorf("base URL is required for provider %s", provider.Name)
+	}
+
+	var clientConfig openai.ClientConfig
+	switch provider.APIType {
+	case "", "openai":
+		clientConfig = openai.DefaultConfig(provider.APIKey)
+		clientConfig.BaseURL = provider.BaseURL
+	case "azure":
+		clientConfig = openai.DefaultAzureConfig(provider.APIKey, provider.BaseURL)
+		if provider.APIVersion != "" {
+			clientConfig.APIVersion = provider.APIVersion
+		}
+	default:
+		return nil, fmt.Errorf("unsupported API type for provider %s: %s (valid options: openai, azure)", provider.Name, provider.APIType)
+	}
+
+	return &OpenAIService{
+		client:        openai.NewClientWithConfig(clientConfig),
+		config:        cfg,
+		name:          provider.Name,
+		apiKey:        provider.APIKey,
+		requiresKey:   provider.APIType == "azure",
+		defaultModel:  provider.DefaultModel,
+		allowedModels: provider.AllowedModels,
+	}, nil
+}
+
+// resolveModel returns the requested model (or the default) and checks it against the allowed list
+func (s *OpenAIService) resolveModel(requested string) (string, error) {
+	model := requested
+	if model == "" {
+		model = s.defaultModel
 	}
+	if model == "" && s.name == "openai" {
+		model = "gpt-4o-mini"
+	}
+	if model == "" {
+		return "", fmt.Errorf("no model specified and provider %s has no default model", s.name)
+	}
+
+	if len(s.allowedModels) > 0 {
+		for _, allowed := range s.allowedModels {
+			if allowed == model {
+				return model, nil
+			}
+		}
+		return "", fmt.Errorf("model %s is not allowed for provider %s (allowed: %s)", model, s.name, strings.Join(s.allowedModels, ", "))
+	}
+
+	return model, nil
+}
+
+// DefaultModel returns the model used when a request does not specify one
+func (s *OpenAIService) DefaultModel() string {
+	model, _ := s.resolveModel("")
+	return model
+}
+
+// AllowedModels returns the models this provider accepts (empty = any model)
+func (s *OpenAIService) AllowedModels() []string {
+	return s.allowedModels
 }
 
 // ChatRequest represents a chat request with all options
 type ChatRequest struct {
-	Messages      []openai.ChatCompletionMessage
-	Model         string
-	Temperature   float32
-	MaxTokens     int
-	SystemPrompt  string
+	Messages       []openai.ChatCompletionMessage
+	Model          string
+	Temperature    float32
+	MaxTokens      int
+	SystemPrompt   string
+	Tools          []openai.Tool
+	ResponseFormat *openai.ChatCompletionResponseFormat
+	ThinkingBudget int // Reasoning tokens for o-series models (0 = model default)
 }
 
 // ChatResponse represents the response from OpenAI
 type ChatResponse struct {
-	Content     string
-	TokensUsed  int
-	Model       string
+	Content      string
+	TokensUsed   int
+	Usage        TokenUsage
+	Model        string
 	FinishReason string
+	ToolCalls    []ToolCall
+	Reasoning    string // reasoning_content (OpenAI-compatible reasoning models)
 }
 
 // SendChatRequest sends a chat request to OpenAI API
 func (s *OpenAIService) SendChatRequest(req ChatRequest) (*ChatResponse, error) {
+	return s.sendChatRequest(context.Background(), req)
+}
+
+// sendChatRequest sends a chat request to OpenAI API using the given context
+func (s *OpenAIService) sendChatRequest(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
 	// Use default model if not specified
-	if req.Model == "" {
-		req.Model = s.config.OpenAIModel
+	model, err := s.resolveModel(req.Model)
+	if err != nil {
+		return nil, err
 	}
+	req.Model = model
 
 	// Use default temperature if not specified
 	if req.Temperature == 0 {
@@ -71,15 +173,16 @@ func (s *OpenAIService) SendChatRequest(req ChatRequest) (*ChatResponse, error)
 	}
 
 	// Call OpenAI API
-	resp, err := s.client.CreateChatCompletion(
-		context.Background(),
-		openai.ChatCompletionRequest{
-			Model:       req.Model,
-			Messages:    messages,
-			Temperature: req.Temperature,
-			MaxTokens:   req.MaxTokens,
-		},
-	)
+	request := openai.ChatCompletionRequest{
+		Model:          req.Model,
+		Messages:       messages,
+		Temperature:    req.Temperature,
+		MaxTokens:      req.MaxTokens,
+		Tools:          req.Tools,
+		ResponseFormat: req.ResponseFormat,
+	}
+	applyOpenAIReasoning(&request, req.ThinkingBudget)
+	resp, err := s.client.CreateChatCompletion(ctx, request)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to call OpenAI API: %w", err)
@@ -91,14 +194,57 @@ func (s *OpenAIService) SendChatRequest(req ChatRequest) (*ChatResponse, error)
 	}
 
 	// Build response
+	toolCalls := make([]ToolCall, 0, len(resp.Choices[0].Message.ToolCalls))
+	for _, call := range resp.Choices[0].Message.ToolCalls {
+		toolCalls = append(toolCalls, ToolCall{
+			ID:        call.ID,
+			Name:      call.Function.Name,
+			Arguments: call.Function.Arguments,
+		})
+	}
+
 	return &ChatResponse{
 		Content:      resp.Choices[0].Message.Content,
 		TokensUsed:   resp.Usage.TotalTokens,
+		Usage:        *openAIUsage(&resp.Usage),
 		Model:        resp.Model,
 		FinishReason: string(resp.Choices[0].FinishReason),
+		ToolCalls:    toolCalls,
+		Reasoning:    resp.Choices[0].Message.ReasoningContent,
 	}, nil
 }
 
+// isOpenAIReasoningModel reports whether a model is an OpenAI reasoning model (o-series, GPT-5)
+func isOpenAIReasoningModel(model string) bool {
+	for _, prefix := range []string{"o1", "o3", "o4", "gpt-5"} {
+		if strings.HasPrefix(model, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyOpenAIReasoning adapts a request to reasoning models: they take max_completion_tokens
+// (reasoning + answer) and no temperature, and a thinking budget maps to reasoning_effort
+func applyOpenAIReasoning(request *openai.ChatCompletionRequest, thinkingBudget int) {
+	if !isOpenAIReasoningModel(request.Model) {
+		return
+	}
+	request.MaxCompletionTokens = request.MaxTokens
+	request.MaxTokens = 0
+	request.Temperature = 0
+
+	switch {
+	case thinkingBudget <= 0:
+	case thinkingBudget <= 2048:
+		request.ReasoningEffort = "low"
+	case thinkingBudget <= 8192:
+		request.ReasoningEffort = "medium"
+	default:
+		request.ReasoningEffort = "high"
+	}
+}
+
 // BuildContextMessages builds context array from recent messages
 func (s *OpenAIService) BuildContextMessages(userMessage string, recentMessages []string, systemPrompt string) []openai.ChatCompletionMessage {
 	messages := []openai.ChatCompletionMessage{}
@@ -133,6 +279,7 @@ type OpenAITranscriptionResponse struct {
 	Text     string
 	Language string
 	Duration float64
+	Model    string
 }
 
 // TranscribeAudio transcribes audio file using OpenAI Whisper API
@@ -144,6 +291,7 @@ func (s *OpenAIService) TranscribeAudio(file io.Reader, filename string) (*OpenA
 		Model:    openai.Whisper1,
 		FilePath: filename,
 		Reader:   file,
+		Format:   openai.AudioResponseFormatVerboseJSON, // verbose_json includes language and duration
 	}
 
 	// Call Whisper API
@@ -152,12 +300,12 @@ func (s *OpenAIService) TranscribeAudio(file io.Reader, filename string) (*OpenA
 		return nil, fmt.Errorf("failed to transcribe audio: %w", err)
 	}
 
-	// Build response (duration is mock for now as API doesn't return it)
+	// Build response
 	return &OpenAITranscriptionResponse{
 		Text:     resp.Text,
 		Language: resp.Language,
-		Duration: 0, // Whisper API doesn't return duration
-		// Duration คือ ระยะเวลา (ความยาว) ของเสียงที่ทำการถอดคำพูด (transcription)
+		Duration: resp.Duration, // Duration คือ ระยะเวลา (ความยาว) ของเสียงที่ทำการถอดคำพูด (transcription)
+		Model:    openai.Whisper1,
 	}, nil
 }
 
@@ -215,20 +363,19 @@ func (s *OpenAIService) GetClient() *openai.Client {
 
 // OpenAIStreamReader implements StreamReader interface for OpenAI streaming
 type OpenAIStreamReader struct {
-	stream *openai.ChatCompletionStream
-	closed bool
+	stream    *openai.ChatCompletionStream
+	closed    bool
+	usage     *TokenUsage // Set by the final chunk (stream_options.include_usage)
+	toolCalls []ToolCall  // Assembled from tool_calls deltas (keyed by delta index)
+
+	reasoning        strings.Builder // reasoning_content deltas (OpenAI-compatible reasoning models)
+	pendingReasoning strings.Builder // Reasoning not yet taken by TakeReasoning
 }
 
 // CreateStreamingChat implements StreamingChatService interface
 func (s *OpenAIService) CreateStreamingChat(ctx context.Context, req StreamingChatRequest) (StreamReader, error) {
 	// Convert messages to OpenAI format
-	var messages []openai.ChatCompletionMessage
-	switch v := req.Messages.(type) {
-	case []openai.ChatCompletionMessage:
-		messages = v
-	default:
-		return nil, fmt.Errorf("unsupported message type for OpenAI")
-	}
+	messages := openAIPromptMessages(req)
 
 	// Use defaults if not specified
 	temperature := float32(req.Temperature)
@@ -247,25 +394,25 @@ func (s *OpenAIService) CreateStreamingChat(ctx context.Context, req StreamingCh
 		}
 	}
 
-	model := req.Model
-	if model == "" {
-		model = s.config.OpenAIModel
-		if model == "" {
-			model = "gpt-4o-mini"
-		}
+	model, err := s.resolveModel(req.Model)
+	if err != nil {
+		return nil, err
 	}
 
 	// Create streaming request
-	stream, err := s.client.CreateChatCompletionStream(
-		ctx,
-		openai.ChatCompletionRequest{
-			Model:       model,
-			Messages:    messages,
-			Temperature: temperature,
-			MaxTokens:   maxTokens,
-			Stream:      true,
-		},
-	)
+	request := openai.ChatCompletionRequest{
+		Model:          model,
+		Messages:       messages,
+		Temperature:    temperature,
+		MaxTokens:      maxTokens,
+		Stream:         true,
+		Tools:          openAITools(req.Tools),
+		ResponseFormat: openAIResponseFormat(req.ResponseSchema),
+		// Ask for a final chunk with token usage
+		StreamOptions: &openai.StreamOptions{IncludeUsage: true},
+	}
+	applyOpenAIReasoning(&request, req.ThinkingBudget)
+	stream, err := s.client.CreateChatCompletionStream(ctx, request)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to create streaming: %w", err)
@@ -291,15 +438,88 @@ func (r *OpenAIStreamReader) Recv() (string, error) {
 		return "", err
 	}
 
+	// The final chunk carries usage and no choices
+	if response.Usage != nil {
+		r.usage = openAIUsage(response.Usage)
+	}
+
 	// Get delta content
 	if len(response.Choices) > 0 {
-		delta := response.Choices[0].Delta.Content
-		return delta, nil
+		delta := response.Choices[0].Delta
+		r.recordToolCalls(delta.ToolCalls)
+		if delta.ReasoningContent != "" {
+			r.reasoning.WriteString(delta.ReasoningContent)
+			r.pendingReasoning.WriteString(delta.ReasoningContent)
+		}
+		return delta.Content, nil
 	}
 
 	return "", nil
 }
 
+// recordToolCalls appends streamed tool call fragments (ID and name come first, arguments in pieces)
+func (r *OpenAIStreamReader) recordToolCalls(deltas []openai.ToolCall) {
+	for _, delta := range deltas {
+		// Deltas without an index continue the last call
+		index := len(r.toolCalls) - 1
+		if delta.Index != nil {
+			index = *delta.Index
+		}
+		if index < 0 {
+			index = 0
+		}
+		for len(r.toolCalls) <= index {
+			r.toolCalls = append(r.toolCalls, ToolCall{})
+		}
+
+		call := &r.toolCalls[index]
+		if delta.ID != "" {
+			call.ID = delta.ID
+		}
+		if delta.Function.Name != "" {
+			call.Name = delta.Function.Name
+		}
+		call.Arguments += delta.Function.Arguments
+	}
+}
+
+// TakeReasoning returns the reasoning received since the last call
+func (r *OpenAIStreamReader) TakeReasoning() string {
+	text := r.pendingReasoning.String()
+	r.pendingReasoning.Reset()
+	return text
+}
+
+// Reasoning returns the reasoning of the stream
+func (r *OpenAIStreamReader) Reasoning() []ReasoningBlock {
+	if r.reasoning.Len() == 0 {
+		return nil
+	}
+	return []ReasoningBlock{{Text: r.reasoning.String()}}
+}
+
+// ToolCalls returns the tool calls requested in the stream
+func (r *OpenAIStreamReader) ToolCalls() []ToolCall {
+	return r.toolCalls
+}
+
+// Usage returns the token usage reported at the end of the stream
+func (r *OpenAIStreamReader) Usage() *TokenUsage {
+	return r.usage
+}
+
+// openAIUsage converts OpenAI usage to TokenUsage
+func openAIUsage(usage *openai.Usage) *TokenUsage {
+	result := &TokenUsage{
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+	}
+	if usage.PromptTokensDetails != nil {
+		result.CachedTokens = usage.PromptTokensDetails.CachedTokens
+	}
+	return result
+}
+
 // Close closes the OpenAI stream
 func (r *OpenAIStreamReader) Close() error {
 	r.closed = true
@@ -309,12 +529,187 @@ func (r *OpenAIStreamReader) Close() error {
 	return nil
 }
 
-// GetProviderName returns "openai"
+// CompleteChat implements ChatCompleter using the non-streaming Chat Completions API
+func (s *OpenAIService) CompleteChat(ctx context.Context, req StreamingChatRequest) (*ChatCompletion, error) {
+	resp, err := s.sendChatRequest(ctx, ChatRequest{
+		Messages:       openAIPromptMessages(req),
+		Model:          req.Model,
+		Temperature:    float32(req.Temperature),
+		MaxTokens:      req.MaxTokens,
+		Tools:          openAITools(req.Tools),
+		ResponseFormat: openAIResponseFormat(req.ResponseSchema),
+		ThinkingBudget: req.ThinkingBudget,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	completion := &ChatCompletion{
+		Content:      resp.Content,
+		TokensUsed:   resp.TokensUsed,
+		Usage:        &resp.Usage,
+		Model:        resp.Model,
+		FinishReason: resp.FinishReason,
+		ToolCalls:    resp.ToolCalls,
+	}
+	if resp.Reasoning != "" {
+		completion.Reasoning = []ReasoningBlock{{Text: resp.Reasoning}}
+	}
+	return completion, nil
+}
+
+// openAITools translates tool definitions to OpenAI function tools
+func openAITools(definitions []ToolDefinition) []openai.Tool {
+	if len(definitions) == 0 {
+		return nil
+	}
+	tools := make([]openai.Tool, 0, len(definitions))
+	for _, definition := range definitions {
+		tools = append(tools, openai.Tool{
+			Type: openai.ToolTypeFunction,
+			Function: &openai.FunctionDefinition{
+				Name:        definition.Name,
+				Description: definition.Description,
+				Parameters:  definition.Parameters,
+			},
+		})
+	}
+	return tools
+}
+
+// openAIResponseFormat translates a response schema to an OpenAI json_schema response format
+func openAIResponseFormat(schema *ResponseSchema) *openai.ChatCompletionResponseFormat {
+	if schema == nil {
+		return nil
+	}
+	return &openai.ChatCompletionResponseFormat{
+		Type: openai.ChatCompletionResponseFormatTypeJSONSchema,
+		JSONSchema: &openai.ChatCompletionResponseFormatJSONSchema{
+			Name:        schema.Name,
+			Description: schema.Description,
+			Schema:      schema.Schema,
+			Strict:      schema.Strict,
+		},
+	}
+}
+
+// openAIPromptMessages translates a request's conversation to OpenAI chat messages
+// With prompt caching, the file context follows the system prompt instead of preceding the user
+// turn, so OpenAI's automatic prefix cache covers it while the user keeps asking about the same files
+func openAIPromptMessages(req StreamingChatRequest) []openai.ChatCompletionMessage {
+	if !req.PromptCaching {
+		return toOpenAIMessages(req.SystemPrompt, req.Messages)
+	}
+
+	fileContext, conversation := HoistFileContext(req.Messages)
+	messages := toOpenAIMessages(req.SystemPrompt, conversation)
+	if fileContext == "" {
+		return messages
+	}
+
+	position := 0
+	if req.SystemPrompt != "" {
+		position = 1
+	}
+	fileMessage := openai.ChatCompletionMessage{Role: openai.ChatMessageRoleSystem, Content: fileContext}
+	return append(messages[:position], append([]openai.ChatCompletionMessage{fileMessage}, messages[position:]...)...)
+}
+
+// toOpenAIMessages translates a provider-neutral conversation to OpenAI chat messages
+// File parts become a system message in front of the user turn, images become image_url parts
+func toOpenAIMessages(systemPrompt string, messages []ConversationMessage) []openai.ChatCompletionMessage {
+	result := make([]openai.ChatCompletionMessage, 0, len(messages)+1)
+
+	if systemPrompt != "" {
+		result = append(result, openai.ChatCompletionMessage{
+			Role:    openai.ChatMessageRoleSystem,
+			Content: systemPrompt,
+		})
+	}
+
+	for _, msg := range messages {
+		switch msg.Role {
+		case ConversationRoleSystem:
+			result = append(result, openai.ChatCompletionMessage{
+				Role:    openai.ChatMessageRoleSystem,
+				Content: msg.Text(),
+			})
+
+		case ConversationRoleTool:
+			result = append(result, openai.ChatCompletionMessage{
+				Role:       openai.ChatMessageRoleTool,
+				Content:    msg.Text(),
+				ToolCallID: msg.ToolCallID,
+			})
+
+		case ConversationRoleAssistant:
+			assistant := openai.ChatCompletionMessage{
+				Role:    openai.ChatMessageRoleAssistant,
+				Content: msg.Text(),
+			}
+			for _, call := range msg.ToolCalls {
+				assistant.ToolCalls = append(assistant.ToolCalls, openai.ToolCall{
+					ID:   call.ID,
+					Type: openai.ToolTypeFunction,
+					Function: openai.FunctionCall{
+						Name:      call.Name,
+						Arguments: call.Arguments,
+					},
+				})
+			}
+			result = append(result, assistant)
+
+		default:
+			// File context goes in a system message right before the user turn
+			if fileParts := msg.PartsOfType(ContentPartFile); len(fileParts) > 0 {
+				result = append(result, openai.ChatCompletionMessage{
+					Role:    openai.ChatMessageRoleSystem,
+					Content: FormatFileContext(fileParts),
+				})
+			}
+
+			imageParts := msg.PartsOfType(ContentPartImage)
+			if len(imageParts) == 0 {
+				result = append(result, openai.ChatCompletionMessage{
+					Role:    openai.ChatMessageRoleUser,
+					Content: msg.Text(),
+				})
+				continue
+			}
+
+			// Build multipart message with text and images
+			multiContent := []openai.ChatMessagePart{
+				{
+					Type: openai.ChatMessagePartTypeText,
+					Text: msg.Text(),
+				},
+			}
+			for _, image := range imageParts {
+				dataURL := fmt.Sprintf("data:%s;base64,%s", image.MimeType, base64.StdEncoding.EncodeToString(image.Data))
+				multiContent = append(multiContent, openai.ChatMessagePart{
+					Type: openai.ChatMessagePartTypeImageURL,
+					ImageURL: &openai.ChatMessageImageURL{
+						URL:    dataURL,
+						Detail: openai.ImageURLDetailAuto,
+					},
+				})
+			}
+			result = append(result, openai.ChatCompletionMessage{
+				Role:         openai.ChatMessageRoleUser,
+				MultiContent: multiContent,
+			})
+		}
+	}
+
+	return result
+}
+
+// GetProviderName returns "openai" or the name of the OpenAI-compatible provider
 func (s *OpenAIService) GetProviderName() string {
-	return "openai"
+	return s.name
 }
 
 // IsAvailable checks if OpenAI service is configured and ready
 func (s *OpenAIService) IsAvailable() bool {
-	return s.client != nil && s.config.OpenAIAPIKey != ""
-}
\ No newline at end of file
+	return s.client != nil && (s.apiKey != "" || !s.requiresKey)
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"math"
+	"strings"
+
+	"chatbot/config"
+)
+
+// PricingService computes the USD cost of chat, TTS and STT calls from the pricing table
+type PricingService struct {
+	table config.PricingTable
+}
+
+// NewPricingService creates a new pricing service
+func NewPricingService(table config.PricingTable) *PricingService {
+	return &PricingService{table: table}
+}
+
+// ChatCost returns the cost of a chat completion; ok is false if the model has no price
+// PromptTokens includes CachedTokens and CacheWriteTokens, which are billed at the cached
+// and cache write rates (input rate if unset)
+func (s *PricingService) ChatCost(model string, usage *TokenUsage) (cost float64, ok bool) {
+	if usage == nil {
+		return 0, false
+	}
+	price, ok := lookupPrice(s.table.Models, model)
+	if !ok {
+		return 0, false
+	}
+
+	cachedRate := price.Cached
+	if cachedRate == 0 {
+		cachedRate = price.Input
+	}
+	writeRate := price.CacheWrite
+	if writeRate == 0 {
+		writeRate = price.Input
+	}
+	cached := min(usage.CachedTokens, usage.PromptTokens)
+	written := min(usage.CacheWriteTokens, usage.PromptTokens-cached)
+	uncached := usage.PromptTokens - cached - written
+
+	cost = (float64(uncached)*price.Input +
+		float64(cached)*cachedRate +
+		float64(written)*writeRate +
+		float64(usage.CompletionTokens)*price.Output) / 1_000_000
+	return roundCost(cost), true
+}
+
+// ModelPrice returns the chat price of a model; ok is false if the model has no price
+func (s *PricingService) ModelPrice(model string) (price config.ModelPrice, ok bool) {
+	return lookupPrice(s.table.Models, model)
+}
+
+// TTSCost returns the cost of synthesizing the given number of characters
+func (s *PricingService) TTSCost(model string, characters int) (cost float64, ok bool) {
+	price, ok := lookupPrice(s.table.TTS, model)
+	if !ok {
+		return 0, false
+	}
+	return roundCost(float64(characters) * price), true
+}
+
+// STTCost returns the cost of transcribing the given audio duration
+func (s *PricingService) STTCost(model string, durationSeconds float64) (cost float64, ok bool) {
+	price, ok := lookupPrice(s.table.STT, model)
+	if !ok {
+		return 0, false
+	}
+	return roundCost(durationSeconds / 60 * price), true
+}
+
+// lookupPrice finds a price for the model name, then for the name without each dotted
+// prefix (Bedrock IDs like "apac.anthropic.claude-sonnet-4-20250514-v1:0")
+func lookupPrice[T any](prices map[string]T, model string) (T, bool) {
+	for name := model; name != ""; {
+		if price, ok := lookupModelPrice(prices, name); ok {
+			return price, true
+		}
+		dot := strings.Index(name, ".")
+		if dot < 0 {
+			break
+		}
+		name = name[dot+1:]
+	}
+	var zero T
+	return zero, false
+}
+
+// lookupModelPrice finds a price by exact model name, then by the longest matching prefix
+func lookupModelPrice[T any](prices map[string]T, model string) (T, bool) {
+	var zero T
+	if price, ok := prices[model]; ok {
+		return price, true
+	}
+
+	bestKey := ""
+	for key := range prices {
+		if strings.HasPrefix(model, key) && len(key) > len(bestKey) {
+			bestKey = key
+		}
+	}
+	if bestKey == "" {
+		return zero, false
+	}
+	return prices[bestKey], true
+}
+
+// roundCost rounds to 8 decimal places (enough for per-token prices)
+func roundCost(cost float64) float64 {
+	return math.Round(cost*1e8) / 1e8
+}
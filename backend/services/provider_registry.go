@@ -0,0 +1,279 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+
+	"chatbot/config"
+)
+
+// ProviderCapabilities describes what a streaming chat provider supports
+type ProviderCapabilities struct {
+	Vision     bool `json:"vision"`      // Accepts image parts in user messages
+	Streaming  bool `json:"streaming"`   // Supports token-by-token streaming
+	MaxContext int  `json:"max_context"` // Context window of the default model (tokens)
+	Local      bool `json:"local"`       // Runs on-premises: requests never fail over to another provider
+}
+
+// ModelLister is implemented by providers that know their default and allowed models
+type ModelLister interface {
+	DefaultModel() string
+	AllowedModels() []string // Empty = any model
+}
+
+// ProviderFactory builds a StreamingChatService from application config
+type ProviderFactory func(cfg *config.Config) (StreamingChatService, error)
+
+// providerDefinition is what each provider file registers in its init()
+type providerDefinition struct {
+	name         string
+	priority     int // Lower value is preferred when auto-detecting
+	capabilities ProviderCapabilities
+	factory      ProviderFactory
+}
+
+var (
+	providerDefinitionsMu sync.Mutex
+	providerDefinitions   = map[string]providerDefinition{}
+)
+
+// RegisterProvider registers a provider factory under a unique name
+// Each StreamingChatService implementation calls this from its own init()
+func RegisterProvider(name string, priority int, capabilities ProviderCapabilities, factory ProviderFactory) {
+	providerDefinitionsMu.Lock()
+	defer providerDefinitionsMu.Unlock()
+
+	name = strings.ToLower(name)
+	if _, exists := providerDefinitions[name]; exists {
+		panic(fmt.Sprintf("provider %q registered twice", name))
+	}
+
+	providerDefinitions[name] = providerDefinition{
+		name:         name,
+		priority:     priority,
+		capabilities: capabilities,
+		factory:      factory,
+	}
+}
+
+// ProviderInfo describes a registered provider (used by the API)
+type ProviderInfo struct {
+	Name          string               `json:"name"`
+	Available     bool                 `json:"available"`
+	Capabilities  ProviderCapabilities `json:"capabilities"`
+	DefaultModel  string               `json:"default_model,omitempty"`
+	AllowedModels []string             `json:"allowed_models,omitempty"`
+}
+
+// registeredProvider is an initialized provider inside a ProviderRegistry
+type registeredProvider struct {
+	priority     int
+	capabilities ProviderCapabilities
+	service      StreamingChatService
+}
+
+// ProviderRegistry resolves streaming chat providers by name
+type ProviderRegistry struct {
+	mu        sync.RWMutex
+	providers map[string]registeredProvider
+}
+
+// NewProviderRegistry creates an empty provider registry
+func NewProviderRegistry() *ProviderRegistry {
+	return &ProviderRegistry{
+		providers: make(map[string]registeredProvider),
+	}
+}
+
+// NewProviderRegistryFromConfig initializes every registered provider factory
+// Providers that fail to initialize are logged and skipped
+func NewProviderRegistryFromConfig(cfg *config.Config) *ProviderRegistry {
+	registry := NewProviderRegistry()
+
+	providerDefinitionsMu.Lock()
+	definitions := make([]providerDefinition, 0, len(providerDefinitions))
+	for _, def := range providerDefinitions {
+		definitions = append(definitions, def)
+	}
+	providerDefinitionsMu.Unlock()
+
+	for _, def := range definitions {
+		service, err := def.factory(cfg)
+		if err != nil {
+			log.Printf("⚠️ Warning: Failed to initialize %s provider: %v", def.name, err)
+			continue
+		}
+		registry.Register(def.name, def.priority, def.capabilities, service)
+	}
+
+	// Named OpenAI-compatible providers from config (after the built-in providers)
+	for i, providerCfg := range cfg.OpenAICompatibleProviders {
+		if _, exists := registry.Capabilities(providerCfg.Name); exists {
+			log.Printf("⚠️ Warning: OpenAI-compatible provider %s conflicts with a built-in provider, skipping", providerCfg.Name)
+			continue
+		}
+
+		service, err := NewOpenAICompatibleService(cfg, providerCfg)
+		if err != nil {
+			log.Printf("⚠️ Warning: Failed to initialize %s provider: %v", providerCfg.Name, err)
+			continue
+		}
+		registry.Register(providerCfg.Name, 100+i, ProviderCapabilities{
+			Vision:    providerCfg.Vision,
+			Streaming: true,
+			Local:     providerCfg.Local,
+		}, service)
+	}
+
+	return registry
+}
+
+// Register adds an initialized provider to the registry (replaces an existing one with the same name)
+func (r *ProviderRegistry) Register(name string, priority int, capabilities ProviderCapabilities, service StreamingChatService) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.providers[strings.ToLower(name)] = registeredProvider{
+		priority:     priority,
+		capabilities: capabilities,
+		service:      service,
+	}
+}
+
+// Get returns the provider with the given name if it is registered and available
+func (r *ProviderRegistry) Get(name string) (StreamingChatService, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	provider, ok := r.providers[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("invalid provider: %s (valid options: %s)", name, strings.Join(r.namesLocked(), ", "))
+	}
+	if provider.service == nil || !provider.service.IsAvailable() {
+		return nil, fmt.Errorf("%s provider not available (check credentials in .env)", name)
+	}
+
+	return provider.service, nil
+}
+
+// Models returns the default and allowed models of a registered provider
+// ok is false if the provider is not registered; allowed is empty when any model is accepted
+func (r *ProviderRegistry) Models(name string) (defaultModel string, allowed []string, ok bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	provider, exists := r.providers[strings.ToLower(name)]
+	if !exists {
+		return "", nil, false
+	}
+	if lister, isLister := provider.service.(ModelLister); isLister {
+		return lister.DefaultModel(), lister.AllowedModels(), true
+	}
+	return "", nil, true
+}
+
+// ValidateModel checks that the provider is registered and accepts the model
+// Returns the provider's default model when model is empty
+func (r *ProviderRegistry) ValidateModel(provider, model string) (string, error) {
+	defaultModel, allowed, ok := r.Models(provider)
+	if !ok {
+		return "", fmt.Errorf("invalid provider: %s (valid options: %v)", provider, r.Names())
+	}
+
+	if model == "" {
+		model = defaultModel
+	}
+	if model == "" {
+		return "", fmt.Errorf("model is required for provider %s", provider)
+	}
+	if len(allowed) == 0 {
+		return model, nil
+	}
+	for _, name := range allowed {
+		if name == model {
+			return model, nil
+		}
+	}
+	return "", fmt.Errorf("model %s is not allowed for provider %s (allowed: %v)", model, provider, allowed)
+}
+
+// Resolve returns the named provider, or the first available provider by priority when name is empty
+func (r *ProviderRegistry) Resolve(name string) (StreamingChatService, error) {
+	if name != "" {
+		return r.Get(name)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, providerName := range r.namesLocked() {
+		provider := r.providers[providerName]
+		if provider.service != nil && provider.service.IsAvailable() {
+			return provider.service, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no AI provider available (check OPENAI_API_KEY or AWS credentials in .env)")
+}
+
+// Capabilities returns the capabilities of a registered provider
+func (r *ProviderRegistry) Capabilities(name string) (ProviderCapabilities, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	provider, ok := r.providers[strings.ToLower(name)]
+	return provider.capabilities, ok
+}
+
+// Names returns registered provider names ordered by priority
+func (r *ProviderRegistry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.namesLocked()
+}
+
+// List returns information about all registered providers ordered by priority
+func (r *ProviderRegistry) List() []ProviderInfo {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := r.namesLocked()
+	infos := make([]ProviderInfo, 0, len(names))
+	for _, name := range names {
+		provider := r.providers[name]
+		info := ProviderInfo{
+			Name:         name,
+			Available:    provider.service != nil && provider.service.IsAvailable(),
+			Capabilities: provider.capabilities,
+		}
+		if lister, ok := provider.service.(ModelLister); ok {
+			info.DefaultModel = lister.DefaultModel()
+			info.AllowedModels = lister.AllowedModels()
+		}
+		infos = append(infos, info)
+	}
+
+	return infos
+}
+
+// namesLocked returns provider names sorted by priority then name (caller must hold the lock)
+func (r *ProviderRegistry) namesLocked() []string {
+	names := make([]string, 0, len(r.providers))
+	for name := range r.providers {
+		names = append(names, name)
+	}
+
+	sort.Slice(names, func(i, j int) bool {
+		pi, pj := r.providers[names[i]].priority, r.providers[names[j]].priority
+		if pi != pj {
+			return pi < pj
+		}
+		return names[i] < names[j]
+	})
+
+	return names
+}
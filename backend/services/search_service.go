@@ -0,0 +1,324 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"chatbot/config"
+	"chatbot/models"
+	"chatbot/repositories"
+	"chatbot/utils"
+
+	"github.com/google/uuid"
+)
+
+// SearchMode selects how messages are matched
+type SearchMode string
+
+// Search modes
+const (
+	SearchModeText     SearchMode = "text"     // PostgreSQL full-text search (Thai-aware, see utils.SearchTerms)
+	SearchModeSemantic SearchMode = "semantic" // Nearest message embeddings
+	SearchModeHybrid   SearchMode = "hybrid"   // Both, merged by reciprocal rank fusion
+)
+
+// Search limits
+const (
+	snippetRadius       = 80 // Characters shown around the first match
+	rrfK                = 60 // Reciprocal rank fusion constant
+	embeddingBackfill   = 100
+	highlightOpen       = "<mark>"
+	highlightClose      = "</mark>"
+	snippetEllipsis     = "…"
+	searchIndexBatch    = 500
+	embedSessionTimeout = 60 * time.Second
+)
+
+// SearchRequest is a message search
+type SearchRequest struct {
+	Query  string
+	Mode   SearchMode
+	Filter repositories.SearchFilter
+}
+
+// SearchResult is a found message with a highlighted snippet
+type SearchResult struct {
+	Message           models.Message
+	Score             float64
+	Snippet           string // HTML-escaped content around the match, matches wrapped in <mark>
+	ConversationTitle string
+}
+
+// SearchService searches chat history by text and, when messages are embedded, by meaning
+type SearchService struct {
+	repo          *repositories.SearchRepository
+	embeddings    *EmbeddingService
+	vectors       bool // pgvector is installed
+	embedMessages bool
+	running       sync.Map
+}
+
+// NewSearchService creates a new search service
+func NewSearchService(repo *repositories.SearchRepository, embeddings *EmbeddingService, cfg *config.Config) *SearchService {
+	return &SearchService{
+		repo:          repo,
+		embeddings:    embeddings,
+		vectors:       repo.VectorsEnabled(),
+		embedMessages: cfg.SearchEmbedMessages,
+	}
+}
+
+// SemanticAvailable reports whether semantic and hybrid search can be used
+func (s *SearchService) SemanticAvailable() bool {
+	return s.vectors && s.embedMessages && s.embeddings.Available()
+}
+
+// Search finds messages matching the request, most relevant first, with the total number of matches
+// (semantic and hybrid searches return the nearest messages, so their total is the number returned)
+func (s *SearchService) Search(ctx context.Context, req SearchRequest) ([]SearchResult, int64, error) {
+	if req.Mode == "" {
+		req.Mode = SearchModeText
+	}
+	if req.Mode != SearchModeText && !s.SemanticAvailable() {
+		return nil, 0, fmt.Errorf("semantic search is not available (needs pgvector, OPENAI_API_KEY and SEARCH_EMBED_MESSAGES=true)")
+	}
+
+	var hits []repositories.SearchHit
+	var total int64
+	var err error
+	switch req.Mode {
+	case SearchModeText:
+		hits, total, err = s.textSearch(req.Query, req.Filter)
+	case SearchModeSemantic:
+		hits, err = s.semanticSearch(ctx, req.Query, req.Filter)
+		total = int64(len(hits))
+	case SearchModeHybrid:
+		hits, err = s.hybridSearch(ctx, req.Query, req.Filter)
+		total = int64(len(hits))
+	default:
+		return nil, 0, fmt.Errorf("unknown search mode %q (valid options: text, semantic, hybrid)", req.Mode)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	results := make([]SearchResult, len(hits))
+	for i, hit := range hits {
+		results[i] = SearchResult{
+			Message:           hit.Message,
+			Score:             hit.Score,
+			Snippet:           Snippet(hit.Content, req.Query, snippetRadius),
+			ConversationTitle: hit.ConversationTitle,
+		}
+	}
+	return results, total, nil
+}
+
+// textSearch runs a full-text search; a query without searchable terms finds nothing
+func (s *SearchService) textSearch(query string, filter repositories.SearchFilter) ([]repositories.SearchHit, int64, error) {
+	tsquery := utils.SearchQuery(query)
+	if tsquery == "" {
+		return nil, 0, nil
+	}
+	return s.repo.TextSearch(tsquery, filter)
+}
+
+// semanticSearch embeds the query and finds the nearest messages
+func (s *SearchService) semanticSearch(ctx context.Context, query string, filter repositories.SearchFilter) ([]repositories.SearchHit, error) {
+	vectors, err := s.embeddings.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.VectorSearch(vectors[0], filter)
+}
+
+// hybridSearch merges the text and semantic rankings with reciprocal rank fusion
+// (score = Σ 1/(60 + rank)), so messages found both ways rank first
+func (s *SearchService) hybridSearch(ctx context.Context, query string, filter repositories.SearchFilter) ([]repositories.SearchHit, error) {
+	candidates := filter
+	candidates.Offset = 0
+	candidates.Limit = (filter.Offset + filter.Limit) * 2
+
+	textHits, _, err := s.textSearch(query, candidates)
+	if err != nil {
+		return nil, err
+	}
+	semanticHits, err := s.semanticSearch(ctx, query, candidates)
+	if err != nil {
+		return nil, err
+	}
+
+	fused := map[uuid.UUID]*repositories.SearchHit{}
+	scores := map[uuid.UUID]float64{}
+	for _, ranking := range [][]repositories.SearchHit{textHits, semanticHits} {
+		for rank := range ranking {
+			hit := ranking[rank]
+			if _, ok := fused[hit.ID]; !ok {
+				fused[hit.ID] = &hit
+			}
+			scores[hit.ID] += 1.0 / float64(rrfK+rank+1)
+		}
+	}
+
+	hits := make([]repositories.SearchHit, 0, len(fused))
+	for id, hit := range fused {
+		hit.Score = scores[id]
+		hits = append(hits, *hit)
+	}
+	sort.SliceStable(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].CreatedAt.After(hits[j].CreatedAt)
+	})
+
+	if filter.Offset >= len(hits) {
+		return nil, nil
+	}
+	return hits[filter.Offset:min(filter.Offset+filter.Limit, len(hits))], nil
+}
+
+// IndexSession embeds the session's new messages in the background (no-op unless semantic search is on)
+func (s *SearchService) IndexSession(sessionID string) {
+	if s == nil || sessionID == "" || !s.SemanticAvailable() {
+		return
+	}
+	if _, busy := s.running.LoadOrStore(sessionID, true); busy {
+		return
+	}
+
+	go func() {
+		defer s.running.Delete(sessionID)
+		ctx, cancel := context.WithTimeout(context.Background(), embedSessionTimeout)
+		defer cancel()
+
+		if _, err := s.embedMissing(ctx, sessionID, embeddingBackfill); err != nil {
+			log.Printf("⚠️  Failed to embed messages of session %s: %v", sessionID, err)
+		}
+	}()
+}
+
+// IndexAll fills the search text of older messages and, with semantic search on, embeds every
+// message that has no embedding yet; meant to run in the background at startup
+func (s *SearchService) IndexAll(ctx context.Context) {
+	if indexed, err := s.repo.IndexSearchText(searchIndexBatch); err != nil {
+		log.Printf("⚠️  Failed to index messages for search: %v", err)
+	} else if indexed > 0 {
+		log.Printf("✓ Indexed %d messages for full-text search", indexed)
+	}
+
+	if !s.SemanticAvailable() {
+		return
+	}
+	total := 0
+	for {
+		embedded, err := s.embedMissing(ctx, "", embeddingBackfill)
+		if err != nil {
+			log.Printf("⚠️  Failed to embed messages: %v", err)
+			return
+		}
+		if embedded == 0 {
+			break
+		}
+		total += embedded
+	}
+	if total > 0 {
+		log.Printf("✓ Embedded %d messages for semantic search", total)
+	}
+}
+
+// embedMissing embeds up to limit messages without an embedding and returns how many were stored
+func (s *SearchService) embedMissing(ctx context.Context, sessionID string, limit int) (int, error) {
+	messages, err := s.repo.MissingEmbeddings(sessionID, limit)
+	if err != nil || len(messages) == 0 {
+		return 0, err
+	}
+
+	texts := make([]string, len(messages))
+	for i, msg := range messages {
+		texts[i] = msg.Content
+	}
+	vectors, err := s.embeddings.Embed(ctx, texts)
+	if err != nil {
+		return 0, err
+	}
+
+	embeddings := make([]models.MessageEmbedding, len(messages))
+	for i, msg := range messages {
+		embeddings[i] = models.MessageEmbedding{MessageID: msg.ID, Model: s.embeddings.Model(), Embedding: vectors[i]}
+	}
+	if err := s.repo.SaveEmbeddings(embeddings); err != nil {
+		return 0, err
+	}
+	return len(embeddings), nil
+}
+
+// Snippet returns the part of content around the first word of query it contains, HTML-escaped,
+// with every occurrence of the query's words wrapped in <mark>; without a literal match (semantic
+// results, prefix matches) it returns the start of content
+func Snippet(content, query string, radius int) string {
+	text := []rune(strings.Join(strings.Fields(content), " "))
+	lower := make([]rune, len(text))
+	for i, r := range text {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	var needles [][]rune
+	for _, word := range strings.Fields(strings.ToLower(query)) {
+		needles = append(needles, []rune(word))
+	}
+
+	first := -1
+	for i := range lower {
+		if matchAt(lower, i, needles) > 0 {
+			first = i
+			break
+		}
+	}
+
+	start, end := 0, min(len(text), radius*2)
+	if first >= 0 {
+		start = max(0, first-radius)
+		end = min(len(text), first+radius)
+	}
+
+	var snippet strings.Builder
+	if start > 0 {
+		snippet.WriteString(snippetEllipsis)
+	}
+	for i := start; i < end; {
+		if length := matchAt(lower, i, needles); length > 0 {
+			stop := min(i+length, len(text))
+			snippet.WriteString(highlightOpen + html.EscapeString(string(text[i:stop])) + highlightClose)
+			i = stop
+			continue
+		}
+		snippet.WriteString(html.EscapeString(string(text[i])))
+		i++
+	}
+	if end < len(text) {
+		snippet.WriteString(snippetEllipsis)
+	}
+	return snippet.String()
+}
+
+// matchAt returns the length of the longest needle found at position i of text (0 = none)
+func matchAt(text []rune, i int, needles [][]rune) int {
+	longest := 0
+	for _, needle := range needles {
+		if len(needle) <= longest || i+len(needle) > len(text) {
+			continue
+		}
+		if string(text[i:i+len(needle)]) == string(needle) {
+			longest = len(needle)
+		}
+	}
+	return longest
+}
@@ -3,6 +3,7 @@ package services
 import (
 	"context"
 	"io"
+	"strings"
 )
 
 // StreamingChatService provides a unified interface for streaming chat across different AI providers
@@ -29,11 +30,88 @@ type StreamReader interface {
 	Close() error
 }
 
+// TokenUsage is the token accounting reported by a provider
+type TokenUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	CachedTokens     int `json:"cached_tokens"`      // Prompt tokens served from the provider's prompt cache
+	CacheWriteTokens int `json:"cache_write_tokens"` // Prompt tokens written to the prompt cache (Claude)
+}
+
+// Total returns prompt + completion tokens
+func (u TokenUsage) Total() int {
+	return u.PromptTokens + u.CompletionTokens
+}
+
+// CacheMetadata describes prompt cache activity for message metadata (nil if the cache was not used)
+func (u *TokenUsage) CacheMetadata() map[string]interface{} {
+	if u == nil || (u.CachedTokens == 0 && u.CacheWriteTokens == 0) {
+		return nil
+	}
+	return map[string]interface{}{
+		"cache_hit":          u.CachedTokens > 0,
+		"cached_tokens":      u.CachedTokens,
+		"cache_write_tokens": u.CacheWriteTokens,
+	}
+}
+
+// UsageReporter is implemented by stream readers that receive final usage from the provider
+// Usage returns nil until the provider has reported it (normally right before io.EOF)
+type UsageReporter interface {
+	Usage() *TokenUsage
+}
+
+// StreamUsage returns the usage reported by a stream, or nil if the provider did not report any
+func StreamUsage(stream StreamReader) *TokenUsage {
+	if reporter, ok := stream.(UsageReporter); ok {
+		return reporter.Usage()
+	}
+	return nil
+}
+
+// ToolCallReporter is implemented by stream readers that collect tool calls from the provider
+// ToolCalls is complete once Recv has returned io.EOF
+type ToolCallReporter interface {
+	ToolCalls() []ToolCall
+}
+
+// StreamToolCalls returns the tool calls requested in a stream, or nil if there were none
+func StreamToolCalls(stream StreamReader) []ToolCall {
+	if reporter, ok := stream.(ToolCallReporter); ok {
+		return reporter.ToolCalls()
+	}
+	return nil
+}
+
+// ReasoningReporter is implemented by stream readers of reasoning models
+// Recv returns "" after a reasoning delta; TakeReasoning returns the reasoning text received
+// since its last call, Reasoning the complete blocks once Recv has returned io.EOF
+type ReasoningReporter interface {
+	TakeReasoning() string
+	Reasoning() []ReasoningBlock
+}
+
+// StreamReasoning returns the reasoning blocks of a stream, or nil if the model sent none
+func StreamReasoning(stream StreamReader) []ReasoningBlock {
+	if reporter, ok := stream.(ReasoningReporter); ok {
+		return reporter.Reasoning()
+	}
+	return nil
+}
+
+// TakeStreamReasoning returns the reasoning text a stream received since the last call
+func TakeStreamReasoning(stream StreamReader) string {
+	if reporter, ok := stream.(ReasoningReporter); ok {
+		return reporter.TakeReasoning()
+	}
+	return ""
+}
+
 // StreamingChatRequest represents a unified request for streaming chat
 type StreamingChatRequest struct {
-	// Messages can be either []openai.ChatCompletionMessage or []ClaudeMessage
-	// The service implementation will handle the conversion
-	Messages interface{}
+	// Messages is the provider-neutral conversation (see conversation.go)
+	// Each service implementation translates it to its own wire format
+	Messages []ConversationMessage
 
 	// SystemPrompt is the system prompt for the conversation
 	SystemPrompt string
@@ -46,6 +124,24 @@ type StreamingChatRequest struct {
 
 	// Model is the specific model ID to use (optional, uses default if empty)
 	Model string
+
+	// Tools the model may call (optional); providers without tool support ignore them
+	Tools []ToolDefinition
+
+	// ResponseSchema asks for JSON matching a schema (optional, see StructuredOutputService)
+	ResponseSchema *ResponseSchema
+
+	// ThinkingBudget is the tokens a reasoning model may spend thinking before it answers
+	// (0 = off). MaxTokens covers reasoning and answer; other models ignore it
+	ThinkingBudget int
+
+	// PromptCaching keeps the system prompt and file context at the start of the prompt and
+	// marks cache breakpoints on providers that need them (Claude); OpenAI caches automatically
+	PromptCaching bool
+
+	// PinProvider keeps the request on its provider: the client chose the provider or model
+	// itself, so FailoverStreamer retries it but never fails over to another provider
+	PinProvider bool
 }
 
 // StreamingChatResponse represents a chunk of streaming response
@@ -63,5 +159,80 @@ type StreamingChatResponse struct {
 	Error error
 }
 
+// ChatCompletion represents a complete (non-streaming) answer from a provider
+type ChatCompletion struct {
+	Content      string
+	TokensUsed   int
+	Usage        *TokenUsage // nil if the provider did not report usage
+	Provider     string      // Set by CompleteChat
+	Model        string
+	FinishReason string
+	ToolCalls    []ToolCall       // Tool calls requested by the model (request had Tools)
+	Reasoning    []ReasoningBlock // Reasoning before the answer (request had ThinkingBudget)
+}
+
+// ChatCompleter is implemented by providers that have a native non-streaming API
+// Providers without it are still usable through CompleteChat, which drains the stream
+type ChatCompleter interface {
+	CompleteChat(ctx context.Context, req StreamingChatRequest) (*ChatCompletion, error)
+}
+
+// CompleteChat returns the full answer for a request using any StreamingChatService
+func CompleteChat(ctx context.Context, service StreamingChatService, req StreamingChatRequest) (*ChatCompletion, error) {
+	if completer, ok := service.(ChatCompleter); ok {
+		completion, err := completer.CompleteChat(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		completion.Provider = service.GetProviderName()
+		if completion.Model == "" {
+			completion.Model = ResolvedModel(service, req.Model)
+		}
+		return completion, nil
+	}
+
+	stream, err := service.CreateStreamingChat(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+
+	var content strings.Builder
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		content.WriteString(chunk)
+	}
+
+	completion := &ChatCompletion{
+		Content:   content.String(),
+		Provider:  service.GetProviderName(),
+		Model:     ResolvedModel(service, req.Model),
+		ToolCalls: StreamToolCalls(stream),
+		Reasoning: StreamReasoning(stream),
+	}
+	if usage := StreamUsage(stream); usage != nil {
+		completion.Usage = usage
+		completion.TokensUsed = usage.Total()
+	}
+
+	return completion, nil
+}
+
+// ResolvedModel returns the requested model, or the provider's default model if none was requested
+func ResolvedModel(service StreamingChatService, model string) string {
+	if model == "" {
+		if lister, ok := service.(ModelLister); ok {
+			return lister.DefaultModel()
+		}
+	}
+	return model
+}
+
 // ErrStreamClosed is returned when trying to read from a closed stream
 var ErrStreamClosed = io.ErrClosedPipe
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// ========================================
+// Structured Output with Repair
+// ========================================
+// The provider is asked for JSON via req.ResponseSchema; the reply is
+// validated and, if it does not match, sent back with the violations so the
+// model can correct it (up to maxRetries more attempts).
+
+// DefaultStructuredOutputRetries is the number of repair attempts after the first reply
+const DefaultStructuredOutputRetries = 2
+
+// StructuredOutputError is returned when no attempt produced JSON matching the schema
+type StructuredOutputError struct {
+	Attempts int
+	Problems []string
+	Content  string // Last reply from the model
+}
+
+func (e *StructuredOutputError) Error() string {
+	return fmt.Sprintf("structured output did not match schema after %d attempts: %s", e.Attempts, strings.Join(e.Problems, "; "))
+}
+
+// StructuredResult is a validated structured reply
+type StructuredResult struct {
+	Completion *ChatCompletion // Last completion (Usage summed over all attempts)
+	Data       json.RawMessage // JSON matching the schema
+	Attempts   int
+}
+
+// StructuredOutputService requests schema-conforming JSON from any provider
+type StructuredOutputService struct {
+	maxRetries int
+}
+
+// NewStructuredOutputService creates a structured output service (maxRetries < 0 uses the default)
+func NewStructuredOutputService(maxRetries int) *StructuredOutputService {
+	if maxRetries < 0 {
+		maxRetries = DefaultStructuredOutputRetries
+	}
+	return &StructuredOutputService{
+		maxRetries: maxRetries,
+	}
+}
+
+// Complete asks the provider for JSON matching req.ResponseSchema, repairing invalid replies
+func (s *StructuredOutputService) Complete(ctx context.Context, service StreamingChatService, req StreamingChatRequest) (*StructuredResult, error) {
+	if req.ResponseSchema == nil {
+		return nil, fmt.Errorf("response schema is required")
+	}
+	if err := req.ResponseSchema.Check(); err != nil {
+		return nil, err
+	}
+
+	messages := append([]ConversationMessage{}, req.Messages...)
+	var usage *TokenUsage
+	var lastErr *SchemaViolationError
+	var lastContent string
+
+	for attempt := 1; attempt <= s.maxRetries+1; attempt++ {
+		attemptReq := req
+		attemptReq.Messages = messages
+
+		completion, err := CompleteChat(ctx, service, attemptReq)
+		if err != nil {
+			return nil, err
+		}
+		usage = addUsage(usage, completion.Usage)
+		completion.Usage = usage
+		if usage != nil {
+			completion.TokensUsed = usage.Total()
+		}
+
+		data := ExtractJSON(completion.Content)
+		err = ValidateJSONSchema(req.ResponseSchema.Schema, []byte(data))
+		if err == nil {
+			completion.Content = data
+			return &StructuredResult{
+				Completion: completion,
+				Data:       json.RawMessage(data),
+				Attempts:   attempt,
+			}, nil
+		}
+
+		violation, ok := err.(*SchemaViolationError)
+		if !ok {
+			return nil, err
+		}
+		lastErr = violation
+		lastContent = completion.Content
+		log.Printf("⚠️  Structured output attempt %d/%d does not match schema %s: %v", attempt, s.maxRetries+1, req.ResponseSchema.Name, violation)
+
+		// Show the model its reply and what is wrong with it
+		messages = append(messages,
+			NewTextMessage(ConversationRoleAssistant, completion.Content),
+			NewTextMessage(ConversationRoleUser, repairPrompt(violation)),
+		)
+	}
+
+	return nil, &StructuredOutputError{
+		Attempts: s.maxRetries + 1,
+		Problems: lastErr.Problems,
+		Content:  lastContent,
+	}
+}
+
+// repairPrompt asks the model to fix a reply that did not match the schema
+func repairPrompt(violation *SchemaViolationError) string {
+	var prompt strings.Builder
+	prompt.WriteString("Your previous reply does not match the required JSON schema:\n")
+	for _, problem := range violation.Problems {
+		prompt.WriteString("- ")
+		prompt.WriteString(problem)
+		prompt.WriteString("\n")
+	}
+	prompt.WriteString("Reply again with only the corrected JSON, no other text.")
+	return prompt.String()
+}
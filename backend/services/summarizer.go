@@ -0,0 +1,216 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"chatbot/config"
+	"chatbot/models"
+	"chatbot/repositories"
+
+	"github.com/google/uuid"
+)
+
+// summarySystemPrompt asks for an updated running summary of a conversation
+const summarySystemPrompt = "You maintain a running summary of a conversation between a user and an assistant. " +
+	"Update the current summary with the new messages. Keep the facts, names, numbers, decisions, user preferences " +
+	"and open questions the assistant needs to continue the conversation; drop greetings and small talk. " +
+	"Write in the language of the conversation, in at most 300 words. Reply with the summary only."
+
+// Summary limits
+const (
+	summaryMaxTokens     = 600  // Longest summary the model may write
+	summaryMessageLength = 2000 // Characters of each message sent to the summary model
+)
+
+// Summarizer folds the older messages of long session branches into a stored rolling summary
+// Each run updates the previous summary with the messages after it, so a session is never
+// summarized from scratch
+type Summarizer struct {
+	messageRepo  *repositories.MessageRepository
+	providers    *ProviderRegistry
+	provider     string
+	model        string
+	tokenBudget  int // Unsummarized history tokens that trigger a summary
+	keepMessages int // Recent messages left out of the summary
+	running      sync.Map
+}
+
+// NewSummarizer creates a new summarizer
+func NewSummarizer(messageRepo *repositories.MessageRepository, providers *ProviderRegistry, cfg *config.Config) *Summarizer {
+	keep := cfg.SummaryKeepMessages
+	if keep <= 0 || keep >= DefaultHistoryLimit {
+		keep = DefaultHistoryLimit / 2
+	}
+	return &Summarizer{
+		messageRepo:  messageRepo,
+		providers:    providers,
+		provider:     cfg.SummaryProvider,
+		model:        cfg.SummaryModel,
+		tokenBudget:  cfg.SummaryTokenBudget,
+		keepMessages: keep,
+	}
+}
+
+// Schedule summarizes the branch ending at leafID in the background if it has grown too long
+// provider is the one that answered, used unless a summary provider is configured
+func (s *Summarizer) Schedule(sessionID string, leafID uuid.UUID, provider string) {
+	if s == nil || sessionID == "" || leafID == uuid.Nil {
+		return
+	}
+	// One run per session at a time; the next turn picks up whatever this one skipped
+	if _, busy := s.running.LoadOrStore(sessionID, true); busy {
+		return
+	}
+
+	go func() {
+		defer s.running.Delete(sessionID)
+		ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+		defer cancel()
+
+		summary, err := s.Summarize(ctx, sessionID, leafID, provider)
+		if err != nil {
+			log.Printf("⚠️  Failed to summarize session %s: %v", sessionID, err)
+			return
+		}
+		if summary != nil {
+			log.Printf("📝 Summarized %d messages of session %s", summary.MessageCount, sessionID)
+		}
+	}()
+}
+
+// Summarize folds the older unsummarized messages of the branch ending at leafID into a new summary
+// when they exceed the token budget or the history window; returns nil when no summary is needed
+func (s *Summarizer) Summarize(ctx context.Context, sessionID string, leafID uuid.UUID, provider string) (*models.SessionSummary, error) {
+	messages, err := s.messageRepo.GetAllBySession(sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load session: %w", err)
+	}
+	summaries, err := s.messageRepo.FindSummariesBySession(sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load summaries: %w", err)
+	}
+
+	previous, tail := SummaryOnPath(NewMessageTree(messages).Path(leafID), summaries)
+	older := s.foldable(tail)
+	if len(older) == 0 {
+		return nil, nil
+	}
+
+	if s.provider != "" {
+		provider = s.provider
+	}
+	service, err := s.providers.Get(provider)
+	if err != nil {
+		return nil, err
+	}
+
+	currentSummary := "(none)"
+	covered := 0
+	if previous != nil {
+		currentSummary = previous.Content
+		covered = previous.MessageCount
+	}
+	completion, err := CompleteChat(ctx, service, StreamingChatRequest{
+		Messages: []ConversationMessage{NewTextMessage(ConversationRoleUser,
+			fmt.Sprintf("Current summary:\n%s\n\nNew messages:\n%s", currentSummary, summaryTranscript(older)))},
+		SystemPrompt: summarySystemPrompt,
+		Model:        s.model,
+		Temperature:  0.2,
+		MaxTokens:    summaryMaxTokens,
+	})
+	if err != nil {
+		return nil, err
+	}
+	content := strings.TrimSpace(completion.Content)
+	if content == "" {
+		return nil, fmt.Errorf("summary model returned no text")
+	}
+
+	summary := &models.SessionSummary{
+		SessionID:    sessionID,
+		ThroughID:    older[len(older)-1].ID,
+		Content:      content,
+		MessageCount: covered + len(older),
+		Provider:     completion.Provider,
+		Model:        completion.Model,
+	}
+	if err := s.messageRepo.CreateSummary(summary); err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+// foldable returns the leading messages of the unsummarized tail to summarize: all but the most
+// recent keepMessages, cut before a user message so a turn (and its tool calls) is never split
+func (s *Summarizer) foldable(tail []models.Message) []models.Message {
+	tokens := 0
+	for _, msg := range tail {
+		tokens += EstimateTokens(msg.Content)
+	}
+	if tokens <= s.tokenBudget && len(tail) <= DefaultHistoryLimit {
+		return nil
+	}
+
+	cut := len(tail) - s.keepMessages
+	for cut > 0 && tail[cut].Role != models.RoleUser {
+		cut--
+	}
+	if cut <= 0 {
+		return nil
+	}
+	return tail[:cut]
+}
+
+// SummaryOnPath finds the summary covering the most of a branch path and the messages after it
+// Without a summary on the path the whole path is returned
+func SummaryOnPath(path []models.Message, summaries []models.SessionSummary) (*models.SessionSummary, []models.Message) {
+	position := make(map[uuid.UUID]int, len(path))
+	for i, msg := range path {
+		position[msg.ID] = i
+	}
+
+	var latest *models.SessionSummary
+	through := -1
+	for i := range summaries {
+		if index, ok := position[summaries[i].ThroughID]; ok && index > through {
+			latest = &summaries[i]
+			through = index
+		}
+	}
+	return latest, path[through+1:]
+}
+
+// SummaryMessage is the system message that stands in for the summarized part of a conversation
+func SummaryMessage(summary *models.SessionSummary) ConversationMessage {
+	return NewTextMessage(ConversationRoleSystem, fmt.Sprintf(
+		"Summary of the earlier conversation (%d messages):\n%s", summary.MessageCount, summary.Content))
+}
+
+// summaryTranscript renders messages as a plain transcript for the summary model
+func summaryTranscript(messages []models.Message) string {
+	var transcript strings.Builder
+	for _, msg := range messages {
+		content := truncateRunes(strings.TrimSpace(msg.Content), summaryMessageLength)
+		switch msg.Role {
+		case models.RoleUser:
+			fmt.Fprintf(&transcript, "User: %s\n\n", content)
+		case models.RoleTool:
+			fmt.Fprintf(&transcript, "Tool result (%s): %s\n\n", msg.ToolName, content)
+		case models.RoleAssistant:
+			if calls, err := msg.GetToolCalls(); err == nil {
+				for _, call := range calls {
+					fmt.Fprintf(&transcript, "Assistant called %s(%s)\n\n", call.Name, call.Arguments)
+				}
+			}
+			if content != "" {
+				fmt.Fprintf(&transcript, "Assistant: %s\n\n", content)
+			}
+		}
+	}
+	return strings.TrimSpace(transcript.String())
+}
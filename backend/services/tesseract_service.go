@@ -0,0 +1,247 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"chatbot/config"
+)
+
+// ocrPageTimeout is how long rendering or reading one page may take
+const ocrPageTimeout = 2 * time.Minute
+
+// TesseractService implements OCREngine using a local Tesseract binary
+// ใช้ tesseract อ่านข้อความจากภาพแบบ offline และใช้ pdftoppm แปลงหน้า PDF เป็นภาพก่อนอ่าน
+type TesseractService struct {
+	config   *config.Config
+	binary   string // Resolved tesseract path
+	renderer string // Resolved pdftoppm path ("" = PDF pages cannot be read)
+}
+
+// NewTesseractService creates a new TesseractService instance
+// ตรวจสอบว่า binary และภาษาที่ตั้งไว้ (เช่น tha+eng) พร้อมใช้งาน และสร้าง temp directory
+func NewTesseractService(cfg *config.Config) (*TesseractService, error) {
+	binary, err := exec.LookPath(cfg.OCRBinaryPath)
+	if err != nil {
+		return nil, fmt.Errorf("tesseract binary not found at: %s", cfg.OCRBinaryPath)
+	}
+	service := &TesseractService{
+		config: cfg,
+		binary: binary,
+	}
+
+	// Validate traineddata of every language
+	installed, err := service.installedLanguages()
+	if err != nil {
+		return nil, err
+	}
+	for _, language := range strings.Split(cfg.OCRLanguages, "+") {
+		if !installed[language] {
+			return nil, fmt.Errorf("tesseract language data not found: %s (install %s.traineddata)", language, language)
+		}
+	}
+
+	// Ensure temp directory exists
+	if err := os.MkdirAll(cfg.OCRTempDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+
+	// PDF pages are rendered with pdftoppm (poppler-utils); images work without it
+	if renderer, err := exec.LookPath(cfg.OCRRendererPath); err == nil {
+		service.renderer = renderer
+	} else {
+		log.Printf("⚠️  pdftoppm not found at: %s (scanned PDFs will not be read with OCR)", cfg.OCRRendererPath)
+	}
+
+	log.Printf("✓ TesseractService initialized (binary: %s, languages: %s)",
+		filepath.Base(binary), cfg.OCRLanguages)
+
+	return service, nil
+}
+
+// RecognizeImage อ่านข้อความจากภาพ (jpeg, png, webp, gif)
+func (s *TesseractService) RecognizeImage(ctx context.Context, data []byte) (*OCRResult, error) {
+	dir, err := os.MkdirTemp(s.config.OCRTempDir, "ocr-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	imagePath := filepath.Join(dir, "image")
+	if err := os.WriteFile(imagePath, data, 0644); err != nil {
+		return nil, fmt.Errorf("failed to save temp file: %w", err)
+	}
+	return s.recognize(ctx, imagePath, 0)
+}
+
+// RecognizePDFPages แปลงหน้า PDF ที่ระบุเป็นภาพทีละหน้าแล้วอ่านข้อความ
+// Returns the text of each page by its 1-based number
+func (s *TesseractService) RecognizePDFPages(ctx context.Context, data []byte, pages []int) (map[int]*OCRResult, error) {
+	if s.renderer == "" {
+		return nil, fmt.Errorf("pdftoppm not found at: %s", s.config.OCRRendererPath)
+	}
+
+	dir, err := os.MkdirTemp(s.config.OCRTempDir, "ocr-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	pdfPath := filepath.Join(dir, "document.pdf")
+	if err := os.WriteFile(pdfPath, data, 0644); err != nil {
+		return nil, fmt.Errorf("failed to save temp file: %w", err)
+	}
+
+	startTime := time.Now()
+	results := make(map[int]*OCRResult, len(pages))
+	for _, page := range pages {
+		// 1. Render the page to a grayscale PNG (<prefix>.png)
+		prefix := filepath.Join(dir, fmt.Sprintf("page-%d", page))
+		number := strconv.Itoa(page)
+		args := []string{
+			"-f", number, "-l", number, // this page only
+			"-r", strconv.Itoa(s.config.OCRDPI), // resolution
+			"-gray", "-png", "-singlefile",
+			pdfPath, prefix,
+		}
+		if _, err := s.execute(ctx, s.renderer, args); err != nil {
+			return nil, fmt.Errorf("page %d: %w", page, err)
+		}
+
+		// 2. Read it
+		result, err := s.recognize(ctx, prefix+".png", s.config.OCRDPI)
+		os.Remove(prefix + ".png")
+		if err != nil {
+			return nil, fmt.Errorf("page %d: %w", page, err)
+		}
+		results[page] = result
+	}
+
+	log.Printf("✅ OCR of %d PDF pages completed in %.2fs", len(pages), time.Since(startTime).Seconds())
+	return results, nil
+}
+
+// ========================================
+// Helper Functions
+// ========================================
+
+// recognize รัน tesseract กับไฟล์ภาพ แล้วอ่านข้อความ (.txt) และความมั่นใจของแต่ละคำ (.tsv)
+// dpi 0 = ใช้ค่าจากไฟล์ภาพ
+func (s *TesseractService) recognize(ctx context.Context, imagePath string, dpi int) (*OCRResult, error) {
+	outputBase := imagePath + "-ocr"
+	args := []string{imagePath, outputBase, "-l", s.config.OCRLanguages}
+	if s.config.OCRDataDir != "" {
+		args = append(args, "--tessdata-dir", s.config.OCRDataDir)
+	}
+	if dpi > 0 {
+		args = append(args, "--dpi", strconv.Itoa(dpi))
+	}
+	args = append(args, "txt", "tsv") // write <output>.txt and <output>.tsv
+
+	if _, err := s.execute(ctx, s.binary, args); err != nil {
+		return nil, err
+	}
+	defer os.Remove(outputBase + ".txt")
+	defer os.Remove(outputBase + ".tsv")
+
+	text, err := os.ReadFile(outputBase + ".txt")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read OCR text: %w", err)
+	}
+	tsv, err := os.ReadFile(outputBase + ".tsv")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read OCR confidence: %w", err)
+	}
+
+	return &OCRResult{
+		Text:       strings.TrimSpace(string(text)),
+		Confidence: parseTSVConfidence(string(tsv)),
+	}, nil
+}
+
+// installedLanguages คืนภาษาที่ tesseract มี traineddata (tesseract --list-langs)
+func (s *TesseractService) installedLanguages() (map[string]bool, error) {
+	args := []string{"--list-langs"}
+	if s.config.OCRDataDir != "" {
+		args = append([]string{"--tessdata-dir", s.config.OCRDataDir}, args...)
+	}
+	output, err := s.execute(context.Background(), s.binary, args)
+	if err != nil {
+		return nil, err
+	}
+
+	// First line is "List of available languages in ..."
+	languages := make(map[string]bool)
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "List of") {
+			languages[line] = true
+		}
+	}
+	return languages, nil
+}
+
+// execute รัน binary และ return stdout กับ stderr รวมกัน (ยกเลิกได้ด้วย ctx และจำกัดเวลาที่ ocrPageTimeout)
+// (tesseract บางเวอร์ชันพิมพ์ --list-langs ออก stderr)
+func (s *TesseractService) execute(ctx context.Context, binary string, args []string) (string, error) {
+	pageCtx, cancel := context.WithTimeout(ctx, ocrPageTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(pageCtx, binary, args...)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	// รัน command
+	err := cmd.Run()
+
+	// ตรวจสอบการยกเลิกและ timeout
+	if ctx.Err() != nil {
+		return "", fmt.Errorf("%s cancelled: %w", filepath.Base(binary), ctx.Err())
+	}
+	if pageCtx.Err() == context.DeadlineExceeded {
+		return "", fmt.Errorf("%s execution timeout after %s", filepath.Base(binary), ocrPageTimeout)
+	}
+
+	// ตรวจสอบ error จากการรัน command
+	if err != nil {
+		return "", fmt.Errorf("%s execution failed: %w, stderr: %s", filepath.Base(binary), err, stderr.String())
+	}
+
+	return stdout.String() + stderr.String(), nil
+}
+
+// parseTSVConfidence คืนค่าเฉลี่ยความมั่นใจของคำทั้งหมด (0-1) จาก TSV ของ tesseract
+// คอลัมน์: level page_num block_num par_num line_num word_num left top width height conf text
+func parseTSVConfidence(tsv string) float64 {
+	total := 0.0
+	words := 0
+	for _, line := range strings.Split(tsv, "\n") {
+		fields := strings.Split(strings.TrimRight(line, "\r"), "\t")
+		// Word rows are level 5; rows without text (blocks, lines) report conf -1
+		if len(fields) < 12 || fields[0] != "5" || strings.TrimSpace(fields[11]) == "" {
+			continue
+		}
+		conf, err := strconv.ParseFloat(fields[10], 64)
+		if err != nil || conf < 0 {
+			continue
+		}
+		total += conf
+		words++
+	}
+
+	if words == 0 {
+		return 0
+	}
+	return math.Round(total/float64(words)) / 100
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/pkoukk/tiktoken-go"
+	tiktoken_loader "github.com/pkoukk/tiktoken-go-loader"
+)
+
+// ========================================
+// Tokenizers
+// ========================================
+// Context assembly counts tokens with the tokenizer of the model that will
+// answer: OpenAI models use their tiktoken encoding (BPE ranks are embedded,
+// nothing is downloaded), other models (Claude on Bedrock, local and
+// OpenAI-compatible providers) use an approximation that errs on the high side.
+
+// Tokenizer counts the prompt tokens of text for a model
+type Tokenizer interface {
+	CountTokens(text string) int
+}
+
+// defaultTiktokenEncoding is used for OpenAI models tiktoken does not know yet (gpt-4.1, o3, ...)
+const defaultTiktokenEncoding = "o200k_base"
+
+var (
+	tiktokenLoader    sync.Once
+	tiktokenEncodings sync.Map // encoding name → *tiktoken.Tiktoken
+	tiktokenPrefixes  = modelPrefixes()
+)
+
+// modelPrefixes returns tiktoken's model prefixes longest first, so a model takes the encoding
+// of its most specific prefix whatever the map's iteration order
+func modelPrefixes() []string {
+	prefixes := make([]string, 0, len(tiktoken.MODEL_PREFIX_TO_ENCODING))
+	for prefix := range tiktoken.MODEL_PREFIX_TO_ENCODING {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Slice(prefixes, func(i, j int) bool {
+		if len(prefixes[i]) != len(prefixes[j]) {
+			return len(prefixes[i]) > len(prefixes[j])
+		}
+		return prefixes[i] < prefixes[j]
+	})
+	return prefixes
+}
+
+// TokenizerFor returns the tokenizer of a provider's model
+func TokenizerFor(provider, model string) Tokenizer {
+	if !usesTiktoken(provider, model) {
+		return ApproximateTokenizer{}
+	}
+
+	tiktokenLoader.Do(func() {
+		tiktoken.SetBpeLoader(tiktoken_loader.NewOfflineLoader())
+	})
+
+	encoding := defaultTiktokenEncoding
+	if model != "" {
+		if name, ok := tiktoken.MODEL_TO_ENCODING[model]; ok {
+			encoding = name
+		} else {
+			for _, prefix := range tiktokenPrefixes {
+				if strings.HasPrefix(model, prefix) {
+					encoding = tiktoken.MODEL_PREFIX_TO_ENCODING[prefix]
+					break
+				}
+			}
+		}
+	}
+
+	if cached, ok := tiktokenEncodings.Load(encoding); ok {
+		return tiktokenTokenizer{cached.(*tiktoken.Tiktoken)}
+	}
+	encoder, err := tiktoken.GetEncoding(encoding)
+	if err != nil {
+		log.Printf("⚠️  Failed to load tiktoken encoding %s: %v", encoding, err)
+		return ApproximateTokenizer{}
+	}
+	tiktokenEncodings.Store(encoding, encoder)
+	return tiktokenTokenizer{encoder}
+}
+
+// usesTiktoken reports whether a model is an OpenAI model with a tiktoken encoding
+func usesTiktoken(provider, model string) bool {
+	if strings.EqualFold(provider, "openai") {
+		return true
+	}
+	for _, prefix := range []string{"gpt-", "o1", "o3", "o4", "chatgpt-"} {
+		if strings.HasPrefix(model, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// tiktokenTokenizer counts tokens exactly with a tiktoken encoding
+type tiktokenTokenizer struct {
+	encoder *tiktoken.Tiktoken
+}
+
+// CountTokens encodes text without special tokens (user text may contain "<|endoftext|>")
+func (t tiktokenTokenizer) CountTokens(text string) int {
+	if text == "" {
+		return 0
+	}
+	return len(t.encoder.EncodeOrdinary(text))
+}
+
+// ApproximateTokenizer estimates tokens for models without a local tokenizer (Claude, local models):
+// about 3.5 characters per token for ASCII text and one token per character for other scripts
+// (Thai, CJK), which tokenize far less efficiently
+type ApproximateTokenizer struct{}
+
+// CountTokens estimates the tokens of text
+func (ApproximateTokenizer) CountTokens(text string) int {
+	ascii, other := 0, 0
+	for _, r := range text {
+		if r < utf8.RuneSelf {
+			ascii++
+		} else {
+			other++
+		}
+	}
+	return (ascii*2+6)/7 + other
+}
@@ -0,0 +1,237 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+)
+
+// ========================================
+// Tool Calling Orchestration
+// ========================================
+// The loop: send the conversation with tool definitions → if the model asks
+// for tools, run them on the server and append the assistant tool-call turn
+// plus one tool result per call → ask the model again, until it answers
+// with text only (or MaxRounds is reached).
+
+// DefaultToolMaxRounds limits how many times the model may call tools per request
+const DefaultToolMaxRounds = 5
+
+// toolTimeout bounds a single tool execution
+const toolTimeout = 30 * time.Second
+
+// StreamOpener opens a stream for one round of the tool loop
+type StreamOpener func(ctx context.Context, req StreamingChatRequest) (StreamReader, error)
+
+// ToolEvents are optional callbacks fired while the tool loop runs
+type ToolEvents struct {
+	OnChunk      func(chunk string) error                              // Text chunk from the model
+	OnReasoning  func(text string) error                               // Reasoning chunk from a reasoning model
+	OnToolCall   func(call ToolCall) error                             // Model requested a tool
+	OnToolResult func(call ToolCall, result string, failed bool) error // Tool finished (failed = handler error)
+}
+
+// ToolRunResult is the outcome of a tool loop
+type ToolRunResult struct {
+	Content   string                // Final assistant answer (text of the last round)
+	Steps     []ConversationMessage // Assistant tool-call turns and tool results, in order (for persistence)
+	Reasoning []ReasoningBlock      // Reasoning of every round, in order
+	Usage     *TokenUsage           // Summed over all rounds (nil if no round reported usage)
+	Rounds    int                   // Model calls made
+}
+
+// ToolOrchestrator runs the model → tool → model loop
+type ToolOrchestrator struct {
+	registry  *ToolRegistry
+	maxRounds int
+}
+
+// NewToolOrchestrator creates a tool orchestrator (maxRounds <= 0 uses DefaultToolMaxRounds)
+func NewToolOrchestrator(registry *ToolRegistry, maxRounds int) *ToolOrchestrator {
+	if maxRounds <= 0 {
+		maxRounds = DefaultToolMaxRounds
+	}
+	return &ToolOrchestrator{
+		registry:  registry,
+		maxRounds: maxRounds,
+	}
+}
+
+// Registry returns the tool registry
+func (o *ToolOrchestrator) Registry() *ToolRegistry {
+	return o.registry
+}
+
+// Stream runs the tool loop over streams, forwarding text chunks as they arrive
+func (o *ToolOrchestrator) Stream(ctx context.Context, req StreamingChatRequest, open StreamOpener, events ToolEvents) (*ToolRunResult, error) {
+	result := &ToolRunResult{}
+	messages := append([]ConversationMessage{}, req.Messages...)
+
+	for {
+		result.Rounds++
+		roundReq := req
+		roundReq.Messages = messages
+
+		stream, err := open(ctx, roundReq)
+		if err != nil {
+			return result, err
+		}
+
+		var content strings.Builder
+		for {
+			chunk, err := stream.Recv()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				stream.Close()
+				return result, fmt.Errorf("stream error: %w", err)
+			}
+			if reasoning := TakeStreamReasoning(stream); reasoning != "" && events.OnReasoning != nil {
+				if err := events.OnReasoning(reasoning); err != nil {
+					stream.Close()
+					return result, err
+				}
+			}
+			if chunk == "" {
+				continue
+			}
+			content.WriteString(chunk)
+			if events.OnChunk != nil {
+				if err := events.OnChunk(chunk); err != nil {
+					stream.Close()
+					return result, err
+				}
+			}
+		}
+		stream.Close()
+
+		result.Usage = addUsage(result.Usage, StreamUsage(stream))
+		reasoning := StreamReasoning(stream)
+		result.Reasoning = append(result.Reasoning, reasoning...)
+		calls := StreamToolCalls(stream)
+		if len(calls) == 0 || len(req.Tools) == 0 {
+			result.Content = content.String()
+			return result, nil
+		}
+
+		step, err := o.runRound(ctx, result, content.String(), reasoning, calls, events)
+		if err != nil {
+			return result, err
+		}
+		messages = append(messages, step...)
+	}
+}
+
+// Complete runs the tool loop with non-streaming completions
+func (o *ToolOrchestrator) Complete(ctx context.Context, service StreamingChatService, req StreamingChatRequest) (*ChatCompletion, *ToolRunResult, error) {
+	result := &ToolRunResult{}
+	messages := append([]ConversationMessage{}, req.Messages...)
+
+	for {
+		result.Rounds++
+		roundReq := req
+		roundReq.Messages = messages
+
+		completion, err := CompleteChat(ctx, service, roundReq)
+		if err != nil {
+			return nil, result, err
+		}
+
+		result.Usage = addUsage(result.Usage, completion.Usage)
+		reasoning := completion.Reasoning
+		result.Reasoning = append(result.Reasoning, reasoning...)
+		if len(completion.ToolCalls) == 0 || len(req.Tools) == 0 {
+			result.Content = completion.Content
+			completion.Usage = result.Usage
+			completion.Reasoning = result.Reasoning
+			if result.Usage != nil {
+				completion.TokensUsed = result.Usage.Total()
+			}
+			return completion, result, nil
+		}
+
+		step, err := o.runRound(ctx, result, completion.Content, reasoning, completion.ToolCalls, ToolEvents{})
+		if err != nil {
+			return nil, result, err
+		}
+		messages = append(messages, step...)
+	}
+}
+
+// runRound executes the tool calls of one round and records the assistant turn and tool results
+// The round's reasoning stays on the assistant turn (Claude requires it back with the results)
+func (o *ToolOrchestrator) runRound(ctx context.Context, result *ToolRunResult, text string, reasoning []ReasoningBlock, calls []ToolCall, events ToolEvents) ([]ConversationMessage, error) {
+	if result.Rounds > o.maxRounds {
+		return nil, fmt.Errorf("tool calling stopped after %d rounds", o.maxRounds)
+	}
+
+	// Providers may omit IDs; every result must reference its call
+	for i := range calls {
+		if calls[i].ID == "" {
+			calls[i].ID = fmt.Sprintf("call_%d_%d", result.Rounds, i)
+		}
+	}
+
+	assistant := NewTextMessage(ConversationRoleAssistant, text)
+	assistant.ToolCalls = calls
+	assistant.Reasoning = reasoning
+	step := []ConversationMessage{assistant}
+
+	for _, call := range calls {
+		if events.OnToolCall != nil {
+			if err := events.OnToolCall(call); err != nil {
+				return nil, err
+			}
+		}
+
+		output, failed := o.execute(ctx, call)
+
+		if events.OnToolResult != nil {
+			if err := events.OnToolResult(call, output, failed); err != nil {
+				return nil, err
+			}
+		}
+
+		toolMessage := NewTextMessage(ConversationRoleTool, output)
+		toolMessage.ToolCallID = call.ID
+		step = append(step, toolMessage)
+	}
+
+	result.Steps = append(result.Steps, step...)
+	return step, nil
+}
+
+// execute runs one tool call; errors are returned to the model as the result text
+func (o *ToolOrchestrator) execute(ctx context.Context, call ToolCall) (string, bool) {
+	toolCtx, cancel := context.WithTimeout(ctx, toolTimeout)
+	defer cancel()
+
+	started := time.Now()
+	output, err := o.registry.Execute(toolCtx, call)
+	if err != nil {
+		log.Printf("⚠️  Tool %s failed: %v", call.Name, err)
+		return "error: " + err.Error(), true
+	}
+
+	log.Printf("🔧 Tool %s executed in %s", call.Name, time.Since(started).Round(time.Millisecond))
+	return output, false
+}
+
+// addUsage adds round usage to the running total
+func addUsage(total, round *TokenUsage) *TokenUsage {
+	if round == nil {
+		return total
+	}
+	if total == nil {
+		total = &TokenUsage{}
+	}
+	total.PromptTokens += round.PromptTokens
+	total.CompletionTokens += round.CompletionTokens
+	total.CachedTokens += round.CachedTokens
+	total.CacheWriteTokens += round.CacheWriteTokens
+	return total
+}
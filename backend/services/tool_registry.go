@@ -0,0 +1,163 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+)
+
+// ========================================
+// Tool / Function Calling Registry
+// ========================================
+// Tools register themselves from init() with RegisterTool; personas enable
+// tools by name and ToolOrchestrator runs the model → tool → model loop.
+
+// ToolDefinition describes a tool to the model
+type ToolDefinition struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Parameters  json.RawMessage `json:"parameters"` // JSON schema of the arguments object
+}
+
+// ToolHandler executes a tool call and returns the result text fed back to the model
+type ToolHandler func(ctx context.Context, arguments json.RawMessage) (string, error)
+
+// Tool is a registered tool: its definition plus the handler that runs it
+type Tool struct {
+	Definition ToolDefinition
+	Handler    ToolHandler
+}
+
+var (
+	registeredToolsMu sync.Mutex
+	registeredTools   = map[string]Tool{}
+)
+
+// RegisterTool makes a tool available to every ToolRegistry created afterwards
+// Tools call this from init(); registering an invalid tool panics
+func RegisterTool(definition ToolDefinition, handler ToolHandler) {
+	if err := validateTool(definition, handler); err != nil {
+		panic(err)
+	}
+
+	registeredToolsMu.Lock()
+	defer registeredToolsMu.Unlock()
+	registeredTools[definition.Name] = Tool{Definition: definition, Handler: handler}
+}
+
+// ToolRegistry holds the tools that can be enabled on personas
+type ToolRegistry struct {
+	tools map[string]Tool
+}
+
+// NewToolRegistry creates a registry with all tools registered via RegisterTool
+func NewToolRegistry() *ToolRegistry {
+	registeredToolsMu.Lock()
+	defer registeredToolsMu.Unlock()
+
+	registry := &ToolRegistry{tools: make(map[string]Tool, len(registeredTools))}
+	for name, tool := range registeredTools {
+		registry.tools[name] = tool
+	}
+	return registry
+}
+
+// Register adds or replaces a tool in this registry
+func (r *ToolRegistry) Register(definition ToolDefinition, handler ToolHandler) error {
+	if err := validateTool(definition, handler); err != nil {
+		return err
+	}
+	r.tools[definition.Name] = Tool{Definition: definition, Handler: handler}
+	return nil
+}
+
+// Get returns a tool by name
+func (r *ToolRegistry) Get(name string) (Tool, bool) {
+	tool, ok := r.tools[name]
+	return tool, ok
+}
+
+// Names returns the registered tool names in alphabetical order
+func (r *ToolRegistry) Names() []string {
+	names := make([]string, 0, len(r.tools))
+	for name := range r.tools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// List returns all tool definitions in alphabetical order
+func (r *ToolRegistry) List() []ToolDefinition {
+	definitions := make([]ToolDefinition, 0, len(r.tools))
+	for _, name := range r.Names() {
+		definitions = append(definitions, r.tools[name].Definition)
+	}
+	return definitions
+}
+
+// Validate returns an error for the first unknown tool name
+func (r *ToolRegistry) Validate(names []string) error {
+	for _, name := range names {
+		if _, ok := r.tools[name]; !ok {
+			return fmt.Errorf("unknown tool: %s (available: %v)", name, r.Names())
+		}
+	}
+	return nil
+}
+
+// Definitions returns the definitions of the named tools, skipping unknown names
+func (r *ToolRegistry) Definitions(names []string) []ToolDefinition {
+	definitions := make([]ToolDefinition, 0, len(names))
+	for _, name := range names {
+		tool, ok := r.tools[name]
+		if !ok {
+			log.Printf("⚠️  Tool %q is enabled but not registered, skipping", name)
+			continue
+		}
+		definitions = append(definitions, tool.Definition)
+	}
+	return definitions
+}
+
+// Execute runs a tool call; arguments must be a JSON object (empty means {})
+func (r *ToolRegistry) Execute(ctx context.Context, call ToolCall) (result string, err error) {
+	tool, ok := r.tools[call.Name]
+	if !ok {
+		return "", fmt.Errorf("unknown tool: %s", call.Name)
+	}
+
+	arguments := json.RawMessage(call.Arguments)
+	if len(arguments) == 0 {
+		arguments = json.RawMessage("{}")
+	}
+	if !json.Valid(arguments) {
+		return "", fmt.Errorf("invalid JSON arguments for tool %s", call.Name)
+	}
+
+	// A panicking handler must not take the chat request down with it
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("tool %s panicked: %v", call.Name, p)
+		}
+	}()
+
+	return tool.Handler(ctx, arguments)
+}
+
+// validateTool checks the tool name, schema and handler
+func validateTool(definition ToolDefinition, handler ToolHandler) error {
+	if definition.Name == "" {
+		return fmt.Errorf("tool name is required")
+	}
+	if handler == nil {
+		return fmt.Errorf("tool %s has no handler", definition.Name)
+	}
+	if len(definition.Parameters) == 0 || !json.Valid(definition.Parameters) {
+		return fmt.Errorf("tool %s has an invalid JSON schema", definition.Name)
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"math"
+	"strconv"
+	"time"
+	_ "time/tzdata" // Time zones for get_current_time without system tzdata
+)
+
+// ========================================
+// Built-in Tools
+// ========================================
+
+func init() {
+	RegisterTool(ToolDefinition{
+		Name:        "get_current_time",
+		Description: "Get the current date and time in a time zone.",
+		Parameters: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"timezone": {"type": "string", "description": "IANA time zone, e.g. Asia/Bangkok (default) or UTC"}
+			}
+		}`),
+	}, getCurrentTimeTool)
+
+	RegisterTool(ToolDefinition{
+		Name:        "calculate",
+		Description: "Evaluate an arithmetic expression with + - * / % and parentheses, e.g. (12.5 * 4) / 3.",
+		Parameters: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"expression": {"type": "string", "description": "Arithmetic expression to evaluate"}
+			},
+			"required": ["expression"]
+		}`),
+	}, calculateTool)
+}
+
+// getCurrentTimeTool returns the current time in the requested time zone
+func getCurrentTimeTool(ctx context.Context, arguments json.RawMessage) (string, error) {
+	var args struct {
+		Timezone string `json:"timezone"`
+	}
+	if err := json.Unmarshal(arguments, &args); err != nil {
+		return "", fmt.Errorf("invalid arguments: %w", err)
+	}
+	if args.Timezone == "" {
+		args.Timezone = "Asia/Bangkok"
+	}
+
+	location, err := time.LoadLocation(args.Timezone)
+	if err != nil {
+		return "", fmt.Errorf("unknown time zone: %s", args.Timezone)
+	}
+
+	now := time.Now().In(location)
+	result, _ := json.Marshal(map[string]string{
+		"timezone": args.Timezone,
+		"datetime": now.Format(time.RFC3339),
+		"weekday":  now.Weekday().String(),
+	})
+	return string(result), nil
+}
+
+// calculateTool evaluates an arithmetic expression
+func calculateTool(ctx context.Context, arguments json.RawMessage) (string, error) {
+	var args struct {
+		Expression string `json:"expression"`
+	}
+	if err := json.Unmarshal(arguments, &args); err != nil {
+		return "", fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	value, err := EvaluateArithmetic(args.Expression)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatFloat(value, 'f', -1, 64), nil
+}
+
+// EvaluateArithmetic evaluates + - * / % and parentheses over float64 numbers
+// The expression is parsed with go/parser and only number literals and operators are accepted
+func EvaluateArithmetic(expression string) (float64, error) {
+	if expression == "" {
+		return 0, fmt.Errorf("expression is required")
+	}
+	expr, err := parser.ParseExpr(expression)
+	if err != nil {
+		return 0, fmt.Errorf("invalid expression: %s", expression)
+	}
+	value, err := evalArithmetic(expr)
+	if err != nil {
+		return 0, err
+	}
+	if math.IsInf(value, 0) || math.IsNaN(value) {
+		return 0, fmt.Errorf("result is not a finite number")
+	}
+	return value, nil
+}
+
+// evalArithmetic walks the parsed expression
+func evalArithmetic(expr ast.Expr) (float64, error) {
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		if e.Kind != token.INT && e.Kind != token.FLOAT {
+			return 0, fmt.Errorf("unsupported literal: %s", e.Value)
+		}
+		return strconv.ParseFloat(e.Value, 64)
+
+	case *ast.ParenExpr:
+		return evalArithmetic(e.X)
+
+	case *ast.UnaryExpr:
+		value, err := evalArithmetic(e.X)
+		if err != nil {
+			return 0, err
+		}
+		switch e.Op {
+		case token.SUB:
+			return -value, nil
+		case token.ADD:
+			return value, nil
+		}
+		return 0, fmt.Errorf("unsupported operator: %s", e.Op)
+
+	case *ast.BinaryExpr:
+		left, err := evalArithmetic(e.X)
+		if err != nil {
+			return 0, err
+		}
+		right, err := evalArithmetic(e.Y)
+		if err != nil {
+			return 0, err
+		}
+		switch e.Op {
+		case token.ADD:
+			return left + right, nil
+		case token.SUB:
+			return left - right, nil
+		case token.MUL:
+			return left * right, nil
+		case token.QUO:
+			if right == 0 {
+				return 0, fmt.Errorf("division by zero")
+			}
+			return left / right, nil
+		case token.REM:
+			if right == 0 {
+				return 0, fmt.Errorf("division by zero")
+			}
+			return math.Mod(left, right), nil
+		}
+		return 0, fmt.Errorf("unsupported operator: %s", e.Op)
+	}
+
+	return 0, fmt.Errorf("unsupported expression")
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"log"
+
+	"chatbot/models"
+	"chatbot/repositories"
+)
+
+// UsageService prices chat messages and records TTS/STT usage events
+type UsageService struct {
+	pricing   *PricingService
+	usageRepo *repositories.UsageRepository
+}
+
+// NewUsageService creates a new usage service
+func NewUsageService(pricing *PricingService, usageRepo *repositories.UsageRepository) *UsageService {
+	return &UsageService{
+		pricing:   pricing,
+		usageRepo: usageRepo,
+	}
+}
+
+// PriceMessage sets provider, model and cost on an assistant message before it is saved
+// Prices are looked up by model, then by provider name; cost stays nil if neither has a price or usage is unknown
+func (s *UsageService) PriceMessage(message *models.Message, provider, model string, usage *TokenUsage) {
+	message.Provider = provider
+	message.Model = model
+	cost, ok := s.pricing.ChatCost(model, usage)
+	if !ok {
+		cost, ok = s.pricing.ChatCost(provider, usage)
+	}
+	if ok {
+		message.Cost = &cost
+	} else if usage != nil {
+		log.Printf("⚠️  No price for model %q (provider %s), cost not recorded", model, provider)
+	}
+}
+
+// RecordTTS saves a TTS usage event and returns its cost (nil if the model has no price)
+func (s *UsageService) RecordTTS(sessionID string, personaID *int, provider, model string, characters int) *float64 {
+	event := &models.UsageEvent{
+		Kind:       models.UsageKindTTS,
+		Provider:   provider,
+		Model:      model,
+		SessionID:  sessionID,
+		PersonaID:  personaID,
+		Characters: characters,
+	}
+	cost, ok := s.pricing.TTSCost(model, characters)
+	if !ok {
+		cost, ok = s.pricing.TTSCost(provider, characters)
+	}
+	if ok {
+		event.Cost = &cost
+	}
+	s.record(event)
+	return event.Cost
+}
+
+// RecordSTT saves an STT usage event and returns its cost (nil if the model has no price)
+func (s *UsageService) RecordSTT(sessionID string, personaID *int, provider, model string, durationSeconds float64) *float64 {
+	event := &models.UsageEvent{
+		Kind:            models.UsageKindSTT,
+		Provider:        provider,
+		Model:           model,
+		SessionID:       sessionID,
+		PersonaID:       personaID,
+		DurationSeconds: durationSeconds,
+	}
+	cost, ok := s.pricing.STTCost(model, durationSeconds)
+	if !ok {
+		cost, ok = s.pricing.STTCost(provider, durationSeconds)
+	}
+	if ok {
+		event.Cost = &cost
+	}
+	s.record(event)
+	return event.Cost
+}
+
+// Summarize returns the usage report grouped by filter.GroupBy
+func (s *UsageService) Summarize(filter repositories.UsageFilter) ([]repositories.UsageSummary, error) {
+	return s.usageRepo.Summarize(filter)
+}
+
+// record saves a usage event; failures are logged, never returned to the caller
+func (s *UsageService) record(event *models.UsageEvent) {
+	if s.usageRepo == nil {
+		return
+	}
+	if err := s.usageRepo.Create(event); err != nil {
+		log.Printf("⚠️  Failed to save %s usage event: %v", event.Kind, err)
+	}
+}
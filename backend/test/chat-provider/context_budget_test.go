@@ -0,0 +1,118 @@
+package chat_provider_test
+
+import (
+	"strings"
+	"testing"
+
+	"chatbot/services"
+)
+
+// ทดสอบ tokenizer: OpenAI ใช้ tiktoken, Claude ใช้การประมาณ (ภาษาไทยนับหนักกว่า)
+func TestTokenizerFor(t *testing.T) {
+	openaiTokenizer := services.TokenizerFor("openai", "gpt-4o-mini")
+	if tokens := openaiTokenizer.CountTokens("hello world"); tokens != 2 {
+		t.Errorf("gpt-4o-mini tokens of \"hello world\" = %d, want 2", tokens)
+	}
+	// ข้อความที่มี special token ต้องนับได้ ไม่ panic
+	if tokens := openaiTokenizer.CountTokens("<|endoftext|>"); tokens == 0 {
+		t.Error("special token text should be counted as ordinary text")
+	}
+
+	claude := services.TokenizerFor("bedrock", "anthropic.claude-3-5-sonnet-20240620-v1:0")
+	if _, ok := claude.(services.ApproximateTokenizer); !ok {
+		t.Fatalf("Claude tokenizer = %T, want ApproximateTokenizer", claude)
+	}
+	if tokens := claude.CountTokens("สวัสดีครับ"); tokens != 10 {
+		t.Errorf("Thai tokens = %d, want one per character", tokens)
+	}
+	if tokens := claude.CountTokens(strings.Repeat("a", 70)); tokens != 20 {
+		t.Errorf("ASCII tokens = %d, want 20", tokens)
+	}
+}
+
+// ทดสอบว่า model ที่มีเลข version ได้ encoding ตาม prefix ที่ตรงที่สุดทุกครั้ง (ไม่ขึ้นกับลำดับของ map)
+func TestTokenizerForModelPrefix(t *testing.T) {
+	text := "สวัสดีครับ ยินดีต้อนรับสู่ระบบแชท"
+	o200k := services.TokenizerFor("openai", "gpt-4o").CountTokens(text)
+	cl100k := services.TokenizerFor("openai", "gpt-4").CountTokens(text)
+	if o200k == cl100k {
+		t.Fatalf("gpt-4o and gpt-4 count %d tokens each, want different encodings", o200k)
+	}
+
+	for i := 0; i < 20; i++ {
+		if tokens := services.TokenizerFor("openai", "gpt-4o-2024-05-13").CountTokens(text); tokens != o200k {
+			t.Fatalf("gpt-4o-2024-05-13 tokens = %d, want %d (o200k_base)", tokens, o200k)
+		}
+		if tokens := services.TokenizerFor("openai", "gpt-4-0613").CountTokens(text); tokens != cl100k {
+			t.Fatalf("gpt-4-0613 tokens = %d, want %d (cl100k_base)", tokens, cl100k)
+		}
+	}
+}
+
+// ทดสอบว่า file excerpt ถูกตัดให้พอดี context window โดยคำถามยังอยู่ครบ
+func TestPrepareTruncatesFileExcerpts(t *testing.T) {
+	catalog := newTestCatalog()
+
+	question := services.NewTextMessage(services.ConversationRoleUser, "สรุปไฟล์นี้")
+	question.Parts = append(question.Parts,
+		services.ContentPart{Type: services.ContentPartFile, FileName: "big.txt", Text: strings.Repeat("word ", 2000)},
+		services.ContentPart{Type: services.ContentPartFile, FileName: "small.txt", Text: "short note"},
+	)
+	req, err := catalog.Prepare("openai", services.StreamingChatRequest{
+		Model:     "gpt-3.5-turbo",
+		MaxTokens: 200,
+		Messages:  []services.ConversationMessage{question},
+	})
+	if err != nil {
+		t.Fatalf("Prepare returned error: %v", err)
+	}
+
+	parts := req.Messages[0].Parts
+	if parts[0].Text != "สรุปไฟล์นี้" || parts[2].Text != "short note" {
+		t.Errorf("question and small file should be kept, got %q and %q", parts[0].Text, parts[2].Text)
+	}
+	if !strings.Contains(parts[1].Text, "truncated") || len(parts[1].Text) >= 10000 {
+		t.Errorf("large file should be truncated, got %d bytes", len(parts[1].Text))
+	}
+	if len(question.Parts[1].Text) != 10000 {
+		t.Error("Prepare must not modify the caller's message parts")
+	}
+}
+
+// ทดสอบลำดับการตัด: history ก่อน, summary (system) ถูกเก็บไว้ถ้ายังพอ
+func TestPrepareKeepsSummaryOverHistory(t *testing.T) {
+	catalog := newTestCatalog()
+
+	long := strings.Repeat("word ", 400)
+	req, err := catalog.Prepare("openai", services.StreamingChatRequest{
+		Model:     "gpt-3.5-turbo",
+		MaxTokens: 200,
+		Messages: []services.ConversationMessage{
+			services.NewTextMessage(services.ConversationRoleSystem, "Summary of the earlier conversation"),
+			services.NewTextMessage(services.ConversationRoleUser, long),
+			services.NewTextMessage(services.ConversationRoleAssistant, long),
+			services.NewTextMessage(services.ConversationRoleUser, "latest question"),
+		},
+	})
+	if err != nil {
+		t.Fatalf("Prepare returned error: %v", err)
+	}
+
+	if len(req.Messages) != 3 || req.Messages[0].Role != services.ConversationRoleSystem || req.Messages[1].Text() != long {
+		t.Errorf("Prepare should drop only the oldest history message, kept %d", len(req.Messages))
+	}
+}
+
+// ทดสอบว่าคำถามที่ยาวเกิน context window ได้ error แทนการส่งไปให้ provider
+func TestPrepareRejectsOversizedQuestion(t *testing.T) {
+	catalog := newTestCatalog()
+
+	_, err := catalog.Prepare("openai", services.StreamingChatRequest{
+		Model:     "gpt-3.5-turbo",
+		MaxTokens: 200,
+		Messages:  []services.ConversationMessage{services.NewTextMessage(services.ConversationRoleUser, strings.Repeat("word ", 2000))},
+	})
+	if err == nil || !strings.Contains(err.Error(), "context window") {
+		t.Errorf("Prepare error = %v, want context window error", err)
+	}
+}
@@ -0,0 +1,44 @@
+package chat_provider_test
+
+import (
+	"testing"
+
+	"chatbot/services"
+)
+
+// TestConversationMessageHelpers ทดสอบ helper ของ provider-neutral message
+func TestConversationMessageHelpers(t *testing.T) {
+	msg := services.ConversationMessage{
+		Role: services.ConversationRoleUser,
+		Parts: []services.ContentPart{
+			{Type: services.ContentPartText, Text: "สรุปไฟล์นี้ให้หน่อย"},
+			{Type: services.ContentPartFile, FileName: "report.pdf", Text: "เนื้อหาไฟล์"},
+			{Type: services.ContentPartImage, MimeType: "image/png", Data: []byte{0x89, 0x50}},
+		},
+	}
+
+	if msg.Text() != "สรุปไฟล์นี้ให้หน่อย" {
+		t.Errorf("Text() = %q, want only text parts", msg.Text())
+	}
+	if len(msg.PartsOfType(services.ContentPartFile)) != 1 {
+		t.Error("PartsOfType(file) should return one part")
+	}
+	if msg.IsEmpty() {
+		t.Error("message with file and image parts should not be empty")
+	}
+
+	conversation := &services.Conversation{Messages: []services.ConversationMessage{msg}}
+	if !conversation.HasImages() {
+		t.Error("HasImages() should be true")
+	}
+
+	// ข้อความที่มีแต่ช่องว่างถือว่าว่าง
+	if !services.NewTextMessage(services.ConversationRoleAssistant, "   ").IsEmpty() {
+		t.Error("whitespace-only message should be empty")
+	}
+
+	simple := services.NewSimpleConversation("system", "hello")
+	if simple.SystemPrompt != "system" || len(simple.Messages) != 1 || simple.HasImages() {
+		t.Errorf("NewSimpleConversation() = %+v", simple)
+	}
+}
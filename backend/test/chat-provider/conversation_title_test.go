@@ -0,0 +1,56 @@
+package chat_provider_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"chatbot/models"
+	"chatbot/services"
+)
+
+// ทดสอบว่าชื่อ conversation ที่ model ตอบมาถูกตัดเครื่องหมายคำพูดและบรรทัดที่เกิน
+func TestGenerateConversationTitle(t *testing.T) {
+	fake := &fakeProvider{name: "openai", available: true, chunks: []string{"\"สรุปบทความ", " AI\"\n", "เพิ่มเติม"}}
+	registry := services.NewProviderRegistry()
+	registry.Register("openai", 10, services.ProviderCapabilities{Streaming: true}, fake)
+
+	conversations := services.NewConversationService(nil, nil, registry, nil, nil, testConfig())
+	title := conversations.GenerateTitle(context.Background(), "openai", "ช่วยสรุปบทความนี้", "บทความพูดถึง AI")
+	if title != "สรุปบทความ AI" {
+		t.Errorf("title = %q, want สรุปบทความ AI", title)
+	}
+}
+
+// ทดสอบว่าเมื่อเรียก model ไม่ได้ ใช้ต้นคำถามเป็นชื่อแทน
+func TestGenerateConversationTitleFallback(t *testing.T) {
+	fake := &fakeProvider{name: "openai", available: true, err: errors.New("unavailable")}
+	registry := services.NewProviderRegistry()
+	registry.Register("openai", 10, services.ProviderCapabilities{Streaming: true}, fake)
+
+	conversations := services.NewConversationService(nil, nil, registry, nil, nil, testConfig())
+	question := "\n  How do I configure a reverse proxy for a Go Fiber application behind nginx?\nthanks"
+	title := conversations.GenerateTitle(context.Background(), "openai", question, "")
+	if title != "How do I configure a reverse proxy for a Go Fiber..." {
+		t.Errorf("title = %q, want the first 50 characters of the question", title)
+	}
+
+	if title := conversations.GenerateTitle(context.Background(), "missing", "สวัสดี", ""); title != "สวัสดี" {
+		t.Errorf("unknown provider title = %q, want สวัสดี", title)
+	}
+}
+
+// ทดสอบ tags ของ conversation
+func TestConversationTags(t *testing.T) {
+	conversation := &models.Conversation{}
+	if tags := conversation.GetTags(); len(tags) != 0 {
+		t.Errorf("empty conversation tags = %v, want none", tags)
+	}
+
+	if err := conversation.SetTags([]string{"work", "ไอเดีย"}); err != nil {
+		t.Fatalf("SetTags returned error: %v", err)
+	}
+	if tags := conversation.GetTags(); len(tags) != 2 || tags[1] != "ไอเดีย" {
+		t.Errorf("tags = %v, want [work ไอเดีย]", tags)
+	}
+}
@@ -0,0 +1,207 @@
+package chat_provider_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+
+	"chatbot/services"
+)
+
+// zipDocument สร้างไฟล์ zip (docx, pptx, odt, epub) จากชื่อ part และเนื้อหา
+func zipDocument(t *testing.T, parts map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for name, content := range parts {
+		w, err := archive.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// extract แยกข้อความและ fail ถ้ามี error
+func extract(t *testing.T, data []byte, mimeType, fileName string) *services.ExtractedText {
+	t.Helper()
+	result, err := services.ExtractText(data, mimeType, fileName)
+	if err != nil {
+		t.Fatalf("ExtractText(%s): %v", fileName, err)
+	}
+	if result == nil {
+		t.Fatalf("ExtractText(%s) = nil, want text", fileName)
+	}
+	return result
+}
+
+// assertContains ตรวจว่าข้อความมีทุกส่วนที่ต้องการ
+func assertContains(t *testing.T, text string, wants ...string) {
+	t.Helper()
+	for _, want := range wants {
+		if !strings.Contains(text, want) {
+			t.Errorf("text missing %q:\n%s", want, text)
+		}
+	}
+}
+
+// ทดสอบการเลือก extractor: นามสกุลมาก่อน MIME type และ text/* อื่นๆ อ่านเป็นข้อความธรรมดา
+func TestExtractorFor(t *testing.T) {
+	cases := []struct {
+		mimeType, fileName, want string
+	}{
+		{"application/octet-stream", "report.docx", "docx"},
+		{"application/vnd.ms-excel", "export.csv", "csv"}, // Windows ส่ง CSV มาเป็น ms-excel
+		{"application/pdf", "upload", "pdf"},
+		{"text/html; charset=utf-8", "page", "html"},
+		{"text/x-rust", "lib", "text"},
+		{"application/epub+zip", "book.EPUB", "epub"},
+	}
+	for _, tc := range cases {
+		format, _, ok := services.ExtractorFor(tc.mimeType, tc.fileName)
+		if !ok || format.Name != tc.want {
+			t.Errorf("ExtractorFor(%q, %q) = %q, %v, want %q", tc.mimeType, tc.fileName, format.Name, ok, tc.want)
+		}
+	}
+
+	if _, _, ok := services.ExtractorFor("image/png", "photo.png"); ok {
+		t.Error("ExtractorFor(image) found an extractor, want none")
+	}
+
+	names := map[string]bool{}
+	for _, format := range services.ExtractorFormats() {
+		names[format.Name] = true
+	}
+	for _, want := range []string{"pdf", "docx", "xlsx", "pptx", "csv", "json", "xml", "html", "markdown", "epub", "rtf", "odt"} {
+		if !names[want] {
+			t.Errorf("no %s extractor registered", want)
+		}
+	}
+}
+
+// ทดสอบว่า CSV ออกมาเป็นตาราง Markdown และ | ในเซลล์ถูก escape
+func TestExtractCSVAsTable(t *testing.T) {
+	result := extract(t, []byte("name,note\nสมชาย,\"a|b\"\n,\n"), "text/csv", "people.csv")
+	want := "| name | note |\n| --- | --- |\n| สมชาย | a\\|b |\n"
+	if result.Text != want {
+		t.Errorf("CSV text = %q, want %q", result.Text, want)
+	}
+}
+
+// ทดสอบว่า DOCX ได้ย่อหน้าตามลำดับ และตารางเป็นตาราง Markdown
+func TestExtractDOCX(t *testing.T) {
+	document := `<?xml version="1.0" encoding="UTF-8"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>
+<w:p><w:pPr><w:tabs><w:tab w:val="left" w:pos="720"/></w:tabs></w:pPr><w:r><w:t>สัญญาเช่า</w:t></w:r><w:r><w:t xml:space="preserve"> ฉบับที่ 1</w:t></w:r></w:p>
+<w:tbl><w:tr><w:tc><w:p><w:r><w:t>Item</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>Price</w:t></w:r></w:p></w:tc></w:tr>
+<w:tr><w:tc><w:p><w:r><w:t>Rent</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>9,000</w:t></w:r></w:p></w:tc></w:tr></w:tbl>
+<w:p><w:r><w:t>End</w:t></w:r><w:r><w:br/><w:t>Signed</w:t></w:r></w:p>
+</w:body></w:document>`
+	data := zipDocument(t, map[string]string{"word/document.xml": document})
+
+	result := extract(t, data, "application/vnd.openxmlformats-officedocument.wordprocessingml.document", "lease.docx")
+	assertContains(t, result.Text, "สัญญาเช่า ฉบับที่ 1\n", "| Item | Price |\n| --- | --- |\n| Rent | 9,000 |", "End\nSigned")
+	if strings.Contains(result.Text, "\t") || strings.Contains(result.Text, "<w:") {
+		t.Errorf("DOCX text has tab stops or markup:\n%s", result.Text)
+	}
+}
+
+// ทดสอบว่า PPTX เรียงสไลด์ตาม presentation.xml ไม่ใช่ตามชื่อไฟล์ และมี speaker notes
+func TestExtractPPTXSlides(t *testing.T) {
+	slide := func(text string) string {
+		return `<p:sld xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main" xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main">
+<p:cSld><p:spTree><p:sp><p:txBody><a:p><a:r><a:t>` + text + `</a:t></a:r></a:p></p:txBody></p:sp></p:spTree></p:cSld></p:sld>`
+	}
+	data := zipDocument(t, map[string]string{
+		"ppt/presentation.xml": `<p:presentation xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<p:sldIdLst><p:sldId id="256" r:id="rId3"/><p:sldId id="257" r:id="rId2"/></p:sldIdLst></p:presentation>`,
+		"ppt/_rels/presentation.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/slide" Target="slides/slide1.xml"/>
+<Relationship Id="rId3" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/slide" Target="slides/slide2.xml"/>
+</Relationships>`,
+		"ppt/slides/slide1.xml": slide("Roadmap"),
+		"ppt/slides/slide2.xml": slide("Welcome"),
+		"ppt/slides/_rels/slide2.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/notesSlide" Target="../notesSlides/notesSlide1.xml"/>
+</Relationships>`,
+		"ppt/notesSlides/notesSlide1.xml": slide("Greet the audience"),
+	})
+
+	result := extract(t, data, "", "deck.pptx")
+	if result.PageCount != 2 {
+		t.Errorf("PageCount = %d, want 2 slides", result.PageCount)
+	}
+	assertContains(t, result.Text, "--- Slide 1 ---\nWelcome\nNotes: Greet the audience", "--- Slide 2 ---\nRoadmap")
+
+	pages := services.SplitPages(result.Text)
+	if len(pages) != 2 || pages[1].Number != 2 || pages[1].Text != "Roadmap" {
+		t.Errorf("SplitPages(deck) = %+v, want one page per slide", pages)
+	}
+	source := services.KnowledgeSource{FileName: "deck.pptx", Page: 2}
+	if got := source.Citation(); got != "deck.pptx, slide 2" {
+		t.Errorf("Citation = %q, want slide number", got)
+	}
+}
+
+// ทดสอบว่า ODT ได้หัวข้อ ย่อหน้า ช่องว่างที่เข้ารหัสด้วย text:s และตาราง
+func TestExtractODT(t *testing.T) {
+	content := `<office:document-content xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0" xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0" xmlns:table="urn:oasis:names:tc:opendocument:xmlns:table:1.0">
+<office:body><office:text>
+<text:h>บทนำ</text:h>
+<text:p>a<text:s text:c="3"/>b<text:span> c</text:span></text:p>
+<table:table><table:table-row><table:table-cell><text:p>k</text:p></table:table-cell><table:table-cell><text:p>v</text:p></table:table-cell></table:table-row></table:table>
+</office:text></office:body></office:document-content>`
+	data := zipDocument(t, map[string]string{"content.xml": content})
+
+	result := extract(t, data, "application/vnd.oasis.opendocument.text", "notes.odt")
+	assertContains(t, result.Text, "บทนำ\na   b c", "| k | v |")
+}
+
+// ทดสอบว่า EPUB อ่านบทตามลำดับ spine และข้าม script/style
+func TestExtractEPUB(t *testing.T) {
+	data := zipDocument(t, map[string]string{
+		"META-INF/container.xml": `<container xmlns="urn:oasis:names:tc:opendocument:xmlns:container"><rootfiles><rootfile full-path="OEBPS/content.opf"/></rootfiles></container>`,
+		"OEBPS/content.opf": `<package xmlns="http://www.idpf.org/2007/opf"><manifest>
+<item id="c1" href="chapter%201.xhtml" media-type="application/xhtml+xml"/><item id="c2" href="text/c2.xhtml" media-type="application/xhtml+xml"/>
+</manifest><spine><itemref idref="c2"/><itemref idref="c1"/></spine></package>`,
+		"OEBPS/chapter 1.xhtml": `<html><head><title>x</title><style>p{}</style></head><body><h1>Second</h1><p>Later   text</p></body></html>`,
+		"OEBPS/text/c2.xhtml":   `<html><body><h1>First</h1><script>alert(1)</script><p>Opening</p></body></html>`,
+	})
+
+	result := extract(t, data, "application/epub+zip", "book.epub")
+	if want := "First\nOpening\n\nSecond\nLater text"; result.Text != want {
+		t.Errorf("EPUB text = %q, want %q", result.Text, want)
+	}
+}
+
+// ทดสอบว่า RTF ภาษาไทยอ่านได้ทั้งแบบ code page 874 (\'hh) และ \uN โดยไม่ซ้ำกับตัวสำรอง
+func TestExtractRTFThai(t *testing.T) {
+	rtf := `{\rtf1\ansi\ansicpg874\deff0{\fonttbl{\f0 Tahoma;}}{\*\generator Writer;}` +
+		`\f0 \'ca\'c7\'d1\'ca\'b4\'d5\par ` +
+		`\u3588?\u3634?\u3609?\par ` +
+		`Tab\tab end\}}`
+
+	result := extract(t, []byte(rtf), "application/rtf", "memo.rtf")
+	if want := "สวัสดี\nคาน\nTab\tend}"; result.Text != want {
+		t.Errorf("RTF text = %q, want %q", result.Text, want)
+	}
+}
+
+// ทดสอบว่า HTML เหลือเฉพาะข้อความที่มองเห็น แยกบรรทัดตาม block และเซลล์ตารางคั่นด้วย |
+func TestExtractHTML(t *testing.T) {
+	page := `<!DOCTYPE html><html><head><title>T</title><script>var x = 1;</script></head>
+<body><nav>Menu</nav><p>Hello &amp; <b>welcome</b></p><table><tr><td>a</td><td>b</td></tr></table></body></html>`
+
+	result := extract(t, []byte(page), "text/html", "index.html")
+	assertContains(t, result.Text, "Menu\n", "Hello & welcome", "| a | b")
+	if strings.Contains(result.Text, "var x") || strings.Contains(result.Text, "<") {
+		t.Errorf("HTML text has script or markup:\n%s", result.Text)
+	}
+}
@@ -0,0 +1,292 @@
+package chat_provider_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"chatbot/config"
+	"chatbot/models"
+	"chatbot/services"
+
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"github.com/sashabaranov/go-openai"
+)
+
+// testPolicy ใช้ delay สั้นๆ เพื่อให้เทสต์เร็ว
+func testPolicy(providers ...string) services.FailoverPolicy {
+	return services.FailoverPolicy{
+		Providers:  providers,
+		MaxRetries: 2,
+		BaseDelay:  time.Millisecond,
+		MaxDelay:   2 * time.Millisecond,
+	}
+}
+
+// readAll อ่าน stream จนจบ
+func readAll(t *testing.T, stream services.StreamReader) string {
+	t.Helper()
+	content := ""
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			return content
+		}
+		if err != nil {
+			t.Fatalf("Recv returned error: %v", err)
+		}
+		content += chunk
+	}
+}
+
+// TestFailoverRetriesThenSwitchesProvider ทดสอบ retry เมื่อเจอ 429 แล้วสลับจาก provider ที่ auto-detect ไป bedrock
+func TestFailoverRetriesThenSwitchesProvider(t *testing.T) {
+	openaiFake := &fakeProvider{name: "openai", available: true, err: &openai.APIError{HTTPStatusCode: 429, Message: "rate limited"}}
+	bedrockFake := &fakeProvider{name: "bedrock", available: true, chunks: []string{"", "สวัสดี", "ครับ"}}
+
+	registry := services.NewProviderRegistry()
+	registry.Register("openai", 10, services.ProviderCapabilities{Streaming: true}, openaiFake)
+	registry.Register("bedrock", 20, services.ProviderCapabilities{Streaming: true}, bedrockFake)
+
+	streamer := services.NewFailoverStreamer(registry, testPolicy("openai", "bedrock"))
+	stream, result, err := streamer.CreateStreamingChat(context.Background(), "", services.StreamingChatRequest{})
+	if err != nil {
+		t.Fatalf("CreateStreamingChat returned error: %v", err)
+	}
+	defer stream.Close()
+
+	if content := readAll(t, stream); content != "สวัสดีครับ" {
+		t.Errorf("content = %q, want สวัสดีครับ", content)
+	}
+	// usage ต้องผ่าน stream ที่ถูก peek ได้
+	if usage := services.StreamUsage(stream); usage == nil || usage.CompletionTokens != 2 {
+		t.Errorf("StreamUsage() = %+v, want completion 2", usage)
+	}
+	if result.Provider != "bedrock" || result.Attempts != 4 {
+		t.Errorf("result = %+v, want bedrock after 4 attempts", result)
+	}
+	if openaiFake.calls != 3 {
+		t.Errorf("openai calls = %d, want 3 (1 + 2 retries)", openaiFake.calls)
+	}
+}
+
+// TestFailoverFromPersonaSettings ทดสอบ failover ผ่าน ResolveGeneration: provider และ model ที่มาจาก persona
+// สลับไป bedrock ได้ ส่วน provider หรือ model ที่ request ระบุเองถูก retry แต่ไม่ถูกสลับ
+func TestFailoverFromPersonaSettings(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides services.GenerationOverrides
+		failover  bool
+	}{
+		{"persona model", services.GenerationOverrides{}, true},
+		{"explicit provider", services.GenerationOverrides{Provider: "openai"}, false},
+		{"explicit model", services.GenerationOverrides{Model: "gpt-4o"}, false},
+	}
+	persona := &models.Persona{Model: "gpt-4o-mini"}
+
+	for _, tt := range tests {
+		openaiFake := &fakeProvider{name: "openai", available: true, err: &openai.APIError{HTTPStatusCode: 503, Message: "unavailable"}}
+		bedrockFake := &fakeProvider{name: "bedrock", available: true, chunks: []string{"ok"}}
+
+		registry := services.NewProviderRegistry()
+		registry.Register("openai", 10, services.ProviderCapabilities{Streaming: true}, openaiFake)
+		registry.Register("bedrock", 20, services.ProviderCapabilities{Streaming: true}, bedrockFake)
+		catalog := services.NewModelCatalog([]config.CatalogModel{
+			{Provider: "openai", ID: "gpt-4o-mini"},
+			{Provider: "openai", ID: "gpt-4o"},
+		}, services.NewPricingService(config.PricingTable{}), registry)
+
+		settings, err := catalog.ResolveGeneration(tt.overrides, persona, "openai")
+		if err != nil {
+			t.Fatalf("%s: ResolveGeneration returned error: %v", tt.name, err)
+		}
+		streamer := services.NewFailoverStreamer(registry, testPolicy("bedrock"))
+		_, result, err := streamer.CreateStreamingChat(context.Background(), settings.Provider, settings.Apply(services.StreamingChatRequest{}))
+
+		if openaiFake.calls != 3 {
+			t.Errorf("%s: openai calls = %d, want 3 (1 + 2 retries)", tt.name, openaiFake.calls)
+		}
+		if !tt.failover {
+			if err == nil || bedrockFake.calls != 0 || result.Provider != "openai" {
+				t.Errorf("%s: err = %v, bedrock calls = %d, result = %+v; want retries on openai only",
+					tt.name, err, bedrockFake.calls, result)
+			}
+			continue
+		}
+		if err != nil || result.Provider != "bedrock" {
+			t.Fatalf("%s: err = %v, result = %+v; want bedrock", tt.name, err, result)
+		}
+		// persona model เป็นของ openai; provider สำรองต้องใช้ model default ของตัวเอง
+		if openaiFake.lastModel != "gpt-4o-mini" || bedrockFake.lastModel != "" {
+			t.Errorf("%s: models = %q then %q, want gpt-4o-mini then the bedrock default",
+				tt.name, openaiFake.lastModel, bedrockFake.lastModel)
+		}
+	}
+}
+
+// TestFailoverFatalErrorStops ทดสอบว่า error ประเภท fatal ไม่ retry และไม่สลับ provider
+func TestFailoverFatalErrorStops(t *testing.T) {
+	openaiFake := &fakeProvider{name: "openai", available: true, err: &openai.APIError{HTTPStatusCode: 400, Message: "bad request"}}
+	bedrockFake := &fakeProvider{name: "bedrock", available: true, chunks: []string{"ok"}}
+
+	registry := services.NewProviderRegistry()
+	registry.Register("openai", 10, services.ProviderCapabilities{}, openaiFake)
+	registry.Register("bedrock", 20, services.ProviderCapabilities{}, bedrockFake)
+
+	streamer := services.NewFailoverStreamer(registry, testPolicy("bedrock"))
+	_, result, err := streamer.CreateStreamingChat(context.Background(), "", services.StreamingChatRequest{})
+	if err == nil {
+		t.Fatal("CreateStreamingChat should fail on a fatal error")
+	}
+	if result.Attempts != 1 || bedrockFake.calls != 0 {
+		t.Errorf("attempts = %d, bedrock calls = %d; want 1 and 0", result.Attempts, bedrockFake.calls)
+	}
+}
+
+// TestFailoverKeepsLocalProvider ทดสอบว่า local LLM ที่ถูก auto-detect ไม่ถูกสลับไป provider บน cloud
+func TestFailoverKeepsLocalProvider(t *testing.T) {
+	localFake := &fakeProvider{name: "local", available: true, err: &services.LocalLLMError{StatusCode: 503, Message: "loading model"}}
+	openaiFake := &fakeProvider{name: "openai", available: true, chunks: []string{"ok"}}
+
+	registry := services.NewProviderRegistry()
+	registry.Register("local", 10, services.ProviderCapabilities{Streaming: true, Local: true}, localFake)
+	registry.Register("openai", 20, services.ProviderCapabilities{Streaming: true}, openaiFake)
+
+	streamer := services.NewFailoverStreamer(registry, testPolicy("openai"))
+	if _, _, err := streamer.CreateStreamingChat(context.Background(), "", services.StreamingChatRequest{}); err == nil {
+		t.Fatal("CreateStreamingChat should fail instead of leaving the local LLM")
+	}
+	if openaiFake.calls != 0 || localFake.calls != 3 {
+		t.Errorf("local calls = %d, openai calls = %d; want 3 and 0", localFake.calls, openaiFake.calls)
+	}
+}
+
+// reasoningStream จำลอง stream ของ reasoning model: แต่ละ step เป็น reasoning delta (Recv คืน "") หรือข้อความ
+type reasoningStream struct {
+	steps   []string // "think:..." = reasoning delta
+	index   int
+	pending string
+	blocks  []services.ReasoningBlock
+	calls   []services.ToolCall
+}
+
+func (s *reasoningStream) Recv() (string, error) {
+	if s.index >= len(s.steps) {
+		return "", io.EOF
+	}
+	step := s.steps[s.index]
+	s.index++
+	if reasoning, ok := strings.CutPrefix(step, "think:"); ok {
+		s.pending += reasoning
+		return "", nil
+	}
+	return step, nil
+}
+
+func (s *reasoningStream) Close() error { return nil }
+
+func (s *reasoningStream) TakeReasoning() string {
+	reasoning := s.pending
+	s.pending = ""
+	return reasoning
+}
+
+func (s *reasoningStream) Reasoning() []services.ReasoningBlock { return s.blocks }
+
+func (s *reasoningStream) ToolCalls() []services.ToolCall { return s.calls }
+
+// reasoningProvider คืน reasoningStream ทีละรอบ
+type reasoningProvider struct {
+	rounds []*reasoningStream
+	calls  int
+}
+
+func (p *reasoningProvider) CreateStreamingChat(ctx context.Context, req services.StreamingChatRequest) (services.StreamReader, error) {
+	p.calls++
+	return p.rounds[p.calls-1], nil
+}
+
+func (p *reasoningProvider) GetProviderName() string { return "bedrock" }
+
+func (p *reasoningProvider) IsAvailable() bool { return true }
+
+// TestFailoverForwardsReasoning ทดสอบว่า reasoning ผ่าน FailoverStreamer ไปถึง ToolOrchestrator
+// ทั้ง reasoning frame และ thinking block พร้อม signature ที่ต้องส่งกลับในรอบ tool
+func TestFailoverForwardsReasoning(t *testing.T) {
+	provider := &reasoningProvider{rounds: []*reasoningStream{
+		{
+			steps:  []string{"think:Need ", "think:a tool."},
+			blocks: []services.ReasoningBlock{{Text: "Need a tool.", Signature: "sig-1"}},
+			calls:  []services.ToolCall{{ID: "call_1", Name: "echo", Arguments: `{"text":"hi"}`}},
+		},
+		{steps: []string{"think:Got it.", "Done"}},
+	}}
+	registry := services.NewProviderRegistry()
+	registry.Register("bedrock", 10, services.ProviderCapabilities{Streaming: true}, provider)
+	streamer := services.NewFailoverStreamer(registry, testPolicy())
+
+	orchestrator := services.NewToolOrchestrator(newTestToolRegistry(t), 3)
+	var requests []services.StreamingChatRequest
+	open := func(ctx context.Context, req services.StreamingChatRequest) (services.StreamReader, error) {
+		requests = append(requests, req)
+		stream, _, err := streamer.CreateStreamingChat(ctx, "", req)
+		return stream, err
+	}
+
+	var reasoning []string
+	result, err := orchestrator.Stream(context.Background(), services.StreamingChatRequest{
+		Messages: []services.ConversationMessage{services.NewTextMessage(services.ConversationRoleUser, "hello")},
+		Tools:    orchestrator.Registry().Definitions([]string{"echo"}),
+	}, open, services.ToolEvents{
+		OnReasoning: func(text string) error {
+			reasoning = append(reasoning, text)
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Stream returned error: %v", err)
+	}
+
+	if got := strings.Join(reasoning, "|"); got != "Need |a tool.|Got it." {
+		t.Errorf("reasoning frames = %q, want Need |a tool.|Got it.", got)
+	}
+	if result.Content != "Done" || len(result.Reasoning) != 1 || result.Reasoning[0].Signature != "sig-1" {
+		t.Errorf("result = %q with reasoning %+v", result.Content, result.Reasoning)
+	}
+	// thinking block ต้องถูกส่งกลับไปกับ assistant turn ในรอบที่สอง
+	if len(requests) != 2 {
+		t.Fatalf("requests = %d, want 2", len(requests))
+	}
+	assistant := requests[1].Messages[1]
+	if len(assistant.Reasoning) != 1 || assistant.Reasoning[0].Signature != "sig-1" {
+		t.Errorf("assistant reasoning = %+v, want the signed block", assistant.Reasoning)
+	}
+}
+
+// TestClassifyProviderError ทดสอบการจัดประเภท error
+func TestClassifyProviderError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want services.ErrorClass
+	}{
+		{"openai 429", &openai.APIError{HTTPStatusCode: 429}, services.ErrorClassRetryable},
+		{"openai 503", &openai.RequestError{HTTPStatusCode: 503, Err: errors.New("unavailable")}, services.ErrorClassRetryable},
+		{"openai 401", &openai.APIError{HTTPStatusCode: 401}, services.ErrorClassFailover},
+		{"openai 400", &openai.APIError{HTTPStatusCode: 400}, services.ErrorClassFatal},
+		{"bedrock throttling", &types.ThrottlingException{}, services.ErrorClassRetryable},
+		{"bedrock access denied", &types.AccessDeniedException{}, services.ErrorClassFailover},
+		{"bedrock validation", &types.ValidationException{}, services.ErrorClassFatal},
+		{"cancelled", context.Canceled, services.ErrorClassFatal},
+		{"unknown", errors.New("boom"), services.ErrorClassFailover},
+	}
+
+	for _, tt := range tests {
+		if got := services.ClassifyProviderError(tt.err); got != tt.want {
+			t.Errorf("%s: ClassifyProviderError = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
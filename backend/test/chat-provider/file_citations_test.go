@@ -0,0 +1,87 @@
+package chat_provider_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"chatbot/models"
+	"chatbot/services"
+)
+
+// filePart สร้าง file part สำหรับทดสอบการอ้างอิง
+func filePart(id, name, text string) services.ContentPart {
+	return services.ContentPart{Type: services.ContentPartFile, FileID: id, FileName: name, Text: text}
+}
+
+// ทดสอบว่าหน้าและชีตของคำพูดที่พบในไฟล์มาจากตัวเอกสาร ไม่ใช่จากที่โมเดลบอก
+func TestFileCitationsLocateQuotes(t *testing.T) {
+	parts := []services.ContentPart{
+		filePart("f1", "report.pdf", "--- Page 1 ---\nIntroduction\n\n--- Page 2 ---\nรายได้เพิ่มขึ้น 12%\nใน ไตรมาสที่   สาม"),
+		filePart("f2", "budget.xlsx", "=== Sheet: Summary ===\n| a | b |\n\n=== Sheet: Costs ===\n| Rent | 9,000 |"),
+	}
+	answer := `รายได้โตขึ้น [File 1, page 1: "ใน ไตรมาสที่ สาม"] ค่าเช่า [File 2, sheet Summary: "rent | 9,000"] ` +
+		`ซ้ำ [File 1, page 2: "ใน ไตรมาสที่ สาม"]`
+
+	citations := services.FileCitations(parts, answer)
+	if len(citations) != 2 {
+		t.Fatalf("FileCitations = %+v, want 2 citations (duplicate dropped)", citations)
+	}
+	if c := citations[0]; c.FileID != "f1" || c.Page != 2 || !c.Verified || c.Quote != "ใน ไตรมาสที่ สาม" {
+		t.Errorf("PDF citation = %+v, want verified on page 2", c)
+	}
+	if c := citations[1]; c.FileID != "f2" || c.Sheet != "Costs" || c.Page != 0 || !c.Verified {
+		t.Errorf("sheet citation = %+v, want verified on sheet Costs", c)
+	}
+}
+
+// ทดสอบว่าคำพูดที่ไม่พบในไฟล์ใช้หน้าหรือชีตที่โมเดลอ้าง และเลขไฟล์ที่ไม่มีถูกข้าม
+func TestFileCitationsUnverified(t *testing.T) {
+	parts := []services.ContentPart{
+		filePart("f1", "deck.pptx", "--- Slide 1 ---\nWelcome"),
+		filePart("f2", "notes.txt", "plain notes"),
+	}
+	answer := `[File 1, slide 3: "Roadmap"] [File 2: "plain notes"] [File 3: "missing file"] [file 2, sheet Q1: “other”]`
+
+	citations := services.FileCitations(parts, answer)
+	want := []models.MessageCitation{
+		{FileID: "f1", FileName: "deck.pptx", Page: 3, Quote: "Roadmap"},
+		{FileID: "f2", FileName: "notes.txt", Quote: "plain notes", Verified: true},
+		{FileID: "f2", FileName: "notes.txt", Sheet: "Q1", Quote: "other"},
+	}
+	if len(citations) != len(want) {
+		t.Fatalf("FileCitations = %+v, want %+v", citations, want)
+	}
+	for i := range want {
+		if citations[i] != want[i] {
+			t.Errorf("citation %d = %+v, want %+v", i, citations[i], want[i])
+		}
+	}
+}
+
+// ทดสอบว่า file context สั่งให้โมเดลอ้างอิงด้วยรูปแบบที่ FileCitations อ่านได้
+func TestFileContextAsksForCitations(t *testing.T) {
+	context := services.FormatFileContext([]services.ContentPart{filePart("f1", "report.pdf", "--- Page 1 ---\nHello")})
+	for _, want := range []string{"--- 📄 File 1: report.pdf ---", `[File N, page P: "exact quote"]`} {
+		if !strings.Contains(context, want) {
+			t.Errorf("file context missing %q:\n%s", want, context)
+		}
+	}
+}
+
+// ทดสอบว่าการอ้างอิงที่บันทึกใน metadata อ่านกลับได้
+func TestMessageCitationsFromMetadata(t *testing.T) {
+	citations := []models.MessageCitation{{FileID: "f1", FileName: "a.pdf", Page: 4, Quote: "q", Verified: true}}
+	metadata, _ := json.Marshal(map[string]interface{}{"provider": "openai", "citations": citations})
+	message := models.Message{Metadata: metadata}
+
+	got, err := message.GetCitations()
+	if err != nil || len(got) != 1 || got[0] != citations[0] {
+		t.Errorf("GetCitations = %+v, %v, want %+v", got, err, citations)
+	}
+
+	empty := models.Message{Metadata: []byte(`{}`)}
+	if got, err := empty.GetCitations(); err != nil || len(got) != 0 {
+		t.Errorf("GetCitations(no citations) = %+v, %v, want none", got, err)
+	}
+}
@@ -0,0 +1,198 @@
+package chat_provider_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"chatbot/models"
+	"chatbot/repositories"
+	"chatbot/services"
+	"chatbot/utils"
+
+	"github.com/google/uuid"
+	"github.com/xuri/excelize/v2"
+)
+
+// ทดสอบว่าไฟล์ข้อความถูกแยกตาม MIME type หรือนามสกุล และรูปภาพ/ไฟล์ binary ไม่มีข้อความ
+func TestExtractTextByType(t *testing.T) {
+	cases := []struct {
+		name     string
+		mimeType string
+		fileName string
+		data     string
+		want     string
+	}{
+		{"plain", "text/plain", "notes.txt", "hello", "hello"},
+		{"json", "application/json", "data.json", `{"a":1}`, `{"a":1}`},
+		{"by extension", "application/octet-stream", "main.go", "package main", "package main"},
+		{"xml", "application/xml", "feed.xml", "<a><b>x</b></a>", "<a>\n  <b>x</b>\n</a>"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := services.ExtractText([]byte(tc.data), tc.mimeType, tc.fileName)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result == nil || strings.TrimSpace(result.Text) != tc.want {
+				t.Errorf("ExtractText = %+v, want %q", result, tc.want)
+			}
+		})
+	}
+
+	for _, tc := range []struct{ mimeType, fileName string }{
+		{"image/png", "photo.png"},
+		{"application/octet-stream", "archive.zip"},
+	} {
+		result, err := services.ExtractText([]byte{0x89, 0x50}, tc.mimeType, tc.fileName)
+		if err != nil || result != nil {
+			t.Errorf("ExtractText(%s) = %+v, %v, want no text", tc.fileName, result, err)
+		}
+	}
+}
+
+// ทดสอบว่าข้อความที่ Postgres เก็บไม่ได้ (NUL, UTF-8 ไม่ถูกต้อง) ถูกตัดออก และภาษาถูกบันทึก
+func TestExtractTextCleansText(t *testing.T) {
+	data := []byte("สัญญาเช่าพื้นที่สำนักงาน\x00 ระยะเวลาหนึ่งปี\xff")
+	result, err := services.ExtractText(data, "text/plain", "contract.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Text != "สัญญาเช่าพื้นที่สำนักงาน ระยะเวลาหนึ่งปี" {
+		t.Errorf("Text = %q", result.Text)
+	}
+	if result.Language != "th" {
+		t.Errorf("Language = %q, want th", result.Language)
+	}
+}
+
+// ทดสอบว่า Excel ถูกแยกทีละ sheet และแต่ละแถวคั่นด้วย |
+func TestExtractTextExcel(t *testing.T) {
+	f := excelize.NewFile()
+	f.SetCellValue("Sheet1", "A1", "Item")
+	f.SetCellValue("Sheet1", "B1", "Price")
+	f.SetCellValue("Sheet1", "A2", "Coffee")
+	f.SetCellValue("Sheet1", "B2", 55)
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := services.ExtractText(buf.Bytes(), "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", "menu.xlsx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"=== Sheet: Sheet1 ===", "Item | Price", "Coffee | 55"} {
+		if !strings.Contains(result.Text, want) {
+			t.Errorf("Excel text missing %q:\n%s", want, result.Text)
+		}
+	}
+}
+
+// ทดสอบการตรวจภาษา: ไทยที่มีอังกฤษปน ยังนับเป็นไทย ข้อความสั้นหรือสคริปต์อื่นไม่ระบุภาษา
+func TestDetectLanguage(t *testing.T) {
+	cases := map[string]string{
+		"รายงานผลการดำเนินงาน Q3 ของ ACME Corporation ประจำปี 2025": "th",
+		"The quarterly report covers revenue and operating costs.":  "en",
+		"ok": "",
+		"これは日本語の文章です。テキストの言語を判定します。": "",
+	}
+	for text, want := range cases {
+		if got := utils.DetectLanguage(text); got != want {
+			t.Errorf("DetectLanguage(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
+
+// ทดสอบว่าไฟล์ที่แยกข้อความเสร็จแล้ว (สำเร็จหรือไม่) ถูกอ่านจาก record โดยไม่แยกใหม่
+func TestExtractionTextFromRecord(t *testing.T) {
+	extraction := services.NewFileExtractionService(nil)
+
+	ready := &models.FileAnalysis{ExtractionStatus: models.ExtractionStatusReady, ExtractedText: "stored", StoragePath: "/missing"}
+	if text, err := extraction.Text(ready); err != nil || text != "stored" {
+		t.Errorf("Text(ready) = %q, %v, want the stored text", text, err)
+	}
+
+	failed := &models.FileAnalysis{ExtractionStatus: models.ExtractionStatusFailed, ExtractionError: "failed to open PDF"}
+	if _, err := extraction.Text(failed); err == nil || err.Error() != "failed to open PDF" {
+		t.Errorf("Text(failed) error = %v, want the stored error", err)
+	}
+}
+
+// ทดสอบว่า Text ของไฟล์ที่ยังแยกไม่เสร็จรอ job ที่กำลังรันอยู่ (หรือเริ่ม job ให้) แล้วคืนข้อความเดียวกัน
+func TestExtractionTextWaitsForRunningJob(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	if err := os.WriteFile(path, []byte("บันทึกการประชุม"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	extraction := services.NewFileExtractionService(repositories.NewFileAnalysisRepository(offlineDB(t)))
+	upload := models.FileAnalysis{
+		ID:               uuid.New(),
+		FileName:         "notes.txt",
+		StoragePath:      path,
+		MimeType:         "text/plain",
+		ExtractionStatus: models.ExtractionStatusPending,
+	}
+
+	extraction.Extract(upload)
+	var wg sync.WaitGroup
+	texts := make([]string, 3)
+	files := make([]models.FileAnalysis, len(texts))
+	for i := range texts {
+		files[i] = upload
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			texts[i], _ = extraction.Text(&files[i])
+		}(i)
+	}
+	wg.Wait()
+
+	for i, text := range texts {
+		if text != "บันทึกการประชุม" || files[i].ExtractionStatus != models.ExtractionStatusReady {
+			t.Errorf("Text #%d = %q (status %s), want the extracted text", i, text, files[i].ExtractionStatus)
+		}
+	}
+}
+
+// ทดสอบว่า Wait รอการแยกข้อความได้นานเท่าที่ ctx อนุญาต (งาน background เช่น knowledge indexing ไม่ต้องยอมแพ้ที่ 15 วินาที)
+func TestExtractionWaitBoundedByContext(t *testing.T) {
+	// FIFO ทำให้การอ่านไฟล์ค้างจนกว่าจะมีคนเขียน จึงคุมได้ว่าการแยกข้อความเสร็จเมื่อไร
+	path := filepath.Join(t.TempDir(), "slow.txt")
+	if err := syscall.Mkfifo(path, 0644); err != nil {
+		t.Skipf("mkfifo not supported: %v", err)
+	}
+	extraction := services.NewFileExtractionService(repositories.NewFileAnalysisRepository(offlineDB(t)))
+	upload := models.FileAnalysis{
+		ID:               uuid.New(),
+		FileName:         "slow.txt",
+		StoragePath:      path,
+		MimeType:         "text/plain",
+		ExtractionStatus: models.ExtractionStatusPending,
+	}
+
+	file := upload
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := extraction.Wait(ctx, &file); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Wait error = %v, want context.DeadlineExceeded while the file is being read", err)
+	}
+
+	go func() {
+		if err := os.WriteFile(path, []byte("เอกสารที่อ่านช้า"), 0644); err != nil {
+			t.Errorf("WriteFile: %v", err)
+		}
+	}()
+	file = upload
+	text, err := extraction.Wait(context.Background(), &file)
+	if err != nil || text != "เอกสารที่อ่านช้า" || file.ExtractionStatus != models.ExtractionStatusReady {
+		t.Errorf("Wait = %q, %v (status %s), want the extracted text", text, err, file.ExtractionStatus)
+	}
+}
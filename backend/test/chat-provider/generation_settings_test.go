@@ -0,0 +1,65 @@
+package chat_provider_test
+
+import (
+	"testing"
+
+	"chatbot/models"
+	"chatbot/services"
+)
+
+// TestResolveGeneration ทดสอบลำดับการเลือก settings: request → persona → provider default
+func TestResolveGeneration(t *testing.T) {
+	catalog := newTestCatalog()
+	claudePersona := &models.Persona{Model: "claude-3-5-sonnet", Temperature: 0.3, MaxTokens: 1500}
+	openAIPersona := &models.Persona{Model: "gpt-4o-mini", Temperature: 0.9, MaxTokens: 800}
+	localPersona := &models.Persona{Provider: "local", Model: "llama3"}
+
+	tests := []struct {
+		name      string
+		overrides services.GenerationOverrides
+		persona   *models.Persona
+		fallback  string
+		want      services.GenerationSettings
+	}{
+		{
+			name:    "persona model selects its provider",
+			persona: claudePersona,
+			want:    services.GenerationSettings{Provider: "bedrock", Model: "claude-3-5-sonnet", Temperature: 0.3, MaxTokens: 1500},
+		},
+		{
+			name:      "request overrides persona",
+			overrides: services.GenerationOverrides{Model: "gpt-4o-mini", Temperature: 0.1, MaxTokens: 100},
+			persona:   claudePersona,
+			want:      services.GenerationSettings{Provider: "openai", Model: "gpt-4o-mini", Temperature: 0.1, MaxTokens: 100, Pinned: true},
+		},
+		{
+			name:      "persona model of another provider is not used",
+			overrides: services.GenerationOverrides{Provider: "bedrock"},
+			persona:   openAIPersona,
+			fallback:  "bedrock",
+			want:      services.GenerationSettings{Provider: "bedrock", Temperature: 0.9, MaxTokens: 800, Pinned: true},
+		},
+		{
+			name:    "uncatalogued model keeps the persona's provider",
+			persona: localPersona,
+			want:    services.GenerationSettings{Provider: "local", Model: "llama3"},
+		},
+		{
+			name:     "no persona uses the fallback provider defaults",
+			fallback: "openai",
+			want:     services.GenerationSettings{Provider: "openai"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := catalog.ResolveGeneration(tt.overrides, tt.persona, tt.fallback)
+			if err != nil {
+				t.Fatalf("ResolveGeneration returned error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ResolveGeneration = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package chat_provider_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"chatbot/models"
+	"chatbot/services"
+	"chatbot/utils"
+)
+
+// ทดสอบว่า chunk ไม่เกินขนาดที่กำหนด ตัดที่ขอบประโยค และ chunk ที่ติดกันซ้อนกัน
+func TestChunkDocument(t *testing.T) {
+	sentence := "The quick brown fox jumps over the lazy dog. "
+	text := strings.Repeat(sentence, 40)
+
+	words := map[string]bool{}
+	for _, word := range strings.Fields(sentence) {
+		words[word] = true
+	}
+
+	chunks := utils.ChunkDocument(text, 300, 60)
+	if len(chunks) < 6 {
+		t.Fatalf("got %d chunks, want at least 6", len(chunks))
+	}
+	for i, chunk := range chunks {
+		if n := len([]rune(chunk)); n > 300 {
+			t.Errorf("chunk %d has %d characters, want at most 300", i, n)
+		}
+		if i < len(chunks)-1 && !strings.HasSuffix(chunk, ".") {
+			t.Errorf("chunk %d = %q, want it cut after a sentence", i, chunk)
+		}
+		if first := strings.Fields(chunk)[0]; !words[first] {
+			t.Errorf("chunk %d starts mid-word: %q", i, chunk)
+		}
+	}
+
+	// ข้อความท้าย chunk แรกต้องปรากฏซ้ำในต้น chunk ที่สอง
+	tail := chunks[0][len(chunks[0])-20:]
+	if !strings.Contains(chunks[1], tail) {
+		t.Errorf("chunk 2 = %q, want it to overlap the end of chunk 1 (%q)", chunks[1], tail)
+	}
+}
+
+// ทดสอบว่าข้อความไทยยาวที่ไม่มีช่องว่างถูกตัดตามขนาด และข้อความว่างไม่มี chunk
+func TestChunkDocumentThaiAndEmpty(t *testing.T) {
+	text := strings.Repeat("ภาษาไทย", 100) // 700 ตัวอักษร ไม่มีช่องว่าง
+	chunks := utils.ChunkDocument(text, 200, 20)
+	if len(chunks) < 4 {
+		t.Fatalf("got %d chunks, want at least 4", len(chunks))
+	}
+	for i, chunk := range chunks {
+		if n := len([]rune(chunk)); n > 200 {
+			t.Errorf("chunk %d has %d characters, want at most 200", i, n)
+		}
+	}
+
+	if got := utils.ChunkDocument("  \n ", 200, 20); len(got) != 0 {
+		t.Errorf("ChunkDocument(blank) = %v, want none", got)
+	}
+}
+
+// ทดสอบว่าข้อความจาก PDF ถูกแยกตามหัวหน้า และเอกสารอื่นเป็นหน้าเดียวไม่มีเลขหน้า
+func TestSplitPages(t *testing.T) {
+	text := "\n--- Page 1 ---\nบทนำ\n--- Page 2 ---\n\n--- Page 3 ---\nสรุป\n\n[Note: Only first 3 of 9 pages shown]"
+	pages := services.SplitPages(text)
+	if len(pages) != 2 {
+		t.Fatalf("got %d pages, want 2 (empty page 2 skipped): %+v", len(pages), pages)
+	}
+	if pages[0].Number != 1 || pages[0].Text != "บทนำ" {
+		t.Errorf("page 1 = %+v", pages[0])
+	}
+	if pages[1].Number != 3 || !strings.HasPrefix(pages[1].Text, "สรุป") {
+		t.Errorf("page 3 = %+v", pages[1])
+	}
+
+	pages = services.SplitPages("plain text")
+	if len(pages) != 1 || pages[0].Number != 0 || pages[0].Text != "plain text" {
+		t.Errorf("SplitPages(plain) = %+v, want one unpaged page", pages)
+	}
+}
+
+// ทดสอบว่า excerpt ถูกใส่เลขอ้างอิงพร้อมชื่อไฟล์และเลขหน้า
+func TestKnowledgeMessage(t *testing.T) {
+	message := services.KnowledgeMessage([]services.KnowledgeSource{
+		{Index: 1, FileName: "contract.pdf", Page: 4, Content: "ระยะเวลาสัญญา 12 เดือน"},
+		{Index: 2, FileName: "faq.md", Content: "Refunds take 7 days."},
+	})
+	if message.Role != services.ConversationRoleSystem {
+		t.Errorf("role = %s, want system", message.Role)
+	}
+	text := message.Text()
+	for _, want := range []string{"[1] contract.pdf, page 4\nระยะเวลาสัญญา 12 เดือน", "[2] faq.md\nRefunds take 7 days.", "e.g. [1]"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("knowledge message missing %q:\n%s", want, text)
+		}
+	}
+}
+
+// ทดสอบว่าไม่มี knowledge service หรือ persona ไม่มี knowledge base บทสนทนาไม่ถูกแก้
+func TestKnowledgeAugmentWithoutKnowledge(t *testing.T) {
+	persona := &models.Persona{ID: 1}
+	if err := persona.SetKnowledgeBases([]string{"8d4f2c1e-0000-4000-8000-000000000001"}); err != nil {
+		t.Fatal(err)
+	}
+	conversation := services.NewSimpleConversation("system", "คำถาม")
+
+	var knowledge *services.KnowledgeService
+	if sources := knowledge.Augment(context.Background(), conversation, persona); sources != nil {
+		t.Errorf("sources = %v, want none", sources)
+	}
+	if len(conversation.Messages) != 1 {
+		t.Errorf("got %d messages, want the question only", len(conversation.Messages))
+	}
+	if got := persona.GetKnowledgeBases(); len(got) != 1 {
+		t.Errorf("GetKnowledgeBases = %v", got)
+	}
+}
@@ -0,0 +1,130 @@
+package chat_provider_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"chatbot/services"
+)
+
+// newFakeOllamaServer จำลอง Ollama /api/chat ที่ stream NDJSON กลับมา
+func newFakeOllamaServer(t *testing.T, chunks []string) (*httptest.Server, *map[string]interface{}) {
+	t.Helper()
+	received := map[string]interface{}{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/chat" {
+			http.NotFound(w, r)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			http.Error(w, `{"error":"bad json"}`, http.StatusBadRequest)
+			return
+		}
+		if received["model"] == "missing" {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"error":"model 'missing' not found"}`)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		if received["stream"] == false {
+			fmt.Fprint(w, `{"model":"llama3.1","message":{"role":"assistant","content":"ตอบครั้งเดียว"},"done":true,"done_reason":"stop","prompt_eval_count":12,"eval_count":5}`)
+			return
+		}
+		for _, chunk := range chunks {
+			line, _ := json.Marshal(map[string]interface{}{
+				"model":   "llama3.1",
+				"message": map[string]string{"role": "assistant", "content": chunk},
+				"done":    false,
+			})
+			fmt.Fprintf(w, "%s\n", line)
+		}
+		fmt.Fprint(w, `{"model":"llama3.1","message":{"role":"assistant","content":""},"done":true,"done_reason":"stop","prompt_eval_count":12,"eval_count":3}`+"\n")
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &received
+}
+
+// TestLocalLLMStreaming ทดสอบ streaming ผ่าน Ollama API จำลอง
+func TestLocalLLMStreaming(t *testing.T) {
+	server, received := newFakeOllamaServer(t, []string{"สวัสดี", "จาก", "local"})
+
+	cfg := testConfig()
+	cfg.LocalLLMBaseURL = server.URL
+
+	registry := services.NewProviderRegistryFromConfig(cfg)
+	provider, err := registry.Get("local")
+	if err != nil {
+		t.Fatalf("Get(local) returned error: %v", err)
+	}
+
+	stream, err := provider.CreateStreamingChat(context.Background(), services.StreamingChatRequest{
+		Messages:     []services.ConversationMessage{services.NewTextMessage(services.ConversationRoleUser, "สวัสดี")},
+		SystemPrompt: "You are helpful",
+	})
+	if err != nil {
+		t.Fatalf("CreateStreamingChat returned error: %v", err)
+	}
+	defer stream.Close()
+
+	if content := readAll(t, stream); content != "สวัสดีจากlocal" {
+		t.Errorf("content = %q, want สวัสดีจากlocal", content)
+	}
+	if usage := services.StreamUsage(stream); usage == nil || usage.PromptTokens != 12 || usage.CompletionTokens != 3 {
+		t.Errorf("StreamUsage() = %+v, want 12/3", usage)
+	}
+
+	// ต้องใช้ model default จาก config และส่ง system prompt เป็นข้อความแรก
+	if (*received)["model"] != "llama3.1" {
+		t.Errorf("model = %v, want llama3.1", (*received)["model"])
+	}
+	messages, _ := (*received)["messages"].([]interface{})
+	if len(messages) != 2 || messages[0].(map[string]interface{})["role"] != "system" {
+		t.Errorf("messages = %v, want system + user", messages)
+	}
+}
+
+// TestLocalLLMCompleteChat ทดสอบ non-streaming และ error จาก server
+func TestLocalLLMCompleteChat(t *testing.T) {
+	server, _ := newFakeOllamaServer(t, nil)
+
+	cfg := testConfig()
+	cfg.LocalLLMBaseURL = server.URL
+	service, err := services.NewLocalLLMService(cfg)
+	if err != nil {
+		t.Fatalf("NewLocalLLMService returned error: %v", err)
+	}
+
+	completion, err := service.CompleteChat(context.Background(), services.StreamingChatRequest{
+		Messages: []services.ConversationMessage{services.NewTextMessage(services.ConversationRoleUser, "hi")},
+	})
+	if err != nil {
+		t.Fatalf("CompleteChat returned error: %v", err)
+	}
+	if completion.Content != "ตอบครั้งเดียว" || completion.TokensUsed != 17 {
+		t.Errorf("completion = %+v, want content and 17 tokens", completion)
+	}
+
+	// model ที่ไม่มีอยู่ต้องได้ LocalLLMError และสลับ provider ได้
+	_, err = service.CreateStreamingChat(context.Background(), services.StreamingChatRequest{Model: "missing"})
+	if err == nil {
+		t.Fatal("CreateStreamingChat should fail for a missing model")
+	}
+	if class := services.ClassifyProviderError(err); class != services.ErrorClassFailover {
+		t.Errorf("ClassifyProviderError = %s, want failover", class)
+	}
+}
+
+// TestLocalLLMUnavailableWithoutBaseURL ทดสอบว่า local ไม่พร้อมใช้งานถ้าไม่ได้ตั้ง base URL
+func TestLocalLLMUnavailableWithoutBaseURL(t *testing.T) {
+	registry := services.NewProviderRegistryFromConfig(testConfig())
+	if _, err := registry.Get("local"); err == nil {
+		t.Error("Get(local) should fail without LOCAL_LLM_BASE_URL")
+	}
+}
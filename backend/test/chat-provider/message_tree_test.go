@@ -0,0 +1,132 @@
+package chat_provider_test
+
+import (
+	"testing"
+	"time"
+
+	"chatbot/models"
+	"chatbot/services"
+
+	"github.com/google/uuid"
+)
+
+// branchingSession สร้าง session ที่มีการแก้ไขคำถามและสร้างคำตอบใหม่
+//
+//	u1 ─ a1 ─ u2 ─ a2
+//	        │    └ a2b (regenerate)
+//	        └ u2e ─ tool ─ a3 (edit u2)
+func branchingSession() (map[string]uuid.UUID, []models.Message) {
+	base := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	ids := map[string]uuid.UUID{}
+	var messages []models.Message
+	add := func(name, parent, role string, minute int) {
+		ids[name] = uuid.New()
+		msg := models.Message{ID: ids[name], SessionID: "s1", Role: role, Content: name, CreatedAt: base.Add(time.Duration(minute) * time.Minute)}
+		if parent != "" {
+			parentID := ids[parent]
+			msg.ParentID = &parentID
+		}
+		messages = append(messages, msg)
+	}
+	add("u1", "", models.RoleUser, 0)
+	add("a1", "u1", models.RoleAssistant, 1)
+	add("u2", "a1", models.RoleUser, 2)
+	add("a2", "u2", models.RoleAssistant, 3)
+	add("a2b", "u2", models.RoleAssistant, 4)
+	add("u2e", "a1", models.RoleUser, 5)
+	add("tool", "u2e", models.RoleTool, 6)
+	add("a3", "tool", models.RoleAssistant, 7)
+	return ids, messages
+}
+
+func contents(messages []models.Message) []string {
+	names := make([]string, len(messages))
+	for i, msg := range messages {
+		names[i] = msg.Content
+	}
+	return names
+}
+
+// ทดสอบว่า Path คืนข้อความจากต้น session ถึงข้อความที่เลือก เฉพาะ branch นั้น
+func TestMessageTreePath(t *testing.T) {
+	ids, messages := branchingSession()
+	tree := services.NewMessageTree(messages)
+
+	got := contents(tree.Path(ids["a2b"]))
+	want := []string{"u1", "a1", "u2", "a2b"}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+
+	if path := tree.Path(uuid.New()); len(path) != 0 {
+		t.Errorf("unknown message should give an empty path, got %v", contents(path))
+	}
+}
+
+// ทดสอบว่า LatestLeaf เลือก branch ที่มีข้อความล่าสุด
+func TestMessageTreeLatestLeaf(t *testing.T) {
+	ids, messages := branchingSession()
+	tree := services.NewMessageTree(messages)
+
+	if leaf := tree.LatestLeaf(ids["u1"]); leaf == nil || leaf.ID != ids["a3"] {
+		t.Errorf("latest branch from u1 should end at a3, got %v", leaf)
+	}
+	if leaf := tree.LatestLeaf(ids["u2"]); leaf == nil || leaf.ID != ids["a2b"] {
+		t.Errorf("latest branch from u2 should end at the regenerated a2b, got %v", leaf)
+	}
+	if leaf := tree.LatestLeaf(ids["a2"]); leaf == nil || leaf.ID != ids["a2"] {
+		t.Errorf("a message without replies is its own leaf, got %v", leaf)
+	}
+	if latest := tree.Latest(); latest == nil || latest.ID != ids["a3"] {
+		t.Errorf("latest message should be a3, got %v", latest)
+	}
+}
+
+// ทดสอบ Siblings (ทางเลือกของข้อความเดียวกัน เรียงจากเก่าไปใหม่)
+func TestMessageTreeSiblings(t *testing.T) {
+	ids, messages := branchingSession()
+	tree := services.NewMessageTree(messages)
+
+	siblings := tree.Siblings(ids["u2e"])
+	if len(siblings) != 2 || siblings[0].ID != ids["u2"] || siblings[1].ID != ids["u2e"] {
+		t.Errorf("u2e should be the second version of u2, got %d siblings", len(siblings))
+	}
+	if siblings := tree.Siblings(ids["u1"]); len(siblings) != 1 {
+		t.Errorf("first message has no alternatives, got %d", len(siblings))
+	}
+}
+
+// ทดสอบ PromptOf ข้ามข้อความ tool ไปหาคำถามของผู้ใช้
+func TestMessageTreePromptOf(t *testing.T) {
+	ids, messages := branchingSession()
+	tree := services.NewMessageTree(messages)
+
+	if prompt, ok := tree.PromptOf(ids["a3"]); !ok || prompt.ID != ids["u2e"] {
+		t.Errorf("a3 answers u2e, got %v", prompt)
+	}
+	if prompt, ok := tree.PromptOf(ids["u2"]); !ok || prompt.ID != ids["u2"] {
+		t.Errorf("a user message is its own prompt, got %v", prompt)
+	}
+}
+
+// ทดสอบว่าข้อความเก่าที่ไม่มี parent (ก่อนมี branching) ไม่ทำให้ tree พัง
+func TestMessageTreeWithoutParents(t *testing.T) {
+	base := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	messages := []models.Message{
+		{ID: uuid.New(), Role: models.RoleUser, Content: "hello", CreatedAt: base},
+		{ID: uuid.New(), Role: models.RoleAssistant, Content: "hi", CreatedAt: base.Add(time.Minute)},
+	}
+	tree := services.NewMessageTree(messages)
+
+	if latest := tree.Latest(); latest == nil || latest.Content != "hi" {
+		t.Fatalf("latest should be the newest message, got %v", latest)
+	}
+	if path := tree.Path(messages[1].ID); len(path) != 1 {
+		t.Errorf("unlinked message is a root, got path %v", contents(path))
+	}
+}
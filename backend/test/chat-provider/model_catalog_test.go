@@ -0,0 +1,120 @@
+package chat_provider_test
+
+import (
+	"strings"
+	"testing"
+
+	"chatbot/config"
+	"chatbot/services"
+)
+
+func newTestCatalog() *services.ModelCatalog {
+	models := []config.CatalogModel{
+		{Provider: "openai", ID: "gpt-4o-mini", ContextWindow: 128000, MaxOutputTokens: 16384, Vision: true},
+		{Provider: "openai", ID: "gpt-3.5-turbo", ContextWindow: 1000, MaxOutputTokens: 200},
+		{Provider: "bedrock", ID: "anthropic.claude-3-5-sonnet-20240620-v1:0", Aliases: []string{"claude-3-5-sonnet"}, ContextWindow: 200000, MaxOutputTokens: 8192, Vision: true},
+		{Provider: "bedrock", ID: "anthropic.claude-3-sonnet-20240229-v1:0", ContextWindow: 200000, MaxOutputTokens: 4096, Deprecated: "2020-01-01"},
+	}
+	return services.NewModelCatalog(models, services.NewPricingService(config.DefaultPricingTable()), nil)
+}
+
+// TestModelCatalogValidate ทดสอบการตรวจสอบ model: alias, deprecated, unknown และ provider ที่ไม่มีใน catalog
+func TestModelCatalogValidate(t *testing.T) {
+	catalog := newTestCatalog()
+
+	tests := []struct {
+		name     string
+		provider string
+		model    string
+		want     string
+		wantErr  string
+	}{
+		{name: "exact id", provider: "openai", model: "gpt-4o-mini", want: "gpt-4o-mini"},
+		{name: "alias", provider: "bedrock", model: "claude-3-5-sonnet", want: "anthropic.claude-3-5-sonnet-20240620-v1:0"},
+		{name: "empty model uses provider default", provider: "openai", model: "", want: ""},
+		{name: "uncatalogued provider", provider: "local", model: "llama3", want: "llama3"},
+		{name: "deprecated", provider: "bedrock", model: "anthropic.claude-3-sonnet-20240229-v1:0", wantErr: "deprecated"},
+		{name: "unknown model", provider: "openai", model: "gpt-5-ultra", wantErr: "unknown model"},
+		{name: "wrong provider", provider: "openai", model: "claude-3-5-sonnet", wantErr: "unknown model"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := catalog.Validate(tt.provider, tt.model)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Validate(%q, %q) error = %v, want %q", tt.provider, tt.model, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Validate(%q, %q) returned error: %v", tt.provider, tt.model, err)
+			}
+			if got != tt.want {
+				t.Errorf("Validate(%q, %q) = %q, want %q", tt.provider, tt.model, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestModelCatalogPrepare ทดสอบการปรับ request ตามข้อจำกัดของ model
+func TestModelCatalogPrepare(t *testing.T) {
+	catalog := newTestCatalog()
+
+	// Images need a vision model
+	image := services.ConversationMessage{
+		Role:  services.ConversationRoleUser,
+		Parts: []services.ContentPart{{Type: services.ContentPartImage, MimeType: "image/png", Data: []byte("png")}},
+	}
+	_, err := catalog.Prepare("openai", services.StreamingChatRequest{Model: "gpt-3.5-turbo", Messages: []services.ConversationMessage{image}})
+	if err == nil || !strings.Contains(err.Error(), "does not support images") {
+		t.Errorf("Prepare with image on text model error = %v", err)
+	}
+
+	// MaxTokens is capped and aliases resolve
+	req, err := catalog.Prepare("bedrock", services.StreamingChatRequest{Model: "claude-3-5-sonnet", MaxTokens: 100000})
+	if err != nil {
+		t.Fatalf("Prepare returned error: %v", err)
+	}
+	if req.Model != "anthropic.claude-3-5-sonnet-20240620-v1:0" || req.MaxTokens != 8192 {
+		t.Errorf("Prepare = model %q max tokens %d", req.Model, req.MaxTokens)
+	}
+
+	// Oldest messages are dropped to fit the context window, the latest is kept
+	long := strings.Repeat("word ", 800) // ~1000 tokens, over the budget on its own
+	req, err = catalog.Prepare("openai", services.StreamingChatRequest{
+		Model:     "gpt-3.5-turbo",
+		MaxTokens: 200,
+		Messages: []services.ConversationMessage{
+			services.NewTextMessage(services.ConversationRoleUser, long),
+			services.NewTextMessage(services.ConversationRoleAssistant, long),
+			services.NewTextMessage(services.ConversationRoleUser, "latest question"),
+		},
+	})
+	if err != nil {
+		t.Fatalf("Prepare returned error: %v", err)
+	}
+	if len(req.Messages) != 1 || req.Messages[0].Text() != "latest question" {
+		t.Errorf("Prepare kept %d messages", len(req.Messages))
+	}
+}
+
+// TestModelCatalogList ทดสอบการแสดง catalog พร้อมราคาและสถานะ deprecated
+func TestModelCatalogList(t *testing.T) {
+	models := newTestCatalog().List("openai")
+	if len(models) != 2 {
+		t.Fatalf("List(openai) returned %d models, want 2", len(models))
+	}
+
+	for _, model := range models {
+		if model.ID == "gpt-4o-mini" && (model.Pricing == nil || model.Pricing.Input != 0.15) {
+			t.Errorf("gpt-4o-mini pricing = %+v", model.Pricing)
+		}
+	}
+
+	for _, model := range newTestCatalog().List("bedrock") {
+		if model.ID == "anthropic.claude-3-sonnet-20240229-v1:0" && !model.IsDeprecated {
+			t.Errorf("%s should be deprecated", model.ID)
+		}
+	}
+}
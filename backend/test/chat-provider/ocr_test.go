@@ -0,0 +1,273 @@
+package chat_provider_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"chatbot/config"
+	"chatbot/models"
+	"chatbot/services"
+)
+
+// fakeOCREngine คืนข้อความที่กำหนดไว้แทนการรัน tesseract จริง
+type fakeOCREngine struct {
+	pages     map[int]*services.OCRResult
+	requested []int
+}
+
+func (e *fakeOCREngine) RecognizeImage(ctx context.Context, data []byte) (*services.OCRResult, error) {
+	return &services.OCRResult{Text: "ใบเสร็จรับเงิน\nTotal 500", Confidence: 0.82}, nil
+}
+
+func (e *fakeOCREngine) RecognizePDFPages(ctx context.Context, data []byte, pages []int) (map[int]*services.OCRResult, error) {
+	e.requested = pages
+	return e.pages, nil
+}
+
+// useFakeOCR ใช้ engine ปลอมระหว่าง test แล้วปิด OCR เมื่อจบ
+func useFakeOCR(t *testing.T, engine services.OCREngine) {
+	t.Helper()
+	services.UseOCR(engine, &config.Config{OCRMinPageChars: 20, OCRMaxPages: 10})
+	t.Cleanup(func() { services.UseOCR(nil, &config.Config{}) })
+}
+
+// extractWithOCR แยกข้อความแบบเดียวกับ job เบื้องหลัง (อ่านด้วย OCR ได้)
+func extractWithOCR(t *testing.T, data []byte, mimeType, fileName string) *services.ExtractedText {
+	t.Helper()
+	result, err := services.ExtractTextWithOCR(context.Background(), data, mimeType, fileName)
+	if err != nil {
+		t.Fatalf("ExtractTextWithOCR(%s): %v", fileName, err)
+	}
+	if result == nil {
+		t.Fatalf("ExtractTextWithOCR(%s) = nil, want text", fileName)
+	}
+	return result
+}
+
+// minimalPDF สร้าง PDF ที่แต่ละหน้ามีข้อความตามที่กำหนด (ข้อความว่าง = หน้าสแกนที่ไม่มี text layer)
+func minimalPDF(pages []string) []byte {
+	objects := []string{"", ""} // 1: catalog, 2: pages (filled in below)
+	var kids []string
+	for _, text := range pages {
+		stream := ""
+		if text != "" {
+			stream = fmt.Sprintf("BT /F1 12 Tf 72 712 Td (%s) Tj ET", text)
+		}
+		objects = append(objects, fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(stream), stream))
+		content := len(objects)
+		objects = append(objects, fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents %d 0 R "+
+			"/Resources << /Font << /F1 << /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >> >> >> >>", content))
+		kids = append(kids, fmt.Sprintf("%d 0 R", len(objects)))
+	}
+	objects[0] = "<< /Type /Catalog /Pages 2 0 R >>"
+	objects[1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages))
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
+
+// ทดสอบว่าหน้า PDF ที่ไม่มีข้อความถูกอ่านด้วย OCR ส่วนหน้าที่มีข้อความใช้ text layer เดิม
+func TestExtractTextOCRScannedPages(t *testing.T) {
+	data := minimalPDF([]string{"This page has a text layer long enough", ""})
+
+	// ไม่มี OCR: หน้าสแกนว่างเปล่า
+	plain := extractWithOCR(t, data, "application/pdf", "contract.pdf")
+	if strings.Contains(plain.Text, "สัญญา") || len(plain.OCRPages) != 0 {
+		t.Fatalf("text without OCR = %+v", plain)
+	}
+
+	engine := &fakeOCREngine{pages: map[int]*services.OCRResult{
+		2: {Text: "สัญญาเช่าอาคาร ฉบับลงนามแล้ว", Confidence: 0.91},
+	}}
+	useFakeOCR(t, engine)
+
+	// ExtractText (ใช้ระหว่าง request) ไม่อ่านด้วย OCR แม้เปิด OCR อยู่
+	if fast := extract(t, data, "application/pdf", "contract.pdf"); engine.requested != nil || len(fast.OCRPages) != 0 {
+		t.Errorf("ExtractText ran OCR on pages %v", engine.requested)
+	}
+
+	result := extractWithOCR(t, data, "application/pdf", "contract.pdf")
+	if len(engine.requested) != 1 || engine.requested[0] != 2 {
+		t.Errorf("OCR pages = %v, want only the scanned page 2", engine.requested)
+	}
+	assertContains(t, result.Text, "--- Page 1 ---\nThis page has a text layer long enough", "--- Page 2 ---\nสัญญาเช่าอาคาร ฉบับลงนามแล้ว")
+	if want := []models.OCRPage{{Page: 2, Confidence: 0.91}}; len(result.OCRPages) != 1 || result.OCRPages[0] != want[0] {
+		t.Errorf("OCRPages = %+v, want %+v", result.OCRPages, want)
+	}
+	if result.PageCount != 2 || result.Language != "th" {
+		t.Errorf("PageCount = %d, Language = %q, want 2 pages in th", result.PageCount, result.Language)
+	}
+}
+
+// ทดสอบว่ารูปภาพถูกอ่านด้วย OCR เมื่อเปิดใช้ และข้ามเมื่อไม่มี OCR
+func TestExtractTextOCRImage(t *testing.T) {
+	if result, err := services.ExtractTextWithOCR(context.Background(), []byte("png"), "image/png", "scan.png"); err != nil || result != nil {
+		t.Fatalf("image without OCR = %+v, %v, want skipped", result, err)
+	}
+
+	useFakeOCR(t, &fakeOCREngine{})
+	if result, err := services.ExtractText([]byte("png"), "image/png", "scan.png"); err != nil || result != nil {
+		t.Errorf("ExtractText(image) = %+v, %v, want skipped without OCR", result, err)
+	}
+	result := extractWithOCR(t, []byte("png"), "application/octet-stream", "scan.png")
+	if result.Text != "ใบเสร็จรับเงิน\nTotal 500" || len(result.OCRPages) != 1 || result.OCRPages[0].Confidence != 0.82 {
+		t.Errorf("image OCR = %+v", result)
+	}
+}
+
+// blankOCREngine จำลองภาพที่ OCR อ่านไม่ได้ข้อความ
+type blankOCREngine struct {
+	images int
+}
+
+func (e *blankOCREngine) RecognizeImage(ctx context.Context, data []byte) (*services.OCRResult, error) {
+	e.images++
+	return &services.OCRResult{}, nil
+}
+
+func (e *blankOCREngine) RecognizePDFPages(ctx context.Context, data []byte, pages []int) (map[int]*services.OCRResult, error) {
+	return nil, nil
+}
+
+// uploadedFile สร้าง multipart.FileHeader แบบเดียวกับไฟล์ที่ client ส่งมาใน form
+func uploadedFile(t *testing.T, fileName, mimeType string, data []byte) *multipart.FileHeader {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename="%s"`, fileName))
+	header.Set("Content-Type", mimeType)
+	part, err := writer.CreatePart(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(data)
+	writer.Close()
+
+	form, err := multipart.NewReader(&body, writer.Boundary()).ReadForm(1 << 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { form.RemoveAll() })
+	return form.File["file"][0]
+}
+
+// ทดสอบว่าไฟล์ที่ส่งมาวิเคราะห์ตรงๆ ถูกอ่านด้วย OCR และได้ error เมื่อไม่มีข้อความ แทนการวิเคราะห์เอกสารว่าง
+func TestAnalyzeFileDirectUploadUsesOCR(t *testing.T) {
+	engine := &blankOCREngine{}
+	useFakeOCR(t, engine)
+
+	fileService := services.NewFileService(services.NewOpenAIService(testConfig()), nil, nil, nil, nil, nil)
+	_, err := fileService.AnalyzeFile(context.Background(), services.FileAnalysisRequest{
+		File:         uploadedFile(t, "scan.png", "image/png", []byte("png")),
+		AnalysisType: "summary",
+	})
+	if err == nil || !strings.Contains(err.Error(), "no text could be extracted") {
+		t.Errorf("AnalyzeFile error = %v, want no text could be extracted", err)
+	}
+	if engine.images != 1 {
+		t.Errorf("OCR read %d images, want 1", engine.images)
+	}
+}
+
+// writeScript เขียน shell script ที่ใช้แทน binary จริง
+func writeScript(t *testing.T, dir, name, body string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// ทดสอบ TesseractService กับ tesseract และ pdftoppm ปลอม: arguments, ข้อความ และความมั่นใจจาก TSV
+func TestTesseractService(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses shell scripts")
+	}
+	dir := t.TempDir()
+	tsv := "level\tpage_num\tblock_num\tpar_num\tline_num\tword_num\tleft\ttop\twidth\theight\tconf\ttext\n" +
+		"4\t1\t1\t1\t1\t0\t0\t0\t10\t10\t-1\t\n" +
+		"5\t1\t1\t1\t1\t1\t0\t0\t10\t10\t96.5\tสัญญา\n" +
+		"5\t1\t1\t1\t1\t2\t0\t0\t10\t10\t81.5\tเช่า\n" +
+		"5\t1\t1\t1\t1\t3\t0\t0\t10\t10\t95\t \n"
+	if err := os.WriteFile(filepath.Join(dir, "page.tsv"), []byte(tsv), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tesseract := writeScript(t, dir, "tesseract", `
+if [ "$1" = "--list-langs" ]; then printf 'List of available languages in "/tessdata/" (3):\neng\nosd\ntha\n' >&2; exit 0; fi
+echo "$@" >> "`+dir+`/calls"
+printf 'สัญญาเช่า\n\f' > "$2.txt"
+cp "`+dir+`/page.tsv" "$2.tsv"
+`)
+	pdftoppm := writeScript(t, dir, "pdftoppm", `
+for last in "$@"; do :; done
+echo "pdftoppm $@" >> "`+dir+`/calls"
+printf 'png' > "$last.png"
+`)
+
+	cfg := &config.Config{
+		OCRBinaryPath:   tesseract,
+		OCRRendererPath: pdftoppm,
+		OCRLanguages:    "tha+eng",
+		OCRTempDir:      filepath.Join(dir, "temp"),
+		OCRDPI:          300,
+	}
+	ocr, err := services.NewTesseractService(cfg)
+	if err != nil {
+		t.Fatalf("NewTesseractService: %v", err)
+	}
+
+	image, err := ocr.RecognizeImage(context.Background(), []byte("png"))
+	if err != nil {
+		t.Fatalf("RecognizeImage: %v", err)
+	}
+	if image.Text != "สัญญาเช่า" || image.Confidence != 0.89 {
+		t.Errorf("RecognizeImage = %+v, want text with confidence 0.89", image)
+	}
+
+	pages, err := ocr.RecognizePDFPages(context.Background(), []byte("%PDF"), []int{3})
+	if err != nil {
+		t.Fatalf("RecognizePDFPages: %v", err)
+	}
+	if pages[3] == nil || pages[3].Text != "สัญญาเช่า" {
+		t.Errorf("RecognizePDFPages = %+v, want page 3", pages)
+	}
+
+	calls, _ := os.ReadFile(filepath.Join(dir, "calls"))
+	assertContains(t, string(calls), "-l tha+eng txt tsv", "pdftoppm -f 3 -l 3 -r 300 -gray -png -singlefile", "-l tha+eng --dpi 300 txt tsv")
+
+	// context ที่ถูกยกเลิกหยุด OCR
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := ocr.RecognizePDFPages(cancelled, []byte("%PDF"), []int{1}); !errors.Is(err, context.Canceled) {
+		t.Errorf("RecognizePDFPages(cancelled) error = %v, want context.Canceled", err)
+	}
+
+	// ภาษาที่ไม่มี traineddata ใช้งานไม่ได้
+	cfg.OCRLanguages = "tha+jpn"
+	if _, err := services.NewTesseractService(cfg); err == nil || !strings.Contains(err.Error(), "jpn") {
+		t.Errorf("NewTesseractService(jpn) error = %v, want missing language", err)
+	}
+}
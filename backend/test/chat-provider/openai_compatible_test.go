@@ -0,0 +1,154 @@
+package chat_provider_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"chatbot/config"
+	"chatbot/services"
+)
+
+// newFakeOpenAIServer จำลอง OpenAI-compatible /chat/completions แบบ SSE
+func newFakeOpenAIServer(t *testing.T, chunks []string) (*httptest.Server, *http.Header, *map[string]interface{}) {
+	t.Helper()
+	headers := http.Header{}
+	received := map[string]interface{}{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			http.NotFound(w, r)
+			return
+		}
+		headers = r.Header.Clone()
+		json.NewDecoder(r.Body).Decode(&received)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, chunk := range chunks {
+			data, _ := json.Marshal(map[string]interface{}{
+				"id":      "chatcmpl-test",
+				"object":  "chat.completion.chunk",
+				"model":   received["model"],
+				"choices": []map[string]interface{}{{"index": 0, "delta": map[string]string{"content": chunk}}},
+			})
+			fmt.Fprintf(w, "data: %s\n\n", data)
+		}
+		// chunk สุดท้ายมีแต่ usage (stream_options.include_usage)
+		fmt.Fprint(w, `data: {"id":"chatcmpl-test","object":"chat.completion.chunk","choices":[],"usage":{"prompt_tokens":20,"completion_tokens":3,"total_tokens":23,"prompt_tokens_details":{"cached_tokens":8}}}`+"\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &headers, &received
+}
+
+// TestOpenAICompatibleProvider ทดสอบ provider ที่ตั้งชื่อเองพร้อม base URL, key และ model ที่อนุญาต
+func TestOpenAICompatibleProvider(t *testing.T) {
+	server, headers, received := newFakeOpenAIServer(t, []string{"Hello", " from", " groq"})
+
+	cfg := testConfig()
+	cfg.OpenAICompatibleProviders = []config.OpenAICompatibleProvider{{
+		Name:          "groq",
+		BaseURL:       server.URL + "/v1",
+		APIKey:        "gsk-test",
+		DefaultModel:  "llama-3.1-8b-instant",
+		AllowedModels: []string{"llama-3.1-8b-instant", "llama-3.3-70b-versatile"},
+	}}
+
+	registry := services.NewProviderRegistryFromConfig(cfg)
+	provider, err := registry.Get("groq")
+	if err != nil {
+		t.Fatalf("Get(groq) returned error: %v", err)
+	}
+	if provider.GetProviderName() != "groq" {
+		t.Errorf("GetProviderName() = %s, want groq", provider.GetProviderName())
+	}
+
+	stream, err := provider.CreateStreamingChat(context.Background(), services.StreamingChatRequest{
+		Messages: []services.ConversationMessage{services.NewTextMessage(services.ConversationRoleUser, "hi")},
+	})
+	if err != nil {
+		t.Fatalf("CreateStreamingChat returned error: %v", err)
+	}
+	defer stream.Close()
+
+	if content := readAll(t, stream); content != "Hello from groq" {
+		t.Errorf("content = %q, want Hello from groq", content)
+	}
+	if got := headers.Get("Authorization"); got != "Bearer gsk-test" {
+		t.Errorf("Authorization = %q, want Bearer gsk-test", got)
+	}
+	if (*received)["model"] != "llama-3.1-8b-instant" {
+		t.Errorf("model = %v, want default model", (*received)["model"])
+	}
+
+	// usage จริงจาก provider ต้องถูกส่งต่อผ่าน StreamReader
+	if options, _ := (*received)["stream_options"].(map[string]interface{}); options["include_usage"] != true {
+		t.Errorf("stream_options = %v, want include_usage", (*received)["stream_options"])
+	}
+	usage := services.StreamUsage(stream)
+	if usage == nil || usage.PromptTokens != 20 || usage.CompletionTokens != 3 || usage.CachedTokens != 8 {
+		t.Errorf("StreamUsage() = %+v, want 20/3/8", usage)
+	}
+
+	// model ที่ไม่อยู่ในรายการต้องถูกปฏิเสธ
+	if _, err := provider.CreateStreamingChat(context.Background(), services.StreamingChatRequest{Model: "gpt-4o"}); err == nil {
+		t.Error("CreateStreamingChat should reject a model outside the allowed list")
+	}
+
+	defaultModel, allowed, ok := registry.Models("groq")
+	if !ok || defaultModel != "llama-3.1-8b-instant" || len(allowed) != 2 {
+		t.Errorf("Models(groq) = %q, %v, %v", defaultModel, allowed, ok)
+	}
+}
+
+// TestOpenAICompatibleProviderNameConflict ทดสอบว่าชื่อซ้ำกับ provider ในตัวจะถูกข้าม
+func TestOpenAICompatibleProviderNameConflict(t *testing.T) {
+	cfg := testConfig()
+	cfg.OpenAICompatibleProviders = []config.OpenAICompatibleProvider{{
+		Name:    "bedrock",
+		BaseURL: "http://localhost:1/v1",
+	}}
+
+	registry := services.NewProviderRegistryFromConfig(cfg)
+	defaultModel, _, ok := registry.Models("bedrock")
+	if !ok || defaultModel != cfg.BedrockModelID {
+		t.Errorf("Models(bedrock) = %q, %v; built-in bedrock provider must not be replaced", defaultModel, ok)
+	}
+}
+
+// TestOpenAICompatibleLocalProvider ทดสอบว่า provider ที่ตั้ง Local (เช่น vLLM on-prem) ไม่ถูกสลับไป provider บน cloud
+func TestOpenAICompatibleLocalProvider(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		http.Error(w, `{"error":{"message":"model loading"}}`, http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := testConfig()
+	cfg.OpenAICompatibleProviders = []config.OpenAICompatibleProvider{{
+		Name:         "vllm",
+		BaseURL:      server.URL + "/v1",
+		DefaultModel: "llama-3.1-70b",
+		Local:        true,
+	}}
+
+	registry := services.NewProviderRegistryFromConfig(cfg)
+	if capabilities, ok := registry.Capabilities("vllm"); !ok || !capabilities.Local {
+		t.Fatalf("Capabilities(vllm) = %+v, %v; want Local", capabilities, ok)
+	}
+	cloud := &fakeProvider{name: "cloud", available: true, chunks: []string{"ok"}}
+	registry.Register("cloud", 200, services.ProviderCapabilities{Streaming: true}, cloud)
+
+	streamer := services.NewFailoverStreamer(registry, testPolicy("cloud"))
+	if _, _, err := streamer.CreateStreamingChat(context.Background(), "vllm", services.StreamingChatRequest{}); err == nil {
+		t.Fatal("CreateStreamingChat should fail instead of leaving the local provider")
+	}
+	if cloud.calls != 0 || requests == 0 {
+		t.Errorf("vllm requests = %d, cloud calls = %d; want > 0 and 0", requests, cloud.calls)
+	}
+}
@@ -0,0 +1,139 @@
+package chat_provider_test
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"chatbot/config"
+	"chatbot/controllers"
+	"chatbot/models"
+	"chatbot/repositories"
+	"chatbot/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sashabaranov/go-openai"
+)
+
+// TestConversationFromOpenAI ทดสอบการแปลง messages รูปแบบ OpenAI เป็น conversation กลาง
+func TestConversationFromOpenAI(t *testing.T) {
+	var messages []openai.ChatCompletionMessage
+	err := json.Unmarshal([]byte(`[
+		{"role": "system", "content": "Be brief."},
+		{"role": "developer", "content": "Answer in Thai."},
+		{"role": "user", "content": "What is 6*7?"},
+		{"role": "assistant", "content": "", "tool_calls": [{"id": "call_1", "type": "function", "function": {"name": "calculate", "arguments": "{\"expression\":\"6*7\"}"}}]},
+		{"role": "tool", "tool_call_id": "call_1", "content": "42"},
+		{"role": "assistant", "content": "42"},
+		{"role": "user", "content": [
+			{"type": "text", "text": "And this?"},
+			{"type": "image_url", "image_url": {"url": "data:image/png;base64,aGVsbG8="}}
+		]}
+	]`), &messages)
+	if err != nil {
+		t.Fatalf("Unmarshal returned error: %v", err)
+	}
+
+	conversation, err := services.ConversationFromOpenAI(messages)
+	if err != nil {
+		t.Fatalf("ConversationFromOpenAI returned error: %v", err)
+	}
+
+	if conversation.SystemPrompt != "Be brief.\n\nAnswer in Thai." {
+		t.Errorf("system prompt = %q", conversation.SystemPrompt)
+	}
+	if len(conversation.Messages) != 5 {
+		t.Fatalf("messages = %+v, want 5", conversation.Messages)
+	}
+	if call := conversation.Messages[1].ToolCalls; len(call) != 1 || call[0].Name != "calculate" {
+		t.Errorf("assistant tool calls = %+v", call)
+	}
+	if conversation.Messages[2].Role != services.ConversationRoleTool || conversation.Messages[2].ToolCallID != "call_1" {
+		t.Errorf("tool message = %+v", conversation.Messages[2])
+	}
+
+	last := conversation.Messages[4]
+	images := last.PartsOfType(services.ContentPartImage)
+	if last.Text() != "And this?" || len(images) != 1 || images[0].MimeType != "image/png" || string(images[0].Data) != "hello" {
+		t.Errorf("last message = %+v", last)
+	}
+}
+
+// TestConversationFromOpenAIRejects ทดสอบ input ที่ไม่รองรับ
+func TestConversationFromOpenAIRejects(t *testing.T) {
+	tests := map[string]string{
+		`[{"role":"user","content":[{"type":"image_url","image_url":{"url":"https://example.com/a.png"}}]}]`: "data URLs",
+		`[{"role":"function","content":"x"}]`: "unsupported role",
+	}
+	for input, want := range tests {
+		var messages []openai.ChatCompletionMessage
+		if err := json.Unmarshal([]byte(input), &messages); err != nil {
+			t.Fatalf("Unmarshal(%s) returned error: %v", input, err)
+		}
+		if _, err := services.ConversationFromOpenAI(messages); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("ConversationFromOpenAI(%s) = %v, want error containing %q", input, err, want)
+		}
+	}
+}
+
+// TestGuardrailInstructions ทดสอบการแปลง guardrails ของ persona เป็นกฎใน system prompt
+func TestGuardrailInstructions(t *testing.T) {
+	if got := services.GuardrailInstructions(models.Guardrails{}); got != "" {
+		t.Errorf("empty guardrails = %q, want empty", got)
+	}
+
+	persona := models.Persona{Guardrails: `{"block_profanity":true,"allowed_topics":["marketing"],"blocked_topics":["politics","religion"],"max_response_length":3000}`}
+	got := services.GuardrailInstructions(persona.GetGuardrails())
+	for _, want := range []string{"--- Guardrails ---", "Only discuss these topics: marketing", "politics, religion", "profanity", "under 3000 characters"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("instructions = %q, want %q", got, want)
+		}
+	}
+}
+
+// ทดสอบว่า model ที่ไม่ระบุ provider (เช่น "gpt-4o-mini") ถูกส่งไป provider ที่ catalog ระบุ แทนการตอบ 404
+func TestOpenAICompatCatalogModel(t *testing.T) {
+	openaiFake := &fakeProvider{name: "openai", available: true, chunks: []string{"สวัสดี"}}
+	db := offlineDB(t)
+	registry := services.NewProviderRegistry()
+	registry.Register("openai", 10, services.ProviderCapabilities{Streaming: true}, openaiFake)
+	pricing := services.NewPricingService(config.PricingTable{})
+	catalog := services.NewModelCatalog([]config.CatalogModel{
+		{Provider: "openai", ID: "gpt-4o-mini", ContextWindow: 128000, MaxOutputTokens: 16384},
+	}, pricing, registry)
+
+	messageRepo := repositories.NewMessageRepository(db)
+	fileRepo := repositories.NewFileAnalysisRepository(db)
+	facade := controllers.NewOpenAICompatController(
+		messageRepo,
+		repositories.NewPersonaRepository(db),
+		services.NewContextService(messageRepo, fileRepo, services.NewFileExtractionService(fileRepo)),
+		registry,
+		services.NewFailoverStreamer(registry, testPolicy()),
+		services.NewUsageService(pricing, repositories.NewUsageRepository(db)),
+		services.NewToolOrchestrator(services.NewToolRegistry(), 3),
+		catalog,
+		nil,
+		nil,
+	)
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Post("/v1/chat/completions", facade.ChatCompletions)
+
+	for model, want := range map[string]int{"gpt-4o-mini": 200, "gpt-9": 404} {
+		body := `{"model":"` + model + `","messages":[{"role":"user","content":"สวัสดี"}]}`
+		req := httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("app.Test(%s): %v", model, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("model %s: status = %d, want %d", model, resp.StatusCode, want)
+		}
+	}
+	if openaiFake.calls != 1 || openaiFake.lastModel != "gpt-4o-mini" {
+		t.Errorf("openai calls = %d, model = %q; want 1 call with gpt-4o-mini", openaiFake.calls, openaiFake.lastModel)
+	}
+}
@@ -0,0 +1,135 @@
+package chat_provider_test
+
+import (
+	"math"
+	"testing"
+
+	"chatbot/config"
+	"chatbot/models"
+	"chatbot/services"
+)
+
+// testPricing ราคาที่ใช้ในเทสต์ (USD ต่อ 1M tokens / ต่อตัวอักษร / ต่อนาที)
+func testPricing() config.PricingTable {
+	return config.PricingTable{
+		Models: map[string]config.ModelPrice{
+			"gpt-4o":          {Input: 2.50, Output: 10.00, Cached: 1.25},
+			"gpt-4o-mini":     {Input: 0.15, Output: 0.60},
+			"claude-sonnet-4": {Input: 3.00, Output: 15.00, Cached: 0.30},
+			"local":           {},
+		},
+		TTS: map[string]float64{"tts-1": 0.000015},
+		STT: map[string]float64{"whisper-1": 0.006, "whispercpp": 0},
+	}
+}
+
+func assertCost(t *testing.T, got, want float64) {
+	t.Helper()
+	if math.Abs(got-want) > 1e-9 {
+		t.Errorf("cost = %v, want %v", got, want)
+	}
+}
+
+// ทดสอบคิดราคา chat: prompt ที่ cache แล้วคิดราคา cached, ที่เหลือคิดราคา input
+func TestPricingChatCostWithCachedTokens(t *testing.T) {
+	pricing := services.NewPricingService(testPricing())
+
+	cost, ok := pricing.ChatCost("gpt-4o", &services.TokenUsage{
+		PromptTokens:     1_000_000,
+		CompletionTokens: 100_000,
+		CachedTokens:     400_000,
+	})
+	if !ok {
+		t.Fatal("expected gpt-4o to have a price")
+	}
+	// 600k * 2.50 + 400k * 1.25 + 100k * 10.00 (ต่อ 1M)
+	assertCost(t, cost, 1.5+0.5+1.0)
+}
+
+// ทดสอบว่าไม่มีราคา cached จะใช้ราคา input แทน
+func TestPricingCachedFallsBackToInput(t *testing.T) {
+	pricing := services.NewPricingService(testPricing())
+
+	cost, _ := pricing.ChatCost("gpt-4o-mini", &services.TokenUsage{PromptTokens: 1_000_000, CachedTokens: 1_000_000})
+	assertCost(t, cost, 0.15)
+}
+
+// ทดสอบการจับคู่ชื่อ model: prefix ที่ยาวที่สุด และ Bedrock ID ที่มี region/vendor prefix
+func TestPricingModelMatching(t *testing.T) {
+	pricing := services.NewPricingService(testPricing())
+	usage := &services.TokenUsage{PromptTokens: 1_000_000}
+
+	tests := []struct {
+		model string
+		want  float64
+	}{
+		{"gpt-4o-mini-2024-07-18", 0.15}, // ต้องได้ gpt-4o-mini ไม่ใช่ gpt-4o
+		{"gpt-4o-2024-08-06", 2.50},
+		{"apac.anthropic.claude-sonnet-4-20250514-v1:0", 3.00},
+		{"claude-sonnet-4-20250514", 3.00},
+	}
+	for _, tt := range tests {
+		cost, ok := pricing.ChatCost(tt.model, usage)
+		if !ok {
+			t.Errorf("%s: expected a price", tt.model)
+			continue
+		}
+		assertCost(t, cost, tt.want)
+	}
+
+	if _, ok := pricing.ChatCost("unknown-model", usage); ok {
+		t.Error("unknown model should have no price")
+	}
+	if _, ok := pricing.ChatCost("gpt-4o", nil); ok {
+		t.Error("nil usage should have no price")
+	}
+}
+
+// ทดสอบคิดราคา TTS ต่อตัวอักษร และ STT ต่อนาที
+func TestPricingTTSAndSTT(t *testing.T) {
+	pricing := services.NewPricingService(testPricing())
+
+	cost, ok := pricing.TTSCost("tts-1", 1000)
+	if !ok {
+		t.Fatal("expected tts-1 to have a price")
+	}
+	assertCost(t, cost, 0.015)
+
+	cost, ok = pricing.STTCost("whisper-1", 90)
+	if !ok {
+		t.Fatal("expected whisper-1 to have a price")
+	}
+	assertCost(t, cost, 0.009)
+}
+
+// ทดสอบ UsageService: ตั้ง provider/model/cost ให้ message และใช้ราคาของ provider เมื่อ model ไม่มีราคา
+func TestUsageServicePriceMessage(t *testing.T) {
+	usage := services.NewUsageService(services.NewPricingService(testPricing()), nil)
+	tokens := &services.TokenUsage{PromptTokens: 1_000_000, CompletionTokens: 1_000_000}
+
+	message := &models.Message{}
+	usage.PriceMessage(message, "openai", "gpt-4o-mini", tokens)
+	if message.Provider != "openai" || message.Model != "gpt-4o-mini" {
+		t.Errorf("provider/model = %s/%s", message.Provider, message.Model)
+	}
+	if message.Cost == nil {
+		t.Fatal("expected cost to be set")
+	}
+	assertCost(t, *message.Cost, 0.75)
+
+	local := &models.Message{}
+	usage.PriceMessage(local, "local", "llama3.1", tokens)
+	if local.Cost == nil || *local.Cost != 0 {
+		t.Errorf("local model should fall back to the local provider price (0), got %v", local.Cost)
+	}
+
+	unknown := &models.Message{}
+	usage.PriceMessage(unknown, "groq", "mixtral", tokens)
+	if unknown.Cost != nil {
+		t.Errorf("unknown model should have no cost, got %v", *unknown.Cost)
+	}
+
+	if cost := usage.RecordSTT("s1", nil, "whispercpp", "small", 30); cost == nil || *cost != 0 {
+		t.Errorf("whispercpp should fall back to the provider price (0), got %v", cost)
+	}
+}
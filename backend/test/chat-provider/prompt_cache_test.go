@@ -0,0 +1,107 @@
+package chat_provider_test
+
+import (
+	"strings"
+	"testing"
+
+	"chatbot/config"
+	"chatbot/models"
+	"chatbot/services"
+)
+
+// ทดสอบว่า HoistFileContext ดึงไฟล์ออกจากข้อความ แต่เก็บข้อความและรูปไว้ที่เดิม
+func TestHoistFileContext(t *testing.T) {
+	current := services.NewTextMessage(services.ConversationRoleUser, "สรุปสัญญานี้ให้หน่อย")
+	current.Parts = append(current.Parts,
+		services.ContentPart{Type: services.ContentPartFile, Text: "ข้อ 1 ผู้เช่าต้องชำระค่าเช่า", FileName: "contract.pdf", MimeType: "application/pdf"},
+		services.ContentPart{Type: services.ContentPartImage, MimeType: "image/png", Data: []byte{1}},
+	)
+	messages := []services.ConversationMessage{
+		services.NewTextMessage(services.ConversationRoleUser, "สวัสดี"),
+		services.NewTextMessage(services.ConversationRoleAssistant, "สวัสดีครับ"),
+		current,
+	}
+
+	fileContext, hoisted := services.HoistFileContext(messages)
+
+	if !strings.Contains(fileContext, "contract.pdf") || !strings.Contains(fileContext, "ผู้เช่าต้องชำระค่าเช่า") {
+		t.Errorf("file context missing the file: %q", fileContext)
+	}
+	if len(hoisted) != 3 {
+		t.Fatalf("got %d messages, want 3", len(hoisted))
+	}
+	last := hoisted[2]
+	if len(last.PartsOfType(services.ContentPartFile)) != 0 {
+		t.Error("file part should be removed from the user message")
+	}
+	if last.Text() != "สรุปสัญญานี้ให้หน่อย" || len(last.PartsOfType(services.ContentPartImage)) != 1 {
+		t.Errorf("text and image should stay: %+v", last.Parts)
+	}
+	if len(messages[2].PartsOfType(services.ContentPartFile)) != 1 {
+		t.Error("input messages should not be modified")
+	}
+}
+
+// ทดสอบว่าไม่มีไฟล์ก็ไม่มี file context
+func TestHoistFileContextWithoutFiles(t *testing.T) {
+	fileContext, hoisted := services.HoistFileContext([]services.ConversationMessage{
+		services.NewTextMessage(services.ConversationRoleUser, "hello"),
+	})
+	if fileContext != "" || len(hoisted) != 1 {
+		t.Errorf("got context %q and %d messages", fileContext, len(hoisted))
+	}
+}
+
+// ทดสอบ metadata ของ prompt cache ที่บันทึกใน message
+func TestTokenUsageCacheMetadata(t *testing.T) {
+	if meta := (&services.TokenUsage{PromptTokens: 100}).CacheMetadata(); meta != nil {
+		t.Errorf("no cache activity should give nil, got %v", meta)
+	}
+	var none *services.TokenUsage
+	if meta := none.CacheMetadata(); meta != nil {
+		t.Errorf("nil usage should give nil, got %v", meta)
+	}
+
+	write := (&services.TokenUsage{PromptTokens: 5000, CacheWriteTokens: 4800}).CacheMetadata()
+	if write["cache_hit"] != false || write["cache_write_tokens"] != 4800 {
+		t.Errorf("cache write metadata = %v", write)
+	}
+
+	hit := (&services.TokenUsage{PromptTokens: 5000, CachedTokens: 4800}).CacheMetadata()
+	if hit["cache_hit"] != true || hit["cached_tokens"] != 4800 {
+		t.Errorf("cache hit metadata = %v", hit)
+	}
+}
+
+// ทดสอบคิดราคา cache write ของ Claude (แพงกว่า input) แยกจาก cache read
+func TestPricingChatCostWithCacheWrites(t *testing.T) {
+	table := testPricing()
+	table.Models["claude-sonnet-4"] = config.ModelPrice{Input: 3.00, Output: 15.00, Cached: 0.30, CacheWrite: 3.75}
+	pricing := services.NewPricingService(table)
+
+	cost, ok := pricing.ChatCost("claude-sonnet-4", &services.TokenUsage{
+		PromptTokens:     1_000_000,
+		CompletionTokens: 100_000,
+		CachedTokens:     200_000,
+		CacheWriteTokens: 400_000,
+	})
+	if !ok {
+		t.Fatal("expected claude-sonnet-4 to have a price")
+	}
+	// 400k * 3.00 + 200k * 0.30 + 400k * 3.75 + 100k * 15.00 (ต่อ 1M)
+	assertCost(t, cost, 1.2+0.06+1.5+1.5)
+}
+
+// ทดสอบว่า persona เปิด prompt caching แล้วส่งต่อไปถึง request
+func TestResolveGenerationPromptCaching(t *testing.T) {
+	catalog := newTestCatalog()
+	persona := &models.Persona{Model: "claude-3-5-sonnet", PromptCaching: true}
+
+	settings, err := catalog.ResolveGeneration(services.GenerationOverrides{}, persona, "openai")
+	if err != nil {
+		t.Fatalf("ResolveGeneration returned error: %v", err)
+	}
+	if req := settings.Apply(services.StreamingChatRequest{}); !req.PromptCaching {
+		t.Error("persona prompt_caching should reach the chat request")
+	}
+}
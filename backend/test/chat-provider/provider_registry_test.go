@@ -0,0 +1,150 @@
+package chat_provider_test
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"chatbot/services"
+)
+
+// fakeProvider เป็น StreamingChatService จำลองสำหรับทดสอบ registry
+type fakeProvider struct {
+	name      string
+	available bool
+	chunks    []string
+	err       error  // error ที่จะคืนจาก CreateStreamingChat
+	calls     int    // จำนวนครั้งที่ถูกเรียก
+	lastModel string // model ของ request ล่าสุด
+}
+
+func (p *fakeProvider) CreateStreamingChat(ctx context.Context, req services.StreamingChatRequest) (services.StreamReader, error) {
+	p.calls++
+	p.lastModel = req.Model
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &fakeStream{chunks: p.chunks}, nil
+}
+
+func (p *fakeProvider) GetProviderName() string { return p.name }
+
+func (p *fakeProvider) IsAvailable() bool { return p.available }
+
+// fakeStream ส่ง chunks ตามลำดับแล้วจบด้วย io.EOF
+type fakeStream struct {
+	chunks []string
+	index  int
+}
+
+func (s *fakeStream) Recv() (string, error) {
+	if s.index >= len(s.chunks) {
+		return "", io.EOF
+	}
+	chunk := s.chunks[s.index]
+	s.index++
+	return chunk, nil
+}
+
+func (s *fakeStream) Close() error { return nil }
+
+// Usage รายงาน completion tokens เท่ากับจำนวน chunk ที่ไม่ว่าง
+func (s *fakeStream) Usage() *services.TokenUsage {
+	completion := 0
+	for _, chunk := range s.chunks {
+		if chunk != "" {
+			completion++
+		}
+	}
+	return &services.TokenUsage{PromptTokens: 10, CompletionTokens: completion}
+}
+
+// TestProviderRegistryResolve ทดสอบการเลือก provider ตามชื่อและตาม priority
+func TestProviderRegistryResolve(t *testing.T) {
+	registry := services.NewProviderRegistry()
+	registry.Register("second", 20, services.ProviderCapabilities{Streaming: true}, &fakeProvider{name: "second", available: true})
+	registry.Register("first", 10, services.ProviderCapabilities{Streaming: true}, &fakeProvider{name: "first", available: false})
+	registry.Register("third", 30, services.ProviderCapabilities{Vision: true}, &fakeProvider{name: "third", available: true})
+
+	// Auto-detect ต้องข้าม provider ที่ไม่พร้อมใช้งาน
+	provider, err := registry.Resolve("")
+	if err != nil {
+		t.Fatalf("Resolve(\"\") returned error: %v", err)
+	}
+	if provider.GetProviderName() != "second" {
+		t.Errorf("Resolve(\"\") = %s, want second", provider.GetProviderName())
+	}
+
+	// ระบุชื่อตรงๆ (ไม่สนตัวพิมพ์เล็กใหญ่)
+	provider, err = registry.Resolve("THIRD")
+	if err != nil {
+		t.Fatalf("Resolve(THIRD) returned error: %v", err)
+	}
+	if provider.GetProviderName() != "third" {
+		t.Errorf("Resolve(THIRD) = %s, want third", provider.GetProviderName())
+	}
+
+	// Provider ที่ลงทะเบียนแต่ไม่พร้อมใช้งาน
+	if _, err := registry.Get("first"); err == nil {
+		t.Error("Get(first) should fail for unavailable provider")
+	}
+
+	// Provider ที่ไม่มีอยู่
+	if _, err := registry.Get("unknown"); err == nil {
+		t.Error("Get(unknown) should fail for unregistered provider")
+	}
+
+	names := registry.Names()
+	expected := []string{"first", "second", "third"}
+	for i, name := range expected {
+		if names[i] != name {
+			t.Errorf("Names()[%d] = %s, want %s", i, names[i], name)
+		}
+	}
+
+	caps, ok := registry.Capabilities("third")
+	if !ok || !caps.Vision {
+		t.Errorf("Capabilities(third) = %+v, %v; want vision", caps, ok)
+	}
+}
+
+// TestProviderRegistryNoneAvailable ทดสอบกรณีไม่มี provider ใดพร้อมใช้งาน
+func TestProviderRegistryNoneAvailable(t *testing.T) {
+	registry := services.NewProviderRegistry()
+	registry.Register("offline", 10, services.ProviderCapabilities{}, &fakeProvider{name: "offline"})
+
+	if _, err := registry.Resolve(""); err == nil {
+		t.Error("Resolve(\"\") should fail when no provider is available")
+	}
+}
+
+// TestCompleteChatDrainsStream ทดสอบ CompleteChat กับ provider ที่ไม่มี non-streaming API
+func TestCompleteChatDrainsStream(t *testing.T) {
+	provider := &fakeProvider{name: "fake", available: true, chunks: []string{"สวัสดี", "ครับ", "!"}}
+
+	completion, err := services.CompleteChat(context.Background(), provider, services.StreamingChatRequest{Model: "fake-model"})
+	if err != nil {
+		t.Fatalf("CompleteChat returned error: %v", err)
+	}
+
+	if completion.Content != "สวัสดีครับ!" {
+		t.Errorf("Content = %q, want %q", completion.Content, "สวัสดีครับ!")
+	}
+	if completion.Model != "fake-model" {
+		t.Errorf("Model = %q, want fake-model", completion.Model)
+	}
+	if completion.Usage == nil || completion.TokensUsed != 13 {
+		t.Errorf("Usage = %+v, TokensUsed = %d; want 10 + 3", completion.Usage, completion.TokensUsed)
+	}
+}
+
+// TestBuiltinProvidersRegistered ทดสอบว่า openai และ bedrock ลงทะเบียนตัวเองไว้
+func TestBuiltinProvidersRegistered(t *testing.T) {
+	registry := services.NewProviderRegistryFromConfig(testConfig())
+
+	for _, name := range []string{"openai", "bedrock"} {
+		if _, ok := registry.Capabilities(name); !ok {
+			t.Errorf("provider %s is not registered", name)
+		}
+	}
+}
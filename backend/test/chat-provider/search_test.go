@@ -0,0 +1,105 @@
+package chat_provider_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"chatbot/models"
+	"chatbot/services"
+	"chatbot/utils"
+)
+
+// ทดสอบว่าข้อความอังกฤษถูกตัดเป็นคำตัวพิมพ์เล็ก และข้อความไทยเป็น bigram ที่ซ้อนกัน
+func TestSearchTerms(t *testing.T) {
+	got := utils.SearchTerms("Hello, World! สวัสดี GPT-4")
+	want := []string{"hello", "world", "สว", "วั", "ัส", "สด", "ดี", "gpt", "4"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SearchTerms = %v, want %v", got, want)
+	}
+
+	// ไทยติดกับอังกฤษโดยไม่มีช่องว่างก็แยกกัน
+	got = utils.SearchTerms("ใช้Goเขียน")
+	want = []string{"ใช", "ช้", "go", "เข", "ขี", "ีย", "ยน"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SearchTerms = %v, want %v", got, want)
+	}
+}
+
+// ทดสอบว่า search text เก็บแต่ละคำครั้งเดียว
+func TestSearchTextUnique(t *testing.T) {
+	if got := utils.SearchText("go Go GO gopher"); got != "go gopher" {
+		t.Errorf("SearchText = %q, want %q", got, "go gopher")
+	}
+}
+
+// ทดสอบว่าคำอังกฤษค้นแบบ prefix ส่วน bigram ไทยค้นแบบตรงตัว และเครื่องหมายวรรคตอนถูกตัดทิ้ง
+func TestSearchQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"run fast", "'run':* & 'fast':*"},
+		{"ภาษา", "'ภา' & 'าษ' & 'ษา'"},
+		{"ก", "'ก':*"},
+		{"o'brien", "'o':* & 'brien':*"},
+		{"  !!  ", ""},
+	}
+	for _, tt := range tests {
+		if got := utils.SearchQuery(tt.query); got != tt.want {
+			t.Errorf("SearchQuery(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+// ทดสอบว่า snippet ไฮไลต์คำที่ค้น (ไม่สนตัวพิมพ์) และ escape HTML ของเนื้อหา
+func TestSnippetHighlight(t *testing.T) {
+	got := services.Snippet("Use <b>Go</b> for the backend, go!", "go", 80)
+	want := "Use &lt;b&gt;<mark>Go</mark>&lt;/b&gt; for the backend, <mark>go</mark>!"
+	if got != want {
+		t.Errorf("Snippet = %q, want %q", got, want)
+	}
+
+	got = services.Snippet("ฉันชอบเขียนภาษาไทย", "ภาษา", 80)
+	if got != "ฉันชอบเขียน<mark>ภาษา</mark>ไทย" {
+		t.Errorf("Snippet = %q", got)
+	}
+}
+
+// ทดสอบว่าข้อความยาวถูกตัดรอบคำที่พบพร้อม "…" และไม่พบคำก็ได้ตอนต้นข้อความ
+func TestSnippetWindow(t *testing.T) {
+	content := strings.Repeat("a ", 100) + "needle" + strings.Repeat(" b", 100)
+	got := services.Snippet(content, "needle", 20)
+	if !strings.HasPrefix(got, "…") || !strings.HasSuffix(got, "…") || !strings.Contains(got, "<mark>needle</mark>") {
+		t.Errorf("Snippet = %q, want a window around the match", got)
+	}
+	if len([]rune(got)) > 60 {
+		t.Errorf("Snippet has %d characters, want about 40", len([]rune(got)))
+	}
+
+	got = services.Snippet(content, "missing", 20)
+	if !strings.HasPrefix(got, "a a a") || !strings.HasSuffix(got, "…") {
+		t.Errorf("Snippet = %q, want the start of the content", got)
+	}
+}
+
+// ทดสอบว่า Vector แปลงเป็นรูปแบบของ pgvector และอ่านกลับได้
+func TestVectorValueScan(t *testing.T) {
+	vector := models.Vector{1, -0.5, 0.25}
+	value, err := vector.Value()
+	if err != nil || value != "[1,-0.5,0.25]" {
+		t.Fatalf("Value = %v, %v, want [1,-0.5,0.25]", value, err)
+	}
+
+	var scanned models.Vector
+	if err := scanned.Scan([]byte("[1,-0.5,0.25]")); err != nil || !reflect.DeepEqual(scanned, vector) {
+		t.Errorf("Scan = %v, %v, want %v", scanned, err, vector)
+	}
+
+	// ใน JSON ยังเป็น array ของตัวเลข ไม่ใช่ string
+	encoded, _ := json.Marshal(scanned)
+	if string(encoded) != "[1,-0.5,0.25]" {
+		t.Errorf("json = %s", encoded)
+	}
+}
@@ -0,0 +1,61 @@
+package chat_provider_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"chatbot/config"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testConfig คืนค่า config สำหรับทดสอบโดยไม่ต้องโหลด .env.development
+func testConfig() *config.Config {
+	return &config.Config{
+		OpenAIModel:        "gpt-4o-mini",
+		OpenAIMaxTokens:    2000,
+		OpenAITemperature:  0.7,
+		AWSRegion:          "ap-southeast-1",
+		BedrockModelID:     "apac.anthropic.claude-sonnet-4-20250514-v1:0",
+		BedrockMaxTokens:   2000,
+		BedrockTemperature: 0.7,
+		LocalLLMAPI:        "ollama",
+		LocalLLMModel:      "llama3.1",
+		LocalLLMMaxTokens:  2000,
+	}
+}
+
+// offlinePool เป็น connection ที่ทุก query ล้มเหลว (การบันทึกลง database จึงถูก log แล้วข้ามไป)
+type offlinePool struct{}
+
+var errOffline = errors.New("database offline")
+
+func (offlinePool) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return nil, errOffline
+}
+
+func (offlinePool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return nil, errOffline
+}
+
+func (offlinePool) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return nil, errOffline
+}
+
+func (offlinePool) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return nil
+}
+
+// offlineDB คืน gorm DB ที่ต่อกับ offlinePool สำหรับ service ที่ต้องมี repository แต่เทสต์ไม่ใช้ database
+func offlineDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: offlinePool{}}), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("gorm.Open: %v", err)
+	}
+	return db
+}
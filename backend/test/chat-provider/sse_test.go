@@ -0,0 +1,234 @@
+package chat_provider_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"chatbot/config"
+	"chatbot/controllers"
+	"chatbot/repositories"
+	"chatbot/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// TestWriteSSEEvent ทดสอบรูปแบบ event ของ SSE (event, data เป็น JSON และบรรทัดว่างปิดท้าย)
+func TestWriteSSEEvent(t *testing.T) {
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+
+	if err := controllers.WriteSSEEvent(w, "chunk", controllers.WSResponse{Type: "chunk", Content: "สวัสดี"}); err != nil {
+		t.Fatalf("WriteSSEEvent returned error: %v", err)
+	}
+	if err := controllers.WriteSSEEvent(w, "done", controllers.WSResponse{Type: "chunk", Done: true, MessageID: "m1", TokensUsed: 12}); err != nil {
+		t.Fatalf("WriteSSEEvent returned error: %v", err)
+	}
+
+	want := "event: chunk\ndata: {\"type\":\"chunk\",\"content\":\"สวัสดี\",\"done\":false}\n\n" +
+		"event: done\ndata: {\"type\":\"chunk\",\"content\":\"\",\"done\":true,\"message_id\":\"m1\",\"tokens_used\":12}\n\n"
+	if buf.String() != want {
+		t.Errorf("events = %q, want %q", buf.String(), want)
+	}
+}
+
+// newSSEApp สร้าง fiber app ที่มี POST /api/chat ต่อกับ provider ปลอมชื่อ openai
+func newSSEApp(t *testing.T, provider services.StreamingChatService) *fiber.App {
+	t.Helper()
+	db := offlineDB(t)
+	registry := services.NewProviderRegistry()
+	registry.Register("openai", 10, services.ProviderCapabilities{Streaming: true}, provider)
+	pricing := services.NewPricingService(config.PricingTable{})
+
+	messageRepo := repositories.NewMessageRepository(db)
+	fileRepo := repositories.NewFileAnalysisRepository(db)
+	chat := controllers.NewChatController(
+		messageRepo,
+		repositories.NewPersonaRepository(db),
+		fileRepo,
+		services.NewContextService(messageRepo, fileRepo, services.NewFileExtractionService(fileRepo)),
+		registry,
+		services.NewUsageService(pricing, repositories.NewUsageRepository(db)),
+		services.NewToolOrchestrator(services.NewToolRegistry(), 3),
+		services.NewStructuredOutputService(1),
+		services.NewFailoverStreamer(registry, testPolicy()),
+		services.NewModelCatalog(nil, pricing, registry),
+		nil,
+		nil,
+	)
+
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Post("/api/chat", chat.HandleChat)
+	return app
+}
+
+// sseEvent คือ event หนึ่งตัวของ response
+type sseEvent struct {
+	name string
+	data map[string]interface{}
+}
+
+// readSSE อ่าน event ทั้งหมดจาก body
+func readSSE(t *testing.T, body io.Reader) []sseEvent {
+	t.Helper()
+	var events []sseEvent
+	var name string
+	scanner := bufio.NewScanner(body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			event := sseEvent{name: name}
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event.data); err != nil {
+				t.Fatalf("event data %q is not JSON: %v", line, err)
+			}
+			events = append(events, event)
+			name = ""
+		}
+	}
+	return events
+}
+
+// postSSE ส่ง request แบบ stream ผ่าน app.Test แล้วคืน event ที่ได้
+func postSSE(t *testing.T, app *fiber.App, body string) []sseEvent {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/api/chat", strings.NewReader(body))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	req.Header.Set(fiber.HeaderAccept, "text/event-stream")
+
+	resp, err := app.Test(req, 5000)
+	if err != nil {
+		t.Fatalf("app.Test: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != fiber.StatusOK || resp.Header.Get(fiber.HeaderContentType) != "text/event-stream" {
+		t.Fatalf("status = %d, content type = %q, want an event stream", resp.StatusCode, resp.Header.Get(fiber.HeaderContentType))
+	}
+	return readSSE(t, resp.Body)
+}
+
+// ทดสอบ POST /api/chat แบบ SSE: chunk ตามลำดับแล้วจบด้วย done ที่บอก provider
+func TestChatSSEStreamsChunksThenDone(t *testing.T) {
+	app := newSSEApp(t, &fakeProvider{name: "openai", available: true, chunks: []string{"สวัสดี", "", "ครับ"}})
+
+	events := postSSE(t, app, `{"message":"hello","stream":true}`)
+	var names []string
+	content := ""
+	for _, event := range events {
+		names = append(names, event.name)
+		if event.name == "chunk" {
+			content += event.data["content"].(string)
+		}
+	}
+	if got := strings.Join(names, ","); got != "chunk,chunk,done" {
+		t.Fatalf("events = %s, want chunk,chunk,done", got)
+	}
+	if content != "สวัสดีครับ" {
+		t.Errorf("content = %q, want สวัสดีครับ", content)
+	}
+	done := events[len(events)-1].data
+	if id, _ := done["message_id"].(string); done["done"] != true || done["provider"] != "openai" || id == "" {
+		t.Errorf("done frame = %v", done)
+	}
+}
+
+// ทดสอบว่าคำตอบที่ไม่ตรง response_schema จบด้วย error event ที่มี problems
+func TestChatSSESchemaErrorEvent(t *testing.T) {
+	app := newSSEApp(t, &fakeProvider{name: "openai", available: true, chunks: []string{`{"answer":`, `42}`}})
+
+	events := postSSE(t, app, `{"message":"hello","stream":true,"response_schema":{"name":"answer",`+
+		`"schema":{"type":"object","properties":{"answer":{"type":"string"}},"required":["answer"]}}}`)
+	if len(events) == 0 {
+		t.Fatal("no events")
+	}
+	last := events[len(events)-1]
+	if last.name != "error" || last.data["type"] != "error" {
+		t.Fatalf("last event = %+v, want error", last)
+	}
+	if problems, ok := last.data["problems"].([]interface{}); !ok || len(problems) == 0 {
+		t.Errorf("error frame = %v, want problems", last.data)
+	}
+	for _, event := range events {
+		if event.name == "done" {
+			t.Errorf("got done event for an invalid reply")
+		}
+	}
+}
+
+// endlessProvider ส่ง chunk ไปเรื่อยๆ จนกว่า context จะถูกยกเลิก
+type endlessProvider struct {
+	closed chan struct{}
+	once   sync.Once
+}
+
+func (p *endlessProvider) CreateStreamingChat(ctx context.Context, req services.StreamingChatRequest) (services.StreamReader, error) {
+	return &endlessStream{ctx: ctx, provider: p}, nil
+}
+
+func (p *endlessProvider) GetProviderName() string { return "openai" }
+
+func (p *endlessProvider) IsAvailable() bool { return true }
+
+type endlessStream struct {
+	ctx      context.Context
+	provider *endlessProvider
+}
+
+func (s *endlessStream) Recv() (string, error) {
+	select {
+	case <-s.ctx.Done():
+		return "", s.ctx.Err()
+	case <-time.After(5 * time.Millisecond):
+		return "ต่อ", nil
+	}
+}
+
+func (s *endlessStream) Close() error {
+	s.provider.once.Do(func() { close(s.provider.closed) })
+	return nil
+}
+
+// ทดสอบว่าเมื่อ client ปิดการเชื่อมต่อ stream ของ provider ถูกยกเลิกและปิด
+func TestChatSSEClientDisconnectCancels(t *testing.T) {
+	provider := &endlessProvider{closed: make(chan struct{})}
+	app := newSSEApp(t, provider)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	go app.Listener(listener)
+	t.Cleanup(func() { app.Shutdown() })
+
+	req, _ := http.NewRequest(http.MethodPost, "http://"+listener.Addr().String()+"/api/chat",
+		strings.NewReader(`{"message":"hello","stream":true}`))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+
+	// รอ chunk แรกแล้วตัดการเชื่อมต่อ
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	if err != nil || line != "event: chunk\n" {
+		t.Fatalf("first line = %q, %v, want a chunk event", line, err)
+	}
+	resp.Body.Close()
+
+	select {
+	case <-provider.closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("provider stream still open after the client went away")
+	}
+}
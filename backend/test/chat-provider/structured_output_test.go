@@ -0,0 +1,204 @@
+package chat_provider_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"chatbot/config"
+	"chatbot/services"
+)
+
+var testPersonSchema = json.RawMessage(`{
+	"type": "object",
+	"properties": {
+		"name": {"type": "string", "minLength": 1},
+		"age": {"type": "integer", "minimum": 0},
+		"tags": {"type": "array", "items": {"type": "string"}, "maxItems": 2},
+		"mood": {"type": "string", "enum": ["happy", "sad"]},
+		"nickname": {"type": ["string", "null"]}
+	},
+	"required": ["name", "age"],
+	"additionalProperties": false
+}`)
+
+// TestValidateJSONSchema ทดสอบ keyword ที่ validator รองรับ
+func TestValidateJSONSchema(t *testing.T) {
+	valid := []string{
+		`{"name":"Somchai","age":30}`,
+		`{"name":"Somchai","age":30,"tags":["a","b"],"mood":"happy","nickname":null}`,
+	}
+	for _, data := range valid {
+		if err := services.ValidateJSONSchema(testPersonSchema, []byte(data)); err != nil {
+			t.Errorf("ValidateJSONSchema(%s) returned error: %v", data, err)
+		}
+	}
+
+	invalid := map[string]string{
+		`{"age":30}`:                                "$.name is required",
+		`{"name":"A","age":30.5}`:                   "$.age must be integer",
+		`{"name":"A","age":-1}`:                     "$.age must be >= 0",
+		`{"name":"","age":1}`:                       "$.name must be at least 1 characters",
+		`{"name":"A","age":1,"tags":["a","b","c"]}`: "$.tags must have at most 2 items",
+		`{"name":"A","age":1,"tags":[1]}`:           "$.tags[0] must be string",
+		`{"name":"A","age":1,"mood":"angry"}`:       `$.mood must be one of ["happy","sad"]`,
+		`{"name":"A","age":1,"extra":true}`:         "$.extra is not allowed",
+		`{"name":"A"`:                               "invalid JSON",
+		`[]`:                                        "$ must be object, got array",
+	}
+	for data, want := range invalid {
+		err := services.ValidateJSONSchema(testPersonSchema, []byte(data))
+		var violation *services.SchemaViolationError
+		if !errors.As(err, &violation) {
+			t.Errorf("ValidateJSONSchema(%s) = %v, want SchemaViolationError", data, err)
+			continue
+		}
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("ValidateJSONSchema(%s) = %v, want %q", data, err, want)
+		}
+	}
+}
+
+// TestExtractJSON ทดสอบการตัด code fence และข้อความรอบ JSON
+func TestExtractJSON(t *testing.T) {
+	tests := map[string]string{
+		`{"a":1}`:                        `{"a":1}`,
+		"```json\n{\"a\":1}\n```":        `{"a":1}`,
+		"Here you go: {\"a\":1} Thanks!": `{"a":1}`,
+		"  {\"a\": {\"b\": [1, 2]}}  \n": `{"a": {"b": [1, 2]}}`,
+	}
+	for input, want := range tests {
+		if got := services.ExtractJSON(input); got != want {
+			t.Errorf("ExtractJSON(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+// TestResponseSchemaCheck ทดสอบการตรวจ schema ที่ส่งมากับ request
+func TestResponseSchemaCheck(t *testing.T) {
+	schema := &services.ResponseSchema{Schema: testPersonSchema}
+	if err := schema.Check(); err != nil || schema.Name != "response" {
+		t.Errorf("Check() = %v, name %q; want nil, response", err, schema.Name)
+	}
+
+	for _, bad := range []services.ResponseSchema{
+		{Name: "has space", Schema: testPersonSchema},
+		{Name: "ok", Schema: json.RawMessage(`[1]`)},
+		{Name: "ok", Schema: json.RawMessage(`{"type":"string"}`)},
+	} {
+		if err := bad.Check(); err == nil {
+			t.Errorf("Check() should fail for %+v", bad)
+		}
+	}
+}
+
+// scriptedCompleter ตอบตามลำดับที่กำหนดและเก็บ request ทุกครั้ง
+type scriptedCompleter struct {
+	fakeProvider
+	replies  []string
+	requests []services.StreamingChatRequest
+}
+
+func (p *scriptedCompleter) CompleteChat(ctx context.Context, req services.StreamingChatRequest) (*services.ChatCompletion, error) {
+	p.requests = append(p.requests, req)
+	reply := p.replies[len(p.requests)-1]
+	return &services.ChatCompletion{
+		Content:    reply,
+		TokensUsed: 15,
+		Usage:      &services.TokenUsage{PromptTokens: 10, CompletionTokens: 5},
+	}, nil
+}
+
+// TestStructuredOutputRepair ทดสอบว่า reply ที่ผิด schema ถูกส่งกลับไปให้ model แก้
+func TestStructuredOutputRepair(t *testing.T) {
+	provider := &scriptedCompleter{
+		fakeProvider: fakeProvider{name: "fake", available: true},
+		replies:      []string{`{"name":"Somchai"}`, "```json\n{\"name\":\"Somchai\",\"age\":30}\n```"},
+	}
+	service := services.NewStructuredOutputService(2)
+
+	result, err := service.Complete(context.Background(), provider, services.StreamingChatRequest{
+		Messages:       []services.ConversationMessage{services.NewTextMessage(services.ConversationRoleUser, "Who?")},
+		ResponseSchema: &services.ResponseSchema{Name: "person", Schema: testPersonSchema},
+	})
+	if err != nil {
+		t.Fatalf("Complete returned error: %v", err)
+	}
+
+	if result.Attempts != 2 || string(result.Data) != `{"name":"Somchai","age":30}` {
+		t.Errorf("result = %s after %d attempts", result.Data, result.Attempts)
+	}
+	if result.Completion.Usage.PromptTokens != 20 || result.Completion.TokensUsed != 30 {
+		t.Errorf("usage = %+v, tokens %d; want summed over 2 attempts", result.Completion.Usage, result.Completion.TokensUsed)
+	}
+
+	repair := provider.requests[1].Messages
+	if len(repair) != 3 || repair[1].Role != services.ConversationRoleAssistant {
+		t.Fatalf("repair request messages = %+v", repair)
+	}
+	if !strings.Contains(repair[2].Text(), "$.age is required") {
+		t.Errorf("repair prompt = %q, want the violation", repair[2].Text())
+	}
+}
+
+// TestStructuredOutputGivesUp ทดสอบ error เมื่อแก้ครบจำนวนครั้งแล้วยังไม่ผ่าน
+func TestStructuredOutputGivesUp(t *testing.T) {
+	provider := &scriptedCompleter{
+		fakeProvider: fakeProvider{name: "fake", available: true},
+		replies:      []string{"not json", `{"name":1,"age":2}`},
+	}
+	service := services.NewStructuredOutputService(1)
+
+	_, err := service.Complete(context.Background(), provider, services.StreamingChatRequest{
+		ResponseSchema: &services.ResponseSchema{Name: "person", Schema: testPersonSchema},
+	})
+	var structuredErr *services.StructuredOutputError
+	if !errors.As(err, &structuredErr) {
+		t.Fatalf("Complete error = %v, want StructuredOutputError", err)
+	}
+	if structuredErr.Attempts != 2 || structuredErr.Content != `{"name":1,"age":2}` {
+		t.Errorf("error = %+v", structuredErr)
+	}
+}
+
+// TestOpenAIResponseFormat ทดสอบว่า schema ถูกส่งเป็น response_format json_schema
+func TestOpenAIResponseFormat(t *testing.T) {
+	var received map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&received)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"c","object":"chat.completion","model":"gpt-4o-mini","choices":[{"index":0,"message":{"role":"assistant","content":"{\"name\":\"A\",\"age\":1}"},"finish_reason":"stop"}],"usage":{"prompt_tokens":5,"completion_tokens":3,"total_tokens":8}}`))
+	}))
+	defer server.Close()
+
+	provider, err := services.NewOpenAICompatibleService(testConfig(), config.OpenAICompatibleProvider{
+		Name:         "structured",
+		BaseURL:      server.URL,
+		APIKey:       "sk-test",
+		DefaultModel: "gpt-4o-mini",
+	})
+	if err != nil {
+		t.Fatalf("NewOpenAICompatibleService returned error: %v", err)
+	}
+
+	result, err := services.NewStructuredOutputService(0).Complete(context.Background(), provider, services.StreamingChatRequest{
+		Messages:       []services.ConversationMessage{services.NewTextMessage(services.ConversationRoleUser, "Who?")},
+		ResponseSchema: &services.ResponseSchema{Name: "person", Schema: testPersonSchema, Strict: true},
+	})
+	if err != nil {
+		t.Fatalf("Complete returned error: %v", err)
+	}
+	if string(result.Data) != `{"name":"A","age":1}` {
+		t.Errorf("data = %s", result.Data)
+	}
+
+	format, _ := received["response_format"].(map[string]interface{})
+	jsonSchema, _ := format["json_schema"].(map[string]interface{})
+	if format["type"] != "json_schema" || jsonSchema["name"] != "person" || jsonSchema["strict"] != true || jsonSchema["schema"] == nil {
+		t.Errorf("response_format = %v", received["response_format"])
+	}
+}
@@ -0,0 +1,56 @@
+package chat_provider_test
+
+import (
+	"strings"
+	"testing"
+
+	"chatbot/models"
+	"chatbot/services"
+)
+
+// ทดสอบว่า SummaryOnPath เลือก summary ที่ครอบคลุม branch มากที่สุด และคืนข้อความหลัง summary
+func TestSummaryOnPath(t *testing.T) {
+	ids, messages := branchingSession()
+	tree := services.NewMessageTree(messages)
+	summaries := []models.SessionSummary{
+		{SessionID: "s1", ThroughID: ids["u2"], Content: "ถึง u2", MessageCount: 3}, // ใหม่สุด แต่ไม่อยู่ใน branch ของ a3
+		{SessionID: "s1", ThroughID: ids["a1"], Content: "ถึง a1", MessageCount: 2},
+		{SessionID: "s1", ThroughID: ids["u1"], Content: "ถึง u1", MessageCount: 1},
+	}
+
+	summary, rest := services.SummaryOnPath(tree.Path(ids["a3"]), summaries)
+	if summary == nil || summary.Content != "ถึง a1" {
+		t.Fatalf("summary = %v, want the one through a1", summary)
+	}
+	if got := contents(rest); len(got) != 3 || got[0] != "u2e" || got[2] != "a3" {
+		t.Errorf("messages after summary = %v, want [u2e tool a3]", got)
+	}
+
+	// branch ของ a2 มี summary ถึง u2 ซึ่งลึกกว่า
+	summary, rest = services.SummaryOnPath(tree.Path(ids["a2"]), summaries)
+	if summary == nil || summary.Content != "ถึง u2" || len(rest) != 1 {
+		t.Errorf("summary = %v with %v, want the one through u2 followed by a2", summary, contents(rest))
+	}
+}
+
+// ทดสอบว่าไม่มี summary ใน branch ได้ข้อความทั้งหมด
+func TestSummaryOnPathWithoutSummary(t *testing.T) {
+	ids, messages := branchingSession()
+	path := services.NewMessageTree(messages).Path(ids["a2b"])
+
+	summary, rest := services.SummaryOnPath(path, nil)
+	if summary != nil || len(rest) != len(path) {
+		t.Errorf("got summary %v and %d messages, want none and %d", summary, len(rest), len(path))
+	}
+}
+
+// ทดสอบว่า summary ถูกส่งให้ model เป็น system message
+func TestSummaryMessage(t *testing.T) {
+	message := services.SummaryMessage(&models.SessionSummary{Content: "ผู้ใช้ชื่อสมชาย", MessageCount: 12})
+	if message.Role != services.ConversationRoleSystem {
+		t.Errorf("role = %s, want system", message.Role)
+	}
+	if text := message.Text(); !strings.Contains(text, "12 messages") || !strings.Contains(text, "ผู้ใช้ชื่อสมชาย") {
+		t.Errorf("text = %q", text)
+	}
+}
@@ -0,0 +1,282 @@
+package chat_provider_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"chatbot/config"
+	"chatbot/services"
+)
+
+// toolStream เป็น stream จำลองที่ส่ง chunks แล้วรายงาน tool calls หลัง io.EOF
+type toolStream struct {
+	fakeStream
+	calls []services.ToolCall
+}
+
+func (s *toolStream) ToolCalls() []services.ToolCall { return s.calls }
+
+// newTestToolRegistry สร้าง registry ที่มี tool echo สำหรับทดสอบ
+func newTestToolRegistry(t *testing.T) *services.ToolRegistry {
+	t.Helper()
+	registry := services.NewToolRegistry()
+	err := registry.Register(services.ToolDefinition{
+		Name:        "echo",
+		Description: "Echo the text argument",
+		Parameters:  json.RawMessage(`{"type":"object","properties":{"text":{"type":"string"}}}`),
+	}, func(ctx context.Context, arguments json.RawMessage) (string, error) {
+		var args struct {
+			Text string `json:"text"`
+		}
+		if err := json.Unmarshal(arguments, &args); err != nil {
+			return "", err
+		}
+		if args.Text == "" {
+			return "", fmt.Errorf("text is required")
+		}
+		return "echo: " + args.Text, nil
+	})
+	if err != nil {
+		t.Fatalf("Register returned error: %v", err)
+	}
+	return registry
+}
+
+// TestToolRegistry ทดสอบ built-in tools, Validate, Definitions และ Execute
+func TestToolRegistry(t *testing.T) {
+	registry := newTestToolRegistry(t)
+
+	for _, name := range []string{"get_current_time", "calculate", "echo"} {
+		if _, ok := registry.Get(name); !ok {
+			t.Errorf("tool %s is not registered", name)
+		}
+	}
+
+	if err := registry.Validate([]string{"calculate", "echo"}); err != nil {
+		t.Errorf("Validate returned error: %v", err)
+	}
+	if err := registry.Validate([]string{"calculate", "missing"}); err == nil {
+		t.Error("Validate should fail for an unknown tool")
+	}
+
+	definitions := registry.Definitions([]string{"echo", "missing"})
+	if len(definitions) != 1 || definitions[0].Name != "echo" {
+		t.Errorf("Definitions = %+v, want only echo", definitions)
+	}
+
+	result, err := registry.Execute(context.Background(), services.ToolCall{Name: "calculate", Arguments: `{"expression":"(2 + 3) * 4"}`})
+	if err != nil || result != "20" {
+		t.Errorf("calculate = %q, %v; want 20", result, err)
+	}
+
+	if _, err := registry.Execute(context.Background(), services.ToolCall{Name: "echo", Arguments: `{not json`}); err == nil {
+		t.Error("Execute should reject invalid JSON arguments")
+	}
+	if _, err := registry.Execute(context.Background(), services.ToolCall{Name: "missing"}); err == nil {
+		t.Error("Execute should fail for an unknown tool")
+	}
+}
+
+// TestEvaluateArithmetic ทดสอบเครื่องคิดเลขของ tool calculate
+func TestEvaluateArithmetic(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       float64
+	}{
+		{"1 + 2 * 3", 7},
+		{"(1 + 2) * 3", 9},
+		{"-4 / 8", -0.5},
+		{"10 % 4", 2},
+		{"2.5 * 4", 10},
+	}
+	for _, tt := range tests {
+		got, err := services.EvaluateArithmetic(tt.expression)
+		if err != nil || got != tt.want {
+			t.Errorf("EvaluateArithmetic(%q) = %v, %v; want %v", tt.expression, got, err, tt.want)
+		}
+	}
+
+	for _, expression := range []string{"", "1 / 0", "os.Exit(1)", "x + 1"} {
+		if _, err := services.EvaluateArithmetic(expression); err == nil {
+			t.Errorf("EvaluateArithmetic(%q) should fail", expression)
+		}
+	}
+}
+
+// TestToolOrchestratorStream ทดสอบ loop: model เรียก tool → server รัน → model ตอบ
+func TestToolOrchestratorStream(t *testing.T) {
+	orchestrator := services.NewToolOrchestrator(newTestToolRegistry(t), 3)
+
+	rounds := []*toolStream{
+		{fakeStream: fakeStream{chunks: []string{"Let me check."}}, calls: []services.ToolCall{
+			{ID: "call_1", Name: "echo", Arguments: `{"text":"hi"}`},
+			{Name: "echo", Arguments: `{}`}, // ไม่มี ID และ handler error
+		}},
+		{fakeStream: fakeStream{chunks: []string{"Done", "!"}}},
+	}
+	var requests []services.StreamingChatRequest
+	open := func(ctx context.Context, req services.StreamingChatRequest) (services.StreamReader, error) {
+		requests = append(requests, req)
+		return rounds[len(requests)-1], nil
+	}
+
+	var chunks, events []string
+	result, err := orchestrator.Stream(context.Background(), services.StreamingChatRequest{
+		Messages: []services.ConversationMessage{services.NewTextMessage(services.ConversationRoleUser, "hello")},
+		Tools:    orchestrator.Registry().Definitions([]string{"echo"}),
+	}, open, services.ToolEvents{
+		OnChunk: func(chunk string) error {
+			chunks = append(chunks, chunk)
+			return nil
+		},
+		OnToolCall: func(call services.ToolCall) error {
+			events = append(events, "call:"+call.ID)
+			return nil
+		},
+		OnToolResult: func(call services.ToolCall, result string, failed bool) error {
+			events = append(events, fmt.Sprintf("result:%s:%v", call.ID, failed))
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Stream returned error: %v", err)
+	}
+
+	if result.Content != "Done!" || result.Rounds != 2 {
+		t.Errorf("result = %q after %d rounds, want Done! after 2", result.Content, result.Rounds)
+	}
+	if strings.Join(chunks, "") != "Let me check.Done!" {
+		t.Errorf("chunks = %v", chunks)
+	}
+	wantEvents := "call:call_1 result:call_1:false call:call_1_1 result:call_1_1:true"
+	if strings.Join(events, " ") != wantEvents {
+		t.Errorf("events = %v, want %s", events, wantEvents)
+	}
+
+	// รอบที่สองต้องได้ assistant tool-call turn และ tool result ทั้งสองต่อท้าย history
+	second := requests[1].Messages
+	if len(second) != 4 {
+		t.Fatalf("second round has %d messages, want 4", len(second))
+	}
+	if len(second[1].ToolCalls) != 2 || second[1].Text() != "Let me check." {
+		t.Errorf("assistant turn = %+v", second[1])
+	}
+	if second[2].Role != services.ConversationRoleTool || second[2].ToolCallID != "call_1" || second[2].Text() != "echo: hi" {
+		t.Errorf("first tool result = %+v", second[2])
+	}
+	if !strings.HasPrefix(second[3].Text(), "error: ") {
+		t.Errorf("failed tool result = %q, want error: prefix", second[3].Text())
+	}
+
+	if len(result.Steps) != 3 {
+		t.Errorf("steps = %d, want 3", len(result.Steps))
+	}
+	// usage รวมทุกรอบ (fakeStream รายงาน prompt 10 ต่อรอบ)
+	if result.Usage == nil || result.Usage.PromptTokens != 20 || result.Usage.CompletionTokens != 3 {
+		t.Errorf("usage = %+v, want prompt 20 completion 3", result.Usage)
+	}
+}
+
+// TestToolOrchestratorMaxRounds ทดสอบว่า loop หยุดเมื่อ model เรียก tool เกินจำนวนรอบ
+func TestToolOrchestratorMaxRounds(t *testing.T) {
+	orchestrator := services.NewToolOrchestrator(newTestToolRegistry(t), 2)
+
+	open := func(ctx context.Context, req services.StreamingChatRequest) (services.StreamReader, error) {
+		return &toolStream{calls: []services.ToolCall{{ID: "c", Name: "echo", Arguments: `{"text":"again"}`}}}, nil
+	}
+	_, err := orchestrator.Stream(context.Background(), services.StreamingChatRequest{
+		Messages: []services.ConversationMessage{services.NewTextMessage(services.ConversationRoleUser, "loop")},
+		Tools:    orchestrator.Registry().Definitions([]string{"echo"}),
+	}, open, services.ToolEvents{})
+	if err == nil {
+		t.Fatal("Stream should fail after max rounds")
+	}
+}
+
+// TestToolStepRecords ทดสอบการแปลง steps เป็น message สำหรับบันทึก และการ replay จาก history
+func TestToolStepRecords(t *testing.T) {
+	assistant := services.NewTextMessage(services.ConversationRoleAssistant, "")
+	assistant.ToolCalls = []services.ToolCall{{ID: "call_1", Name: "calculate", Arguments: `{"expression":"1+1"}`}}
+	tool := services.NewTextMessage(services.ConversationRoleTool, "2")
+	tool.ToolCallID = "call_1"
+
+	records := services.ToolStepRecords([]services.ConversationMessage{assistant, tool}, "session_1", nil)
+	if len(records) != 2 {
+		t.Fatalf("records = %d, want 2", len(records))
+	}
+	calls, err := records[0].GetToolCalls()
+	if err != nil || len(calls) != 1 || calls[0].Name != "calculate" {
+		t.Errorf("assistant tool calls = %+v, %v", calls, err)
+	}
+	if records[1].Role != "tool" || records[1].ToolCallID != "call_1" || records[1].ToolName != "calculate" {
+		t.Errorf("tool record = %+v", records[1])
+	}
+
+	// tool result ที่ไม่มี call และ call ที่ไม่มี result ถูกตัดออกเมื่อ history ถูกตัด
+	orphan := services.NewTextMessage(services.ConversationRoleTool, "old")
+	orphan.ToolCallID = "call_0"
+	unanswered := services.NewTextMessage(services.ConversationRoleAssistant, "thinking")
+	unanswered.ToolCalls = []services.ToolCall{{ID: "call_2", Name: "calculate"}}
+
+	normalized := services.NormalizeToolMessages([]services.ConversationMessage{orphan, assistant, tool, unanswered})
+	if len(normalized) != 3 {
+		t.Fatalf("normalized = %d messages, want 3", len(normalized))
+	}
+	if len(normalized[2].ToolCalls) != 0 || normalized[2].Text() != "thinking" {
+		t.Errorf("unanswered call turn = %+v, want text only", normalized[2])
+	}
+}
+
+// TestOpenAIToolCallStreaming ทดสอบการรวม tool call deltas จาก OpenAI stream
+func TestOpenAIToolCallStreaming(t *testing.T) {
+	var received map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&received)
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, delta := range []string{
+			`{"tool_calls":[{"index":0,"id":"call_abc","type":"function","function":{"name":"calculate","arguments":""}}]}`,
+			`{"tool_calls":[{"index":0,"function":{"arguments":"{\"expression\":"}}]}`,
+			`{"tool_calls":[{"index":0,"function":{"arguments":"\"6*7\"}"}}]}`,
+		} {
+			fmt.Fprintf(w, "data: {\"id\":\"c\",\"object\":\"chat.completion.chunk\",\"choices\":[{\"index\":0,\"delta\":%s}]}\n\n", delta)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	provider, err := services.NewOpenAICompatibleService(testConfig(), config.OpenAICompatibleProvider{
+		Name:         "tools",
+		BaseURL:      server.URL,
+		APIKey:       "sk-test",
+		DefaultModel: "gpt-4o-mini",
+	})
+	if err != nil {
+		t.Fatalf("NewOpenAICompatibleService returned error: %v", err)
+	}
+
+	registry := newTestToolRegistry(t)
+	stream, err := provider.CreateStreamingChat(context.Background(), services.StreamingChatRequest{
+		Messages: []services.ConversationMessage{services.NewTextMessage(services.ConversationRoleUser, "6*7?")},
+		Tools:    registry.Definitions([]string{"calculate"}),
+	})
+	if err != nil {
+		t.Fatalf("CreateStreamingChat returned error: %v", err)
+	}
+	defer stream.Close()
+	readAll(t, stream)
+
+	calls := services.StreamToolCalls(stream)
+	if len(calls) != 1 || calls[0].ID != "call_abc" || calls[0].Name != "calculate" || calls[0].Arguments != `{"expression":"6*7"}` {
+		t.Errorf("tool calls = %+v", calls)
+	}
+
+	tools, _ := received["tools"].([]interface{})
+	if len(tools) != 1 {
+		t.Errorf("request tools = %v, want 1 tool", received["tools"])
+	}
+}
@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+)
+
+// ChunkDocument แบ่งเอกสารยาวเป็นช่วงละไม่เกิน size ตัวอักษร สำหรับทำ embedding
+// พยายามตัดที่ขอบย่อหน้า → บรรทัด → ประโยค → ช่องว่าง ตามลำดับ (ภาษาไทยใช้ช่องว่างคั่นประโยค)
+// chunk ที่ติดกันซ้อนกันประมาณ overlap ตัวอักษร เพื่อไม่ให้ข้อความที่อยู่ตรงรอยตัดหายไปจากการค้นหา
+func ChunkDocument(text string, size, overlap int) []string {
+	runes := []rune(strings.TrimSpace(text))
+	if len(runes) == 0 || size <= 0 {
+		return []string{}
+	}
+	if overlap < 0 || overlap >= size/2 {
+		overlap = size / 4
+	}
+
+	var chunks []string
+	for start := 0; start < len(runes); {
+		end := len(runes)
+		if start+size < len(runes) {
+			end = breakPoint(runes, start+size/2, start+size)
+		}
+
+		if chunk := strings.TrimSpace(string(runes[start:end])); chunk != "" {
+			chunks = append(chunks, chunk)
+		}
+		if end >= len(runes) {
+			break
+		}
+
+		// เริ่ม chunk ถัดไปย้อนหลังไป overlap ตัวอักษร โดยขยับไปต้นคำถัดไปเพื่อไม่ให้ขึ้นต้นกลางคำ
+		next := max(end-overlap, start+1)
+		for i := next; i < end; i++ {
+			if unicode.IsSpace(runes[i]) {
+				next = i + 1
+				break
+			}
+		}
+		start = next
+	}
+	return chunks
+}
+
+// breakPoint หาตำแหน่งตัดที่ดีที่สุดในช่วง [from, to) โดยค้นจากท้ายช่วง
+// ถ้าไม่มีขอบใดเลย (เช่นข้อความไทยยาวไม่มีช่องว่าง) ตัดที่ to
+func breakPoint(runes []rune, from, to int) int {
+	for _, isBreak := range []func(i int) bool{
+		func(i int) bool { return runes[i] == '\n' && i > 0 && runes[i-1] == '\n' },
+		func(i int) bool { return runes[i] == '\n' },
+		func(i int) bool {
+			return unicode.IsSpace(runes[i]) && i > 0 && strings.ContainsRune(".!?。", runes[i-1])
+		},
+		func(i int) bool { return unicode.IsSpace(runes[i]) },
+	} {
+		for i := to - 1; i >= from; i-- {
+			if isBreak(i) {
+				return i + 1
+			}
+		}
+	}
+	return to
+}
@@ -0,0 +1,48 @@
+package utils
+
+import "unicode"
+
+// เอกสารไทยมักมีคำภาษาอังกฤษปนอยู่มาก (ชื่อเฉพาะ ศัพท์เทคนิค ตัวย่อ)
+// จึงถือว่าเป็นภาษาไทยเมื่อตัวอักษรไทยมีอย่างน้อย thaiLanguageShare ของตัวอักษรทั้งหมด
+// ไม่ใช่เมื่อมีมากกว่าครึ่ง
+
+// Language detection limits
+const (
+	languageSampleRunes = 20000 // Characters of the text that are looked at
+	minLanguageLetters  = 20    // Fewer letters than this are too little to tell
+	thaiLanguageShare   = 0.3
+	latinLanguageShare  = 0.6
+)
+
+// DetectLanguage returns the main language of text by its script: "th", "en", or "" when unsure
+// Latin text is reported as English, which is what the app's language settings distinguish
+func DetectLanguage(text string) string {
+	var thai, latin, letters, seen int
+	for _, r := range text {
+		if seen++; seen > languageSampleRunes {
+			break
+		}
+		switch {
+		case isThaiLetter(r):
+			thai++
+			letters++
+		case unicode.Is(unicode.Latin, r):
+			latin++
+			letters++
+		case unicode.IsLetter(r):
+			letters++
+		}
+	}
+
+	if letters < minLanguageLetters {
+		return ""
+	}
+	switch {
+	case float64(thai) >= thaiLanguageShare*float64(letters):
+		return "th"
+	case float64(latin) >= latinLanguageShare*float64(letters):
+		return "en"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,115 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+)
+
+// ภาษาไทยไม่มีช่องว่างระหว่างคำ จึงตัดคำแบบ dictionary ไม่ได้ง่ายๆ
+// ข้อความไทยจึงถูก index เป็น character bigram ที่ซ้อนกัน ("สวัสดี" → "สว", "วั", "ัส", ...)
+// คำค้นก็ถูกแปลงแบบเดียวกัน ทำให้ค้นเจอคำไทยที่อยู่กลางประโยคได้
+// ภาษาอื่นถูก index เป็นคำตัวพิมพ์เล็ก
+
+// Search text limits
+const (
+	maxSearchRunes = 100000 // Characters of a message that are indexed
+	maxTermRunes   = 100    // Longer words (hashes, base64, ...) are skipped
+)
+
+// SearchTerms splits text into search terms: lowercase words, and character bigrams for Thai
+func SearchTerms(text string) []string {
+	var terms []string
+	var word []rune
+	thai := false
+
+	flush := func() {
+		switch {
+		case len(word) == 0 || len(word) > maxTermRunes && !thai:
+		case thai:
+			terms = append(terms, thaiBigrams(word)...)
+		default:
+			terms = append(terms, string(word))
+		}
+		word = word[:0]
+	}
+
+	count := 0
+	for _, r := range text {
+		if count++; count > maxSearchRunes {
+			break
+		}
+		r = unicode.ToLower(r)
+		isThai := isThaiLetter(r)
+		if !isThai && !isWordRune(r) {
+			flush()
+			continue
+		}
+		if len(word) > 0 && isThai != thai {
+			flush()
+		}
+		thai = isThai
+		word = append(word, r)
+	}
+	flush()
+
+	return terms
+}
+
+// SearchText returns the stored search text of a message: its unique search terms separated by spaces
+func SearchText(text string) string {
+	seen := map[string]bool{}
+	unique := make([]string, 0)
+	for _, term := range SearchTerms(text) {
+		if !seen[term] {
+			seen[term] = true
+			unique = append(unique, term)
+		}
+	}
+	return strings.Join(unique, " ")
+}
+
+// SearchQuery builds a PostgreSQL tsquery matching every term of a search query
+// Words (and single Thai characters) match as prefixes ("run" finds "running"), Thai bigrams match exactly
+// Returns "" when the query has no searchable terms
+func SearchQuery(query string) string {
+	seen := map[string]bool{}
+	var parts []string
+	for _, field := range strings.Fields(query) {
+		terms := SearchTerms(field)
+		for _, term := range terms {
+			if seen[term] {
+				continue
+			}
+			seen[term] = true
+
+			lexeme := "'" + strings.ReplaceAll(term, "'", "''") + "'"
+			if runes := []rune(term); !isThaiLetter(runes[0]) || len(runes) == 1 {
+				lexeme += ":*"
+			}
+			parts = append(parts, lexeme)
+		}
+	}
+	return strings.Join(parts, " & ")
+}
+
+// thaiBigrams splits a run of Thai characters into overlapping two-character terms
+func thaiBigrams(word []rune) []string {
+	if len(word) == 1 {
+		return []string{string(word)}
+	}
+	bigrams := make([]string, 0, len(word)-1)
+	for i := 0; i < len(word)-1; i++ {
+		bigrams = append(bigrams, string(word[i:i+2]))
+	}
+	return bigrams
+}
+
+// isThaiLetter reports whether r is a Thai consonant, vowel or tone mark
+func isThaiLetter(r rune) bool {
+	return r >= 0x0E00 && r <= 0x0E7F && (unicode.IsLetter(r) || unicode.IsMark(r))
+}
+
+// isWordRune reports whether r is part of a word in other scripts
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r)
+}
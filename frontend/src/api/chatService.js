@@ -21,6 +21,31 @@ export const chatService = {
   getChatHistory: (params = { limit: 50, offset: 0 }) =>
     apiClient.get('/api/chats', { params }),
 
+  /**
+   * Get the active branch of a session (edited and regenerated alternatives stay on other branches)
+   * @param {string} sessionId - Session ID
+   * @param {string} messageId - Show the branch through this message (latest branch if empty)
+   */
+  getBranch: (sessionId, messageId = '') =>
+    apiClient.get(`/api/chats/session/${sessionId}/branch`, {
+      params: messageId ? { message_id: messageId } : {}
+    }),
+
+  /**
+   * Edit a user message (answered on a new branch, non-streaming)
+   * @param {string} messageId - User message to edit
+   * @param {Object} data - { content, file_ids, persona_id, provider, model }
+   */
+  editMessage: (messageId, data) => apiClient.post(`/api/chats/messages/${messageId}/edit`, data),
+
+  /**
+   * Regenerate a reply (answered on a new branch, non-streaming)
+   * @param {string} messageId - Assistant message to regenerate
+   * @param {Object} data - { persona_id, provider, model }
+   */
+  regenerateMessage: (messageId, data = {}) =>
+    apiClient.post(`/api/chats/messages/${messageId}/regenerate`, data),
+
   /**
    * Delete all messages
    */
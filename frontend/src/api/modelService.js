@@ -0,0 +1,9 @@
+import apiClient from './axios'
+
+export const modelService = {
+  /**
+   * Get the model catalog
+   * @param {string} provider - Optional provider filter ('openai', 'bedrock')
+   */
+  getModels: (provider) => apiClient.get('/api/models', { params: { provider } })
+}
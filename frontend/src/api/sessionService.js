@@ -2,53 +2,58 @@ import apiClient from './axios'
 
 export const sessionService = {
   /**
-   * ดูรายการ sessions จาก messages (group by session_id)
+   * ดูรายการ sessions (conversations) เรียงจาก pinned และอัปเดตล่าสุด
    * @param {number} limit - จำนวน sessions ที่ต้องการ
+   * @param {Object} filters - { search, archived, tag, offset }
    * @returns {Promise<Array>} - รายการ sessions
    */
-  async getSessionsList(limit = 50) {
+  async getSessionsList(limit = 50, filters = {}) {
     try {
-      const response = await apiClient.get('/api/chats', {
-        params: { limit: 1000 } // ดึงข้อความมาเยอะๆ เพื่อ group
+      const response = await apiClient.get('/api/conversations', {
+        params: { limit, ...filters }
       })
 
-      // Group messages by session_id
-      const sessionMap = new Map()
-
-      response.data.messages.forEach(msg => {
-        if (!msg.session_id) return
-
-        if (!sessionMap.has(msg.session_id)) {
-          sessionMap.set(msg.session_id, {
-            session_id: msg.session_id,
-            first_message: msg.content,
-            created_at: msg.created_at,
-            messages: []
-          })
-        }
-        sessionMap.get(msg.session_id).messages.push(msg)
-      })
-
-      // Convert to array and calculate stats
-      const sessions = Array.from(sessionMap.values()).map(session => ({
-        session_id: session.session_id,
-        title: session.first_message.substring(0, 30) +
-               (session.first_message.length > 30 ? '...' : ''),
-        message_count: session.messages.length,
-        last_message_at: session.messages[session.messages.length - 1].created_at,
-        created_at: session.created_at
-      }))
-
-      // Sort by last message time (newest first)
-      return sessions.sort((a, b) =>
-        new Date(b.last_message_at) - new Date(a.last_message_at)
-      ).slice(0, limit)
+      return response.data.conversations.map(toSession)
     } catch (error) {
       console.error('Failed to get sessions list:', error)
       return []
     }
   },
 
+  /**
+   * แก้ไข session (title, archived, pinned, tags)
+   * @param {string} sessionId - Session ID
+   * @param {Object} changes - fields ที่จะเปลี่ยน
+   * @returns {Promise<Object>} - session ที่แก้ไขแล้ว
+   */
+  async updateSession(sessionId, changes) {
+    const response = await apiClient.patch(`/api/conversations/${sessionId}`, changes)
+    return toSession(response.data)
+  },
+
+  /**
+   * สร้างชื่อ session ใหม่จากคำถามแรกด้วย LLM
+   * @param {string} sessionId - Session ID
+   * @returns {Promise<Object>} - session ที่มีชื่อใหม่
+   */
+  async generateTitle(sessionId) {
+    const response = await apiClient.post(`/api/conversations/${sessionId}/title`)
+    return toSession(response.data)
+  },
+
+  /**
+   * ค้นหาข้อความในประวัติแชททุก session
+   * @param {string} query - คำค้น
+   * @param {Object} filters - { mode, persona_id, session_id, role, from, to, limit, offset }
+   * @returns {Promise<Object>} - { results, total, ... } (snippet เป็น HTML ที่ escape แล้ว)
+   */
+  async searchMessages(query, filters = {}) {
+    const response = await apiClient.get('/api/chats/search', {
+      params: { q: query, ...filters }
+    })
+    return response.data
+  },
+
   /**
    * ดู messages ของ session เฉพาะ
    * @param {string} sessionId - Session ID
@@ -64,6 +69,16 @@ export const sessionService = {
    * @returns {Promise}
    */
   deleteSession(sessionId) {
-    return apiClient.delete(`/api/chats/session/${sessionId}`)
+    return apiClient.delete(`/api/conversations/${sessionId}`)
+  }
+}
+
+// แปลง conversation จาก API เป็น session ที่ sidebar ใช้
+function toSession(conversation) {
+  return {
+    ...conversation,
+    session_id: conversation.id,
+    title: conversation.title || 'New chat',
+    last_message_at: conversation.last_message_at || conversation.updated_at
   }
 }
@@ -1,6 +1,7 @@
 import { defineStore } from 'pinia'
 import { ref } from 'vue'
 import { chatService } from '@/api/chatService'
+import { modelService } from '@/api/modelService'
 import { AI_PROVIDERS, getRecommendedModel } from '@/config/aiModels'
 
 export const useChatStore = defineStore('chat', () => {
@@ -11,14 +12,30 @@ export const useChatStore = defineStore('chat', () => {
   const webSocket = ref(null)
   const isConnected = ref(false)
   const currentStreamingMessage = ref(null)
+  const leafId = ref('')  // Last saved message of the shown branch (parent_id of the next message)
 
   // NEW: AI Provider and Model Selection State
   const selectedProvider = ref(AI_PROVIDERS.BEDROCK)  // default to Bedrock
-  const selectedModel = ref(getRecommendedModel(AI_PROVIDERS.BEDROCK).id)
+  const selectedModel = ref('')  // '' = provider default until the catalog is loaded
+  const models = ref([])  // Model catalog from GET /api/models
   const temperature = ref(0.7)
   const maxTokens = ref(2000)
 
   // Actions
+  // Add the assistant message that streaming frames are appended to
+  const startStreamingMessage = () => {
+    if (currentStreamingMessage.value) return
+    currentStreamingMessage.value = {
+      id: Date.now(),
+      role: 'assistant',
+      content: '',
+      reasoning: '',
+      timestamp: new Date().toISOString(),
+      isStreaming: true
+    }
+    messages.value.push(currentStreamingMessage.value)
+  }
+
   const connectWebSocket = () => {
     return new Promise((resolve, reject) => {
       try {
@@ -38,30 +55,39 @@ export const useChatStore = defineStore('chat', () => {
             return
           }
 
+          if (data.type === 'tool_call' || data.type === 'tool_result') {
+            // The server runs tools itself; the answer keeps streaming as chunks
+            console.log(`🔧 ${data.type}:`, data.tool_call?.name, data.content || data.tool_call?.arguments)
+            return
+          }
+
+          if (data.type === 'reasoning') {
+            // Thinking of reasoning models streams before the answer
+            startStreamingMessage()
+            currentStreamingMessage.value.reasoning += data.content
+            return
+          }
+
           if (data.type === 'chunk') {
             if (!data.done) {
               // Streaming in progress
-              if (!currentStreamingMessage.value) {
-                currentStreamingMessage.value = {
-                  id: Date.now(),
-                  role: 'assistant',
-                  content: data.content,
-                  timestamp: new Date().toISOString(),
-                  isStreaming: true
-                }
-                messages.value.push(currentStreamingMessage.value)
-              } else {
-                currentStreamingMessage.value.content += data.content
-              }
+              startStreamingMessage()
+              currentStreamingMessage.value.content += data.content
             } else {
               // Streaming complete
               if (currentStreamingMessage.value) {
                 currentStreamingMessage.value.isStreaming = false
                 currentStreamingMessage.value.message_id = data.message_id
                 currentStreamingMessage.value.tokens_used = data.tokens_used
+                currentStreamingMessage.value.citations = data.citations || []
               }
               currentStreamingMessage.value = null
               isLoading.value = false
+              if (data.message_id) {
+                // Reload the branch for the saved message IDs and the alternatives of each message
+                leafId.value = data.message_id
+                loadBranch(data.message_id).catch(() => {})
+              }
             }
           }
         }
@@ -92,11 +118,23 @@ export const useChatStore = defineStore('chat', () => {
   }
 
   // NEW: Actions for provider/model selection
+  const loadModels = async () => {
+    try {
+      const response = await modelService.getModels()
+      models.value = response.data.models || []
+      if (!selectedModel.value) {
+        setProvider(selectedProvider.value)
+      }
+    } catch (error) {
+      console.error('Failed to load model catalog:', error)
+    }
+  }
+
   const setProvider = (provider) => {
     selectedProvider.value = provider
     // Auto-select recommended model for this provider
-    const recommended = getRecommendedModel(provider)
-    selectedModel.value = recommended.id
+    const recommended = getRecommendedModel(models.value, provider)
+    selectedModel.value = recommended ? recommended.id : ''
   }
 
   const setModel = (modelId) => {
@@ -111,6 +149,68 @@ export const useChatStore = defineStore('chat', () => {
     maxTokens.value = tokens
   }
 
+  // Settings sent with every chat message
+  const generationSettings = () => ({
+    provider: selectedProvider.value,
+    model: selectedModel.value,
+    temperature: temperature.value,
+    max_tokens: maxTokens.value
+  })
+
+  // Load the branch through messageId (the latest branch if empty) into the chat
+  const loadBranch = async (messageId = '') => {
+    const response = await chatService.getBranch(sessionId.value, messageId)
+    leafId.value = response.data.leaf_id || ''
+    // Tool results and tool-call-only turns are replayed to the model, not shown
+    messages.value = (response.data.messages || [])
+      .filter((m) => m.role !== 'tool' && !(m.tool_calls?.length && !m.content))
+      .map((m) => ({ ...m, timestamp: m.created_at }))
+    return response.data
+  }
+
+  // Show another alternative of a message (offset -1 = previous, 1 = next)
+  const switchBranch = async (message, offset) => {
+    const siblings = message.branch?.sibling_ids || []
+    const target = siblings[message.branch.index + offset]
+    if (!target || isLoading.value) return
+    try {
+      await loadBranch(target)
+    } catch (error) {
+      console.error('Failed to switch branch:', error)
+    }
+  }
+
+  // Send an edit or regenerate request for a saved message over the WebSocket
+  const sendBranchRequest = async (data) => {
+    if (!isConnected.value) {
+      await connectWebSocket()
+    }
+    isLoading.value = true
+    webSocket.value.send(JSON.stringify({ ...data, session_id: sessionId.value, ...generationSettings() }))
+  }
+
+  // Edit a user message: the new version and its answer go on a new branch
+  const editMessage = async (message, content, personaId) => {
+    if (!content.trim() || isLoading.value) return
+    const index = messages.value.findIndex((m) => m.id === message.id)
+    messages.value = messages.value.slice(0, Math.max(index, 0))
+    messages.value.push({
+      id: Date.now(),
+      role: 'user',
+      content: content,
+      timestamp: new Date().toISOString()
+    })
+    await sendBranchRequest({ type: 'edit', message_id: message.id, content: content, persona_id: personaId })
+  }
+
+  // Answer the user message before an assistant reply again on a new branch
+  const regenerateMessage = async (message, personaId) => {
+    if (isLoading.value) return
+    const index = messages.value.findIndex((m) => m.id === message.id)
+    messages.value = messages.value.slice(0, Math.max(index, 0))
+    await sendBranchRequest({ type: 'regenerate', message_id: message.id, persona_id: personaId })
+  }
+
   const sendMessage = async (content, personaId, fileIds = []) => {
     if (!content.trim() && fileIds.length === 0) {
       return
@@ -139,10 +239,8 @@ export const useChatStore = defineStore('chat', () => {
       content: content,
       persona_id: personaId,
       session_id: sessionId.value,
-      provider: selectedProvider.value,
-      model: selectedModel.value,
-      temperature: temperature.value,
-      max_tokens: maxTokens.value,
+      parent_id: leafId.value,  // Continue the branch on screen
+      ...generationSettings(),
       file_ids: fileIds
     }
 
@@ -167,6 +265,7 @@ export const useChatStore = defineStore('chat', () => {
     try {
       await chatService.deleteAllMessages()
       messages.value = []
+      leafId.value = ''
       sessionId.value = `session_${Date.now()}`
     } catch (error) {
       console.error('Failed to clear chat history:', error)
@@ -176,6 +275,7 @@ export const useChatStore = defineStore('chat', () => {
 
   const resetSession = () => {
     messages.value = []
+    leafId.value = ''
     sessionId.value = `session_${Date.now()}`
     currentStreamingMessage.value = null
     isLoading.value = false
@@ -183,6 +283,7 @@ export const useChatStore = defineStore('chat', () => {
 
   const newChat = () => {
     messages.value = []
+    leafId.value = ''
     sessionId.value = `session_${Date.now()}`
     currentStreamingMessage.value = null
     isLoading.value = false
@@ -196,10 +297,12 @@ export const useChatStore = defineStore('chat', () => {
     webSocket,
     isConnected,
     currentStreamingMessage,
+    leafId,
 
     // NEW: AI Provider and Model Selection State
     selectedProvider,
     selectedModel,
+    models,
     temperature,
     maxTokens,
 
@@ -212,7 +315,14 @@ export const useChatStore = defineStore('chat', () => {
     resetSession,
     newChat,
 
+    // Conversation branching
+    loadBranch,
+    switchBranch,
+    editMessage,
+    regenerateMessage,
+
     // NEW: Provider/Model Selection Actions
+    loadModels,
     setProvider,
     setModel,
     setTemperature,
@@ -9,43 +9,17 @@ export const useSessionStore = defineStore('sessions', () => {
   // State
   const sessions = ref([])
   const isLoading = ref(false)
-  const sessionTitles = ref({}) // เก็บ custom titles ใน memory
 
   // Computed
   const sortedSessions = computed(() => sessions.value)
 
-  // Load custom titles from localStorage
-  const loadCustomTitles = () => {
-    try {
-      const saved = localStorage.getItem('session_titles')
-      if (saved) {
-        sessionTitles.value = JSON.parse(saved)
-      }
-    } catch (error) {
-      console.error('Failed to load custom titles:', error)
-      sessionTitles.value = {}
-    }
-  }
-
-  // Save custom titles to localStorage
-  const saveCustomTitles = () => {
-    try {
-      localStorage.setItem('session_titles', JSON.stringify(sessionTitles.value))
-    } catch (error) {
-      console.error('Failed to save custom titles:', error)
-    }
-  }
-
-  // Get title for session (custom or auto-generated)
-  const getSessionTitle = (session) => {
-    return sessionTitles.value[session.session_id] || session.title
-  }
+  // Get title for session (set by the user or generated from the first exchange)
+  const getSessionTitle = (session) => session.title
 
   // Actions
   const loadSessions = async () => {
     try {
       isLoading.value = true
-      loadCustomTitles()
       sessions.value = await sessionService.getSessionsList(50)
     } catch (error) {
       console.error('Failed to load sessions:', error)
@@ -57,9 +31,9 @@ export const useSessionStore = defineStore('sessions', () => {
 
   const switchToSession = async (sessionId) => {
     try {
-      const response = await sessionService.getSessionMessages(sessionId)
       chatStore.sessionId = sessionId
-      chatStore.messages = response.data.messages || []
+      // Show the most recent branch; edited and regenerated alternatives are reached from the bubbles
+      await chatStore.loadBranch()
     } catch (error) {
       console.error('Failed to switch session:', error)
       throw error
@@ -71,14 +45,40 @@ export const useSessionStore = defineStore('sessions', () => {
     // Reload sessions list after user sends first message
   }
 
-  const renameSession = (sessionId, newTitle) => {
-    sessionTitles.value[sessionId] = newTitle
-    saveCustomTitles()
+  // Apply an updated session to the sessions array
+  const replaceSession = (updated) => {
+    const index = sessions.value.findIndex(s => s.session_id === updated.session_id)
+    if (index !== -1) {
+      sessions.value[index] = updated
+    }
+  }
 
-    // Update in sessions array
-    const session = sessions.value.find(s => s.session_id === sessionId)
-    if (session) {
-      session.title = newTitle
+  const renameSession = async (sessionId, newTitle) => {
+    try {
+      replaceSession(await sessionService.updateSession(sessionId, { title: newTitle }))
+    } catch (error) {
+      console.error('Failed to rename session:', error)
+      throw error
+    }
+  }
+
+  const setSessionPinned = async (sessionId, pinned) => {
+    try {
+      replaceSession(await sessionService.updateSession(sessionId, { pinned }))
+      await loadSessions() // pinned sessions are listed first
+    } catch (error) {
+      console.error('Failed to pin session:', error)
+      throw error
+    }
+  }
+
+  const archiveSession = async (sessionId) => {
+    try {
+      await sessionService.updateSession(sessionId, { archived: true })
+      sessions.value = sessions.value.filter(s => s.session_id !== sessionId)
+    } catch (error) {
+      console.error('Failed to archive session:', error)
+      throw error
     }
   }
 
@@ -87,10 +87,6 @@ export const useSessionStore = defineStore('sessions', () => {
       await sessionService.deleteSession(sessionId)
       sessions.value = sessions.value.filter(s => s.session_id !== sessionId)
 
-      // Remove custom title
-      delete sessionTitles.value[sessionId]
-      saveCustomTitles()
-
       // If deleted active session, create new
       if (chatStore.sessionId === sessionId) {
         createNewChat()
@@ -109,6 +105,8 @@ export const useSessionStore = defineStore('sessions', () => {
     switchToSession,
     createNewChat,
     renameSession,
+    setSessionPinned,
+    archiveSession,
     deleteSession,
     getSessionTitle
   }